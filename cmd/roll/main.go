@@ -1,21 +1,246 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"log"
-	"math/rand"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Domo929/roll/pkg/rolls"
 )
 
+var (
+	adv     = flag.Bool("adv", false, "roll a d20 with advantage, followed by an optional [+/-]modifier")
+	dis     = flag.Bool("dis", false, "roll a d20 with disadvantage, followed by an optional [+/-]modifier")
+	portent = flag.Int("portent", 0, "replace the kept d20 with this pre-rolled value")
+	forward = flag.Bool("take-forward", false, "apply and use up banked forward and ongoing modifiers on the roll")
+	bless   = flag.Bool("bless", false, "add a 1d4 to the d20 roll")
+	bane    = flag.Bool("bane", false, "subtract a 1d4 from the d20 roll")
+	dialect = flag.String("dialect", "", "notation to read expressions in: default, roll20 or foundry")
+	full    = flag.Bool("full", false, "print every die of long rolls instead of the first and last few")
+	explain = flag.Bool("explain", false, "describe the expressions in plain English without rolling them")
+	reveal  = flag.Duration("reveal", 0, "print each die as it lands, waiting this long before each one, e.g. 200ms")
+	secret  = flag.Bool("secret", false, "roll the expressions secretly, printing only a commitment to reveal later")
+	color   = flag.String("color", "", "highlight expressions: auto, always or never")
+	a11y    = flag.Bool("a11y", false, "describe each roll in a plain sentence for screen readers, without brackets or symbols")
+	times   = flag.Int("n", 1, "roll each expression this many times")
+	summary = flag.Bool("summary", false, "follow each expression's rolls with a sparkline of their totals and a five-number summary")
+	quiet   = flag.Bool("quiet", false, "print no rolls or receipts, only their --summary")
+	plain   = flag.Bool("plain", false, "draw --summary sparklines in ASCII")
+	streaks = flag.Bool("streaks", false, "after rolling d20s, check the latest natural d20s in the history for an unusual run of 1s or 20s")
+	profile = flag.String("profile", "", "only allow the dice and notation of a system profile, e.g. pbta, 5e or fudge, or one from the config")
+)
+
 func main() {
+	if err := rolls.ApplyDefaults(flag.CommandLine, "roll"); err != nil {
+		log.Fatal(err)
+	}
 	flag.Parse()
-	rand.Seed(time.Now().UnixNano())
+	if *full {
+		rolls.TruncateDice = 0
+	}
+
+	if *adv || *dis || *portent != 0 {
+		modifier, err := d20Modifier(flag.CommandLine, flag.Args())
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *forward {
+			banked, used, err := rolls.TakeForward()
+			if err != nil {
+				log.Fatal(err)
+			}
+			for _, m := range used {
+				fmt.Println("Taken:", m)
+			}
+			modifier += banked
+		}
+		e := &rolls.Expression{Groups: []rolls.Group{{Dice: rolls.D20(modifier, *adv, *dis)}}}
+		prof, err := rolls.LoadProfile(*profile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := prof.Check(e); err != nil {
+			log.Fatal(err)
+		}
+		e, err = rolls.AddBlessBane(e, *bless, *bane)
+		if err != nil {
+			log.Fatal(err)
+		}
+		e, notes, err := rolls.ApplyConditions(e)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, n := range notes {
+			fmt.Println(n)
+		}
+		res := rollD20(e, *adv, *dis)
+		if *portent != 0 {
+			if *portent < 1 || *portent > 20 {
+				log.Fatalf("portent value %d must be between 1 and 20", *portent)
+			}
+			if err := res.Substitute(*portent); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if *a11y {
+			fmt.Println(res.Accessible())
+		} else {
+			fmt.Println(res)
+		}
+		if err := rolls.AppendHistory(res); err != nil {
+			log.Println("could not write history:", err)
+		}
+		if *streaks {
+			s, err := rolls.CheckStreak()
+			if err != nil {
+				log.Println("could not check for streaks:", err)
+			} else if s != nil {
+				fmt.Println(s)
+			}
+		}
+		return
+	}
+
+	if flag.Arg(0) == "serve" {
+		if err := serve(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *explain {
+		for _, expr := range flag.Args() {
+			text, err := rolls.Explain(expr)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println(text)
+		}
+		return
+	}
+
+	if *reveal > 0 {
+		if *secret {
+			log.Fatal("--reveal cannot be combined with --secret")
+		}
+		if err := revealDice(flag.Args(), *reveal); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
 	if len(flag.Args()) == 0 {
 		log.Fatal("need to provide 'age [+/-]modifier or a list of die rolls (3d6, 2d8, etc)")
 	}
 
-	rolls.Roll(flag.Args())
+	args := flag.Args()
+	if *bless {
+		args = append(args, "--bless")
+	}
+	if *bane {
+		args = append(args, "--bane")
+	}
+	if *dialect != "" {
+		args = append(args, "--dialect", *dialect)
+	}
+	if *color != "" {
+		args = append(args, "--color", *color)
+	}
+	if *a11y {
+		args = append(args, "--a11y")
+	}
+	if *times != 1 {
+		args = append(args, "-n", strconv.Itoa(*times))
+	}
+	if *summary {
+		args = append(args, "--summary")
+	}
+	if *quiet {
+		args = append(args, "--quiet")
+	}
+	if *plain {
+		args = append(args, "--plain")
+	}
+	if *streaks {
+		args = append(args, "--streaks")
+	}
+	if *profile != "" {
+		args = append(args, "--profile", *profile)
+	}
+	if *forward {
+		args = append(args, "--take-forward")
+	}
+	if *secret {
+		args = append(args, "--secret")
+	}
+	if err := rolls.Roll(args); err != nil {
+		log.Println(err)
+		if errors.Is(err, rolls.ErrMacroTest) {
+			os.Exit(1)
+		}
+	}
+}
+
+// d20Modifier reads the optional modifier after --adv, --dis or --portent
+// from args and parses the flags after it with fs, as the flag package stops
+// at the modifier in "roll --adv 5 --bless". Anything else left over is an
+// error rather than ignored.
+func d20Modifier(fs *flag.FlagSet, args []string) (int, error) {
+	if len(args) == 0 {
+		return 0, nil
+	}
+	modifier, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, err
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return 0, err
+	}
+	if fs.NArg() > 0 {
+		return 0, fmt.Errorf("unexpected arguments after the modifier %d: %s", modifier, strings.Join(fs.Args(), " "))
+	}
+	return modifier, nil
+}
+
+// rollD20 rolls e, the d20 roll built from the flags, noting when advantage
+// and disadvantage cancelled out as RollD20 does.
+func rollD20(e *rolls.Expression, adv, dis bool) *rolls.Result {
+	res := rolls.RollExpression(e)
+	if adv && dis {
+		res.Expression += " (advantage cancelled)"
+	}
+	return res
+}
+
+// revealDice rolls every expression, printing each die on its own line as
+// it lands and then the grand total.
+func revealDice(exprs []string, delay time.Duration) error {
+	if len(exprs) == 0 {
+		return fmt.Errorf("need to provide die rolls to reveal (3d6, 2d8, etc)")
+	}
+	total := 0
+	results := make([]*rolls.Result, 0, len(exprs))
+	for _, expr := range exprs {
+		e, err := rolls.ParseExpression(expr)
+		if err != nil {
+			return err
+		}
+		res := rolls.RollExpressionWithObserver(e, func(i, value int) {
+			time.Sleep(delay)
+			fmt.Printf("%s #%d: %d\n", expr, i+1, value)
+		})
+		fmt.Println(res)
+		results = append(results, res)
+		total += res.Total
+	}
+	if err := rolls.AppendHistory(results...); err != nil {
+		log.Println("could not write history:", err)
+	}
+	fmt.Println("total: ", total)
+	return nil
 }
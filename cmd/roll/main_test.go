@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Domo929/roll/pkg/rolls"
+)
+
+func TestD20Modifier(t *testing.T) {
+	tests := []struct {
+		args     []string
+		modifier int
+		bless    bool
+		err      bool
+	}{
+		{nil, 0, false, false},
+		{[]string{"5"}, 5, false, false},
+		{[]string{"-2"}, -2, false, false},
+		{[]string{"5", "--bless"}, 5, true, false},
+		{[]string{"+3", "-bless"}, 3, true, false},
+		{[]string{"five"}, 0, false, true},
+		{[]string{"5", "3d6"}, 0, false, true},
+		{[]string{"5", "--bless", "extra"}, 0, true, true},
+		{[]string{"5", "--nope"}, 0, false, true},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("roll", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		bless := fs.Bool("bless", false, "")
+		modifier, err := d20Modifier(fs, tt.args)
+		if (err != nil) != tt.err {
+			t.Errorf("d20Modifier(%q) error = %v, want error %v", tt.args, err, tt.err)
+			continue
+		}
+		if err == nil && modifier != tt.modifier {
+			t.Errorf("d20Modifier(%q) = %d, want %d", tt.args, modifier, tt.modifier)
+		}
+		if *bless != tt.bless {
+			t.Errorf("d20Modifier(%q) set --bless to %v, want %v", tt.args, *bless, tt.bless)
+		}
+	}
+}
+
+func TestRollD20(t *testing.T) {
+	tests := []struct {
+		adv, dis, bless bool
+		cancelled       bool
+	}{
+		{true, false, false, false},
+		{true, true, false, true},
+		{true, true, true, true},
+		{false, true, true, false},
+	}
+	for _, tt := range tests {
+		e := &rolls.Expression{Groups: []rolls.Group{{Dice: rolls.D20(5, tt.adv, tt.dis)}}}
+		e, err := rolls.AddBlessBane(e, tt.bless, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := rollD20(e, tt.adv, tt.dis)
+		if got := strings.HasSuffix(res.Expression, " (advantage cancelled)"); got != tt.cancelled {
+			t.Errorf("adv %v, dis %v, bless %v rolled %q, want the cancelled note %v", tt.adv, tt.dis, tt.bless, res.Expression, tt.cancelled)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/Domo929/roll/pkg/server"
+)
+
+func serve(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	grpcAddr := fs.String("grpc", "", "address to also serve the gRPC API on, such as :9090")
+	ttl := fs.Duration("idempotency-ttl", server.DefaultIdempotencyTTL, "how long responses are replayed for an Idempotency-Key")
+	rate := fs.Float64("rate", server.DefaultRequestsPerSecond, "requests per second allowed per client IP, negative to disable")
+	burst := fs.Int("burst", server.DefaultBurst, "requests a client IP may make at once")
+	maxDice := fs.Int("max-dice", server.DefaultMaxDice, "most dice a single request may roll")
+	maxSides := fs.Int("max-sides", server.DefaultMaxSides, "most sides a die may have")
+	var webhooks stringList
+	fs.Var(&webhooks, "webhook", "URL every roll is POSTed to, may be repeated")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	srv := server.New(server.Options{
+		IdempotencyTTL:    *ttl,
+		RequestsPerSecond: *rate,
+		Burst:             *burst,
+		MaxDice:           *maxDice,
+		MaxSides:          *maxSides,
+		Webhooks:          webhooks,
+	})
+	errs := make(chan error, 2)
+	if *grpcAddr != "" {
+		lis, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			return err
+		}
+		log.Printf("serving gRPC rolls on %s", *grpcAddr)
+		go func() { errs <- srv.NewGRPC().Serve(lis) }()
+	}
+	log.Printf("serving rolls on %s", *addr)
+	go func() { errs <- http.ListenAndServe(*addr, srv) }()
+	return <-errs
+}
+
+// stringList is a flag that collects every value it is given.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
@@ -0,0 +1,38 @@
+//go:build js && wasm
+
+// Command rollwasm exposes the roller to JavaScript as a global
+// roll(expr) function returning the result as a JSON string, or
+// {"error": "..."} when expr cannot be rolled.
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+
+	"github.com/Domo929/roll/pkg/rolls"
+)
+
+func main() {
+	js.Global().Set("roll", js.FuncOf(roll))
+	select {}
+}
+
+func roll(this js.Value, args []js.Value) interface{} {
+	if len(args) != 1 || args[0].Type() != js.TypeString {
+		return errorJSON("roll takes a single expression string")
+	}
+	res, err := rolls.RollString(args[0].String())
+	if err != nil {
+		return errorJSON(err.Error())
+	}
+	raw, err := json.Marshal(res)
+	if err != nil {
+		return errorJSON(err.Error())
+	}
+	return string(raw)
+}
+
+func errorJSON(msg string) string {
+	raw, _ := json.Marshal(map[string]string{"error": msg})
+	return string(raw)
+}
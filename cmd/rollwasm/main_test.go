@@ -0,0 +1,53 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"syscall/js"
+	"testing"
+
+	"github.com/Domo929/roll/pkg/rolls"
+)
+
+// TestRoll calls roll from JavaScript, as a character sheet would, and reads
+// its JSON back into a Result.
+func TestRoll(t *testing.T) {
+	js.Global().Set("roll", js.FuncOf(roll))
+	defer js.Global().Delete("roll")
+
+	out := js.Global().Call("roll", "3d1+2")
+	if out.Type() != js.TypeString {
+		t.Fatalf("roll(\"3d1+2\") returned a %s, want a string", out.Type())
+	}
+	var res rolls.Result
+	if err := json.Unmarshal([]byte(out.String()), &res); err != nil {
+		t.Fatalf("roll(\"3d1+2\") = %s: %v", out.String(), err)
+	}
+	if res.Total != 5 || len(res.Rolls) != 3 {
+		t.Errorf("roll(\"3d1+2\") = %s, want a total of 5 from three dice", out.String())
+	}
+}
+
+func TestRollErrors(t *testing.T) {
+	js.Global().Set("roll", js.FuncOf(roll))
+	defer js.Global().Delete("roll")
+
+	tests := []struct {
+		args []any
+		want string
+	}{
+		{[]any{"3d"}, "3d"},
+		{[]any{}, "single expression"},
+		{[]any{20}, "single expression"},
+		{[]any{"1d20", "1d4"}, "single expression"},
+	}
+	for _, tt := range tests {
+		out := js.Global().Call("roll", tt.args...).String()
+		var body struct{ Error string }
+		if err := json.Unmarshal([]byte(out), &body); err != nil || !strings.Contains(body.Error, tt.want) {
+			t.Errorf("roll%v = %s, want an error mentioning %q", tt.args, out, tt.want)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+// Package botcmd parses chat bot roll commands such as
+// "!roll 2d6 adv" or "!r 1d20+5 x3 # attack" and formats their results as
+// chat-ready markdown.
+package botcmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Domo929/roll/pkg/rolls"
+)
+
+// DefaultTriggers are the words a command may start with.
+var DefaultTriggers = []string{"!roll", "!r", "/roll", "/r"}
+
+// MaxRepeat bounds the repeat count of a command.
+const MaxRepeat = 20
+
+// ErrNoTrigger is returned for input that does not start with a trigger, which
+// bots should usually ignore silently.
+var ErrNoTrigger = errors.New("input does not start with a roll trigger")
+
+// Command is a parsed bot command.
+type Command struct {
+	Trigger      string
+	Expressions  []string
+	Advantage    bool
+	Disadvantage bool
+	Label        string
+	// Repeat is how many times every expression is rolled.
+	Repeat int
+}
+
+var keywords = map[string]string{
+	"adv":          "advantage",
+	"advantage":    "advantage",
+	"dis":          "disadvantage",
+	"disadvantage": "disadvantage",
+}
+
+// ParseCommand parses input with the DefaultTriggers.
+func ParseCommand(input string) (*Command, error) {
+	return ParseCommandWithTriggers(input, DefaultTriggers)
+}
+
+// ParseCommandWithTriggers parses input, which must start with one of
+// triggers. A command without expressions rolls 1d20. Everything after a #
+// is the label.
+func ParseCommandWithTriggers(input string, triggers []string) (*Command, error) {
+	body, label, _ := strings.Cut(input, "#")
+	words := strings.Fields(body)
+	if len(words) == 0 {
+		return nil, ErrNoTrigger
+	}
+
+	c := &Command{Label: strings.TrimSpace(label), Repeat: 1}
+	for _, t := range triggers {
+		if strings.EqualFold(words[0], t) {
+			c.Trigger = t
+			break
+		}
+	}
+	if c.Trigger == "" {
+		return nil, ErrNoTrigger
+	}
+
+	for _, w := range words[1:] {
+		lower := strings.ToLower(w)
+		switch keywords[lower] {
+		case "advantage":
+			c.Advantage = true
+			continue
+		case "disadvantage":
+			c.Disadvantage = true
+			continue
+		}
+
+		if n, ok := repeatCount(lower); ok {
+			if n < 1 || n > MaxRepeat {
+				return nil, fmt.Errorf("repeat count %s must be between x1 and x%d", w, MaxRepeat)
+			}
+			c.Repeat = n
+			continue
+		}
+
+		expr := lowerNotation(w)
+		if _, err := rolls.ParseExpression(expr); err != nil {
+			if s := suggest(lower); s != "" && !looksLikeDice(w) {
+				return nil, fmt.Errorf("unknown word %q, did you mean %q?", w, s)
+			}
+			return nil, fmt.Errorf("cannot roll %q: %w", w, err)
+		}
+		c.Expressions = append(c.Expressions, expr)
+	}
+	if len(c.Expressions) == 0 {
+		c.Expressions = []string{"1d20"}
+	}
+
+	return c, nil
+}
+
+func repeatCount(w string) (int, bool) {
+	if !strings.HasPrefix(w, "x") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(w[1:])
+	return n, err == nil
+}
+
+// lowerNotation lowercases the notation of w, so 1D20 rolls as 1d20, but
+// not the faces of custom dice between braces, as in d{Red,Blue}.
+func lowerNotation(w string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range w {
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			depth = max(depth-1, 0)
+		}
+		if depth == 0 {
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// looksLikeDice reports whether w has the digits or symbols of dice
+// notation, as 2d and 1d20+ do, so that a mistyped roll is reported as one
+// rather than as a mistyped keyword.
+func looksLikeDice(w string) bool {
+	return strings.ContainsAny(w, "0123456789+-*/(){}%")
+}
+
+// suggest returns the keyword w is most likely a typo of, if any.
+func suggest(w string) string {
+	best, bestDist := "", 3
+	for k, full := range keywords {
+		if d := distance(w, k); d < bestDist {
+			best, bestDist = full, d
+		}
+	}
+	return best
+}
+
+// distance is the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// Expression returns the expression expr, which may sum several dice
+// groups, with the command's advantage applied to every single d20 group.
+// Advantage and disadvantage together cancel out.
+func (c *Command) Expression(expr string) (*rolls.Expression, error) {
+	e, err := rolls.ParseExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range e.AllDice() {
+		if d.Count == 1 && d.Sides == 20 && c.Advantage != c.Disadvantage {
+			d.Count, d.Modifier, d.ModifierCount = 2, rolls.KeepHighest, 1
+			if c.Disadvantage {
+				d.Modifier = rolls.KeepLowest
+			}
+		}
+	}
+	return e, nil
+}
+
+// Execute rolls every expression Repeat times with r, in order.
+func (c *Command) Execute(r *rolls.Roller) ([]*rolls.Result, error) {
+	results := make([]*rolls.Result, 0, len(c.Expressions)*c.Repeat)
+	for i := 0; i < c.Repeat; i++ {
+		for _, expr := range c.Expressions {
+			e, err := c.Expression(expr)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, r.RollExpression(e))
+		}
+	}
+	return results, nil
+}
+
+// Format renders results as markdown, one line per result, with each group
+// of a sum in brackets, dropped dice struck through and totals in bold.
+func Format(c *Command, results []*rolls.Result) string {
+	var b strings.Builder
+	if c.Label != "" {
+		fmt.Fprintf(&b, "**%s**\n", c.Label)
+	}
+	if c.Advantage && c.Disadvantage {
+		b.WriteString("_advantage and disadvantage cancel out_\n")
+	}
+	for _, res := range results {
+		fmt.Fprintf(&b, "`%s`:", res.Expression)
+		if len(res.Groups) == 0 {
+			b.WriteString(" " + diceText(res))
+		}
+		b.WriteString(groupsText(res))
+		fmt.Fprintf(&b, " = **%s**\n", totalText(res))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// groupsText lists the groups of res in brackets, and its bonus, each after
+// a space. A group in parentheses or scaled is written in parentheses with
+// its own total, as in ([3 4] +2 = 9)*2.
+func groupsText(res *rolls.Result) string {
+	var b strings.Builder
+	for i, g := range res.Groups {
+		switch {
+		case g.Negative:
+			b.WriteString(" -")
+		case i > 0:
+			b.WriteString(" +")
+		}
+		if len(g.Groups) == 0 && len(g.Scale) == 0 {
+			b.WriteString(" [" + diceText(g) + "]")
+			continue
+		}
+		inner, total := "["+diceText(g)+"]", g.Total
+		if len(g.Groups) > 0 {
+			inner = strings.TrimPrefix(groupsText(g), " ")
+		}
+		steps := ""
+		for _, s := range g.Scale {
+			steps += s.String()
+		}
+		if len(g.Scale) > 0 {
+			total = g.Unscaled
+		}
+		fmt.Fprintf(&b, " (%s = %d)%s", inner, total, steps)
+	}
+	if res.Bonus != 0 {
+		fmt.Fprintf(&b, " %+d", res.Bonus)
+	}
+	return b.String()
+}
+
+// totalText is the total of res, or the count of each face a pool of
+// labeled dice landed on, as in 2 Red, 1 Blue.
+func totalText(res *rolls.Result) string {
+	if len(res.Tally) == 0 {
+		return strconv.Itoa(res.Total)
+	}
+	counts := make([]string, len(res.Tally))
+	for i, c := range res.Tally {
+		counts[i] = fmt.Sprintf("%d %s", c.Count, c.Label)
+	}
+	return strings.Join(counts, ", ")
+}
+
+// diceText lists the dice of res, dropped dice struck through and dice
+// raised to a minimum or lowered to a maximum followed by what they count
+// as. Labeled dice are listed by the faces they landed on.
+func diceText(res *rolls.Result) string {
+	if len(res.Faces) > 0 {
+		return strings.Join(res.Faces, " ")
+	}
+	dropped := res.DroppedMask()
+	dice := make([]string, len(res.Rolls))
+	for i, r := range res.Rolls {
+		dice[i] = strconv.Itoa(r)
+		switch {
+		case res.AdjustedRolls == nil || res.AdjustedRolls[i] == r:
+		case res.AdjustedRolls[i] > r:
+			dice[i] += "↑" + strconv.Itoa(res.AdjustedRolls[i])
+		default:
+			dice[i] += "↓" + strconv.Itoa(res.AdjustedRolls[i])
+		}
+		if dropped[i] {
+			dice[i] = "~~" + dice[i] + "~~"
+		}
+	}
+	return strings.Join(dice, " ")
+}
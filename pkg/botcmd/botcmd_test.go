@@ -0,0 +1,146 @@
+package botcmd
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Domo929/roll/pkg/rolls"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Command
+	}{
+		{"!r", Command{Trigger: "!r", Expressions: []string{"1d20"}, Repeat: 1}},
+		{"!ROLL 2d6 adv", Command{Trigger: "!roll", Expressions: []string{"2d6"}, Advantage: true, Repeat: 1}},
+		{"/r 1D20+5 DIS x3 # Attack roll", Command{Trigger: "/r", Expressions: []string{"1d20+5"}, Disadvantage: true, Label: "Attack roll", Repeat: 3}},
+		{"!r 1d8 2d6+1", Command{Trigger: "!r", Expressions: []string{"1d8", "2d6+1"}, Repeat: 1}},
+		{"!r d{Red,Blue} X2", Command{Trigger: "!r", Expressions: []string{"d{Red,Blue}"}, Repeat: 2}},
+		{"!r 3D{Hit,Miss}", Command{Trigger: "!r", Expressions: []string{"3d{Hit,Miss}"}, Repeat: 1}},
+	}
+	for _, tt := range tests {
+		got, err := ParseCommand(tt.input)
+		if err != nil {
+			t.Errorf("ParseCommand(%q): %v", tt.input, err)
+			continue
+		}
+		if got.Trigger != tt.want.Trigger || !slices.Equal(got.Expressions, tt.want.Expressions) ||
+			got.Advantage != tt.want.Advantage || got.Disadvantage != tt.want.Disadvantage ||
+			got.Label != tt.want.Label || got.Repeat != tt.want.Repeat {
+			t.Errorf("ParseCommand(%q) = %+v, want %+v", tt.input, *got, tt.want)
+		}
+	}
+}
+
+func TestParseCommandErrors(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"!r advantge", `did you mean "advantage"?`},
+		{"!r dsadv", `did you mean "advantage"?`},
+		{"!r disadvantag", `did you mean "disadvantage"?`},
+		// Mistyped rolls are reported as rolls, not as keywords.
+		{"!r 2d", `cannot roll "2d"`},
+		{"!r 1d", `cannot roll "1d"`},
+		{"!r 1d20+", `cannot roll "1d20+"`},
+		{"!r 1d20 x0", "repeat count x0 must be between x1 and x20"},
+		{"!r 1d20 x21", "repeat count x21 must be between x1 and x20"},
+	}
+	for _, tt := range tests {
+		_, err := ParseCommand(tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseCommand(%q) = %v, want an error containing %q", tt.input, err, tt.want)
+		}
+		if err != nil && strings.HasPrefix(tt.want, "cannot roll") && strings.Contains(err.Error(), "did you mean") {
+			t.Errorf("ParseCommand(%q) = %v, suggesting a keyword for dice", tt.input, err)
+		}
+	}
+
+	for _, input := range []string{"", "   ", "hello", "!rolls 1d20", "roll 1d20"} {
+		if _, err := ParseCommand(input); !errors.Is(err, ErrNoTrigger) {
+			t.Errorf("ParseCommand(%q) = %v, want ErrNoTrigger", input, err)
+		}
+	}
+	if c, err := ParseCommandWithTriggers("?dice 1d6", []string{"?dice"}); err != nil || c.Trigger != "?dice" {
+		t.Errorf("ParseCommandWithTriggers with a custom trigger = %+v, %v", c, err)
+	}
+}
+
+func TestExpressionAdvantage(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"!r 1d20+5 adv", "2d20kh1+5"},
+		{"!r 1d20+5 dis", "2d20kl1+5"},
+		{"!r 1d20+5 adv dis", "1d20+5"},
+		{"!r 1d20+5+3 adv", "2d20kh1+8"},
+		{"!r 1d20+1d4+2 adv", "2d20kh1+1d4+2"},
+		{"!r 2d20 adv", "2d20"},
+		{"!r 1d6 adv", "1d6"},
+	}
+	for _, tt := range tests {
+		c, err := ParseCommand(tt.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := c.Expression(c.Expressions[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := e.String(); got != tt.want {
+			t.Errorf("%s rolls %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+// TestFormat formats dice that can only roll one way, so the output is
+// exact.
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"!r 3d1+2", "`3d1+2`: 1 1 1 +2 = **5**"},
+		{"!r 1d1+5+3", "`1d1+8`: 1 +8 = **9**"},
+		{"!r 4d1kh2+3 # hit", "**hit**\n`4d1kh2+3`: 1 1 ~~1~~ ~~1~~ +3 = **5**"},
+		{"!r 2d1+1d1-1 x2", "`2d1+1d1-1`: [1 1] + [1] -1 = **2**\n`2d1+1d1-1`: [1 1] + [1] -1 = **2**"},
+		{"!r (2d1+3)*2", "`(2d1+3)*2`: ([1 1] +3 = 5)*2 = **10**"},
+		{"!r 1d1 adv dis", "_advantage and disadvantage cancel out_\n`1d1`: 1 = **1**"},
+		{"!r 2d{Red}", "`2d{Red}`: Red Red = **2 Red**"},
+		{"!r 1d1 2d1", "`1d1`: 1 = **1**\n`2d1`: 1 1 = **2**"},
+	}
+	for _, tt := range tests {
+		c, err := ParseCommand(tt.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results, err := c.Execute(rolls.NewRoller(rolls.WithSeed(1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Format(c, results); got != tt.want {
+			t.Errorf("Format(%s) =\n%s\nwant\n%s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"adv", "adv", 0},
+		{"adv", "", 3},
+		{"advantge", "advantage", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,696 @@
+// Protocol definition for the roll service. The messages mirror the Go types
+// in pkg/rolls.
+//
+// Generate Go stubs from the repository root with:
+//
+//   protoc --go_out=. --go_opt=module=github.com/Domo929/roll \
+//     --go-grpc_out=. --go-grpc_opt=module=github.com/Domo929/roll \
+//     proto/roll/v1/roll.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        v5.28.3
+// source: proto/roll/v1/roll.proto
+
+package rollpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type RollRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Expression string `protobuf:"bytes,1,opt,name=expression,proto3" json:"expression,omitempty"`
+	// seed, when set, makes the roll reproducible.
+	Seed *int64 `protobuf:"varint,2,opt,name=seed,proto3,oneof" json:"seed,omitempty"`
+}
+
+func (x *RollRequest) Reset() {
+	*x = RollRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_roll_v1_roll_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RollRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RollRequest) ProtoMessage() {}
+
+func (x *RollRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_roll_v1_roll_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RollRequest.ProtoReflect.Descriptor instead.
+func (*RollRequest) Descriptor() ([]byte, []int) {
+	return file_proto_roll_v1_roll_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RollRequest) GetExpression() string {
+	if x != nil {
+		return x.Expression
+	}
+	return ""
+}
+
+func (x *RollRequest) GetSeed() int64 {
+	if x != nil && x.Seed != nil {
+		return *x.Seed
+	}
+	return 0
+}
+
+// Substitution mirrors rolls.Substitution.
+type Substitution struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Original int64 `protobuf:"varint,1,opt,name=original,proto3" json:"original,omitempty"`
+	Value    int64 `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (x *Substitution) Reset() {
+	*x = Substitution{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_roll_v1_roll_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Substitution) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Substitution) ProtoMessage() {}
+
+func (x *Substitution) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_roll_v1_roll_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Substitution.ProtoReflect.Descriptor instead.
+func (*Substitution) Descriptor() ([]byte, []int) {
+	return file_proto_roll_v1_roll_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Substitution) GetOriginal() int64 {
+	if x != nil {
+		return x.Original
+	}
+	return 0
+}
+
+func (x *Substitution) GetValue() int64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+// RollSummary mirrors rolls.RollSummary.
+type RollSummary struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Count int64   `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	Min   int64   `protobuf:"varint,2,opt,name=min,proto3" json:"min,omitempty"`
+	Max   int64   `protobuf:"varint,3,opt,name=max,proto3" json:"max,omitempty"`
+	Mean  float64 `protobuf:"fixed64,4,opt,name=mean,proto3" json:"mean,omitempty"`
+}
+
+func (x *RollSummary) Reset() {
+	*x = RollSummary{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_roll_v1_roll_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RollSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RollSummary) ProtoMessage() {}
+
+func (x *RollSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_roll_v1_roll_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RollSummary.ProtoReflect.Descriptor instead.
+func (*RollSummary) Descriptor() ([]byte, []int) {
+	return file_proto_roll_v1_roll_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RollSummary) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *RollSummary) GetMin() int64 {
+	if x != nil {
+		return x.Min
+	}
+	return 0
+}
+
+func (x *RollSummary) GetMax() int64 {
+	if x != nil {
+		return x.Max
+	}
+	return 0
+}
+
+func (x *RollSummary) GetMean() float64 {
+	if x != nil {
+		return x.Mean
+	}
+	return 0
+}
+
+// RollResult mirrors rolls.Result.
+type RollResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Expression  string          `protobuf:"bytes,1,opt,name=expression,proto3" json:"expression,omitempty"`
+	Sides       int64           `protobuf:"varint,2,opt,name=sides,proto3" json:"sides,omitempty"`
+	Rolls       []int64         `protobuf:"varint,3,rep,packed,name=rolls,proto3" json:"rolls,omitempty"`
+	Kept        []int64         `protobuf:"varint,4,rep,packed,name=kept,proto3" json:"kept,omitempty"`
+	Dropped     []int64         `protobuf:"varint,5,rep,packed,name=dropped,proto3" json:"dropped,omitempty"`
+	Bonus       int64           `protobuf:"varint,6,opt,name=bonus,proto3" json:"bonus,omitempty"`
+	Total       int64           `protobuf:"varint,7,opt,name=total,proto3" json:"total,omitempty"`
+	Successes   int64           `protobuf:"varint,8,opt,name=successes,proto3" json:"successes,omitempty"`
+	Substituted []*Substitution `protobuf:"bytes,9,rep,name=substituted,proto3" json:"substituted,omitempty"`
+	Summarized  bool            `protobuf:"varint,10,opt,name=summarized,proto3" json:"summarized,omitempty"`
+	Summary     *RollSummary    `protobuf:"bytes,11,opt,name=summary,proto3" json:"summary,omitempty"`
+	// groups holds the result of each dice group of a sum such as
+	// "2d6+1d8+3", or of a parenthesized one, as rolls.Result.Groups does.
+	Groups   []*RollResult `protobuf:"bytes,12,rep,name=groups,proto3" json:"groups,omitempty"`
+	Negative bool          `protobuf:"varint,13,opt,name=negative,proto3" json:"negative,omitempty"`
+	Label    string        `protobuf:"bytes,14,opt,name=label,proto3" json:"label,omitempty"`
+}
+
+func (x *RollResult) Reset() {
+	*x = RollResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_roll_v1_roll_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RollResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RollResult) ProtoMessage() {}
+
+func (x *RollResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_roll_v1_roll_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RollResult.ProtoReflect.Descriptor instead.
+func (*RollResult) Descriptor() ([]byte, []int) {
+	return file_proto_roll_v1_roll_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RollResult) GetExpression() string {
+	if x != nil {
+		return x.Expression
+	}
+	return ""
+}
+
+func (x *RollResult) GetSides() int64 {
+	if x != nil {
+		return x.Sides
+	}
+	return 0
+}
+
+func (x *RollResult) GetRolls() []int64 {
+	if x != nil {
+		return x.Rolls
+	}
+	return nil
+}
+
+func (x *RollResult) GetKept() []int64 {
+	if x != nil {
+		return x.Kept
+	}
+	return nil
+}
+
+func (x *RollResult) GetDropped() []int64 {
+	if x != nil {
+		return x.Dropped
+	}
+	return nil
+}
+
+func (x *RollResult) GetBonus() int64 {
+	if x != nil {
+		return x.Bonus
+	}
+	return 0
+}
+
+func (x *RollResult) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *RollResult) GetSuccesses() int64 {
+	if x != nil {
+		return x.Successes
+	}
+	return 0
+}
+
+func (x *RollResult) GetSubstituted() []*Substitution {
+	if x != nil {
+		return x.Substituted
+	}
+	return nil
+}
+
+func (x *RollResult) GetSummarized() bool {
+	if x != nil {
+		return x.Summarized
+	}
+	return false
+}
+
+func (x *RollResult) GetSummary() *RollSummary {
+	if x != nil {
+		return x.Summary
+	}
+	return nil
+}
+
+func (x *RollResult) GetGroups() []*RollResult {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
+func (x *RollResult) GetNegative() bool {
+	if x != nil {
+		return x.Negative
+	}
+	return false
+}
+
+func (x *RollResult) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+type SimulateRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Expression string `protobuf:"bytes,1,opt,name=expression,proto3" json:"expression,omitempty"`
+	Trials     int64  `protobuf:"varint,2,opt,name=trials,proto3" json:"trials,omitempty"`
+	Seed       *int64 `protobuf:"varint,3,opt,name=seed,proto3,oneof" json:"seed,omitempty"`
+}
+
+func (x *SimulateRequest) Reset() {
+	*x = SimulateRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_roll_v1_roll_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SimulateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SimulateRequest) ProtoMessage() {}
+
+func (x *SimulateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_roll_v1_roll_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SimulateRequest.ProtoReflect.Descriptor instead.
+func (*SimulateRequest) Descriptor() ([]byte, []int) {
+	return file_proto_roll_v1_roll_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SimulateRequest) GetExpression() string {
+	if x != nil {
+		return x.Expression
+	}
+	return ""
+}
+
+func (x *SimulateRequest) GetTrials() int64 {
+	if x != nil {
+		return x.Trials
+	}
+	return 0
+}
+
+func (x *SimulateRequest) GetSeed() int64 {
+	if x != nil && x.Seed != nil {
+		return *x.Seed
+	}
+	return 0
+}
+
+type HistogramBucket struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Total int64 `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	Count int64 `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+}
+
+func (x *HistogramBucket) Reset() {
+	*x = HistogramBucket{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_roll_v1_roll_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HistogramBucket) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistogramBucket) ProtoMessage() {}
+
+func (x *HistogramBucket) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_roll_v1_roll_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistogramBucket.ProtoReflect.Descriptor instead.
+func (*HistogramBucket) Descriptor() ([]byte, []int) {
+	return file_proto_roll_v1_roll_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *HistogramBucket) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *HistogramBucket) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+var File_proto_roll_v1_roll_proto protoreflect.FileDescriptor
+
+var file_proto_roll_v1_roll_proto_rawDesc = []byte{
+	0x0a, 0x18, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x72, 0x6f, 0x6c, 0x6c, 0x2f, 0x76, 0x31, 0x2f,
+	0x72, 0x6f, 0x6c, 0x6c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x07, 0x72, 0x6f, 0x6c, 0x6c,
+	0x2e, 0x76, 0x31, 0x22, 0x4f, 0x0a, 0x0b, 0x52, 0x6f, 0x6c, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x65, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x65, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69,
+	0x6f, 0x6e, 0x12, 0x17, 0x0a, 0x04, 0x73, 0x65, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03,
+	0x48, 0x00, 0x52, 0x04, 0x73, 0x65, 0x65, 0x64, 0x88, 0x01, 0x01, 0x42, 0x07, 0x0a, 0x05, 0x5f,
+	0x73, 0x65, 0x65, 0x64, 0x22, 0x40, 0x0a, 0x0c, 0x53, 0x75, 0x62, 0x73, 0x74, 0x69, 0x74, 0x75,
+	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x6c,
+	0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x5b, 0x0a, 0x0b, 0x52, 0x6f, 0x6c, 0x6c, 0x53, 0x75,
+	0x6d, 0x6d, 0x61, 0x72, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x6d,
+	0x69, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x03, 0x6d, 0x69, 0x6e, 0x12, 0x10, 0x0a,
+	0x03, 0x6d, 0x61, 0x78, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x03, 0x6d, 0x61, 0x78, 0x12,
+	0x12, 0x0a, 0x04, 0x6d, 0x65, 0x61, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x04, 0x6d,
+	0x65, 0x61, 0x6e, 0x22, 0xb8, 0x03, 0x0a, 0x0a, 0x52, 0x6f, 0x6c, 0x6c, 0x52, 0x65, 0x73, 0x75,
+	0x6c, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x65, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x65, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69,
+	0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x69, 0x64, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x05, 0x73, 0x69, 0x64, 0x65, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x72, 0x6f, 0x6c, 0x6c,
+	0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x03, 0x52, 0x05, 0x72, 0x6f, 0x6c, 0x6c, 0x73, 0x12, 0x12,
+	0x0a, 0x04, 0x6b, 0x65, 0x70, 0x74, 0x18, 0x04, 0x20, 0x03, 0x28, 0x03, 0x52, 0x04, 0x6b, 0x65,
+	0x70, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x64, 0x72, 0x6f, 0x70, 0x70, 0x65, 0x64, 0x18, 0x05, 0x20,
+	0x03, 0x28, 0x03, 0x52, 0x07, 0x64, 0x72, 0x6f, 0x70, 0x70, 0x65, 0x64, 0x12, 0x14, 0x0a, 0x05,
+	0x62, 0x6f, 0x6e, 0x75, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x62, 0x6f, 0x6e,
+	0x75, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x18, 0x07, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x75, 0x63, 0x63,
+	0x65, 0x73, 0x73, 0x65, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x73, 0x75, 0x63,
+	0x63, 0x65, 0x73, 0x73, 0x65, 0x73, 0x12, 0x37, 0x0a, 0x0b, 0x73, 0x75, 0x62, 0x73, 0x74, 0x69,
+	0x74, 0x75, 0x74, 0x65, 0x64, 0x18, 0x09, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x72, 0x6f,
+	0x6c, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x75, 0x62, 0x73, 0x74, 0x69, 0x74, 0x75, 0x74, 0x69,
+	0x6f, 0x6e, 0x52, 0x0b, 0x73, 0x75, 0x62, 0x73, 0x74, 0x69, 0x74, 0x75, 0x74, 0x65, 0x64, 0x12,
+	0x1e, 0x0a, 0x0a, 0x73, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x69, 0x7a, 0x65, 0x64, 0x18, 0x0a, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x0a, 0x73, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x69, 0x7a, 0x65, 0x64, 0x12,
+	0x2e, 0x0a, 0x07, 0x73, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x14, 0x2e, 0x72, 0x6f, 0x6c, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x6f, 0x6c, 0x6c, 0x53,
+	0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x52, 0x07, 0x73, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x12,
+	0x2b, 0x0a, 0x06, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x18, 0x0c, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x13, 0x2e, 0x72, 0x6f, 0x6c, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x6f, 0x6c, 0x6c, 0x52, 0x65,
+	0x73, 0x75, 0x6c, 0x74, 0x52, 0x06, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x12, 0x1a, 0x0a, 0x08,
+	0x6e, 0x65, 0x67, 0x61, 0x74, 0x69, 0x76, 0x65, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08,
+	0x6e, 0x65, 0x67, 0x61, 0x74, 0x69, 0x76, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x61, 0x62, 0x65,
+	0x6c, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x22, 0x6b,
+	0x0a, 0x0f, 0x53, 0x69, 0x6d, 0x75, 0x6c, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x65, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x65, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f,
+	0x6e, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x72, 0x69, 0x61, 0x6c, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x06, 0x74, 0x72, 0x69, 0x61, 0x6c, 0x73, 0x12, 0x17, 0x0a, 0x04, 0x73, 0x65, 0x65,
+	0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x48, 0x00, 0x52, 0x04, 0x73, 0x65, 0x65, 0x64, 0x88,
+	0x01, 0x01, 0x42, 0x07, 0x0a, 0x05, 0x5f, 0x73, 0x65, 0x65, 0x64, 0x22, 0x3d, 0x0a, 0x0f, 0x48,
+	0x69, 0x73, 0x74, 0x6f, 0x67, 0x72, 0x61, 0x6d, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x12, 0x14,
+	0x0a, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x74,
+	0x6f, 0x74, 0x61, 0x6c, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x32, 0x82, 0x01, 0x0a, 0x0b, 0x52,
+	0x6f, 0x6c, 0x6c, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x31, 0x0a, 0x04, 0x52, 0x6f,
+	0x6c, 0x6c, 0x12, 0x14, 0x2e, 0x72, 0x6f, 0x6c, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x6f, 0x6c,
+	0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x72, 0x6f, 0x6c, 0x6c, 0x2e,
+	0x76, 0x31, 0x2e, 0x52, 0x6f, 0x6c, 0x6c, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x40, 0x0a,
+	0x08, 0x53, 0x69, 0x6d, 0x75, 0x6c, 0x61, 0x74, 0x65, 0x12, 0x18, 0x2e, 0x72, 0x6f, 0x6c, 0x6c,
+	0x2e, 0x76, 0x31, 0x2e, 0x53, 0x69, 0x6d, 0x75, 0x6c, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x72, 0x6f, 0x6c, 0x6c, 0x2e, 0x76, 0x31, 0x2e, 0x48, 0x69,
+	0x73, 0x74, 0x6f, 0x67, 0x72, 0x61, 0x6d, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x30, 0x01, 0x42,
+	0x2b, 0x5a, 0x29, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x44, 0x6f,
+	0x6d, 0x6f, 0x39, 0x32, 0x39, 0x2f, 0x72, 0x6f, 0x6c, 0x6c, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x72,
+	0x6f, 0x6c, 0x6c, 0x70, 0x62, 0x3b, 0x72, 0x6f, 0x6c, 0x6c, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_proto_roll_v1_roll_proto_rawDescOnce sync.Once
+	file_proto_roll_v1_roll_proto_rawDescData = file_proto_roll_v1_roll_proto_rawDesc
+)
+
+func file_proto_roll_v1_roll_proto_rawDescGZIP() []byte {
+	file_proto_roll_v1_roll_proto_rawDescOnce.Do(func() {
+		file_proto_roll_v1_roll_proto_rawDescData = protoimpl.X.CompressGZIP(file_proto_roll_v1_roll_proto_rawDescData)
+	})
+	return file_proto_roll_v1_roll_proto_rawDescData
+}
+
+var file_proto_roll_v1_roll_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_proto_roll_v1_roll_proto_goTypes = []any{
+	(*RollRequest)(nil),     // 0: roll.v1.RollRequest
+	(*Substitution)(nil),    // 1: roll.v1.Substitution
+	(*RollSummary)(nil),     // 2: roll.v1.RollSummary
+	(*RollResult)(nil),      // 3: roll.v1.RollResult
+	(*SimulateRequest)(nil), // 4: roll.v1.SimulateRequest
+	(*HistogramBucket)(nil), // 5: roll.v1.HistogramBucket
+}
+var file_proto_roll_v1_roll_proto_depIdxs = []int32{
+	1, // 0: roll.v1.RollResult.substituted:type_name -> roll.v1.Substitution
+	2, // 1: roll.v1.RollResult.summary:type_name -> roll.v1.RollSummary
+	3, // 2: roll.v1.RollResult.groups:type_name -> roll.v1.RollResult
+	0, // 3: roll.v1.RollService.Roll:input_type -> roll.v1.RollRequest
+	4, // 4: roll.v1.RollService.Simulate:input_type -> roll.v1.SimulateRequest
+	3, // 5: roll.v1.RollService.Roll:output_type -> roll.v1.RollResult
+	5, // 6: roll.v1.RollService.Simulate:output_type -> roll.v1.HistogramBucket
+	5, // [5:7] is the sub-list for method output_type
+	3, // [3:5] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_proto_roll_v1_roll_proto_init() }
+func file_proto_roll_v1_roll_proto_init() {
+	if File_proto_roll_v1_roll_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_proto_roll_v1_roll_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*RollRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_roll_v1_roll_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*Substitution); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_roll_v1_roll_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*RollSummary); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_roll_v1_roll_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*RollResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_roll_v1_roll_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*SimulateRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_roll_v1_roll_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*HistogramBucket); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_proto_roll_v1_roll_proto_msgTypes[0].OneofWrappers = []any{}
+	file_proto_roll_v1_roll_proto_msgTypes[4].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_proto_roll_v1_roll_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_roll_v1_roll_proto_goTypes,
+		DependencyIndexes: file_proto_roll_v1_roll_proto_depIdxs,
+		MessageInfos:      file_proto_roll_v1_roll_proto_msgTypes,
+	}.Build()
+	File_proto_roll_v1_roll_proto = out.File
+	file_proto_roll_v1_roll_proto_rawDesc = nil
+	file_proto_roll_v1_roll_proto_goTypes = nil
+	file_proto_roll_v1_roll_proto_depIdxs = nil
+}
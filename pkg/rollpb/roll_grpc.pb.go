@@ -0,0 +1,191 @@
+// Protocol definition for the roll service. The messages mirror the Go types
+// in pkg/rolls.
+//
+// Generate Go stubs from the repository root with:
+//
+//   protoc --go_out=. --go_opt=module=github.com/Domo929/roll \
+//     --go-grpc_out=. --go-grpc_opt=module=github.com/Domo929/roll \
+//     proto/roll/v1/roll.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             v5.28.3
+// source: proto/roll/v1/roll.proto
+
+package rollpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	RollService_Roll_FullMethodName     = "/roll.v1.RollService/Roll"
+	RollService_Simulate_FullMethodName = "/roll.v1.RollService/Simulate"
+)
+
+// RollServiceClient is the client API for RollService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RollServiceClient interface {
+	// Roll parses and rolls a single expression.
+	Roll(ctx context.Context, in *RollRequest, opts ...grpc.CallOption) (*RollResult, error)
+	// Simulate rolls an expression repeatedly, streaming histogram buckets as
+	// they fill so clients can render progress.
+	Simulate(ctx context.Context, in *SimulateRequest, opts ...grpc.CallOption) (RollService_SimulateClient, error)
+}
+
+type rollServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRollServiceClient(cc grpc.ClientConnInterface) RollServiceClient {
+	return &rollServiceClient{cc}
+}
+
+func (c *rollServiceClient) Roll(ctx context.Context, in *RollRequest, opts ...grpc.CallOption) (*RollResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RollResult)
+	err := c.cc.Invoke(ctx, RollService_Roll_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rollServiceClient) Simulate(ctx context.Context, in *SimulateRequest, opts ...grpc.CallOption) (RollService_SimulateClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RollService_ServiceDesc.Streams[0], RollService_Simulate_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &rollServiceSimulateClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RollService_SimulateClient interface {
+	Recv() (*HistogramBucket, error)
+	grpc.ClientStream
+}
+
+type rollServiceSimulateClient struct {
+	grpc.ClientStream
+}
+
+func (x *rollServiceSimulateClient) Recv() (*HistogramBucket, error) {
+	m := new(HistogramBucket)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// RollServiceServer is the server API for RollService service.
+// All implementations must embed UnimplementedRollServiceServer
+// for forward compatibility
+type RollServiceServer interface {
+	// Roll parses and rolls a single expression.
+	Roll(context.Context, *RollRequest) (*RollResult, error)
+	// Simulate rolls an expression repeatedly, streaming histogram buckets as
+	// they fill so clients can render progress.
+	Simulate(*SimulateRequest, RollService_SimulateServer) error
+	mustEmbedUnimplementedRollServiceServer()
+}
+
+// UnimplementedRollServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedRollServiceServer struct {
+}
+
+func (UnimplementedRollServiceServer) Roll(context.Context, *RollRequest) (*RollResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Roll not implemented")
+}
+func (UnimplementedRollServiceServer) Simulate(*SimulateRequest, RollService_SimulateServer) error {
+	return status.Errorf(codes.Unimplemented, "method Simulate not implemented")
+}
+func (UnimplementedRollServiceServer) mustEmbedUnimplementedRollServiceServer() {}
+
+// UnsafeRollServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RollServiceServer will
+// result in compilation errors.
+type UnsafeRollServiceServer interface {
+	mustEmbedUnimplementedRollServiceServer()
+}
+
+func RegisterRollServiceServer(s grpc.ServiceRegistrar, srv RollServiceServer) {
+	s.RegisterService(&RollService_ServiceDesc, srv)
+}
+
+func _RollService_Roll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RollRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RollServiceServer).Roll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RollService_Roll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RollServiceServer).Roll(ctx, req.(*RollRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RollService_Simulate_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SimulateRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RollServiceServer).Simulate(m, &rollServiceSimulateServer{ServerStream: stream})
+}
+
+type RollService_SimulateServer interface {
+	Send(*HistogramBucket) error
+	grpc.ServerStream
+}
+
+type rollServiceSimulateServer struct {
+	grpc.ServerStream
+}
+
+func (x *rollServiceSimulateServer) Send(m *HistogramBucket) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// RollService_ServiceDesc is the grpc.ServiceDesc for RollService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RollService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "roll.v1.RollService",
+	HandlerType: (*RollServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Roll",
+			Handler:    _RollService_Roll_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Simulate",
+			Handler:       _RollService_Simulate_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/roll/v1/roll.proto",
+}
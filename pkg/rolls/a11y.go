@@ -0,0 +1,321 @@
+package rolls
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Accessible renders r as a sentence for screen readers, without the
+// brackets, arrows and runs of symbols String uses: "Rolled two d six:
+// three and five, plus three, total eleven." Dropped, rerolled and
+// exploded dice are each spelled out, and numbers up to twenty are
+// written as words.
+func (r *Result) Accessible() string {
+	var b strings.Builder
+	b.WriteString("Rolled ")
+	if len(r.Groups) > 0 {
+		b.WriteString(r.groupsPhrase())
+	} else {
+		b.WriteString(r.dicePhrase())
+	}
+	if r.Bonus != 0 {
+		b.WriteString(", " + spokenBonus(r.Bonus))
+	}
+	if len(r.Faces) == 0 {
+		b.WriteString(", total " + r.totalPhrase())
+	}
+	for _, re := range r.Rerolled {
+		fmt.Fprintf(&b, ", the %s die rerolled from %s to %s", ordinal(re.Index+1), spell(re.Original), spell(re.Value))
+	}
+	if r.Nudge != nil {
+		deviations := "standard deviations"
+		if r.Nudge.Sigma == 1 || r.Nudge.Sigma == -1 {
+			deviations = "standard deviation"
+		}
+		fmt.Fprintf(&b, ", nudged by %g %s from %s", r.Nudge.Sigma, deviations, spokenNumber(r.Nudge.Unbiased))
+	}
+	return b.String() + "."
+}
+
+// groupsPhrase reads the groups of r, each after plus or minus but the first.
+func (r *Result) groupsPhrase() string {
+	var b strings.Builder
+	for i, g := range r.Groups {
+		switch {
+		case g.Negative:
+			b.WriteString("minus ")
+		case i > 0:
+			b.WriteString("plus ")
+		}
+		if g.Label != "" {
+			b.WriteString(g.Label + " ")
+		}
+		b.WriteString(g.groupPhrase())
+		if i < len(r.Groups)-1 {
+			b.WriteString(", ")
+		}
+	}
+	return b.String()
+}
+
+// groupPhrase reads one group of an expression: its dice, or the sum of
+// the groups in its parentheses, and then what scaled it.
+func (r *Result) groupPhrase() string {
+	if len(r.Groups) == 0 && len(r.Scale) == 0 {
+		return r.dicePhrase()
+	}
+	var b strings.Builder
+	total := r.Total
+	if len(r.Scale) > 0 {
+		total = r.Unscaled
+	}
+	if len(r.Groups) > 0 {
+		b.WriteString("the sum of " + r.groupsPhrase())
+		if r.Bonus != 0 {
+			b.WriteString(", " + spokenBonus(r.Bonus))
+		}
+		b.WriteString(", making " + spokenNumber(total))
+	} else {
+		b.WriteString(r.dicePhrase())
+	}
+	for _, s := range r.Scale {
+		b.WriteString(", " + s.phrase())
+	}
+	if len(r.Scale) > 0 {
+		b.WriteString(", making " + spokenNumber(r.Total))
+	}
+	return b.String()
+}
+
+// phrase reads s as it applies to a total already said.
+func (s ScaleStep) phrase() string {
+	switch {
+	case s.Op == "*":
+		return "times " + spell(s.By)
+	case s.RoundUp:
+		return fmt.Sprintf("divided by %s, rounded up", spell(s.By))
+	}
+	return fmt.Sprintf("divided by %s, rounded down", spell(s.By))
+}
+
+// dicePhrase reads the dice of a single group: how many of what were
+// rolled, what each landed on and which were dropped or swapped.
+func (r *Result) dicePhrase() string {
+	if r.Summarized {
+		s := fmt.Sprintf("%s: lowest %s, highest %s, average %.2f", r.diceName(r.Summary.Count), spokenNumber(r.Summary.Min), spokenNumber(r.Summary.Max), r.Summary.Mean)
+		switch {
+		case r.Kept != nil:
+			s += ", kept " + joinWithAnd(spokenFaces(r.Kept))
+		case r.Dropped != nil:
+			s += ", dropped " + spokenQuantity(len(r.Dropped), "die")
+		}
+		return s
+	}
+	if len(r.Faces) > 0 {
+		var counts []string
+		for _, t := range r.Tally {
+			if t.Count > 0 {
+				counts = append(counts, fmt.Sprintf("%s %s", spell(t.Count), t.Label))
+			}
+		}
+		return fmt.Sprintf("%s: %s, counting %s", r.diceName(len(r.Faces)), spokenList(r.Faces, ", "), joinWithAnd(counts))
+	}
+
+	faces, sep := r.facePhrases()
+	s := r.diceName(len(faces)) + ": " + spokenList(faces, sep)
+	if len(r.Dropped) > 0 {
+		dropped := spokenFaces(r.Dropped)
+		if r.Fudge {
+			dropped = spokenFudge(r.Dropped)
+		}
+		s += ", dropped " + joinWithAnd(dropped)
+	}
+	for _, sub := range r.Substituted {
+		s += fmt.Sprintf(", %s counted as %s", spokenNumber(sub.Original), spokenNumber(sub.Value))
+	}
+	return s
+}
+
+// diceName reads n of r's dice, as "two d six", "one d twenty" or "four
+// Fudge dice".
+func (r *Result) diceName(n int) string {
+	switch {
+	case r.Fudge:
+		return fmt.Sprintf("%s Fudge %s", spell(n), pluralize("die", n))
+	case len(r.Faces) > 0:
+		return fmt.Sprintf("%s labeled %s", spell(n), pluralize("die", n))
+	case strings.Contains(r.Expression, "{"):
+		return fmt.Sprintf("%s custom %s", spell(n), pluralize("die", n))
+	}
+	return fmt.Sprintf("%s d %s", spell(n), spell(r.Sides))
+}
+
+// facePhrases reads each die r rolled, explosions and rerolls included,
+// and the separator to list them with: a semicolon once a die takes more
+// than a word, so its own "and" does not run into the list's.
+func (r *Result) facePhrases() ([]string, string) {
+	if len(r.Percentiles) > 0 {
+		faces := make([]string, len(r.Percentiles))
+		for i, p := range r.Percentiles {
+			faces[i] = fmt.Sprintf("%s from %s and %s", spokenNumber(r.Rolls[i]), spokenNumber(p.Tens), spell(p.Ones))
+		}
+		return faces, "; "
+	}
+	if r.Fudge {
+		return spokenFudge(r.Rolls), ", "
+	}
+
+	rerolls := make(map[int][]int, len(r.RerolledOnce)+len(r.RerollChains))
+	for _, re := range r.RerolledOnce {
+		rerolls[re.Index] = []int{re.Original}
+	}
+	for _, c := range r.RerollChains {
+		rerolls[c.Index] = c.Chain[:len(c.Chain)-1]
+	}
+	added := make(map[int]int, len(r.Exploded))
+	for _, ex := range r.Exploded {
+		added[ex.Index] = ex.Count
+	}
+	chains := make(map[int]string, len(r.Compounded)+len(r.Penetrated))
+	for _, c := range r.Compounded {
+		chains[c.Index] = ", from " + joinWithAnd(spokenFaces(c.Chain))
+	}
+	for _, c := range r.Penetrated {
+		chains[c.Index] = ", from " + joinWithAnd(spokenFaces(c.Chain)) + ", each after the first counting one less"
+	}
+
+	value := func(i int) string {
+		v := spokenNumber(r.Rolls[i])
+		switch {
+		case r.AdjustedRolls == nil || r.AdjustedRolls[i] == r.Rolls[i]:
+		case r.AdjustedRolls[i] > r.Rolls[i]:
+			v += " raised to " + spokenNumber(r.AdjustedRolls[i])
+		default:
+			v += " lowered to " + spokenNumber(r.AdjustedRolls[i])
+		}
+		return v
+	}
+	sep := ", "
+	var faces []string
+	for i := 0; i < len(r.Rolls); i++ {
+		face := value(i)
+		if first, ok := rerolls[i]; ok {
+			face = joinWithAnd(spokenFaces(first)) + " rerolled to " + face
+		}
+		if face != spokenNumber(r.Rolls[i]) {
+			sep = "; "
+		}
+		if chain, ok := chains[i]; ok {
+			face += chain
+			sep = "; "
+		}
+		if n := added[i]; n > 0 {
+			extra := make([]string, n)
+			for j := range extra {
+				extra[j] = value(i + 1 + j)
+			}
+			face += " exploding into " + joinWithAnd(extra)
+			sep = "; "
+			i += n
+		}
+		faces = append(faces, face)
+	}
+	return faces, sep
+}
+
+// totalPhrase reads r's total, as successes for a success pool.
+func (r *Result) totalPhrase() string {
+	if !r.SuccessPool {
+		return spokenNumber(r.Total)
+	}
+	s := spokenQuantity(r.Total, "success")
+	if r.Failures > 0 {
+		s += fmt.Sprintf(", from %s and %s", spokenQuantity(r.Successes, "success"), spokenQuantity(r.Failures, "failure"))
+	}
+	if r.Botch {
+		s += ", a botch"
+	}
+	return s
+}
+
+// spokenBatchSummary is batchSummary as a sentence, without the sparkline:
+// "Rolled 2d6 20 times: lowest three, lower quartile 5.75, median seven,
+// upper quartile nine, highest twelve."
+func spokenBatchSummary(expr string, totals []int) string {
+	if len(totals) == 0 {
+		return fmt.Sprintf("Rolled %s no times.", expr)
+	}
+	f := fiveNumbers(totals)
+	say := func(v float64) string {
+		if v == math.Trunc(v) {
+			return spokenNumber(int(v))
+		}
+		return fmt.Sprint(v)
+	}
+	return fmt.Sprintf("Rolled %s %s: lowest %s, lower quartile %s, median %s, upper quartile %s, highest %s.",
+		expr, spokenQuantity(len(totals), "time"), say(f[0]), say(f[1]), say(f[2]), say(f[3]), say(f[4]))
+}
+
+// spokenNumber spells n as spell does, reading a negative number with
+// minus rather than a sign.
+func spokenNumber(n int) string {
+	if n < 0 {
+		return "minus " + spell(-n)
+	}
+	return spell(n)
+}
+
+// spokenBonus reads a bonus or penalty as "plus three" or "minus two".
+func spokenBonus(n int) string {
+	if n < 0 {
+		return "minus " + spell(-n)
+	}
+	return "plus " + spell(n)
+}
+
+// spokenQuantity is quantity with n spelled, as in "one success".
+func spokenQuantity(n int, word string) string {
+	return spokenNumber(n) + " " + pluralize(word, n)
+}
+
+func spokenFaces(rolls []int) []string {
+	words := make([]string, len(rolls))
+	for i, v := range rolls {
+		words[i] = spokenNumber(v)
+	}
+	return words
+}
+
+// spokenFudge reads Fudge dice by their faces: plus, blank and minus.
+func spokenFudge(rolls []int) []string {
+	words := make([]string, len(rolls))
+	for i, v := range rolls {
+		switch {
+		case v > 0:
+			words[i] = "plus"
+		case v < 0:
+			words[i] = "minus"
+		default:
+			words[i] = "blank"
+		}
+	}
+	return words
+}
+
+// spokenList lists words, keeping TruncateDice at each end as
+// truncateFaces does and saying how many were left out between them.
+// Lists joined by a semicolon end with one rather than "and".
+func spokenList(words []string, sep string) string {
+	join := func(words []string) string {
+		if sep == ", " {
+			return joinWithAnd(words)
+		}
+		return strings.Join(words, sep)
+	}
+	k := TruncateDice
+	if k == 0 || len(words) <= 2*k {
+		return join(words)
+	}
+	return fmt.Sprintf("%s%s%s more%sthen %s", strings.Join(words[:k], sep), sep, spell(len(words)-2*k), sep, join(words[len(words)-k:]))
+}
@@ -0,0 +1,144 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// SaveTarget is a creature caught in an area effect.
+type SaveTarget struct {
+	Name    string
+	SaveMod int
+	// Evasion takes no damage on a successful save and half on a failure.
+	Evasion bool
+}
+
+// AoERules selects how an area effect deals damage.
+type AoERules struct {
+	// NoneOnSave makes a successful save take no damage rather than half.
+	NoneOnSave bool
+	// Evasion gives every target Evasion.
+	Evasion bool
+}
+
+// AoETarget is the outcome for a single target.
+type AoETarget struct {
+	Target SaveTarget
+	Save   *Result
+	Saved  bool
+	Damage int
+}
+
+// AoEResult is an area effect rolled against every target.
+type AoEResult struct {
+	Damage  *Result
+	DC      int
+	Targets []AoETarget
+}
+
+// RollAoE rolls damage once and a save against dc for each target. Halved
+// damage rounds down.
+func RollAoE(damage *Dice, dc int, targets []SaveTarget, rules AoERules) *AoEResult {
+	res := &AoEResult{Damage: damage.Roll(), DC: dc}
+	full := max(res.Damage.Total, 0)
+	for _, t := range targets {
+		at := AoETarget{Target: t, Save: RollD20(t.SaveMod, false, false)}
+		at.Saved = at.Save.Total >= dc
+
+		evasion := t.Evasion || rules.Evasion
+		switch {
+		case at.Saved && (evasion || rules.NoneOnSave):
+			at.Damage = 0
+		case at.Saved || evasion:
+			at.Damage = full / 2
+		default:
+			at.Damage = full
+		}
+		res.Targets = append(res.Targets, at)
+	}
+	return res
+}
+
+// parseTargets parses targets such as "goblin:+1 x6, ogre:-1". Repeated
+// targets are numbered goblin1, goblin2 and so on.
+func parseTargets(s string) ([]SaveTarget, error) {
+	var targets []SaveTarget
+	for _, group := range strings.Split(s, ",") {
+		fields := strings.Fields(group)
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("passed illegal target: %q, want NAME:MOD [xCOUNT]", group)
+		}
+		name, mod, ok := strings.Cut(fields[0], ":")
+		if !ok {
+			return nil, fmt.Errorf("passed illegal target: %q, want NAME:MOD [xCOUNT]", group)
+		}
+		saveMod, err := strconv.Atoi(mod)
+		if err != nil {
+			return nil, err
+		}
+
+		count := 1
+		if len(fields) == 2 {
+			if count, err = strconv.Atoi(strings.TrimPrefix(fields[1], "x")); err != nil {
+				return nil, err
+			}
+			if count < 1 {
+				return nil, fmt.Errorf("target count must be at least 1, got %d", count)
+			}
+		}
+		for i := 1; i <= count; i++ {
+			t := SaveTarget{Name: name, SaveMod: saveMod}
+			if count > 1 {
+				t.Name = fmt.Sprintf("%s%d", name, i)
+			}
+			targets = append(targets, t)
+		}
+	}
+	return targets, nil
+}
+
+func aoeGen(args []string) error {
+	fs := flag.NewFlagSet("aoe", flag.ContinueOnError)
+	dc := fs.Int("dc", 10, "save DC")
+	save := fs.String("save", "dex", "ability used for the save")
+	targetList := fs.String("targets", "", "targets and save modifiers, e.g. \"goblin:+1 x6, ogre:-1\"")
+	evasion := fs.Bool("evasion", false, "give every target Evasion")
+	noneOnSave := fs.Bool("none-on-save", false, "deal no damage on a successful save instead of half")
+	exprs, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(exprs) != 1 {
+		return fmt.Errorf("need to provide one damage expression (8d6)")
+	}
+
+	d, err := Parse(exprs[0])
+	if err != nil {
+		return err
+	}
+	targets, err := parseTargets(*targetList)
+	if err != nil {
+		return err
+	}
+
+	res := RollAoE(d, *dc, targets, AoERules{NoneOnSave: *noneOnSave, Evasion: *evasion})
+	rolled := []*Result{res.Damage}
+	fmt.Println("Damage:", res.Damage)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Target\t%s save\tDC %d\tDamage\n", strings.ToUpper(*save), res.DC)
+	for _, t := range res.Targets {
+		outcome := "fail"
+		if t.Saved {
+			outcome = "save"
+		}
+		fmt.Fprintf(w, "%s\t%d (%d%+d)\t%s\t%d\n", t.Target.Name, t.Save.Total, t.Save.Rolls[0], t.Target.SaveMod, outcome, t.Damage)
+		rolled = append(rolled, t.Save)
+	}
+	logHistory(rolled...)
+
+	return w.Flush()
+}
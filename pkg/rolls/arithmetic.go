@@ -0,0 +1,272 @@
+package rolls
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tokenKind is the kind of a token of an arithmetic expression.
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenNumber
+	tokenDice
+	tokenOp
+	tokenOpen
+	tokenClose
+)
+
+// token is a token of an arithmetic expression, with the position of its
+// first character from 1.
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEnd {
+		return "the end"
+	}
+	return fmt.Sprintf("the %s at %d", t.text, t.pos)
+}
+
+// tokenize splits expr, with its spaces already removed, into numbers, dice
+// groups, the operators + - * / and /^, and parentheses. A dice group runs
+// up to the next operator or parenthesis, bar a sign straight after a
+// comparison, which belongs to its threshold.
+func tokenize(expr string) []token {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		switch c := expr[i]; c {
+		case '(':
+			tokens = append(tokens, token{tokenOpen, "(", i + 1})
+			i++
+		case ')':
+			tokens = append(tokens, token{tokenClose, ")", i + 1})
+			i++
+		case '/':
+			op := "/"
+			if strings.HasPrefix(expr[i:], "/^") {
+				op = "/^"
+			}
+			tokens = append(tokens, token{tokenOp, op, i + 1})
+			i += len(op)
+		case '+', '-', '*':
+			tokens = append(tokens, token{tokenOp, string(c), i + 1})
+			i++
+		default:
+			j := i + 1
+			for j < len(expr) && (!strings.ContainsRune("+-*/()", rune(expr[j])) || strings.ContainsRune("+-", rune(expr[j])) && strings.ContainsRune("<>=", rune(expr[j-1]))) {
+				j++
+			}
+			kind := tokenNumber
+			if isDiceTerm(expr[i:j]) {
+				kind = tokenDice
+			}
+			tokens = append(tokens, token{kind, expr[i:j], i + 1})
+			i = j
+		}
+	}
+	return append(tokens, token{kind: tokenEnd, pos: len(expr) + 1})
+}
+
+// arithmetic is a recursive descent parser of sums, products and
+// parentheses of dice groups and numbers:
+//
+//	sum     = ["+" | "-"] product {("+" | "-") product}
+//	product = operand {("*" | "/" | "/^") operand}
+//	operand = number | dice | "(" sum ")"
+type arithmetic struct {
+	expr   string
+	tokens []token
+	next   int
+}
+
+// operand is the value of an operand or product: a group, or a whole number
+// when group is nil.
+type operand struct {
+	n     int
+	group *Group
+}
+
+// parseArithmetic parses a sum of dice groups and numbers that may multiply,
+// divide and nest them in parentheses, such as 2*(1d6+1d8) or
+// ((1d4+1)*2+1d6)/2.
+func parseArithmetic(expr string) (*Expression, error) {
+	p := &arithmetic{expr: expr, tokens: tokenize(expr)}
+	e, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	switch t := p.peek(); t.kind {
+	case tokenEnd:
+	case tokenClose:
+		return nil, p.errorf("%s closes no (", t)
+	default:
+		return nil, p.errorf("expected an operator before %s", t)
+	}
+	if len(e.Groups) == 0 {
+		return nil, p.errorf("no dice to roll")
+	}
+	return e, nil
+}
+
+func (p *arithmetic) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *arithmetic) take() token {
+	t := p.tokens[p.next]
+	if t.kind != tokenEnd {
+		p.next++
+	}
+	return t
+}
+
+func (p *arithmetic) errorf(format string, args ...any) error {
+	return fmt.Errorf("passed illegal die command: %s, %s", p.expr, fmt.Sprintf(format, args...))
+}
+
+// sum parses a sum of products up to the next ) or the end.
+func (p *arithmetic) sum() (*Expression, error) {
+	e := &Expression{}
+	negative := false
+	if t := p.peek(); t.kind == tokenOp && (t.text == "+" || t.text == "-") {
+		negative = p.take().text == "-"
+	}
+	for {
+		v, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		if v.group == nil {
+			if negative {
+				v.n = -v.n
+			}
+			if e.Bonus, err = addBonuses(e.Bonus, v.n, p.expr); err != nil {
+				return nil, err
+			}
+		} else {
+			g := *v.group
+			g.Negative = g.Negative != negative
+			e.Groups = append(e.Groups, g)
+		}
+
+		t := p.peek()
+		if t.kind != tokenOp || t.text != "+" && t.text != "-" {
+			return e, nil
+		}
+		negative = p.take().text == "-"
+	}
+}
+
+// product parses operands multiplied and divided from left to right.
+func (p *arithmetic) product() (operand, error) {
+	v, err := p.operand()
+	if err != nil {
+		return operand{}, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokenOp || t.text == "+" || t.text == "-" {
+			return v, nil
+		}
+		p.take()
+		rhs, err := p.operand()
+		if err != nil {
+			return operand{}, err
+		}
+		if v, err = p.scale(v, t, rhs); err != nil {
+			return operand{}, err
+		}
+	}
+}
+
+// scale returns v multiplied or divided by rhs, as op says. Either side may
+// be a group when the other is a number, bar dividing a number by a group,
+// and a negative number flips the group's sign, as the rounding of a
+// division applies to the group's own total before its sign.
+func (p *arithmetic) scale(v operand, op token, rhs operand) (operand, error) {
+	switch {
+	case v.group != nil && rhs.group != nil:
+		return operand{}, p.errorf("%s combines two rolls, multiply or divide by a whole number instead", op)
+	case rhs.group != nil && op.text != "*":
+		return operand{}, p.errorf("%s divides by a roll, divide by a whole number instead", op)
+	case rhs.group != nil:
+		v, rhs = rhs, v
+	}
+	switch {
+	case rhs.n == 0 && op.text == "*":
+		return operand{}, p.errorf("%s multiplies by zero", op)
+	case rhs.n == 0:
+		return operand{}, p.errorf("%s divides by zero", op)
+	}
+
+	step := ScaleStep{Op: op.text[:1], By: rhs.n, RoundUp: op.text == "/^"}
+	if v.group == nil {
+		if step.By < 0 {
+			v.n, step.By = -v.n, -step.By
+		}
+		n, err := checkBonus(step.Apply(v.n), p.expr)
+		return operand{n: n}, err
+	}
+	g := *v.group
+	if step.By < 0 {
+		g.Negative, step.By = !g.Negative, -step.By
+	}
+	g.Scale = append(append([]ScaleStep(nil), g.Scale...), step)
+	factor := 1
+	for _, s := range g.Scale {
+		if s.Op == "*" {
+			factor *= s.By
+		}
+		if MaxModifier > 0 && factor > MaxModifier {
+			return operand{}, &LimitError{Expr: p.expr, What: "multiplier", Value: fmt.Sprint(factor), Limit: MaxModifier}
+		}
+	}
+	return operand{group: &g}, nil
+}
+
+// operand parses a number, a dice group or a sum in parentheses. A sum
+// without dice is the number it adds up to, and one of a single positive
+// group is that group, so (1d6)*2 is 1d6*2.
+func (p *arithmetic) operand() (operand, error) {
+	switch t := p.take(); t.kind {
+	case tokenNumber:
+		n, err := parseBonus(t.text, p.expr)
+		return operand{n: n}, err
+	case tokenDice:
+		d, err := Parse(t.text)
+		if err != nil {
+			return operand{}, err
+		}
+		if d.Success != nil {
+			return operand{}, p.errorf("success thresholds only apply to a single dice group")
+		}
+		return operand{group: &Group{Dice: d}}, nil
+	case tokenOpen:
+		sub, err := p.sum()
+		if err != nil {
+			return operand{}, err
+		}
+		switch next := p.take(); {
+		case next.kind == tokenEnd:
+			return operand{}, p.errorf("%s is never closed", t)
+		case next.kind != tokenClose:
+			return operand{}, p.errorf("expected an operator or ) before %s", next)
+		}
+		switch {
+		case len(sub.Groups) == 0:
+			return operand{n: sub.Bonus}, nil
+		case len(sub.Groups) == 1 && sub.Bonus == 0 && !sub.Groups[0].Negative:
+			return operand{group: &sub.Groups[0]}, nil
+		}
+		return operand{group: &Group{Sub: sub}}, nil
+	case tokenEnd:
+		return operand{}, p.errorf("expected a number, dice or ( at the end")
+	default:
+		return operand{}, p.errorf("expected a number, dice or ( at %d, not %s", t.pos, t.text)
+	}
+}
@@ -0,0 +1,297 @@
+package rolls
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BankedModifier is a pending bonus, such as "+1 forward" from aiding.
+type BankedModifier struct {
+	Value int    `json:"value"`
+	Label string `json:"label,omitempty"`
+}
+
+func (m BankedModifier) String() string {
+	if m.Label == "" {
+		return fmt.Sprintf("%+d", m.Value)
+	}
+	return fmt.Sprintf("%+d (%s)", m.Value, m.Label)
+}
+
+// ModifierBank holds the modifiers and hold that carry between rolls in
+// Powered by the Apocalypse games.
+type ModifierBank struct {
+	// Forward modifiers apply to the next roll only.
+	Forward []BankedModifier `json:"forward,omitempty"`
+	// Ongoing modifiers apply to every roll until cleared.
+	Ongoing []BankedModifier `json:"ongoing,omitempty"`
+	// Hold counts hold by label.
+	Hold map[string]int `json:"hold,omitempty"`
+}
+
+// AddForward banks a modifier for the next roll.
+func (b *ModifierBank) AddForward(value int, label string) {
+	b.Forward = append(b.Forward, BankedModifier{Value: value, Label: label})
+}
+
+// AddOngoing banks a modifier for every roll until ClearOngoing.
+func (b *ModifierBank) AddOngoing(value int, label string) {
+	b.Ongoing = append(b.Ongoing, BankedModifier{Value: value, Label: label})
+}
+
+// ClearOngoing removes every ongoing modifier.
+func (b *ModifierBank) ClearOngoing() {
+	b.Ongoing = nil
+}
+
+// Consume returns the modifiers that apply to a roll being made now and
+// their sum. Forward modifiers are used up; ongoing ones stay.
+func (b *ModifierBank) Consume() (int, []BankedModifier) {
+	used := append(append([]BankedModifier(nil), b.Forward...), b.Ongoing...)
+	b.Forward = nil
+	total := 0
+	for _, m := range used {
+		total += m.Value
+	}
+	return total, used
+}
+
+// AddHold adds n hold under label.
+func (b *ModifierBank) AddHold(label string, n int) {
+	if b.Hold == nil {
+		b.Hold = make(map[string]int)
+	}
+	b.Hold[label] += n
+}
+
+// SpendHold spends one hold under label, failing if there is none left.
+// With no label it spends unlabeled hold, or the only labeled hold there
+// is, and otherwise fails naming the labels to choose from.
+func (b *ModifierBank) SpendHold(label string) (int, error) {
+	if label == "" && b.Hold[""] <= 0 {
+		labels := b.holdLabels()
+		switch len(labels) {
+		case 0:
+			return 0, fmt.Errorf("no hold left")
+		case 1:
+			label = labels[0]
+		default:
+			for i, l := range labels {
+				labels[i] = strconv.Quote(l)
+			}
+			return 0, fmt.Errorf("no unlabeled hold left, spend one with --label %s", strings.Join(labels, ", "))
+		}
+	}
+	if b.Hold[label] <= 0 {
+		return 0, fmt.Errorf("no hold left for %q", label)
+	}
+	b.Hold[label]--
+	left := b.Hold[label]
+	if left == 0 {
+		delete(b.Hold, label)
+	}
+	return left, nil
+}
+
+// holdLabels returns the labels with hold left, in order, leaving out
+// unlabeled hold.
+func (b *ModifierBank) holdLabels() []string {
+	var labels []string
+	for l, n := range b.Hold {
+		if l != "" && n > 0 {
+			labels = append(labels, l)
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// ReadBank reads a bank written by WriteBank.
+func ReadBank(r io.Reader) (*ModifierBank, error) {
+	b := &ModifierBank{}
+	if err := json.NewDecoder(r).Decode(b); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return b, nil
+}
+
+// WriteBank writes b as JSON.
+func WriteBank(w io.Writer, b *ModifierBank) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// TakeForward consumes the saved bank's modifiers for a roll being made now
+// and saves what is left.
+func TakeForward() (int, []BankedModifier, error) {
+	b, err := LoadBank()
+	if err != nil {
+		return 0, nil, err
+	}
+	total, used := b.Consume()
+	if len(used) == 0 {
+		return 0, nil, nil
+	}
+	return total, used, SaveBank(b)
+}
+
+// takeForwardOn adds the saved bank's modifiers to e's bonus for a roll
+// being made now, using up the forward ones, and reports those it took.
+func takeForwardOn(e *Expression) error {
+	banked, used, err := TakeForward()
+	if err != nil {
+		return err
+	}
+	if e.Bonus, err = addBonuses(e.Bonus, banked, e.String()); err != nil {
+		return err
+	}
+	printTaken(used)
+	return nil
+}
+
+// printTaken reports the banked modifiers applied to a roll.
+func printTaken(used []BankedModifier) {
+	if len(used) == 0 {
+		return
+	}
+	taken := make([]string, len(used))
+	for i, m := range used {
+		taken[i] = m.String()
+	}
+	fmt.Println("Taken:", strings.Join(taken, ", "))
+}
+
+func pbtaGen(args []string) error {
+	fs := flag.NewFlagSet("pbta", flag.ContinueOnError)
+	takeForward := fs.Bool("take-forward", false, "apply and use up banked forward and ongoing modifiers")
+	mods, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	modifier := 0
+	if len(mods) > 0 {
+		if modifier, err = strconv.Atoi(mods[0]); err != nil {
+			return err
+		}
+	}
+
+	var used []BankedModifier
+	if *takeForward {
+		var banked int
+		if banked, used, err = TakeForward(); err != nil {
+			return err
+		}
+		modifier += banked
+	}
+
+	res := (&Dice{Count: 2, Sides: 6, Bonus: modifier}).Roll()
+	logHistory(res)
+
+	printTaken(used)
+	fmt.Println(res)
+	switch {
+	case res.Total >= 10:
+		fmt.Println("Strong hit")
+	case res.Total >= 7:
+		fmt.Println("Weak hit")
+	default:
+		fmt.Println("Miss")
+	}
+
+	return nil
+}
+
+func forwardGen(args []string) error {
+	fs := flag.NewFlagSet("forward", flag.ContinueOnError)
+	label := fs.String("label", "", "what the modifier is for")
+	ongoing := fs.Bool("ongoing", false, "keep the modifier for every roll until cleared")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	b, err := LoadBank()
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(rest) == 2 && rest[0] == "add":
+		value, err := strconv.Atoi(rest[1])
+		if err != nil {
+			return err
+		}
+		if *ongoing {
+			b.AddOngoing(value, *label)
+		} else {
+			b.AddForward(value, *label)
+		}
+	case len(rest) == 1 && rest[0] == "clear":
+		b.Forward = nil
+		b.ClearOngoing()
+	case len(rest) == 0:
+	default:
+		return fmt.Errorf("need to provide add [+/-]N or clear")
+	}
+
+	for _, m := range b.Forward {
+		fmt.Println("Forward:", m)
+	}
+	for _, m := range b.Ongoing {
+		fmt.Println("Ongoing:", m)
+	}
+	return SaveBank(b)
+}
+
+func holdGen(args []string) error {
+	fs := flag.NewFlagSet("hold", flag.ContinueOnError)
+	label := fs.String("label", "", "what the hold is for")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	b, err := LoadBank()
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(rest) == 2 && rest[0] == "add":
+		n, err := strconv.Atoi(rest[1])
+		if err != nil {
+			return err
+		}
+		if n < 1 {
+			return fmt.Errorf("hold to add must be at least 1, got %d", n)
+		}
+		b.AddHold(*label, n)
+	case len(rest) == 1 && rest[0] == "spend":
+		if _, err := b.SpendHold(*label); err != nil {
+			return err
+		}
+	case len(rest) == 0:
+	default:
+		return fmt.Errorf("need to provide add N or spend")
+	}
+
+	labels := b.holdLabels()
+	if b.Hold[""] > 0 {
+		labels = append([]string{""}, labels...)
+	}
+	for _, l := range labels {
+		if l == "" {
+			fmt.Printf("Hold: %d\n", b.Hold[l])
+			continue
+		}
+		fmt.Printf("Hold: %d (%s)\n", b.Hold[l], l)
+	}
+	if len(labels) == 0 {
+		fmt.Println("Hold: none")
+	}
+	return SaveBank(b)
+}
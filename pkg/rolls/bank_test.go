@@ -0,0 +1,87 @@
+package rolls
+
+import (
+	"maps"
+	"testing"
+)
+
+// TestTakeForward checks a plain roll and a check both add the banked
+// modifiers, using up the forward ones and keeping the ongoing ones.
+func TestTakeForward(t *testing.T) {
+	tests := []struct {
+		command string
+		gen     func([]string) error
+		args    []string
+		// bonus is the logged roll's bonus, or -1 if nothing is rolled.
+		bonus int
+	}{
+		{"roll", normGen, []string{"--take-forward", "1d1+5"}, 5 + 2 + 1},
+		{"roll", normGen, []string{"2d6", "--take-forward"}, 2 + 1},
+		{"check", checkGen, []string{"--take-forward", "--dc", "7", "2d2"}, 2 + 1},
+		{"check", checkGen, []string{"--take-forward", "--dc", "12", "2"}, 2 + 2 + 1},
+		// The banked +3 makes DC 6 impossible to fail: nothing is rolled,
+		// but the forward modifier was still spent on the check.
+		{"check", checkGen, []string{"--take-forward", "--dc", "6", "2d1+1"}, -1},
+	}
+	for _, tt := range tests {
+		SetStorage(NewMemoryStorage())
+		b := &ModifierBank{}
+		b.AddForward(2, "aid")
+		b.AddOngoing(1, "")
+		if err := SaveBank(b); err != nil {
+			t.Fatal(err)
+		}
+		if err := tt.gen(tt.args); err != nil {
+			t.Errorf("%s %v: %v", tt.command, tt.args, err)
+			continue
+		}
+		b, err := LoadBank()
+		if err != nil || len(b.Forward) != 0 || len(b.Ongoing) != 1 {
+			t.Errorf("%s %v left the bank %+v, %v, want only the ongoing +1", tt.command, tt.args, b, err)
+		}
+		entries, _, _ := LoadHistory()
+		switch {
+		case tt.bonus < 0 && len(entries) != 0:
+			t.Errorf("%s %v logged a roll it could not fail", tt.command, tt.args)
+		case tt.bonus >= 0 && len(entries) != 1:
+			t.Errorf("%s %v logged %d rolls, want 1", tt.command, tt.args, len(entries))
+		case tt.bonus >= 0 && entries[0].Result.Bonus != tt.bonus:
+			t.Errorf("%s %v logged %s, want a bonus of %+d", tt.command, tt.args, entries[0].Result, tt.bonus)
+		}
+	}
+	SetStorage(nil)
+
+	if err := normGen([]string{"--take-forward", "1d20", "1d6"}); err == nil {
+		t.Error("--take-forward on two expressions did not fail")
+	}
+}
+
+func TestSpendHold(t *testing.T) {
+	tests := []struct {
+		hold  map[string]int
+		label string
+		left  map[string]int
+		err   string
+	}{
+		{map[string]int{"": 2}, "", map[string]int{"": 1}, ""},
+		{map[string]int{"read a sitch": 3}, "", map[string]int{"read a sitch": 2}, ""},
+		{map[string]int{"read a sitch": 1}, "", map[string]int{}, ""},
+		{map[string]int{"": 1, "hack": 2}, "", map[string]int{"hack": 2}, ""},
+		{map[string]int{"hack": 2, "read a sitch": 3}, "hack", map[string]int{"hack": 1, "read a sitch": 3}, ""},
+		{map[string]int{"hack": 2, "read a sitch": 3}, "", nil, `no unlabeled hold left, spend one with --label "hack", "read a sitch"`},
+		{map[string]int{"hack": 2}, "read a sitch", nil, `no hold left for "read a sitch"`},
+		{nil, "", nil, "no hold left"},
+	}
+	for _, tt := range tests {
+		b := &ModifierBank{Hold: maps.Clone(tt.hold)}
+		_, err := b.SpendHold(tt.label)
+		switch {
+		case tt.err != "" && (err == nil || err.Error() != tt.err):
+			t.Errorf("SpendHold(%q) from %v = %v, want %q", tt.label, tt.hold, err, tt.err)
+		case tt.err == "" && err != nil:
+			t.Errorf("SpendHold(%q) from %v: %v", tt.label, tt.hold, err)
+		case tt.err == "" && !maps.Equal(b.Hold, tt.left):
+			t.Errorf("SpendHold(%q) from %v left %v, want %v", tt.label, tt.hold, b.Hold, tt.left)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package rolls
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// BatchResult is the outcome of one expression of a batch.
+type BatchResult struct {
+	Result *Result
+	Err    error
+}
+
+// RollAll rolls every expression in turn with the default roller.
+func RollAll(ctx context.Context, exprs []string) ([]BatchResult, error) {
+	return defaultRoller.RollAll(ctx, exprs)
+}
+
+// RollAllParallel rolls the expressions across workers goroutines with
+// rollers split from the default roller.
+func RollAllParallel(ctx context.Context, exprs []string, workers int) ([]BatchResult, error) {
+	return defaultRoller.RollAllParallel(ctx, exprs, workers)
+}
+
+// RollAll rolls every expression in turn and returns their results in
+// order. An expression that fails to parse gets its error in its own
+// BatchResult; only ctx ending stops the batch, with ctx's error and the
+// results so far.
+func (r *Roller) RollAll(ctx context.Context, exprs []string) ([]BatchResult, error) {
+	results := make([]BatchResult, len(exprs))
+	for i, expr := range exprs {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		results[i] = r.rollBatchItem(ctx, expr)
+	}
+	return results, nil
+}
+
+// RollAllParallel is like RollAll but rolls across workers goroutines, each
+// with its own roller from Split. Expression i always goes to worker i mod
+// workers, so a Roller created WithSeed gives the same results for the same
+// expressions and number of workers, however the goroutines are scheduled.
+func (r *Roller) RollAllParallel(ctx context.Context, exprs []string, workers int) ([]BatchResult, error) {
+	if workers < 1 {
+		return nil, fmt.Errorf("need at least one worker, got %d", workers)
+	}
+	workers = min(workers, max(len(exprs), 1))
+
+	results := make([]BatchResult, len(exprs))
+	var wg sync.WaitGroup
+	for w, child := range r.Split(workers) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := w; i < len(exprs); i += workers {
+				if ctx.Err() != nil {
+					return
+				}
+				results[i] = child.rollBatchItem(ctx, exprs[i])
+			}
+		}()
+	}
+	wg.Wait()
+	return results, ctx.Err()
+}
+
+func (r *Roller) rollBatchItem(ctx context.Context, expr string) BatchResult {
+	e, err := ParseExpression(expr)
+	if err != nil {
+		return BatchResult{Err: err}
+	}
+	res, err := r.RollExpressionContext(ctx, e)
+	return BatchResult{Result: res, Err: err}
+}
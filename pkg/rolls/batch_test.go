@@ -0,0 +1,105 @@
+package rolls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// batchExprs returns n expressions of mixed shapes, every tenth of which
+// does not parse.
+func batchExprs(n int) []string {
+	shapes := []string{"1d20+5", "4d6kh3", "2d6+1d8+3", "1d20+5-1d4", "(2d6+3)*2", "8d6", "3d6!", "5d10>=7", "4dF", "2d"}
+	exprs := make([]string, n)
+	for i := range exprs {
+		exprs[i] = shapes[i%len(shapes)]
+	}
+	return exprs
+}
+
+func TestRollAllParallelDeterministic(t *testing.T) {
+	exprs := batchExprs(10000)
+	first, err := NewRoller(WithSeed(7)).RollAllParallel(context.Background(), exprs, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewRoller(WithSeed(7)).RollAllParallel(context.Background(), exprs, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Fatal("two batches at the same seed and worker count differ")
+	}
+
+	// Worker w rolls expressions w, w+4, w+8 and so on with the w-th split
+	// roller, so rolling them in turn gives the same results.
+	for w, child := range NewRoller(WithSeed(7)).Split(4) {
+		for i := w; i < len(exprs); i += 4 {
+			want := child.rollBatchItem(context.Background(), exprs[i])
+			if !reflect.DeepEqual(first[i], want) {
+				t.Fatalf("%s at %d = %+v, want %+v", exprs[i], i, first[i], want)
+			}
+		}
+	}
+
+	for i, br := range first {
+		if bad := i%10 == 9; (br.Err != nil) != bad || (br.Result == nil) != bad {
+			t.Fatalf("%s at %d = %+v, %v", exprs[i], i, br.Result, br.Err)
+		}
+	}
+}
+
+func TestRollAll(t *testing.T) {
+	exprs := batchExprs(20)
+	results, err := NewRoller(WithSeed(7)).RollAll(context.Background(), exprs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRoller(WithSeed(7))
+	for i, expr := range exprs {
+		if want := r.rollBatchItem(context.Background(), expr); !reflect.DeepEqual(results[i], want) {
+			t.Errorf("%s at %d = %+v, want %+v", expr, i, results[i], want)
+		}
+	}
+}
+
+func TestRollAllParallelErrors(t *testing.T) {
+	if _, err := RollAllParallel(context.Background(), batchExprs(10), 0); err == nil {
+		t.Error("RollAllParallel with no workers did not fail")
+	}
+	results, err := RollAllParallel(context.Background(), nil, 8)
+	if err != nil || len(results) != 0 {
+		t.Errorf("RollAllParallel of nothing = %v, %v", results, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := RollAllParallel(ctx, batchExprs(100), 4); !errors.Is(err, context.Canceled) {
+		t.Errorf("RollAllParallel with a cancelled context = %v, want context.Canceled", err)
+	}
+	if _, err := RollAll(ctx, batchExprs(100)); !errors.Is(err, context.Canceled) {
+		t.Errorf("RollAll with a cancelled context = %v, want context.Canceled", err)
+	}
+}
+
+func BenchmarkRollAll(b *testing.B) {
+	exprs := batchExprs(10000)
+	r := NewRoller(WithSeed(1))
+	for i := 0; i < b.N; i++ {
+		r.RollAll(context.Background(), exprs)
+	}
+}
+
+func BenchmarkRollAllParallel(b *testing.B) {
+	exprs := batchExprs(10000)
+	for _, workers := range []int{2, 4, 8} {
+		b.Run(fmt.Sprint(workers, "workers"), func(b *testing.B) {
+			r := NewRoller(WithSeed(1))
+			for i := 0; i < b.N; i++ {
+				r.RollAllParallel(context.Background(), exprs, workers)
+			}
+		})
+	}
+}
@@ -0,0 +1,412 @@
+package rolls
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BatchFile is a turn of a play-by-mail game: named rolls to be made
+// together, as ReadBatchFile reads them from TOML:
+//
+//	title = "Turn 7"
+//
+//	[[roll]]
+//	name = "stealth"
+//	expr = "1d20+5"
+//	dc = 15
+//	note = "sneaking past the gate"
+type BatchFile struct {
+	Title string
+	Rolls []BatchRoll
+}
+
+// BatchRoll is one named roll of a BatchFile. A DC of zero means none.
+type BatchRoll struct {
+	Name string `json:"name"`
+	Expr string `json:"expr"`
+	DC   int    `json:"dc,omitempty"`
+	Note string `json:"note,omitempty"`
+}
+
+// ReadBatchFile reads a batch file from r, named name in errors. It takes
+// the part of TOML a batch needs: a title, then a [[roll]] table for each
+// roll with a name, an expr and an optional dc and note, whose values are
+// strings or whole numbers, and # comments. Every roll needs an expression
+// and a name no other roll has, as its name picks its dice.
+func ReadBatchFile(name string, r io.Reader) (*BatchFile, error) {
+	f := &BatchFile{}
+	var roll *BatchRoll
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(stripComment(sc.Text()))
+		switch {
+		case text == "":
+			continue
+		case text == "[[roll]]":
+			f.Rolls = append(f.Rolls, BatchRoll{})
+			roll = &f.Rolls[len(f.Rolls)-1]
+			continue
+		case strings.HasPrefix(text, "["):
+			return nil, fmt.Errorf("%s:%d: unknown table %s, want [[roll]]", name, line, text)
+		}
+		key, raw, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: want key = value, got %q", name, line, text)
+		}
+		key, raw = strings.TrimSpace(key), strings.TrimSpace(raw)
+		var err error
+		switch {
+		case roll == nil && key == "title":
+			f.Title, err = tomlString(raw)
+		case roll == nil:
+			err = fmt.Errorf("unknown key %q before the first [[roll]]", key)
+		case key == "name":
+			roll.Name, err = tomlString(raw)
+		case key == "expr":
+			roll.Expr, err = tomlString(raw)
+		case key == "note":
+			roll.Note, err = tomlString(raw)
+		case key == "dc":
+			roll.DC, err = strconv.Atoi(raw)
+			if err != nil {
+				err = fmt.Errorf("dc must be a whole number, not %s", raw)
+			}
+		default:
+			err = fmt.Errorf("unknown key %q, want name, expr, dc or note", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading batch %s: %w", name, err)
+	}
+
+	seen := make(map[string]bool, len(f.Rolls))
+	for i, r := range f.Rolls {
+		switch {
+		case r.Name == "":
+			return nil, fmt.Errorf("%s: roll %d has no name", name, i+1)
+		case seen[r.Name]:
+			return nil, fmt.Errorf("%s: two rolls are named %q", name, r.Name)
+		case r.Expr == "":
+			return nil, fmt.Errorf("%s: roll %q has no expr", name, r.Name)
+		}
+		seen[r.Name] = true
+	}
+	if len(f.Rolls) == 0 {
+		return nil, fmt.Errorf("%s: no rolls, add a [[roll]] for each", name)
+	}
+	return f, nil
+}
+
+// stripComment removes a # comment from a line of TOML, leaving any # inside
+// a string.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// tomlString reads a TOML basic string, "with \"escapes\"", or literal
+// string, 'as written'.
+func tomlString(raw string) (string, error) {
+	switch {
+	case len(raw) >= 2 && raw[0] == '\'' && raw[len(raw)-1] == '\'' && !strings.Contains(raw[1:len(raw)-1], "'"):
+		return raw[1 : len(raw)-1], nil
+	case len(raw) >= 2 && raw[0] == '"':
+		if s, err := strconv.Unquote(raw); err == nil {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("want a quoted string, got %s", raw)
+}
+
+// batchReportVersion is the version of the report layout written by
+// RunBatch.
+const batchReportVersion = 1
+
+// BatchReport is the signed outcome of a batch: every roll with its result,
+// or the error that stopped it, in the batch's order. Each result carries a
+// receipt as roll verify checks them, and Signature covers the whole report.
+type BatchReport struct {
+	Version    int          `json:"v"`
+	Title      string       `json:"title,omitempty"`
+	SeedPhrase string       `json:"seed_phrase"`
+	Time       time.Time    `json:"time"`
+	Entries    []BatchEntry `json:"entries"`
+	Signature  string       `json:"signature,omitempty"`
+}
+
+// BatchEntry is a roll of a BatchReport. Success is set for rolls with a
+// DC, and Error, instead of Result and Receipt, for those that failed.
+type BatchEntry struct {
+	BatchRoll
+	Result  *Result `json:"result,omitempty"`
+	Success *bool   `json:"success,omitempty"`
+	Receipt string  `json:"receipt,omitempty"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// Errored returns the number of entries of r that failed.
+func (r *BatchReport) Errored() int {
+	n := 0
+	for _, e := range r.Entries {
+		if e.Error != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// batchSeed returns the seed of the roll named name in a batch rolled with
+// phrase.
+func batchSeed(phrase, name string) int64 {
+	sum := sha256.Sum256([]byte(phrase + "\x00" + name))
+	return int64(binary.BigEndian.Uint64(sum[:8]))
+}
+
+// rollBatchEntry rolls b with the seed phrase.
+func rollBatchEntry(b BatchRoll, phrase string) BatchEntry {
+	entry := BatchEntry{BatchRoll: b}
+	e, err := ParseExpression(b.Expr)
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	entry.Result = NewRoller(WithSeed(batchSeed(phrase, b.Name))).RollExpression(e)
+	if b.DC != 0 {
+		success := entry.Result.Total >= b.DC
+		entry.Success = &success
+	}
+	return entry
+}
+
+// RunBatch rolls every roll of f and returns the report, signed under key,
+// with t as its time. The dice of each roll depend only on the seed phrase
+// and the roll's name, so the same phrase always gives the same results,
+// and adding, removing, reordering or failing one roll leaves the others
+// as they were. A roll that fails, such as one whose expression does not
+// parse, is marked with its error and the rest are still rolled.
+func RunBatch(f *BatchFile, phrase string, t time.Time, key []byte) (*BatchReport, error) {
+	switch {
+	case phrase == "":
+		return nil, fmt.Errorf("cannot roll a batch without a seed phrase")
+	case len(key) == 0:
+		return nil, fmt.Errorf("cannot sign a batch report without a key")
+	}
+	report := &BatchReport{
+		Version:    batchReportVersion,
+		Title:      f.Title,
+		SeedPhrase: phrase,
+		Time:       t.UTC().Truncate(time.Second),
+	}
+	for _, b := range f.Rolls {
+		entry := rollBatchEntry(b, phrase)
+		if entry.Result != nil {
+			receipt, err := SignReceipt(entry.Result, report.Time, key)
+			if err != nil {
+				return nil, err
+			}
+			entry.Receipt = receipt
+		}
+		report.Entries = append(report.Entries, entry)
+	}
+	sig, err := report.sign(key)
+	if err != nil {
+		return nil, err
+	}
+	report.Signature = sig
+	return report, nil
+}
+
+// sign returns the signature of r: the HMAC-SHA256 under key of its JSON
+// with no signature, in URL-safe base64.
+func (r *BatchReport) sign(key []byte) (string, error) {
+	unsigned := *r
+	unsigned.Signature = ""
+	body, err := json.Marshal(unsigned)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(receiptMAC(body, key)), nil
+}
+
+// VerifyBatchReport checks a report written by RunBatch: its signature and
+// every receipt under key, and every roll made again from the seed phrase,
+// which must give the same result, or fail again for an entry marked as
+// failed. It returns a problem for each entry that does not check out. An
+// error means the report as a whole cannot be trusted.
+func VerifyBatchReport(r *BatchReport, key []byte) ([]string, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("cannot verify a batch report without a key")
+	}
+	if r.Version != batchReportVersion {
+		return nil, fmt.Errorf("batch report has version %d, want %d", r.Version, batchReportVersion)
+	}
+	sig, err := r.sign(key)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(sig), []byte(r.Signature)) {
+		return nil, fmt.Errorf("batch report does not match the key, it was changed or signed with another key")
+	}
+
+	var problems []string
+	for i, entry := range r.Entries {
+		where := fmt.Sprintf("entry %d (%s)", i+1, entry.Name)
+		again := rollBatchEntry(entry.BatchRoll, r.SeedPhrase)
+		switch {
+		case entry.Error != "" && again.Error == "":
+			problems = append(problems, fmt.Sprintf("%s: marked as failed, but %s rolls", where, entry.Expr))
+			continue
+		case entry.Error != "":
+			continue
+		case again.Error != "":
+			problems = append(problems, fmt.Sprintf("%s: %s no longer rolls: %s", where, entry.Expr, again.Error))
+			continue
+		}
+		receipt, err := VerifyReceipt(entry.Receipt, key)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", where, err))
+			continue
+		}
+		if !sameResult(receipt.Result, entry.Result) || !sameResult(again.Result, entry.Result) {
+			problems = append(problems, fmt.Sprintf("%s: %s is not what the seed phrase rolls, %s", where, entry.Result, again.Result))
+		}
+	}
+	return problems, nil
+}
+
+// sameResult reports whether a and b encode to the same JSON.
+func sameResult(a, b *Result) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// ReadBatchReport reads a report written by WriteBatchReport.
+func ReadBatchReport(r io.Reader) (*BatchReport, error) {
+	report := &BatchReport{}
+	if err := json.NewDecoder(r).Decode(report); err != nil {
+		return nil, fmt.Errorf("reading batch report: %w", err)
+	}
+	return report, nil
+}
+
+// WriteBatchReport writes report as JSON.
+func WriteBatchReport(w io.Writer, report *BatchReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// String describes e on a line.
+func (e BatchEntry) String() string {
+	switch {
+	case e.Error != "":
+		return fmt.Sprintf("%s: error: %s", e.Name, e.Error)
+	case e.Success == nil:
+		return fmt.Sprintf("%s: %s", e.Name, e.Result)
+	case *e.Success:
+		return fmt.Sprintf("%s: %s vs DC %d: success", e.Name, e.Result, e.DC)
+	}
+	return fmt.Sprintf("%s: %s vs DC %d: failure", e.Name, e.Result, e.DC)
+}
+
+func batchGen(args []string) error {
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	phrase := fs.String("seed-phrase", "", "phrase the rolls are seeded from, such as \"turn 7\"")
+	out := fs.String("out", "", "file to write the signed report to (default the batch file's name with .report.json)")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 2 && rest[0] == "verify" {
+		return verifyBatchGen(rest[1])
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("need to provide a batch file to roll, or verify and a report to check")
+	}
+	if *phrase == "" {
+		return fmt.Errorf("need to provide a --seed-phrase, so the rolls can be checked")
+	}
+	key, err := receiptKey()
+	if err != nil {
+		return err
+	}
+	f, err := LoadBatchFile(rest[0])
+	if err != nil {
+		return err
+	}
+
+	report, err := RunBatch(f, *phrase, time.Now(), key)
+	if err != nil {
+		return err
+	}
+	path := *out
+	if path == "" {
+		path = strings.TrimSuffix(rest[0], filepath.Ext(rest[0])) + ".report.json"
+	}
+	if err := SaveBatchReport(path, report); err != nil {
+		return err
+	}
+	for _, e := range report.Entries {
+		fmt.Println(e)
+	}
+	fmt.Println("Report written to", path)
+	if n := report.Errored(); n > 0 {
+		return fmt.Errorf("%d of %d rolls failed, marked in %s", n, len(report.Entries), path)
+	}
+	return nil
+}
+
+func verifyBatchGen(path string) error {
+	key, err := receiptKey()
+	if err != nil {
+		return err
+	}
+	report, err := LoadBatchReport(path)
+	if err != nil {
+		return err
+	}
+	problems, err := VerifyBatchReport(report, key)
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("batch report %s does not check out: %s", path, quantity(len(problems), "problem"))
+	}
+	fmt.Printf("Batch report verified: %s rolled from %q on %s", quantity(len(report.Entries), "roll"), report.SeedPhrase, report.Time.Local().Format("2006-01-02 15:04:05"))
+	if n := report.Errored(); n > 0 {
+		fmt.Printf(", %d marked as failed", n)
+	}
+	fmt.Println()
+	return nil
+}
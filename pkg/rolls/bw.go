@@ -0,0 +1,121 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+)
+
+// BWTest is the Burning Wheel advancement class of a test.
+type BWTest int
+
+const (
+	BWRoutine BWTest = iota
+	BWDifficult
+	BWChallenging
+)
+
+var bwTestNames = []string{"routine", "difficult", "challenging"}
+
+func (t BWTest) String() string {
+	return bwTestNames[t]
+}
+
+// bwRoutineMax is the highest obstacle that is routine for 1 to 10 dice.
+var bwRoutineMax = []int{0, 1, 2, 2, 3, 4, 4, 5, 6, 7}
+
+// ClassifyBW returns the advancement class of a test of obstacle rolling
+// dice dice: challenging above the dice rolled, routine at or below the
+// routine limit for that many dice, and difficult in between.
+func ClassifyBW(dice, obstacle int) BWTest {
+	routine := dice - 3
+	if dice <= len(bwRoutineMax) {
+		routine = bwRoutineMax[max(dice, 1)-1]
+	}
+	switch {
+	case obstacle > dice:
+		return BWChallenging
+	case obstacle > routine:
+		return BWDifficult
+	}
+	return BWRoutine
+}
+
+// BWResult is a Burning Wheel test.
+type BWResult struct {
+	Exponent int
+	Helpers  int
+	Obstacle int
+	// Rolls holds every die rolled, including those added by open-ended 6s.
+	Rolls     []int
+	Successes int
+	Margin    int
+	Passed    bool
+	Test      BWTest
+}
+
+// RollBW rolls a Burning Wheel test of exponent plus one die per helper
+// against obstacle, counting 4s and up as successes. When openEnded is set,
+// every 6 adds another die.
+func RollBW(exponent, obstacle int, openEnded bool, helpers int) (*BWResult, error) {
+	if exponent < 1 || helpers < 0 || obstacle < 0 {
+		return nil, fmt.Errorf("passed illegal test: exponent %d, %d helpers, Ob %d", exponent, helpers, obstacle)
+	}
+
+	dice := exponent + helpers
+	res := &BWResult{Exponent: exponent, Helpers: helpers, Obstacle: obstacle}
+	for left := dice; left > 0; left-- {
+		r := result(6)
+		res.Rolls = append(res.Rolls, r)
+		if r >= 4 {
+			res.Successes++
+		}
+		if openEnded && r == 6 {
+			left++
+		}
+	}
+	res.Margin = res.Successes - obstacle
+	res.Passed = res.Margin >= 0
+	res.Test = ClassifyBW(dice, obstacle)
+	return res, nil
+}
+
+func bwGen(args []string) error {
+	fs := flag.NewFlagSet("bw", flag.ContinueOnError)
+	ob := fs.Int("ob", 1, "obstacle of the test")
+	open := fs.Bool("open", false, "roll open-ended, adding a die for every 6")
+	help := fs.Int("help", 0, "number of helping dice")
+	exps, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(exps) != 1 {
+		return fmt.Errorf("need to provide the exponent of the test")
+	}
+	exponent, err := strconv.Atoi(exps[0])
+	if err != nil {
+		return err
+	}
+
+	res, err := RollBW(exponent, *ob, *open, *help)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Rolled:", res.Rolls)
+	outcome := "failed"
+	if res.Passed {
+		outcome = "passed"
+	}
+	fmt.Printf("Successes: %d against Ob %d, %s by %d\n", res.Successes, res.Obstacle, outcome, abs(res.Margin))
+	fmt.Printf("Test: %s\n", res.Test)
+
+	return nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
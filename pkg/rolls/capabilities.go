@@ -0,0 +1,162 @@
+package rolls
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// CapabilitiesSchema is the version of the layout of Caps, raised whenever a
+// field changes meaning or goes away. New fields leave it as it is.
+const CapabilitiesSchema = 1
+
+// Caps describes what this version of the package supports, so bots and
+// frontends can offer only what it will accept.
+type Caps struct {
+	Schema int `json:"schema"`
+	// Notation lists the features of the default notation.
+	Notation []NotationFeature `json:"notation"`
+	// Operators are the comparisons thresholds, rerolls and failures take.
+	Operators []string     `json:"operators"`
+	Dialects  []DialectCap `json:"dialects"`
+	// Commands are the subcommands Roll dispatches, in order. Anything
+	// else is rolled as expressions.
+	Commands []string `json:"commands"`
+	Limits   Limits   `json:"limits"`
+}
+
+// NotationFeature is a feature of the notation, with an expression using
+// it.
+type NotationFeature struct {
+	Name        string `json:"name"`
+	Example     string `json:"example"`
+	Description string `json:"description"`
+}
+
+// DialectCap is a dialect ParseDialect reads, with the notations from roll
+// dialects it accepts.
+type DialectCap struct {
+	Name  string   `json:"name"`
+	Reads []string `json:"reads"`
+}
+
+// Limits are the bounds the parser and roller hold expressions to. Zero
+// means no limit.
+type Limits struct {
+	MaxModifier    int `json:"max_modifier"`
+	ExplodeLimit   int `json:"explode_limit"`
+	RerollLimit    int `json:"reroll_limit"`
+	SummarizeAbove int `json:"summarize_above"`
+}
+
+// notationFeatures are the features Capabilities reports, in the order Parse
+// documents them.
+var notationFeatures = []NotationFeature{
+	{"dice", "3d6", "a pool of dice, summed"},
+	{"bonus", "3d6+4", "a flat modifier added to the total"},
+	{"expression", "2d6+1d8+3-1d4", "a sum of dice groups and flat modifiers"},
+	{"multiply", "2d6*100", "multiply a group's total by a whole number on either side"},
+	{"divide", "4d6/2", "divide a group's total, rounding down, or up with /^"},
+	{"parentheses", "2*((1d4+1)*2+1d6)", "roll a sum in parentheses, nested as deep as need be, as one group"},
+	{"keep", "4d6kh3", "keep the highest or lowest dice"},
+	{"drop", "4d6dl1", "drop the lowest or highest dice"},
+	{"keep_middle", "3d20km1", "keep the middle dice, the lower middle of an even pool"},
+	{"drop_middle", "5d6dm1", "drop the middle dice, the lower middle of an even pool"},
+	{"explode", "3d6!", "roll another die for every highest face"},
+	{"compound", "5d6!!", "add to a die again on its highest face"},
+	{"penetrate", "1d6!p", "compound, with one less for every extra roll"},
+	{"reroll", "2d6r1", "reroll matching dice once"},
+	{"reroll_recursive", "1d8rr<3", "reroll matching dice until they stop matching"},
+	{"minimum", "8d6min2", "count any die below a minimum as the minimum"},
+	{"maximum", "3d6max4", "count any die above a maximum as the maximum"},
+	{"advantage", "1d20+7adv", "roll twice over and keep the higher or lower half"},
+	{"labels", "3d{red,blue,green}", "dice with labeled faces, counted by label"},
+	{"success_pool", "8d6>=5", "count the kept dice meeting a threshold"},
+	{"failures", "10d10>=8f1", "take a success away for each failing die"},
+	{"percentile", "d%", "percentile dice, read as d100"},
+	{"fudge", "4dF+2", "Fudge dice, with faces of -1, 0 and +1"},
+}
+
+// Capabilities describes what this version of the package supports. Every
+// feature it reports is one whose example ParseExpression reads, every
+// dialect one ParseDialectName accepts and every command one roll
+// dispatches, so it never claims what the package refuses.
+func Capabilities() Caps {
+	c := Caps{
+		Schema:    CapabilitiesSchema,
+		Operators: append([]string(nil), thresholdOps...),
+		Limits: Limits{
+			MaxModifier:    MaxModifier,
+			ExplodeLimit:   ExplodeLimit,
+			RerollLimit:    RerollLimit,
+			SummarizeAbove: SummarizeAbove,
+		},
+	}
+	for _, f := range notationFeatures {
+		if _, err := ParseExpression(f.Example); err == nil {
+			c.Notation = append(c.Notation, f)
+		}
+	}
+
+	dialects := make([]Dialect, 0, len(dialectNames))
+	for _, d := range dialectNames {
+		dialects = append(dialects, d)
+	}
+	sort.Slice(dialects, func(i, j int) bool { return dialects[i] < dialects[j] })
+	for _, d := range dialects {
+		dc := DialectCap{Name: d.String(), Reads: []string{}}
+		for _, ex := range dialectExamples {
+			if ex.reads[d] != "" {
+				dc.Reads = append(dc.Reads, ex.expr)
+			}
+		}
+		c.Dialects = append(c.Dialects, dc)
+	}
+
+	for name := range commands {
+		c.Commands = append(c.Commands, name)
+	}
+	sort.Strings(c.Commands)
+	return c
+}
+
+func capabilitiesGen(args []string) error {
+	fs := flag.NewFlagSet("capabilities", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the capabilities as JSON")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("roll capabilities takes no arguments")
+	}
+
+	c := Capabilities()
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(c)
+	}
+	fmt.Printf("Schema %d\n\nNotation:\n", c.Schema)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, f := range c.Notation {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", f.Name, f.Example, f.Description)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\nOperators: %s\n", strings.Join(c.Operators, " "))
+	names := make([]string, len(c.Dialects))
+	for i, d := range c.Dialects {
+		names[i] = d.Name
+	}
+	fmt.Printf("Dialects: %s\n", strings.Join(names, ", "))
+	fmt.Printf("Commands: %s\n", strings.Join(c.Commands, ", "))
+	fmt.Printf("Limits: modifiers within %d, %d explosions and %d rerolls a die, pools over %d dice summarized\n",
+		c.Limits.MaxModifier, c.Limits.ExplodeLimit, c.Limits.RerollLimit, c.Limits.SummarizeAbove)
+	return nil
+}
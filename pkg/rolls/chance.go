@@ -0,0 +1,73 @@
+package rolls
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ChanceResult is the outcome of a percentage chance roll.
+type ChanceResult struct {
+	Chance float64
+	// Roll is the underlying roll on a 0-100 scale; it succeeds when it is
+	// below Chance*100.
+	Roll    float64
+	Success bool
+}
+
+// RollChance succeeds with probability p. A p of 0 never succeeds and a p of
+// 1 always does.
+func RollChance(p float64) (*ChanceResult, error) {
+	if !(p >= 0 && p <= 1) {
+		return nil, fmt.Errorf("chance %g must be between 0 and 1", p)
+	}
+	// Float64 is uniform over [0, 1), so the comparison is exact at both ends.
+	x := defaultRoller.float64()
+	return &ChanceResult{Chance: p, Roll: x * 100, Success: x < p}, nil
+}
+
+// plainPercent matches a percentage written as a plain decimal, such as 30
+// or 12.5, rather than in exponent form or as a word such as NaN.
+var plainPercent = regexp.MustCompile(`^(\d+(\.\d*)?|\.\d+)$`)
+
+// ParseChance parses a percentage chance such as c30% or 30%. The
+// percentage must be a plain decimal between 0 and 100.
+func ParseChance(s string) (float64, error) {
+	pct, ok := strings.CutSuffix(strings.TrimPrefix(s, "c"), "%")
+	if !ok || !plainPercent.MatchString(pct) {
+		return 0, fmt.Errorf("passed illegal chance: %s, want a percentage such as 30%% or 12.5%%", s)
+	}
+	p, err := strconv.ParseFloat(pct, 64)
+	if err != nil || p > 100 {
+		return 0, fmt.Errorf("passed illegal chance: %s, a chance cannot be above 100%%", s)
+	}
+	return p / 100, nil
+}
+
+func isChance(s string) bool {
+	return strings.HasPrefix(s, "c") && strings.HasSuffix(s, "%")
+}
+
+func chanceGen(chances []string) error {
+	if len(chances) == 0 {
+		return fmt.Errorf("need to provide a percentage chance (30%%)")
+	}
+	for _, c := range chances {
+		p, err := ParseChance(c)
+		if err != nil {
+			return err
+		}
+		res, err := RollChance(p)
+		if err != nil {
+			return err
+		}
+
+		outcome := "failure"
+		if res.Success {
+			outcome = "success"
+		}
+		fmt.Printf("%s: rolled %.2f against %g%%: %s\n", c, res.Roll, res.Chance*100, outcome)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package rolls
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseChance(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+	}{
+		{"c30%", 0.3},
+		{"30%", 0.3},
+		{"c12.5%", 0.125},
+		{"c.5%", 0.005},
+		{"c0%", 0},
+		{"c100%", 1},
+	}
+	for _, tt := range tests {
+		if got, err := ParseChance(tt.s); err != nil || got != tt.want {
+			t.Errorf("ParseChance(%q) = %v, %v, want %v", tt.s, got, err, tt.want)
+		}
+	}
+
+	for _, s := range []string{"cNaN%", "cInf%", "c+Inf%", "c1e1%", "c0x1p4%", "c-5%", "c+5%", "c1_0%", "c30", "c%", "c.%", "c101%", "c" + strings.Repeat("9", 400) + "%"} {
+		if p, err := ParseChance(s); err == nil {
+			t.Errorf("ParseChance(%q) = %v, want an error", s, p)
+		}
+	}
+}
+
+func TestRollChanceRange(t *testing.T) {
+	for _, p := range []float64{-0.1, 1.1, math.NaN(), math.Inf(1)} {
+		if _, err := RollChance(p); err == nil {
+			t.Errorf("RollChance(%v) succeeded, want an error", p)
+		}
+	}
+}
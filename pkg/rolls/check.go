@@ -0,0 +1,154 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+)
+
+// ReliableTalentFloor is the lowest a d20 can count as under Reliable
+// Talent.
+const ReliableTalentFloor = 10
+
+// Feasible reports whether expr can total at least dc at all, and whether
+// it always does, from the lowest and highest totals its dice can roll.
+// Advantage, disadvantage and keep modifiers narrow those bounds as they
+// do the roll, and exploding dice leave the total no upper limit.
+func Feasible(expr string, dc int) (possible bool, guaranteed bool, err error) {
+	e, err := ParseExpression(expr)
+	if err != nil {
+		return false, false, err
+	}
+	lo, hi, unbounded := bounds(e, 0)
+	return unbounded || hi >= dc, lo >= dc, nil
+}
+
+// bounds returns the lowest and highest totals of e rolled with kept d20s
+// floored at d20Floor, and whether exploding dice leave the total no upper
+// limit, hi then being as far as ExplodeLimit lets them go.
+func bounds(e *Expression, d20Floor int) (lo, hi int, unbounded bool) {
+	lo = e.Min()
+	if d20Floor > 1 {
+		for _, g := range e.Groups {
+			if g.Dice != nil && len(g.Scale) == 0 && g.Dice.Sides == 20 && !g.Negative {
+				lo += g.Dice.keptCount() * (min(d20Floor, 20) - 1)
+			}
+		}
+	}
+	return lo, e.Max(), e.unbounded(false)
+}
+
+// unbounded reports whether e's highest total, or with below set its
+// lowest, has no limit but ExplodeLimit, as a group of exploding dice adds
+// to it or a subtracted one takes from it.
+func (e *Expression) unbounded(below bool) bool {
+	for _, g := range e.Groups {
+		flip := below != g.Negative
+		switch {
+		case g.Sub != nil:
+			if g.Sub.unbounded(flip) {
+				return true
+			}
+		case !flip && g.Dice.explodesWithoutLimit():
+			return true
+		}
+	}
+	return false
+}
+
+// explodesWithoutLimit reports whether d's dice can explode past any total.
+// A success pool or a MaxPerDie rules it out, and so does keeping a fixed
+// number of exploding dice, whose extra dice only add more to choose from.
+func (d *Dice) explodesWithoutLimit() bool {
+	switch {
+	case d.Explode == NoExplosion, d.Success != nil, d.MaxPerDie > 0, d.Count == 0:
+		return false
+	case d.Explode == Exploding:
+		return d.Modifier == NoModifier || d.Modifier == DropMiddle
+	}
+	return true
+}
+
+func (e *Expression) hasD20() bool {
+	for _, d := range e.AllDice() {
+		if d.Sides == 20 {
+			return true
+		}
+	}
+	return false
+}
+
+func checkGen(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	dc := fs.Int("dc", 0, "difficulty class to meet or beat")
+	adv := fs.Bool("adv", false, "roll the d20 with advantage")
+	dis := fs.Bool("dis", false, "roll the d20 with disadvantage")
+	bless := fs.Bool("bless", false, "add a 1d4")
+	bane := fs.Bool("bane", false, "subtract a 1d4")
+	reliable := fs.Bool("reliable", false, "treat any kept d20 below 10 as a 10")
+	takeForward := fs.Bool("take-forward", false, "apply and use up banked forward and ongoing modifiers")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("need to provide the check's [+/-]modifier or expression")
+	}
+
+	var e *Expression
+	if modifier, err := strconv.Atoi(rest[0]); err == nil {
+		e = &Expression{Groups: []Group{{Dice: D20(modifier, *adv, *dis)}}}
+	} else {
+		if *adv || *dis {
+			return fmt.Errorf("--adv and --dis need a modifier, write the expression with adv or dis instead, e.g. 1d20+5adv")
+		}
+		if e, err = ParseExpression(rest[0]); err != nil {
+			return err
+		}
+	}
+	if e.countsSuccesses() {
+		return fmt.Errorf("passed illegal check: %s, a check needs a total rather than successes", rest[0])
+	}
+	if e, err = AddBlessBane(e, *bless, *bane); err != nil {
+		return err
+	}
+	if e, err = applyConditions(e); err != nil {
+		return err
+	}
+	if *takeForward {
+		if err := takeForwardOn(e); err != nil {
+			return err
+		}
+	}
+
+	roller := defaultRoller
+	if *reliable {
+		roller = NewRoller(WithD20Floor(ReliableTalentFloor))
+	}
+
+	lo, hi, unbounded := bounds(e, roller.d20Floor)
+	switch {
+	case hi < *dc && unbounded:
+		fmt.Printf("Impossible in practice: %s has no upper limit, but explodes at most %d times a die, totalling at most %d against DC %d\n", e, ExplodeLimit, hi, *dc)
+		return nil
+	case hi < *dc && e.hasD20():
+		fmt.Printf("Impossible even on a 20: %s totals at most %d against DC %d\n", e, hi, *dc)
+		return nil
+	case hi < *dc:
+		fmt.Printf("Impossible: %s totals at most %d against DC %d\n", e, hi, *dc)
+		return nil
+	case lo >= *dc:
+		fmt.Printf("Cannot fail: %s totals at least %d against DC %d\n", e, lo, *dc)
+		return nil
+	}
+
+	res := roller.RollExpression(e)
+	logHistory(res)
+	fmt.Println(res)
+	if res.Total >= *dc {
+		fmt.Printf("Success against DC %d\n", *dc)
+	} else {
+		fmt.Printf("Failure against DC %d\n", *dc)
+	}
+	return nil
+}
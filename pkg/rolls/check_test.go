@@ -0,0 +1,60 @@
+package rolls
+
+import "testing"
+
+func TestBounds(t *testing.T) {
+	tests := []struct {
+		expr      string
+		floor     int
+		lo, hi    int
+		unbounded bool
+	}{
+		{"1d20+5", 0, 6, 25, false},
+		{"1d20+5", ReliableTalentFloor, 15, 25, false},
+		{"2d20kh1-1d4", 0, -3, 19, false},
+		{"3d6!", 0, 3, 3 * (ExplodeLimit + 1) * 6, true},
+		{"3d6!!", 0, 3, 3 * (ExplodeLimit + 1) * 6, true},
+		{"1d6!p", 0, 1, 6 + ExplodeLimit*5, true},
+		{"4d6!kh1+2", 0, 3, 6 + 2, false},
+		{"4d6!!kh1+2", 0, 3, 6*(ExplodeLimit+1) + 2, true},
+		{"0d6!", 0, 0, 0, false},
+		{"(1d6!+1)*2", 0, 4, (6*(ExplodeLimit+1) + 1) * 2, true},
+		{"1d20-1d6!", 0, 1 - 6*(ExplodeLimit+1), 19, false},
+		{"10-(5-1d6!)", 0, 6, 11 + 6*(ExplodeLimit+1) - 6 + 1 - 1, true},
+		{"3d6!min2max5", 0, 6, 3 * (ExplodeLimit + 1) * 5, false},
+		{"5d10!>=8", 0, 0, 5 * (ExplodeLimit + 1), false},
+	}
+	for _, tt := range tests {
+		e, err := ParseExpression(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lo, hi, unbounded := bounds(e, tt.floor)
+		if lo != tt.lo || hi != tt.hi || unbounded != tt.unbounded {
+			t.Errorf("bounds(%s, %d) = %d, %d, %v, want %d, %d, %v", tt.expr, tt.floor, lo, hi, unbounded, tt.lo, tt.hi, tt.unbounded)
+		}
+	}
+}
+
+func TestFeasible(t *testing.T) {
+	tests := []struct {
+		expr                 string
+		dc                   int
+		possible, guaranteed bool
+	}{
+		{"1d20+5", 26, false, false},
+		{"1d20+5", 25, true, false},
+		{"1d20+5", 6, true, true},
+		{"3d6", 19, false, false},
+		// Exploding dice can total anything, however high.
+		{"3d6!", 19, true, false},
+		{"3d6!", 5000, true, false},
+		{"3d6!min2max5", 5000, false, false},
+	}
+	for _, tt := range tests {
+		possible, guaranteed, err := Feasible(tt.expr, tt.dc)
+		if err != nil || possible != tt.possible || guaranteed != tt.guaranteed {
+			t.Errorf("Feasible(%s, %d) = %v, %v, %v, want %v, %v", tt.expr, tt.dc, possible, guaranteed, err, tt.possible, tt.guaranteed)
+		}
+	}
+}
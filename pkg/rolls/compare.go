@@ -0,0 +1,97 @@
+package rolls
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+)
+
+// Difference is one way a result differs from the one it was compared with.
+type Difference struct {
+	// Field names what differs, such as sides or groups[2].bonus.
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+func (d Difference) String() string {
+	return fmt.Sprintf("%s changed from %s to %s", d.Field, d.Old, d.New)
+}
+
+// CompareResults lists how b differs from a: first its shape, the
+// expression, sides, number of dice rolled and kept, bonus and groups, and
+// then what it rolled. Rolls are only compared when both rolled the same
+// number of dice, since otherwise every die differs. Groups are compared in
+// order, their fields named groups[1], groups[2] and so on. A nil result
+// differs from any other.
+func CompareResults(a, b *Result) []Difference {
+	var diffs []Difference
+	compareResults(&diffs, "", a, b)
+	return diffs
+}
+
+func compareResults(diffs *[]Difference, prefix string, a, b *Result) {
+	add := func(field, old, new string) {
+		if old != new {
+			*diffs = append(*diffs, Difference{Field: prefix + field, Old: old, New: new})
+		}
+	}
+	if a == nil || b == nil {
+		if a != b {
+			add("result", describeResult(a), describeResult(b))
+		}
+		return
+	}
+
+	add("expression", a.Expression, b.Expression)
+	add("sides", strconv.Itoa(a.Sides), strconv.Itoa(b.Sides))
+	add("dice", strconv.Itoa(a.diceRolled()), strconv.Itoa(b.diceRolled()))
+	add("kept", strconv.Itoa(len(a.Kept)), strconv.Itoa(len(b.Kept)))
+	add("bonus", strconv.Itoa(a.Bonus), strconv.Itoa(b.Bonus))
+	add("negative", strconv.FormatBool(a.Negative), strconv.FormatBool(b.Negative))
+	add("label", strconv.Quote(a.Label), strconv.Quote(b.Label))
+	add("groups", strconv.Itoa(len(a.Groups)), strconv.Itoa(len(b.Groups)))
+	for i := range min(len(a.Groups), len(b.Groups)) {
+		compareResults(diffs, fmt.Sprintf("%sgroups[%d].", prefix, i+1), a.Groups[i], b.Groups[i])
+	}
+
+	if a.diceRolled() == b.diceRolled() && !slices.Equal(a.Rolls, b.Rolls) {
+		add("rolls", "["+joinInts(a.Rolls, " ")+"]", "["+joinInts(b.Rolls, " ")+"]")
+	}
+	add("total", strconv.Itoa(a.Total), strconv.Itoa(b.Total))
+	add("successes", strconv.Itoa(a.Successes), strconv.Itoa(b.Successes))
+}
+
+// diceRolled returns how many dice r rolled, counting summarized ones.
+func (r *Result) diceRolled() int {
+	if r.Summary != nil {
+		return r.Summary.Count
+	}
+	return len(r.Rolls)
+}
+
+func describeResult(r *Result) string {
+	if r == nil {
+		return "nothing"
+	}
+	return r.Expression
+}
+
+// ReadGolden reads named results written by WriteGolden.
+func ReadGolden(r io.Reader) (map[string]*Result, error) {
+	golden := make(map[string]*Result)
+	if err := json.NewDecoder(r).Decode(&golden); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return golden, nil
+}
+
+// WriteGolden writes named results as JSON, for later rolls to be compared
+// with.
+func WriteGolden(w io.Writer, golden map[string]*Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(golden)
+}
@@ -0,0 +1,64 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+)
+
+// ConcentrationResult is a Constitution save to keep concentration after
+// taking damage.
+type ConcentrationResult struct {
+	Damage int
+	DC     int
+	Save   *Result
+	Passed bool
+}
+
+// ConcentrationDC returns the save DC for taking damage while concentrating:
+// half the damage rounded down, but never less than 10.
+func ConcentrationDC(damage int) int {
+	return max(10, damage/2)
+}
+
+// RollConcentration rolls the Constitution save to keep concentration after
+// taking damage, with advantage when adv is set (as from War Caster).
+func RollConcentration(damage, conSaveMod int, adv bool) (*ConcentrationResult, error) {
+	if damage < 0 {
+		return nil, fmt.Errorf("damage must not be negative, got %d", damage)
+	}
+	dc := ConcentrationDC(damage)
+	save := RollD20(conSaveMod, adv, false)
+	return &ConcentrationResult{
+		Damage: damage,
+		DC:     dc,
+		Save:   save,
+		Passed: save.Total >= dc,
+	}, nil
+}
+
+func concentrationGen(args []string) error {
+	fs := flag.NewFlagSet("concentration", flag.ContinueOnError)
+	damage := fs.Int("damage", 0, "damage taken")
+	mod := fs.Int("mod", 0, "constitution saving throw modifier")
+	adv := fs.Bool("adv", false, "roll the save with advantage")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	res, err := RollConcentration(*damage, *mod, *adv)
+	if err != nil {
+		return err
+	}
+
+	logHistory(res.Save)
+
+	fmt.Printf("DC: %d (%d damage)\n", res.DC, res.Damage)
+	fmt.Println("Save:", res.Save)
+	if res.Passed {
+		fmt.Println("Concentration held")
+	} else {
+		fmt.Println("Concentration lost")
+	}
+
+	return nil
+}
@@ -0,0 +1,292 @@
+package rolls
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Condition is an ongoing effect on every d20 roll until it is cleared.
+type Condition struct {
+	Name  string `json:"name"`
+	Level int    `json:"level,omitempty"`
+	// Effect is adv, dis, or dice or a number to add, such as -1d4 or -2.
+	Effect string `json:"effect"`
+	// Rounds is how many more rounds of a saved encounter the condition
+	// lasts, counted down by roll turn next. Zero lasts until cleared.
+	Rounds int `json:"rounds,omitempty"`
+}
+
+// ExhaustionPenalty is the penalty to d20 rolls per level of exhaustion.
+const ExhaustionPenalty = 2
+
+// MaxExhaustion is the highest level of exhaustion.
+const MaxExhaustion = 6
+
+// knownConditions are the effects of conditions that can be set by name
+// alone.
+var knownConditions = map[string]string{
+	"poisoned":   "dis",
+	"frightened": "dis",
+	"blessed":    "+1d4",
+	"baned":      "-1d4",
+}
+
+// NewCondition returns the named condition. Exhaustion takes its level as
+// arg, other known conditions take nothing, and any other name takes its
+// effect as arg.
+func NewCondition(name, arg string) (*Condition, error) {
+	c := &Condition{Name: name}
+	switch effect, known := knownConditions[name]; {
+	case name == "exhaustion":
+		level, err := strconv.Atoi(arg)
+		if err != nil || level < 1 || level > MaxExhaustion {
+			return nil, fmt.Errorf("passed illegal exhaustion level: %q, want 1 to %d", arg, MaxExhaustion)
+		}
+		c.Level, c.Effect = level, strconv.Itoa(-ExhaustionPenalty*level)
+	case known && arg == "":
+		c.Effect = effect
+	case arg == "":
+		return nil, fmt.Errorf("need to provide the effect of %s, such as dis, adv, -1d4 or -2", name)
+	default:
+		c.Effect = arg
+	}
+	if _, err := c.effect(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Condition) String() string {
+	s := fmt.Sprintf("%s (%s)", c.Name, c.Effect)
+	if c.Level > 0 {
+		s = fmt.Sprintf("%s %d (%s)", c.Name, c.Level, c.Effect)
+	}
+	if c.Rounds > 0 {
+		s += fmt.Sprintf(", %s left", quantity(c.Rounds, "round"))
+	}
+	return s
+}
+
+// conditionEffect is a parsed Condition.Effect.
+type conditionEffect struct {
+	mode   Modifier
+	groups []Group
+	bonus  int
+}
+
+func (c *Condition) effect() (conditionEffect, error) {
+	switch c.Effect {
+	case "adv":
+		return conditionEffect{mode: KeepHighest}, nil
+	case "dis":
+		return conditionEffect{mode: KeepLowest}, nil
+	}
+	if n, err := strconv.Atoi(c.Effect); err == nil {
+		return conditionEffect{bonus: n}, nil
+	}
+	e, err := ParseExpression(c.Effect)
+	if err != nil {
+		return conditionEffect{}, fmt.Errorf("passed illegal effect for %s: %q, want dis, adv, dice such as -1d4 or a number", c.Name, c.Effect)
+	}
+	e = e.flatten()
+	for i := range e.Groups {
+		e.Groups[i].Label = c.Name
+	}
+	return conditionEffect{groups: e.Groups, bonus: e.Bonus}, nil
+}
+
+// ConditionSet holds the active conditions by name.
+type ConditionSet map[string]*Condition
+
+// Apply rewrites e with every condition in s, in name order, and returns
+// notes describing each change for the output. Expressions without a d20,
+// or that count successes, are returned unchanged. Added dice and numbers
+// all apply. Advantage and disadvantage do not stack: any source of each,
+// the expression's own included, cancel out to a straight roll. They apply
+// to the first single d20 of the expression, rolled plainly or with
+// advantage or disadvantage.
+func (s ConditionSet) Apply(e *Expression) (*Expression, []string, error) {
+	if len(s) == 0 || !e.hasD20() {
+		return e, nil, nil
+	}
+	if e.countsSuccesses() {
+		return e, nil, nil
+	}
+
+	out := e.flatten()
+	var notes []string
+	adv, dis := false, false
+	for _, name := range s.names() {
+		c := s[name]
+		eff, err := c.effect()
+		if err != nil {
+			return nil, nil, err
+		}
+		out.Groups = append(out.Groups, eff.groups...)
+		out.Bonus += eff.bonus
+		adv = adv || eff.mode == KeepHighest
+		dis = dis || eff.mode == KeepLowest
+		notes = append(notes, "Condition: "+c.String())
+	}
+	if !adv && !dis {
+		return out, notes, nil
+	}
+
+	for i, g := range out.Groups {
+		if g.Dice == nil || len(g.Scale) > 0 {
+			continue
+		}
+		own, ok := d20Mode(g.Dice)
+		if !ok || g.Negative {
+			continue
+		}
+		a, d := adv || own == KeepHighest, dis || own == KeepLowest
+		var mode Modifier
+		switch {
+		case a && d:
+			notes = append(notes, "Advantage and disadvantage cancel out")
+		case a:
+			mode = KeepHighest
+		case d:
+			mode = KeepLowest
+		}
+		dice := D20(0, mode == KeepHighest, mode == KeepLowest)
+		out.Groups[i].Dice = dice
+		return out, notes, nil
+	}
+	return out, append(notes, "No single d20 to apply advantage or disadvantage to"), nil
+}
+
+// d20Mode reports whether d is a single d20 and whether it is rolled with
+// advantage or disadvantage.
+func d20Mode(d *Dice) (Modifier, bool) {
+	switch {
+	case d.Sides != 20:
+		return NoModifier, false
+	case d.Count == 1:
+		return NoModifier, true
+	case d.Count == 2 && d.ModifierCount == 1:
+		return d.Modifier, d.Modifier == KeepHighest || d.Modifier == KeepLowest
+	}
+	return NoModifier, false
+}
+
+// Tick counts a round down on every condition that lasts a number of
+// rounds, removes those that run out and returns them.
+func (s ConditionSet) Tick() []*Condition {
+	var ended []*Condition
+	for _, name := range s.names() {
+		c := s[name]
+		if c.Rounds == 0 {
+			continue
+		}
+		if c.Rounds--; c.Rounds == 0 {
+			delete(s, name)
+			ended = append(ended, c)
+		}
+	}
+	return ended
+}
+
+func (s ConditionSet) names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ReadConditions reads a condition set written by WriteConditions.
+func ReadConditions(r io.Reader) (ConditionSet, error) {
+	s := ConditionSet{}
+	if err := json.NewDecoder(r).Decode(&s); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return s, nil
+}
+
+// WriteConditions writes s as JSON.
+func WriteConditions(w io.Writer, s ConditionSet) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// ApplyConditions rewrites e with the saved conditions, as the d20 commands
+// do before rolling.
+func ApplyConditions(e *Expression) (*Expression, []string, error) {
+	s, err := LoadConditions()
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.Apply(e)
+}
+
+// applyConditions is ApplyConditions for the subcommands, which print the
+// notes before rolling.
+func applyConditions(e *Expression) (*Expression, error) {
+	e, notes, err := ApplyConditions(e)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range notes {
+		fmt.Println(n)
+	}
+	return e, nil
+}
+
+func conditionGen(args []string) error {
+	fs := flag.NewFlagSet("condition", flag.ContinueOnError)
+	rounds := fs.Int("rounds", 0, "with set, how many rounds of a saved encounter the condition lasts")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		return fmt.Errorf("need to provide a condition command: set, clear or list")
+	}
+
+	s, err := LoadConditions()
+	if err != nil {
+		return err
+	}
+	switch {
+	case rest[0] == "set" && (len(rest) == 2 || len(rest) == 3):
+		arg := ""
+		if len(rest) == 3 {
+			arg = rest[2]
+		}
+		c, err := NewCondition(rest[1], arg)
+		if err != nil {
+			return err
+		}
+		if *rounds < 0 {
+			return fmt.Errorf("passed illegal rounds: %d", *rounds)
+		}
+		c.Rounds = *rounds
+		s[c.Name] = c
+		fmt.Println("Set:", c)
+		return SaveConditions(s)
+	case rest[0] == "clear" && len(rest) <= 2:
+		if len(rest) == 1 {
+			return SaveConditions(ConditionSet{})
+		}
+		if _, ok := s[rest[1]]; !ok {
+			return fmt.Errorf("no condition named %q", rest[1])
+		}
+		delete(s, rest[1])
+		return SaveConditions(s)
+	case rest[0] == "list" && len(rest) == 1:
+		for _, name := range s.names() {
+			fmt.Println(s[name])
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown condition command %q, want set NAME [LEVEL|EFFECT], clear [NAME] or list", strings.Join(rest, " "))
+}
@@ -0,0 +1,122 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Conditional rolls OnSuccess only when the total of Check meets Gate, such
+// as damage gated on an attack roll or an effect gated on a failed save.
+type Conditional struct {
+	Check     *Dice
+	Gate      *Threshold
+	OnSuccess *Dice
+	// RollOnFailure rolls OnSuccess even when the gate fails, so the roll
+	// can be shown struck out.
+	RollOnFailure bool
+}
+
+// ConditionalResult is a rolled Conditional.
+type ConditionalResult struct {
+	Check  *Result
+	Gate   *Threshold
+	Passed bool
+	// Then is the OnSuccess roll. It is nil when the gate failed unless the
+	// Conditional rolls on failure, in which case it does not count.
+	Then *Result
+}
+
+// RollConditional rolls check and, if its total is at least dc, onSuccess.
+func RollConditional(check *Dice, dc int, onSuccess *Dice) *ConditionalResult {
+	return (&Conditional{Check: check, Gate: &Threshold{Op: ">=", Target: dc}, OnSuccess: onSuccess}).Roll()
+}
+
+// ParseConditional parses a conditional such as "1d20+7 >= 15 ? 2d6+4".
+func ParseConditional(expr string) (*Conditional, error) {
+	gate, then, ok := strings.Cut(strings.ReplaceAll(expr, " ", ""), "?")
+	if !ok {
+		return nil, fmt.Errorf("passed illegal conditional: %s, want CHECK>=DC?DICE", expr)
+	}
+	c := &Conditional{}
+	for _, op := range thresholdOps {
+		i := strings.Index(gate, op)
+		if i < 0 {
+			continue
+		}
+		target, err := strconv.Atoi(gate[i+len(op):])
+		if err != nil {
+			return nil, err
+		}
+		c.Gate = &Threshold{Op: op, Target: target}
+		gate = gate[:i]
+		break
+	}
+	if c.Gate == nil {
+		return nil, fmt.Errorf("passed illegal conditional: %s, the check needs a comparison such as >=15", expr)
+	}
+
+	var err error
+	if c.Check, err = Parse(gate); err != nil {
+		return nil, err
+	}
+	if c.OnSuccess, err = Parse(then); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Roll rolls the check and, when it passes the gate, the follow-up dice.
+func (c *Conditional) Roll() *ConditionalResult {
+	res := &ConditionalResult{Check: c.Check.Roll(), Gate: c.Gate}
+	res.Passed = c.Gate.Matches(res.Check.Total)
+	if res.Passed || c.RollOnFailure {
+		res.Then = c.OnSuccess.Roll()
+	}
+	return res
+}
+
+func (r *ConditionalResult) String() string {
+	var b strings.Builder
+	outcome := "failed"
+	if r.Passed {
+		outcome = "passed"
+	}
+	fmt.Fprintf(&b, "%s %s %s", r.Check, r.Gate, outcome)
+	switch {
+	case r.Then == nil:
+	case r.Passed:
+		fmt.Fprintf(&b, "\nThen: %s", r.Then)
+	default:
+		fmt.Fprintf(&b, "\nSkipped: %s", r.Then)
+	}
+	return b.String()
+}
+
+func conditionalGen(args []string) error {
+	fs := flag.NewFlagSet("conditional", flag.ContinueOnError)
+	showSkipped := fs.Bool("show-skipped", false, "roll and show the follow-up dice even when the check fails")
+	exprs, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	for _, expr := range exprs {
+		c, err := ParseConditional(expr)
+		if err != nil {
+			return err
+		}
+		c.RollOnFailure = *showSkipped
+		res := c.Roll()
+
+		rolled := []*Result{res.Check}
+		if res.Passed {
+			rolled = append(rolled, res.Then)
+		}
+		logHistory(rolled...)
+		fmt.Println(res)
+	}
+
+	return nil
+}
@@ -0,0 +1,255 @@
+package rolls
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Config is the user's configuration file.
+type Config struct {
+	// Sets maps a dice set's name to its dice, such as
+	// "d4 d6 d8 d10 d% d12 d20".
+	Sets map[string]string `json:"sets,omitempty"`
+	// Defaults maps a subcommand to default values for its flags, such as
+	// {"check": {"dc": "15"}}. Defaults under AllCommands apply to every
+	// subcommand with a flag of that name; roll itself, without a
+	// subcommand, is "roll".
+	Defaults map[string]map[string]string `json:"defaults,omitempty"`
+	// ReceiptKey signs the receipts of roll --receipt and checks them for
+	// roll verify. Share it only with whoever should check receipts: it can
+	// sign them too.
+	ReceiptKey string `json:"receipt_key,omitempty"`
+	// Profiles maps a system profile's name to the dice and notation it
+	// allows, alongside or replacing BuiltinProfiles, for roll --profile.
+	Profiles map[string]*Profile `json:"profiles,omitempty"`
+}
+
+// AllCommands is the Config.Defaults key whose flags apply to every
+// subcommand.
+const AllCommands = "*"
+
+// ReadConfig reads a JSON config from r.
+func ReadConfig(r io.Reader) (*Config, error) {
+	c := &Config{}
+	if err := json.NewDecoder(r).Decode(c); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	return c, nil
+}
+
+// FlagSource is the layer a flag's value came from. Each layer overrides
+// the ones before it: built-in defaults, then the config file, then the
+// environment, then the command line.
+type FlagSource int
+
+const (
+	FromBuiltin FlagSource = iota
+	FromConfig
+	FromEnv
+	FromCommandLine
+)
+
+func (s FlagSource) String() string {
+	switch s {
+	case FromConfig:
+		return "config"
+	case FromEnv:
+		return "env"
+	case FromCommandLine:
+		return "command line"
+	}
+	return "built-in"
+}
+
+// FlagDefault is a default a layer sets for a subcommand's flag.
+type FlagDefault struct {
+	Command string
+	Flag    string
+	Value   string
+	Source  FlagSource
+	// From names where the value was set, such as the environment variable.
+	From string
+}
+
+// EnvName returns the environment variable that sets the default of flag
+// for command: ROLL_CHECK_DC for check's --dc, or ROLL_DEFAULT_FULL for a
+// --full default under AllCommands.
+func EnvName(command, flag string) string {
+	if command == AllCommands {
+		command = "default"
+	}
+	name := "ROLL_" + command + "_" + flag
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// FlagDefaults returns every default cfg and environ, a list of KEY=value
+// pairs as os.Environ returns, set for the flags of command, in the order
+// they apply: a later default for the same flag wins. Within a layer,
+// defaults for command win over those under AllCommands. flags lists the
+// flags command has.
+func FlagDefaults(cfg *Config, environ []string, command string, flags []string) ([]FlagDefault, error) {
+	has := make(map[string]bool, len(flags))
+	for _, f := range flags {
+		has[f] = true
+	}
+	env := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+
+	scopes := []string{AllCommands, command}
+	if command == AllCommands {
+		scopes = scopes[:1]
+	}
+	var defaults []FlagDefault
+	for _, scope := range scopes {
+		names := make([]string, 0, len(cfg.Defaults[scope]))
+		for name := range cfg.Defaults[scope] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !has[name] {
+				if scope == AllCommands {
+					continue
+				}
+				return nil, fmt.Errorf("the config file sets a default for --%s, which %s does not have", name, command)
+			}
+			defaults = append(defaults, FlagDefault{
+				Command: command,
+				Flag:    name,
+				Value:   cfg.Defaults[scope][name],
+				Source:  FromConfig,
+				From:    "defaults." + scope,
+			})
+		}
+	}
+	for _, scope := range scopes {
+		for _, name := range flags {
+			key := EnvName(scope, name)
+			if v, ok := env[key]; ok {
+				defaults = append(defaults, FlagDefault{Command: command, Flag: name, Value: v, Source: FromEnv, From: key})
+			}
+		}
+	}
+	return defaults, nil
+}
+
+// ApplyDefaults sets fs's flags to the defaults the config file and the
+// environment hold for command, ready for the command line to override.
+// Every subcommand's flags go through it before parsing.
+func ApplyDefaults(fs *flag.FlagSet, command string) error {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	var flags []string
+	fs.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, f.Name)
+	})
+	defaults, err := FlagDefaults(cfg, os.Environ(), command, flags)
+	if err != nil {
+		return err
+	}
+	for _, d := range defaults {
+		if err := fs.Set(d.Flag, d.Value); err != nil {
+			return fmt.Errorf("bad default for %s --%s from %s: %w", command, d.Flag, d.From, err)
+		}
+	}
+	return nil
+}
+
+func configGen(args []string) error {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || positional[0] != "show" {
+		return fmt.Errorf("need a config command: show")
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	path, err := ConfigPath()
+	if err != nil {
+		return err
+	}
+	fmt.Println("config file:", path)
+	for _, line := range showDefaults(cfg, os.Environ()) {
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// showDefaults describes every flag default in cfg and environ, one line per
+// command and flag, with the value that wins, where it came from and what it
+// overrides.
+func showDefaults(cfg *Config, environ []string) []string {
+	flags := make(map[string]map[string]bool)
+	add := func(command, flag string) {
+		if flags[command] == nil {
+			flags[command] = make(map[string]bool)
+		}
+		flags[command][flag] = true
+	}
+	for command, defaults := range cfg.Defaults {
+		for flag := range defaults {
+			add(command, flag)
+		}
+	}
+	for _, kv := range environ {
+		k, _, _ := strings.Cut(kv, "=")
+		command, flag, ok := strings.Cut(strings.TrimPrefix(k, "ROLL_"), "_")
+		if !ok || !strings.HasPrefix(k, "ROLL_") {
+			continue
+		}
+		command = strings.ToLower(command)
+		if command == "default" {
+			command = AllCommands
+		}
+		add(command, strings.ToLower(strings.ReplaceAll(flag, "_", "-")))
+	}
+
+	commands := make([]string, 0, len(flags))
+	for command := range flags {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+	var lines []string
+	for _, command := range commands {
+		names := make([]string, 0, len(flags[command]))
+		for name := range flags[command] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		defaults, _ := FlagDefaults(cfg, environ, command, names)
+		for _, name := range names {
+			var layers []FlagDefault
+			for _, d := range defaults {
+				if d.Flag == name {
+					layers = append(layers, d)
+				}
+			}
+			win := layers[len(layers)-1]
+			line := fmt.Sprintf("%s --%s=%s (%s %s)", command, name, win.Value, win.Source, win.From)
+			for i := len(layers) - 2; i >= 0; i-- {
+				line += fmt.Sprintf(", overrides %s %s=%s", layers[i].Source, layers[i].From, layers[i].Value)
+			}
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "no flag defaults set")
+	}
+	return lines
+}
@@ -0,0 +1,132 @@
+package rolls
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFlagDefaults(t *testing.T) {
+	cfg := &Config{Defaults: map[string]map[string]string{
+		AllCommands: {"plain": "true", "full": "true", "nope": "1"},
+		"check":     {"dc": "15", "plain": "false"},
+	}}
+	tests := []struct {
+		name    string
+		environ []string
+		command string
+		flags   []string
+		want    []string
+	}{
+		{"config only", nil, "check", []string{"dc", "plain", "full"}, []string{
+			"full=true config defaults.*",
+			"plain=true config defaults.*",
+			"dc=15 config defaults.check",
+			"plain=false config defaults.check",
+		}},
+		{"env after config", []string{"ROLL_CHECK_DC=12", "ROLL_DEFAULT_FULL=false", "HOME=/root"}, "check", []string{"dc", "plain", "full"}, []string{
+			"full=true config defaults.*",
+			"plain=true config defaults.*",
+			"dc=15 config defaults.check",
+			"plain=false config defaults.check",
+			"full=false env ROLL_DEFAULT_FULL",
+			"dc=12 env ROLL_CHECK_DC",
+		}},
+		{"command env after default env", []string{"ROLL_ATTACK_PLAIN=false", "ROLL_DEFAULT_PLAIN=true"}, "attack", []string{"plain"}, []string{
+			"plain=true config defaults.*",
+			"plain=true env ROLL_DEFAULT_PLAIN",
+			"plain=false env ROLL_ATTACK_PLAIN",
+		}},
+		{"flags the command lacks", []string{"ROLL_ROLL_DC=3"}, "roll", []string{"full"}, []string{
+			"full=true config defaults.*",
+		}},
+	}
+	for _, tt := range tests {
+		defaults, err := FlagDefaults(cfg, tt.environ, tt.command, tt.flags)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []string
+		for _, d := range defaults {
+			got = append(got, d.Flag+"="+d.Value+" "+d.Source.String()+" "+d.From)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// Only a default under AllCommands may name a flag the command lacks.
+	if _, err := FlagDefaults(cfg, nil, "check", []string{"plain"}); err == nil {
+		t.Error("a check default for --dc, which check lacks, was accepted")
+	}
+}
+
+func TestShowDefaults(t *testing.T) {
+	cfg := &Config{Defaults: map[string]map[string]string{
+		AllCommands: {"plain": "true"},
+		"check":     {"dc": "15"},
+	}}
+	got := showDefaults(cfg, []string{"ROLL_CHECK_DC=12", "ROLL_DEFAULT_PLAIN=false", "PATH=/bin"})
+	want := []string{
+		"* --plain=false (env ROLL_DEFAULT_PLAIN), overrides config defaults.*=true",
+		"check --dc=12 (env ROLL_CHECK_DC), overrides config defaults.check=15",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("showDefaults = %q, want %q", got, want)
+	}
+	if got := showDefaults(&Config{}, nil); !slices.Equal(got, []string{"no flag defaults set"}) {
+		t.Errorf("showDefaults with none set = %q", got)
+	}
+}
+
+// TestParseArgsPrecedence checks each layer overrides the ones before it:
+// built-in defaults, the config file, the environment, the command line.
+func TestParseArgsPrecedence(t *testing.T) {
+	if runtime.GOOS == "js" {
+		t.Skip("js builds read no config file")
+	}
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"defaults": {"*": {"plain": "true"}, "check": {"dc": "15", "bonus": "2"}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ROLL_CONFIG", path)
+	t.Setenv("ROLL_CHECK_BONUS", "4")
+
+	tests := []struct {
+		args              []string
+		dc, bonus, reroll int
+		plain             bool
+	}{
+		{nil, 15, 4, 0, true},
+		{[]string{"--dc", "18"}, 18, 4, 0, true},
+		{[]string{"1d20", "--bonus=-1", "--plain=false"}, 15, -1, 0, false},
+		{[]string{"--reroll", "1", "-2"}, 15, 4, 1, true},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("check", flag.ContinueOnError)
+		dc := fs.Int("dc", 10, "")
+		bonus := fs.Int("bonus", 0, "")
+		reroll := fs.Int("reroll", 0, "")
+		plain := fs.Bool("plain", false, "")
+		if _, err := parseArgs(fs, tt.args); err != nil {
+			t.Fatalf("%q: %v", tt.args, err)
+		}
+		if *dc != tt.dc || *bonus != tt.bonus || *reroll != tt.reroll || *plain != tt.plain {
+			t.Errorf("%q = dc %d, bonus %d, reroll %d, plain %v, want %d, %d, %d, %v",
+				tt.args, *dc, *bonus, *reroll, *plain, tt.dc, tt.bonus, tt.reroll, tt.plain)
+		}
+	}
+
+	t.Setenv("ROLL_CHECK_DC", "hard")
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	fs.Int("dc", 10, "")
+	fs.Bool("plain", false, "")
+	fs.Int("bonus", 0, "")
+	if _, err := parseArgs(fs, nil); err == nil || !strings.Contains(err.Error(), "ROLL_CHECK_DC") {
+		t.Errorf("a bad default from the environment = %v, want an error naming it", err)
+	}
+}
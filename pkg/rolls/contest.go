@@ -0,0 +1,144 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+	"math"
+)
+
+// Estimate is a simulated probability with its 95% confidence interval.
+type Estimate struct {
+	P    float64
+	Low  float64
+	High float64
+}
+
+// ContestOdds are the exact chances of each outcome of a contest.
+type ContestOdds struct {
+	Win  float64
+	Tie  float64
+	Loss float64
+}
+
+// ContestResult summarizes n simulated opposed rolls of A against B, with
+// outcomes counted from A's side.
+type ContestResult struct {
+	A, B   *Dice
+	N      int
+	Wins   int
+	Ties   int
+	Losses int
+	Win    Estimate
+	Tie    Estimate
+	Loss   Estimate
+	// Exact holds the exact odds when both distributions are available.
+	Exact *ContestOdds
+}
+
+// SimulateContest rolls the expressions a and b against each other n times
+// and reports how often a wins, ties and loses. The exact odds are computed
+// too when both expressions have a distribution.
+func SimulateContest(a, b string, n int) (*ContestResult, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("need at least one contest, got %d", n)
+	}
+	da, err := Parse(a)
+	if err != nil {
+		return nil, err
+	}
+	db, err := Parse(b)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ContestResult{A: da, B: db, N: n}
+	for i := 0; i < n; i++ {
+		ta, tb := da.Roll().Total, db.Roll().Total
+		switch {
+		case ta > tb:
+			res.Wins++
+		case ta < tb:
+			res.Losses++
+		default:
+			res.Ties++
+		}
+	}
+	res.Win = wilson(res.Wins, n)
+	res.Tie = wilson(res.Ties, n)
+	res.Loss = wilson(res.Losses, n)
+
+	if exact, err := ExactContest(da, db); err == nil {
+		res.Exact = exact
+	}
+	return res, nil
+}
+
+// ExactContest returns the exact odds of a beating, tying and losing to b.
+func ExactContest(a, b *Dice) (*ContestOdds, error) {
+	distA, err := a.Distribution()
+	if err != nil {
+		return nil, err
+	}
+	distB, err := b.Distribution()
+	if err != nil {
+		return nil, err
+	}
+
+	odds := &ContestOdds{}
+	for ta, pa := range distA {
+		for tb, pb := range distB {
+			switch {
+			case ta > tb:
+				odds.Win += pa * pb
+			case ta < tb:
+				odds.Loss += pa * pb
+			default:
+				odds.Tie += pa * pb
+			}
+		}
+	}
+	return odds, nil
+}
+
+// wilson returns the Wilson score interval for k successes in n trials,
+// which stays inside [0, 1] even for rare outcomes.
+func wilson(k, n int) Estimate {
+	const z = 1.96
+	p := float64(k) / float64(n)
+	nf := float64(n)
+	denom := 1 + z*z/nf
+	center := (p + z*z/(2*nf)) / denom
+	half := z * math.Sqrt(p*(1-p)/nf+z*z/(4*nf*nf)) / denom
+	return Estimate{P: p, Low: center - half, High: center + half}
+}
+
+func (e Estimate) String() string {
+	return fmt.Sprintf("%.2f%% (95%% CI %.2f%%-%.2f%%)", e.P*100, e.Low*100, e.High*100)
+}
+
+func contestGen(args []string) error {
+	fs := flag.NewFlagSet("contest", flag.ContinueOnError)
+	n := fs.Int("n", 10000, "number of contests to simulate")
+	exprs, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(exprs) != 2 {
+		return fmt.Errorf("need to provide two expressions to contest (1d20+7adv 1d20+9)")
+	}
+
+	res, err := SimulateContest(exprs[0], exprs[1], *n)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s vs %s over %d contests\n", res.A, res.B, res.N)
+	fmt.Println("Win: ", res.Win)
+	fmt.Println("Tie: ", res.Tie)
+	fmt.Println("Loss:", res.Loss)
+	if res.Exact != nil {
+		fmt.Printf("Exact: win %.2f%% tie %.2f%% loss %.2f%%\n", res.Exact.Win*100, res.Exact.Tie*100, res.Exact.Loss*100)
+	}
+
+	return nil
+}
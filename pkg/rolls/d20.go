@@ -0,0 +1,64 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+)
+
+// D20 returns the dice of a d20 check, as RollD20 rolls them.
+func D20(modifier int, adv, dis bool) *Dice {
+	d := &Dice{Count: 1, Sides: 20, Bonus: modifier}
+	switch {
+	case adv && !dis:
+		d.Count, d.Modifier, d.ModifierCount = 2, KeepHighest, 1
+	case dis && !adv:
+		d.Count, d.Modifier, d.ModifierCount = 2, KeepLowest, 1
+	}
+	return d
+}
+
+// RollD20 rolls a d20 check. Advantage and disadvantage cancel out to a
+// straight roll when both apply.
+func RollD20(modifier int, adv, dis bool) *Result {
+	res := D20(modifier, adv, dis).Roll()
+	if adv && dis {
+		res.Expression += " (advantage cancelled)"
+	}
+	return res
+}
+
+// Passive returns a passive score: 10 plus the modifier, with 5 more for
+// advantage or 5 less for disadvantage. Both together cancel out.
+func Passive(modifier int, adv, dis bool) int {
+	score := 10 + modifier
+	switch {
+	case adv && !dis:
+		score += 5
+	case dis && !adv:
+		score -= 5
+	}
+	return score
+}
+
+func passiveGen(args []string) error {
+	fs := flag.NewFlagSet("passive", flag.ContinueOnError)
+	adv := fs.Bool("adv", false, "the check has advantage")
+	dis := fs.Bool("dis", false, "the check has disadvantage")
+	bonus := fs.Int("bonus", 0, "flat bonus to the passive score, such as +5 from Observant")
+	mods, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(mods) != 1 {
+		return fmt.Errorf("need to provide the check's [+/-]modifier")
+	}
+	modifier, err := strconv.Atoi(mods[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Passive: ", Passive(modifier+*bonus, *adv, *dis))
+
+	return nil
+}
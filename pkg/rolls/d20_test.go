@@ -0,0 +1,47 @@
+package rolls
+
+import "testing"
+
+func TestPassive(t *testing.T) {
+	tests := []struct {
+		modifier int
+		adv, dis bool
+		want     int
+	}{
+		{0, false, false, 10},
+		{7, false, false, 17},
+		{-2, false, false, 8},
+		{7, true, false, 22},
+		{7, false, true, 12},
+		{7, true, true, 17},
+		{7 + 5, true, false, 27},
+		{-3, false, true, 2},
+	}
+	for _, tt := range tests {
+		if got := Passive(tt.modifier, tt.adv, tt.dis); got != tt.want {
+			t.Errorf("Passive(%d, %t, %t) = %d, want %d", tt.modifier, tt.adv, tt.dis, got, tt.want)
+		}
+	}
+}
+
+func TestPassiveGen(t *testing.T) {
+	for _, args := range [][]string{
+		{"+7"},
+		{"-1", "--dis"},
+		{"+7", "--adv", "--bonus", "5"},
+	} {
+		if err := passiveGen(args); err != nil {
+			t.Errorf("roll passive %v: %v", args, err)
+		}
+	}
+	for _, args := range [][]string{
+		{},
+		{"+7", "+2"},
+		{"seven"},
+		{"+7", "--bonus", "x"},
+	} {
+		if err := passiveGen(args); err == nil {
+			t.Errorf("roll passive %v succeeded, want an error", args)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package rolls
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SystemShockDC is the Constitution save DC of the DMG's system shock
+// variant.
+const SystemShockDC = 15
+
+// DamageRules selects how ApplyDamage treats a hit.
+type DamageRules struct {
+	// SystemShock applies the DMG massive damage variant: a single hit of
+	// at least half the hit point maximum calls for a DC 15 Constitution
+	// save. Instant death from the PHB applies either way.
+	SystemShock bool
+	// TempHP is the creature's temporary hit points, lost before its hit
+	// points.
+	TempHP int
+}
+
+// DamageOutcome is the state of a creature after taking damage.
+type DamageOutcome struct {
+	Damage int
+	// AbsorbedByTemp is the damage taken by temporary hit points.
+	AbsorbedByTemp int
+	TempHP         int
+	// HP is the remaining hit points, which never go below 0.
+	HP int
+	// Overflow is the damage left over after hit points reach 0.
+	Overflow      int
+	ReducedToZero bool
+	InstantDeath  bool
+	// DeathSaveFailure is set when a creature already at 0 hit points takes
+	// damage and survives it.
+	DeathSaveFailure bool
+	// SaveDC is the system shock save DC, or 0 when no save is called for.
+	SaveDC int
+}
+
+// ApplyDamage applies the total of dmg to a creature with current out of
+// maxHP hit points and reports the consequences under rules.
+func ApplyDamage(current, maxHP int, dmg *Result, rules DamageRules) *DamageOutcome {
+	out := &DamageOutcome{Damage: dmg.Total, TempHP: rules.TempHP}
+	if out.Damage <= 0 {
+		out.Damage = 0
+		out.HP = current
+		return out
+	}
+
+	remaining := out.Damage
+	if rules.TempHP > 0 {
+		out.AbsorbedByTemp = min(rules.TempHP, remaining)
+		out.TempHP -= out.AbsorbedByTemp
+		remaining -= out.AbsorbedByTemp
+	}
+	if remaining == 0 {
+		out.HP = current
+		return out
+	}
+
+	atZero := current <= 0
+	out.HP = current - remaining
+	if out.HP < 0 {
+		out.HP = 0
+	}
+	if out.HP == 0 {
+		out.Overflow = remaining - max(current, 0)
+		out.ReducedToZero = !atZero
+		out.InstantDeath = out.Overflow >= maxHP
+	}
+	if atZero && !out.InstantDeath {
+		out.DeathSaveFailure = true
+	}
+	if rules.SystemShock && !out.InstantDeath && out.HP > 0 && remaining >= maxHP/2 {
+		out.SaveDC = SystemShockDC
+	}
+	return out
+}
+
+func (o *DamageOutcome) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d damage", o.Damage)
+	if o.AbsorbedByTemp > 0 {
+		fmt.Fprintf(&b, " (%d absorbed by temporary hit points, %d left)", o.AbsorbedByTemp, o.TempHP)
+	}
+	fmt.Fprintf(&b, ", %d hit points left", o.HP)
+	switch {
+	case o.InstantDeath:
+		fmt.Fprintf(&b, ", instant death (%d damage past 0)", o.Overflow)
+	case o.DeathSaveFailure:
+		b.WriteString(", one death saving throw failure")
+	case o.ReducedToZero:
+		b.WriteString(", unconscious")
+	case o.SaveDC > 0:
+		fmt.Fprintf(&b, ", DC %d Constitution save against system shock", o.SaveDC)
+	}
+	return b.String()
+}
+
+// parseHP parses hit points written as CURRENT/MAX, such as 45/45.
+func parseHP(s string) (int, int, error) {
+	cur, maxHP, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("passed illegal hit points: %q, want CURRENT/MAX", s)
+	}
+	current, err := strconv.Atoi(cur)
+	if err != nil {
+		return 0, 0, err
+	}
+	maximum, err := strconv.Atoi(maxHP)
+	if err != nil {
+		return 0, 0, err
+	}
+	if maximum < 1 {
+		return 0, 0, fmt.Errorf("maximum hit points must be at least 1, got %d", maximum)
+	}
+	return current, maximum, nil
+}
+
+// parseDamageRules parses a --rules value.
+func parseDamageRules(s string) (DamageRules, error) {
+	switch s {
+	case "instant-death":
+		return DamageRules{}, nil
+	case "system-shock":
+		return DamageRules{SystemShock: true}, nil
+	}
+	return DamageRules{}, fmt.Errorf("unknown damage rules %q, want instant-death or system-shock", s)
+}
@@ -0,0 +1,148 @@
+package rolls
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Deck is a deck of cards drawn without replacement. Cards holds the draw
+// pile with the top card first.
+type Deck struct {
+	Name     string   `json:"name"`
+	Cards    []string `json:"cards"`
+	Discards []string `json:"discards,omitempty"`
+}
+
+// NewDeck returns an unshuffled deck of the given cards.
+func NewDeck(name string, cards []string) *Deck {
+	return &Deck{Name: name, Cards: append([]string(nil), cards...)}
+}
+
+// StandardDeck returns an unshuffled 54-card deck: the 52 standard cards and
+// two jokers.
+func StandardDeck() *Deck {
+	cards := make([]string, 0, 54)
+	for _, suit := range []string{"Clubs", "Diamonds", "Hearts", "Spades"} {
+		for _, rank := range []string{"2", "3", "4", "5", "6", "7", "8", "9", "10", "Jack", "Queen", "King", "Ace"} {
+			cards = append(cards, rank+" of "+suit)
+		}
+	}
+	cards = append(cards, "Red Joker", "Black Joker")
+	return &Deck{Name: "standard54", Cards: cards}
+}
+
+// Shuffle shuffles the draw pile with r's source, or the default source if
+// r is nil.
+func (d *Deck) Shuffle(r *Roller) {
+	if r == nil {
+		r = defaultRoller
+	}
+	for i := len(d.Cards) - 1; i > 0; i-- {
+		j := r.intn(i + 1)
+		d.Cards[i], d.Cards[j] = d.Cards[j], d.Cards[i]
+	}
+}
+
+// Draw takes n cards from the top of the draw pile and discards them.
+func (d *Deck) Draw(n int) ([]string, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("need to draw at least one card, got %d", n)
+	}
+	if n > len(d.Cards) {
+		return nil, fmt.Errorf("cannot draw %s from deck %s, %d left", quantity(n, "card"), d.Name, len(d.Cards))
+	}
+	drawn := append([]string(nil), d.Cards[:n]...)
+	d.Cards = d.Cards[n:]
+	d.Discards = append(d.Discards, drawn...)
+	return drawn, nil
+}
+
+// Reshuffle returns the discards to the draw pile and shuffles it.
+func (d *Deck) Reshuffle(r *Roller) {
+	d.Cards = append(d.Cards, d.Discards...)
+	d.Discards = nil
+	d.Shuffle(r)
+}
+
+// ReadDecks reads named decks written by WriteDecks.
+func ReadDecks(r io.Reader) (map[string]*Deck, error) {
+	decks := make(map[string]*Deck)
+	if err := json.NewDecoder(r).Decode(&decks); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return decks, nil
+}
+
+// WriteDecks writes named decks as JSON.
+func WriteDecks(w io.Writer, decks map[string]*Deck) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(decks)
+}
+
+// newDeck returns a fresh shuffled deck: standard54 or a table file with one
+// card per row.
+func newDeck(name string) (*Deck, error) {
+	d := StandardDeck()
+	if name != "standard54" {
+		t, err := LoadTable(name)
+		if err != nil {
+			return nil, err
+		}
+		d = NewDeck(name, t.Entries)
+	}
+	d.Shuffle(nil)
+	return d, nil
+}
+
+func deckGen(args []string) error {
+	fs := flag.NewFlagSet("deck", flag.ContinueOnError)
+	deckName := fs.String("deck", "standard54", "standard54 or a CSV file with one card per row")
+	n := fs.Int("n", 1, "number of cards to draw")
+	persist := fs.String("persist", "", "keep the deck's state under this name between runs")
+	cmds, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(cmds) != 1 {
+		return fmt.Errorf("need to provide a deck command: draw, reshuffle or reset")
+	}
+
+	var decks map[string]*Deck
+	var d *Deck
+	if *persist != "" {
+		if decks, err = LoadDecks(); err != nil {
+			return err
+		}
+		d = decks[*persist]
+	}
+	if d == nil || cmds[0] == "reset" {
+		if d, err = newDeck(*deckName); err != nil {
+			return err
+		}
+	}
+
+	switch cmds[0] {
+	case "draw":
+		cards, err := d.Draw(*n)
+		if err != nil {
+			return err
+		}
+		fmt.Println(strings.Join(cards, "\n"))
+	case "reshuffle":
+		d.Reshuffle(nil)
+	case "reset":
+	default:
+		return fmt.Errorf("unknown deck command %q, want draw, reshuffle or reset", cmds[0])
+	}
+	fmt.Printf("%s: %s left, %d discarded\n", d.Name, quantity(len(d.Cards), "card"), len(d.Discards))
+
+	if *persist == "" {
+		return nil
+	}
+	decks[*persist] = d
+	return SaveDecks(decks)
+}
@@ -0,0 +1,180 @@
+package rolls
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DelayedRoll is a roll scheduled for a random time within a window, for
+// solo oracles and prep that should turn up unannounced. Its dice come from
+// Seed, drawn when it was scheduled, so the outcome is fixed from then on;
+// Commitment is the SHA-256 of the seed, shown at scheduling so the result
+// can be checked against it. Resolve refuses to roll it before Due, and
+// nothing else shown before then depends on the seed, so the only way to
+// see the result early is to read the seed out of the saved record by hand.
+type DelayedRoll struct {
+	ID         string    `json:"id"`
+	Expr       string    `json:"expr"`
+	Created    time.Time `json:"created"`
+	Due        time.Time `json:"due"`
+	Seed       string    `json:"seed"`
+	Commitment string    `json:"commitment"`
+}
+
+// ScheduleDelay schedules expr for a time drawn uniformly from the window
+// within after now, using r's source. The seed comes from crypto/rand, so r
+// being seeded cannot give the outcome away either.
+func ScheduleDelay(expr string, within time.Duration, now time.Time, r *Roller) (*DelayedRoll, error) {
+	if within <= 0 {
+		return nil, fmt.Errorf("need a window to roll within, got %s", within)
+	}
+	if _, err := ParseExpression(expr); err != nil {
+		return nil, err
+	}
+	if r == nil {
+		r = defaultRoller
+	}
+	seed := make([]byte, 32)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(seed)
+	commitment := hex.EncodeToString(sum[:])
+	return &DelayedRoll{
+		ID:         commitment[:8],
+		Expr:       expr,
+		Created:    now.UTC(),
+		Due:        now.UTC().Add(time.Duration(r.intn(int(within)))),
+		Seed:       hex.EncodeToString(seed),
+		Commitment: commitment,
+	}, nil
+}
+
+// Resolve rolls d once it is due at now, checking its seed against its
+// commitment first. The same record always rolls the same result.
+func (d *DelayedRoll) Resolve(now time.Time) (*Result, error) {
+	if now.Before(d.Due) {
+		return nil, fmt.Errorf("delayed roll %s is not due yet", d.ID)
+	}
+	seed, err := hex.DecodeString(d.Seed)
+	if err != nil {
+		return nil, fmt.Errorf("delayed roll %s has a damaged seed: %w", d.ID, err)
+	}
+	sum := sha256.Sum256(seed)
+	if subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(d.Commitment)) != 1 {
+		return nil, fmt.Errorf("delayed roll %s does not match its commitment %s, its seed was changed", d.ID, d.Commitment)
+	}
+	e, err := ParseExpression(d.Expr)
+	if err != nil {
+		return nil, err
+	}
+	// The expression goes into the roller's seed, so changing it in the
+	// record after the fact does not just reuse the committed dice.
+	key := sha256.Sum256(append(seed, d.Expr...))
+	return NewRoller(WithSeed(int64(binary.BigEndian.Uint64(key[:8])))).RollExpression(e), nil
+}
+
+// ResolvedDelay is a delayed roll made by CheckDelays.
+type ResolvedDelay struct {
+	Delay  *DelayedRoll
+	Result *Result
+}
+
+// CheckDelays makes every saved delayed roll due at now, in the order they
+// fell due, logs each to the history and removes it from the saved ones.
+// It returns them with the number still pending.
+func CheckDelays(now time.Time) ([]ResolvedDelay, int, error) {
+	delays, err := LoadDelays()
+	if err != nil {
+		return nil, 0, err
+	}
+	var due []*DelayedRoll
+	for _, d := range delays {
+		if !now.Before(d.Due) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].Due.Before(due[j].Due) })
+
+	var resolved []ResolvedDelay
+	for _, d := range due {
+		res, err := d.Resolve(now)
+		if err != nil {
+			return resolved, len(delays) - len(resolved), err
+		}
+		if err := AppendHistory(res); err != nil {
+			return resolved, len(delays) - len(resolved), err
+		}
+		delete(delays, d.ID)
+		if err := SaveDelays(delays); err != nil {
+			return resolved, len(delays), err
+		}
+		resolved = append(resolved, ResolvedDelay{Delay: d, Result: res})
+	}
+	return resolved, len(delays), nil
+}
+
+func delayGen(args []string) error {
+	fs := flag.NewFlagSet("delay", flag.ContinueOnError)
+	within := fs.Duration("within", 0, "window to roll at a random time within, e.g. 2h")
+	detach := fs.Bool("detach", false, "save the roll and return at once, for roll delay check to make once due")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 1 && rest[0] == "check" {
+		return checkDelaysGen(time.Now())
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("need to provide an expression to roll later, or check")
+	}
+	if *within <= 0 {
+		return fmt.Errorf("need to provide a --within window, e.g. --within 2h")
+	}
+
+	d, err := ScheduleDelay(rest[0], *within, time.Now(), nil)
+	if err != nil {
+		return err
+	}
+	// The roll is saved even when waiting for it, so that it is still made
+	// by roll delay check if this process does not live to see it.
+	delays, err := LoadDelays()
+	if err != nil {
+		return err
+	}
+	delays[d.ID] = d
+	if err := SaveDelays(delays); err != nil {
+		return err
+	}
+	fmt.Printf("Delayed %s as %s, commitment %s, due within %s\n", d.Expr, d.ID, d.Commitment, *within)
+	if *detach {
+		fmt.Println("Run roll delay check to make it once it is due")
+		return nil
+	}
+	time.Sleep(time.Until(d.Due))
+	return checkDelaysGen(d.Due)
+}
+
+func checkDelaysGen(now time.Time) error {
+	resolved, pending, err := CheckDelays(now)
+	for _, r := range resolved {
+		fmt.Printf("%s (delayed %s, due %s)\n", r.Result, r.Delay.ID, r.Delay.Due.Local().Format("2006-01-02 15:04:05"))
+		fmt.Println("Commitment verified")
+	}
+	if err != nil {
+		return err
+	}
+	if pending > 0 {
+		fmt.Printf("%s not due yet\n", quantity(pending, "delayed roll"))
+	} else if len(resolved) == 0 {
+		fmt.Println("No delayed rolls")
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+)
+
+// Dialect is a dice notation to read expressions in.
+type Dialect int
+
+const (
+	// DialectDefault is this package's own notation, as Parse reads it.
+	DialectDefault Dialect = iota
+	// DialectRoll20 reads Roll20 rolls: /r commands, [[inline]] rolls and
+	// [labels] are accepted, and 3d6>4 counts dice of 4 or more, since
+	// Roll20's > and < include the target. Dice reroll once with ro and
+	// until they stop matching with r.
+	DialectRoll20
+	// DialectFoundry reads FoundryVTT rolls: /r commands, [[inline]] rolls
+	// and [flavor] are accepted, successes are counted with cs, as in
+	// 5d10cs>=8, and failures deducted from them with df, dice reroll once
+	// with r and until they stop matching with rr.
+	DialectFoundry
+)
+
+var dialectNames = map[string]Dialect{
+	"default": DialectDefault,
+	"roll20":  DialectRoll20,
+	"foundry": DialectFoundry,
+}
+
+// ParseDialectName parses a dialect name: default, roll20 or foundry.
+func ParseDialectName(s string) (Dialect, error) {
+	if d, ok := dialectNames[strings.ToLower(s)]; ok {
+		return d, nil
+	}
+	return 0, fmt.Errorf("passed illegal dialect: %s, want default, roll20 or foundry", s)
+}
+
+func (d Dialect) String() string {
+	for name, v := range dialectNames {
+		if v == d {
+			return name
+		}
+	}
+	return fmt.Sprintf("Dialect(%d)", int(d))
+}
+
+var (
+	rollCommand    = regexp.MustCompile(`^/(r|roll)\s+`)
+	rollLabel      = regexp.MustCompile(`\[[^\[\]]*\]`)
+	bareDrop       = regexp.MustCompile(`(\dd\d+)d(\d)`)
+	explodingOn    = regexp.MustCompile(`!([<>=]|\d)`)
+	foundryExplode = regexp.MustCompile(`(d\d+)x`)
+	roll20Reroll   = regexp.MustCompile(`(d\d+)r([^o]|$)`)
+	roll20Compare  = regexp.MustCompile(`([<>])(\d)`)
+	foundryCount   = regexp.MustCompile(`cs(>=|<=|>|<|=)?(\d)`)
+	foundryDeduct  = regexp.MustCompile(`df(>=|<=|>|<|=)?(\d)`)
+)
+
+// ParseDialect parses expr written in dialect d. Roll20 and Foundry rolls
+// are rewritten into this package's notation before ParseExpression reads
+// them; notation either platform has that this package cannot roll, such
+// as dice exploding on a target, is refused by name rather than misread.
+func ParseDialect(expr string, d Dialect) (*Expression, error) {
+	native, err := translateDialect(expr, d)
+	if err != nil {
+		return nil, err
+	}
+	return ParseExpression(native)
+}
+
+func translateDialect(expr string, d Dialect) (string, error) {
+	if d == DialectDefault {
+		return expr, nil
+	}
+	if d != DialectRoll20 && d != DialectFoundry {
+		return "", fmt.Errorf("unknown dialect %d", int(d))
+	}
+
+	s := strings.TrimSpace(expr)
+	if strings.HasPrefix(s, "[[") && strings.HasSuffix(s, "]]") {
+		s = strings.TrimSpace(s[2 : len(s)-2])
+	}
+	s = rollCommand.ReplaceAllString(s, "")
+	s = rollLabel.ReplaceAllString(s, "")
+	s = strings.Join(strings.Fields(s), "")
+
+	if d == DialectFoundry {
+		s = foundryExplode.ReplaceAllString(s, "$1!")
+	}
+	if explodingOn.MatchString(s) {
+		return "", fmt.Errorf("cannot read %q in the %s dialect: exploding on a target is not supported", expr, d)
+	}
+	// Roll20's r rerolls until a die stops matching, as rr does here.
+	if d == DialectRoll20 {
+		s = roll20Reroll.ReplaceAllString(s, "${1}rr$2")
+	}
+
+	// Both platforms read a bare d after the dice as drop lowest.
+	s = bareDrop.ReplaceAllString(s, "${1}dl$2")
+
+	if d == DialectRoll20 {
+		return roll20Compare.ReplaceAllString(s, "$1=$2"), nil
+	}
+	if strings.Contains(s, "cf") {
+		return "", fmt.Errorf("cannot read %q in the foundry dialect: counting failures (cf) is not supported", expr)
+	}
+	s = foundryDeduct.ReplaceAllString(s, "f$1$2")
+	return foundryCount.ReplaceAllStringFunc(s, func(m string) string {
+		parts := foundryCount.FindStringSubmatch(m)
+		op := parts[1]
+		if op == "" {
+			op = "="
+		}
+		return op + parts[2]
+	}), nil
+}
+
+// dialectExample is a notation and what each dialect reads it as, indexed
+// by Dialect, with "" where the dialect refuses it.
+type dialectExample struct {
+	expr  string
+	reads [3]string
+}
+
+// dialectExamples are the notations roll dialects compares. The table it
+// prints is these readings, and TestParseDialect checks ParseDialect gives
+// every one of them, so the table cannot drift from the parser.
+var dialectExamples = []dialectExample{
+	{"4d6k3", [3]string{"4d6kh3", "4d6kh3", "4d6kh3"}},
+	{"4d6d1", [3]string{"", "4d6kh3", "4d6kh3"}},
+	{"3d6>4", [3]string{"3d6>4", "3d6>=4", "3d6>4"}},
+	{"3d6<3", [3]string{"3d6<3", "3d6<=3", "3d6<3"}},
+	{"5d10cs>=8", [3]string{"", "", "5d10>=8"}},
+	{"10d10>7f1", [3]string{"10d10>7f1", "10d10>=7f1", "10d10>7f1"}},
+	{"10d10cs>=8df1", [3]string{"", "", "10d10>=8f1"}},
+	{"1d20+5[STR]", [3]string{"", "1d20+5", "1d20+5"}},
+	{"[[1d20+5]]", [3]string{"", "1d20+5", "1d20+5"}},
+	{"/r 2d6 + 3", [3]string{"", "2d6+3", "2d6+3"}},
+	{"1d6!", [3]string{"1d6!", "1d6!", "1d6!"}},
+	{"3d6x", [3]string{"", "", "3d6!"}},
+	{"2d6r<2", [3]string{"2d6r<2", "2d6rr<=2", "2d6r<2"}},
+	{"2d6ro1", [3]string{"2d6r1", "2d6r1", "2d6r1"}},
+	{"2d6rr1", [3]string{"2d6rr1", "", "2d6rr1"}},
+}
+
+func dialectsGen(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("roll dialects takes no arguments")
+	}
+	dialects := []Dialect{DialectDefault, DialectRoll20, DialectFoundry}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "Notation")
+	for _, d := range dialects {
+		fmt.Fprintf(w, "\t%s", d)
+	}
+	fmt.Fprintln(w)
+	for _, ex := range dialectExamples {
+		fmt.Fprint(w, ex.expr)
+		for _, d := range dialects {
+			read := ex.reads[d]
+			if read == "" {
+				read = "not read"
+			}
+			fmt.Fprintf(w, "\t%s", read)
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
+
+// dialectFlag adds the --dialect flag to fs.
+func dialectFlag(fs *flag.FlagSet) *string {
+	return fs.String("dialect", "default", "notation to read expressions in: default, roll20 or foundry")
+}
@@ -0,0 +1,46 @@
+package rolls
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParseDialect reads every notation roll dialects compares in each
+// dialect, as the table it prints says.
+func TestParseDialect(t *testing.T) {
+	for _, d := range []Dialect{DialectDefault, DialectRoll20, DialectFoundry} {
+		t.Run(d.String(), func(t *testing.T) {
+			for _, ex := range dialectExamples {
+				e, err := ParseDialect(ex.expr, d)
+				switch want := ex.reads[d]; {
+				case want == "" && err == nil:
+					t.Errorf("ParseDialect(%q) = %s, want it refused", ex.expr, e)
+				case want != "" && err != nil:
+					t.Errorf("ParseDialect(%q): %v, want %s", ex.expr, err, want)
+				case want != "" && e.String() != want:
+					t.Errorf("ParseDialect(%q) = %s, want %s", ex.expr, e, want)
+				}
+			}
+		})
+	}
+}
+
+// TestParseDialectRefusals checks notation a platform has but this package
+// cannot roll is refused by name.
+func TestParseDialectRefusals(t *testing.T) {
+	tests := []struct {
+		expr string
+		d    Dialect
+		want string
+	}{
+		{"1d6!>5", DialectRoll20, "exploding on a target is not supported"},
+		{"1d6x>5", DialectFoundry, "exploding on a target is not supported"},
+		{"10d10cs>=8cf1", DialectFoundry, "counting failures (cf) is not supported"},
+		{"1d20", Dialect(9), "unknown dialect 9"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseDialect(tt.expr, tt.d); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseDialect(%q, %s) = %v, want an error saying %s", tt.expr, tt.d, err, tt.want)
+		}
+	}
+}
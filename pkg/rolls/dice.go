@@ -0,0 +1,587 @@
+package rolls
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Modifier selects which dice of a pool count toward the total.
+type Modifier int
+
+const (
+	NoModifier Modifier = iota
+	KeepHighest
+	KeepLowest
+	// KeepMiddle keeps the middle dice of the pool by value and DropMiddle
+	// keeps all but them, as 3d20km1 and 5d6dm1 do. When the middle falls
+	// between two dice, as the middle one of four does, the lower of them
+	// is taken as the middle.
+	KeepMiddle
+	DropMiddle
+)
+
+// Dice describes a pool of identical dice plus a flat bonus.
+type Dice struct {
+	Count    int
+	Sides    int
+	Modifier Modifier
+	// ModifierCount is how many dice the Modifier keeps.
+	ModifierCount int
+	Bonus         int
+	// Success, when set, is tested against every kept die, and the dice
+	// make a success pool: the total counts the kept dice matching it,
+	// plus the bonus as extra successes, rather than summing them.
+	Success *Threshold
+	// Failure, when set alongside Success, counts every kept die matching
+	// it as a failure, taking away a success.
+	Failure *Threshold
+	// Explode selects what a die landing on its highest face does.
+	Explode ExplodeMode
+	// Reroll, when set, rolls every die matching it once more as it lands,
+	// keeping the new value even if it is worse, before any modifier
+	// chooses among the dice. RerollRecursive rolls it again instead until
+	// it stops matching, up to RerollLimit times.
+	Reroll          *Threshold
+	RerollRecursive bool
+	// MinPerDie, when above zero, counts every die landing below it as
+	// MinPerDie, as 8d6min2 does for Elemental Adept. The raw rolls stay in
+	// the Result's Rolls, and modifiers choose among the raised values.
+	MinPerDie int
+	// MaxPerDie, when above zero, likewise counts every die landing above
+	// it as MaxPerDie, as 3d6max4 does.
+	MaxPerDie int
+	// Labels, when set, name the faces of the dice in order, and Sides is
+	// len(Labels). Labeled dice land on a label rather than a number, so
+	// they have no total and take no modifiers, bonus or threshold.
+	Labels []string
+	// FaceValues, when set, are what the faces of the dice count for in
+	// order, and Sides is len(FaceValues), as for Fudge dice, whose faces
+	// are FudgeFaces. The dice land on these values, so they may total
+	// less than zero, and they cannot explode or reroll.
+	FaceValues []int
+}
+
+// FudgeFaces are the faces of a Fudge die, as rolled for FATE: a minus, a
+// blank and a plus.
+var FudgeFaces = []int{-1, 0, 1}
+
+// ExplodeMode selects how dice explode.
+type ExplodeMode int
+
+const (
+	NoExplosion ExplodeMode = iota
+	// Exploding dice roll another die, added to the pool, for every die
+	// that lands on its highest face: 3d6!.
+	Exploding
+	// Compounding dice roll again on their highest face and add the new
+	// roll to the same die, so one die can total more than its sides: 5d6!!.
+	Compounding
+	// Penetrating dice compound, but every roll after the first counts one
+	// less, as in HackMaster: 1d6!p rolling 6, 6, 4 is 6+5+3 = 14.
+	Penetrating
+)
+
+// ExplodeLimit bounds how many times a single die can explode or compound,
+// so a chain of maximum rolls always ends.
+const ExplodeLimit = 100
+
+// RerollLimit bounds how many times a recursive reroll rolls a single die
+// again. A die still matching after that many keeps its last roll.
+const RerollLimit = 100
+
+// Threshold tests a die value against a target, such as ">=5".
+type Threshold struct {
+	Op     string
+	Target int
+}
+
+var thresholdOps = []string{">=", "<=", ">", "<", "="}
+
+// Matches reports whether v satisfies the threshold.
+func (t *Threshold) Matches(v int) bool {
+	switch t.Op {
+	case ">=":
+		return v >= t.Target
+	case "<=":
+		return v <= t.Target
+	case ">":
+		return v > t.Target
+	case "<":
+		return v < t.Target
+	default:
+		return v == t.Target
+	}
+}
+
+func (t *Threshold) String() string {
+	return fmt.Sprintf("%s%d", t.Op, t.Target)
+}
+
+// Result holds the outcome of rolling a Dice.
+type Result struct {
+	Expression string `json:"expression"`
+	Sides      int    `json:"sides"`
+	Rolls      []int  `json:"rolls"`
+	// AdjustedRolls, for dice with a MinPerDie or MaxPerDie, holds each of
+	// Rolls as it counts once raised to the minimum or lowered to the
+	// maximum. Kept and Dropped hold these.
+	AdjustedRolls []int `json:"adjusted_rolls,omitempty"`
+	Kept          []int `json:"kept"`
+	Dropped       []int `json:"dropped,omitempty"`
+	Bonus         int   `json:"bonus"`
+	Total         int   `json:"total"`
+	// Successes counts the kept dice matching the Dice's Success threshold
+	// and Failures those matching its Failure threshold. NetSuccesses is
+	// the successes the failures leave, and Botch is set when a die failed
+	// and none succeeded. SuccessPool is set when the Dice had a Success
+	// threshold, and Total is then NetSuccesses plus Bonus.
+	Successes    int  `json:"successes,omitempty"`
+	Failures     int  `json:"failures,omitempty"`
+	NetSuccesses int  `json:"net_successes,omitempty"`
+	Botch        bool `json:"botch,omitempty"`
+	SuccessPool  bool `json:"success_pool,omitempty"`
+	// Substituted records kept dice whose value was replaced after rolling.
+	Substituted []Substitution `json:"substituted,omitempty"`
+	// Percentiles splits every roll of a d100 into the tens and ones dice
+	// of a physical pair, for Rollers made WithPercentileDice.
+	Percentiles []PercentileRoll `json:"percentiles,omitempty"`
+	// Fudge marks a roll of Fudge dice, whose Rolls are -1, 0 and 1 and
+	// which String shows as [-][0][+].
+	Fudge bool `json:"fudge,omitempty"`
+	// Summarized is set for pools too large to list every die. Rolls is nil,
+	// and Kept or Dropped only hold whichever side of a modifier is smaller.
+	Summarized bool         `json:"summarized,omitempty"`
+	Summary    *RollSummary `json:"summary,omitempty"`
+	// Nudge is set when a Roller created WithNudge shifted the total.
+	Nudge *Nudge `json:"nudge,omitempty"`
+	// Groups holds the result of each dice group of a multi-group
+	// Expression, in order. A negative group's Total is the sum of its kept
+	// dice before the subtraction. A group in parentheses has groups of its
+	// own.
+	Groups   []*Result `json:"groups,omitempty"`
+	Negative bool      `json:"negative,omitempty"`
+	Label    string    `json:"label,omitempty"`
+	// Scale records the multiplications and divisions of a scaled group,
+	// whose Total is then scaled and Unscaled holds its total before them.
+	Scale    []ScaleStep `json:"scale,omitempty"`
+	Unscaled int         `json:"unscaled,omitempty"`
+	// Rerolled records dice rolled again after the fact by ForceReroll.
+	Rerolled []Reroll `json:"rerolled,omitempty"`
+	// RerolledOnce records the dice the Dice's Reroll rolled again as they
+	// landed, with Rolls holding the new values.
+	RerolledOnce []Reroll `json:"rerolled_once,omitempty"`
+	// RerollChains records the dice a recursive Reroll rolled again, with
+	// every roll each took.
+	RerollChains []RerollChain `json:"reroll_chains,omitempty"`
+	// Exploded records the dice of an exploding pool that landed on their
+	// highest face and the extra dice they added, which follow them in
+	// Rolls.
+	Exploded []Explosion `json:"exploded,omitempty"`
+	// Compounded records the dice of a compounding pool that rolled again,
+	// with every roll that went into each.
+	Compounded []Compound `json:"compounded,omitempty"`
+	// Penetrated records the dice of a penetrating pool that rolled again,
+	// with the raw rolls that went into each.
+	Penetrated []Compound `json:"penetrated,omitempty"`
+	// Faces holds the label each die of a labeled pool landed on, in the
+	// order of Rolls, whose values are the faces' positions from 1. Tally
+	// counts the dice showing each label, and Total is always zero.
+	Faces []string    `json:"faces,omitempty"`
+	Tally []FaceCount `json:"tally,omitempty"`
+}
+
+// FaceCount is how many dice of a labeled pool landed on Label.
+type FaceCount struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// Compound is a die of a compounding pool whose value in Rolls is the sum
+// of Chain, its first roll and every roll after a highest face. For a
+// penetrating die every roll of Chain after the first counts one less.
+type Compound struct {
+	// Index is the die's position in Rolls.
+	Index int   `json:"index"`
+	Chain []int `json:"chain"`
+}
+
+// RerollChain is a die rerolled until it stopped matching its Dice's
+// Reroll. Chain holds every roll, the last being the one kept.
+type RerollChain struct {
+	// Index is the die's position in Rolls.
+	Index int   `json:"index"`
+	Chain []int `json:"chain"`
+}
+
+// Explosion is a die of an exploding pool that added Count extra dice,
+// rolled immediately after it.
+type Explosion struct {
+	// Index is the die's position in Rolls.
+	Index int `json:"index"`
+	Count int `json:"count"`
+}
+
+// Nudge records a deliberate shift of a result's total.
+type Nudge struct {
+	Sigma float64 `json:"sigma"`
+	// Unbiased is the total before the nudge.
+	Unbiased int `json:"unbiased"`
+}
+
+// PercentileRoll is a d100 roll read from a tens die, 00 to 90, and a ones
+// die, 0 to 9, where 00 and 0 read as 100.
+type PercentileRoll struct {
+	Tens int `json:"tens"`
+	Ones int `json:"ones"`
+}
+
+// splitPercentile returns the tens and ones dice that read as v.
+func splitPercentile(v int) PercentileRoll {
+	return PercentileRoll{Tens: v % 100 / 10 * 10, Ones: v % 10}
+}
+
+// Substitution is a kept die that was replaced with a fixed value.
+type Substitution struct {
+	Original int `json:"original"`
+	Value    int `json:"value"`
+}
+
+func (d *Dice) String() string {
+	if len(d.Labels) > 0 {
+		return fmt.Sprintf("%dd{%s}", d.Count, strings.Join(d.Labels, ","))
+	}
+	s := fmt.Sprintf("%dd%d", d.Count, d.Sides)
+	switch {
+	case d.fudge():
+		s = fmt.Sprintf("%ddF", d.Count)
+	case len(d.FaceValues) > 0:
+		s = fmt.Sprintf("%dd[%s]", d.Count, joinInts(d.FaceValues, ","))
+	}
+	switch d.Explode {
+	case Exploding:
+		s += "!"
+	case Compounding:
+		s += "!!"
+	case Penetrating:
+		s += "!p"
+	}
+	if d.Reroll != nil {
+		s += "r"
+		if d.RerollRecursive {
+			s += "r"
+		}
+		if d.Reroll.Op == "=" {
+			s += strconv.Itoa(d.Reroll.Target)
+		} else {
+			s += d.Reroll.String()
+		}
+	}
+	if d.MinPerDie > 0 {
+		s += "min" + strconv.Itoa(d.MinPerDie)
+	}
+	if d.MaxPerDie > 0 {
+		s += "max" + strconv.Itoa(d.MaxPerDie)
+	}
+	switch d.Modifier {
+	case KeepHighest:
+		s = fmt.Sprintf("%skh%d", s, d.ModifierCount)
+	case KeepLowest:
+		s = fmt.Sprintf("%skl%d", s, d.ModifierCount)
+	case KeepMiddle:
+		s = fmt.Sprintf("%skm%d", s, d.ModifierCount)
+	case DropMiddle:
+		s = fmt.Sprintf("%sdm%d", s, d.Count-d.ModifierCount)
+	}
+	if d.Bonus != 0 {
+		s = fmt.Sprintf("%s%+d", s, d.Bonus)
+	}
+	if d.Success != nil {
+		s += d.Success.String()
+	}
+	if d.Failure != nil {
+		s += "f"
+		if d.Failure.Op == "=" {
+			s += strconv.Itoa(d.Failure.Target)
+		} else {
+			s += d.Failure.String()
+		}
+	}
+	return s
+}
+
+// CanSucceed reports whether any face of the dice satisfies the Success
+// threshold. Dice without a threshold always succeed.
+func (d *Dice) CanSucceed() bool {
+	if d.Success == nil {
+		return true
+	}
+	for face := 1; face <= d.Sides; face++ {
+		if d.Success.Matches(d.face(face)) {
+			return true
+		}
+	}
+	return false
+}
+
+// fudge reports whether d are Fudge dice.
+func (d *Dice) fudge() bool {
+	return slices.Equal(d.FaceValues, FudgeFaces)
+}
+
+// face returns what a die of d landing on its i-th face, from 1, shows.
+func (d *Dice) face(i int) int {
+	if len(d.FaceValues) > 0 {
+		return d.FaceValues[i-1]
+	}
+	return i
+}
+
+// clamped reports whether d has a MinPerDie or MaxPerDie, so that its dice
+// can count as other than they show.
+func (d *Dice) clamped() bool {
+	return d.MinPerDie > 0 || d.MaxPerDie > 0
+}
+
+// adjust returns what a die of d showing v counts as once raised to its
+// MinPerDie or lowered to its MaxPerDie.
+func (d *Dice) adjust(v int) int {
+	switch {
+	case d.MinPerDie > 0 && v < d.MinPerDie:
+		return d.MinPerDie
+	case d.MaxPerDie > 0 && v > d.MaxPerDie:
+		return d.MaxPerDie
+	}
+	return v
+}
+
+// Roll rolls every die in the pool and applies the modifier and bonus.
+func (d *Dice) Roll() *Result {
+	return defaultRoller.Roll(d)
+}
+
+// RollContext is like Roll but stops early with ctx's error if ctx is done
+// before every die has been rolled.
+func (d *Dice) RollContext(ctx context.Context) (*Result, error) {
+	return defaultRoller.RollContext(ctx, d)
+}
+
+// RollWithObserver is like Roll but calls onDie with the index and value of
+// every die as it lands, in roll order.
+func (d *Dice) RollWithObserver(onDie func(i, value int)) *Result {
+	return defaultRoller.RollWithObserver(d, onDie)
+}
+
+// RollWithSubstitution rolls d and replaces the first kept die with value,
+// as a divination wizard's portent does.
+func RollWithSubstitution(d *Dice, value int) (*Result, error) {
+	if value < 1 || value > d.Sides {
+		return nil, fmt.Errorf("substitute value %d is not a face of a d%d", value, d.Sides)
+	}
+	res := d.Roll()
+	if err := res.Substitute(value); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Substitute replaces the first kept die with value and recomputes the total.
+// The original die stays visible in Rolls and is recorded in Substituted.
+// A result with dice groups has the first kept die of its first group
+// replaced, and the change counts with that group's sign and through the
+// scaling of every group around it.
+func (r *Result) Substitute(value int) error {
+	sum := r.Total
+	if len(r.Scale) > 0 {
+		sum = r.Unscaled
+	}
+	if len(r.Groups) > 0 {
+		g := r.Groups[0]
+		before := g.Total
+		if err := g.Substitute(value); err != nil {
+			return err
+		}
+		if g.Negative {
+			sum -= g.Total - before
+		} else {
+			sum += g.Total - before
+		}
+	} else {
+		if len(r.Kept) == 0 {
+			return fmt.Errorf("no kept dice to substitute in %s", r.Expression)
+		}
+		r.Substituted = append(r.Substituted, Substitution{Original: r.Kept[0], Value: value})
+		sum += value - r.Kept[0]
+		r.Kept[0] = value
+	}
+	r.Total = sum
+	if len(r.Scale) > 0 {
+		r.Unscaled, r.Total = sum, scaleTotal(sum, r.Scale)
+	}
+	return nil
+}
+
+// applyRollModifier splits rolls into kept and dropped dice. Both keep the
+// order the dice were rolled in. Only the smaller of the two sides is
+// selected, through a bounded heap, so keeping a few dice of a large pool
+// never sorts the whole pool.
+func applyRollModifier(rolls []int, m Modifier, n int) ([]int, []int) {
+	if m == NoModifier || n >= len(rolls) {
+		return append([]int(nil), rolls...), nil
+	}
+	if n < 0 {
+		n = 0
+	}
+	if m == KeepMiddle || m == DropMiddle {
+		return middleDice(rolls, m, n)
+	}
+
+	better := keepsBefore(m)
+	holdsKept := n <= len(rolls)-n
+	held := n
+	h := &dieHeap{less: better}
+	if holdsKept {
+		h.less = func(a, b rankedDie) bool { return better(b, a) }
+	} else {
+		held = len(rolls) - n
+	}
+	h.dice = make([]rankedDie, 0, held)
+	for i, r := range rolls {
+		h.offer(rankedDie{index: i, value: r}, held)
+	}
+
+	selected := make([]bool, len(rolls))
+	for _, d := range h.dice {
+		selected[d.index] = true
+	}
+	var kept, dropped []int
+	for i, r := range rolls {
+		if selected[i] == holdsKept {
+			kept = append(kept, r)
+		} else {
+			dropped = append(dropped, r)
+		}
+	}
+	return kept, dropped
+}
+
+// middleDice splits rolls for a KeepMiddle or DropMiddle modifier keeping n
+// dice. The middle dice by value, ties going to the earlier die, are those
+// left once as many are taken off the bottom as the top, or one fewer off
+// the bottom when they cannot be even.
+func middleDice(rolls []int, m Modifier, n int) ([]int, []int) {
+	order := make([]int, len(rolls))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return rolls[order[i]] < rolls[order[j]] })
+	middle := n
+	if m == DropMiddle {
+		middle = len(rolls) - n
+	}
+	start := (len(rolls) - middle) / 2
+	inMiddle := make([]bool, len(rolls))
+	for _, i := range order[start : start+middle] {
+		inMiddle[i] = true
+	}
+	var kept, dropped []int
+	for i, r := range rolls {
+		if inMiddle[i] == (m == KeepMiddle) {
+			kept = append(kept, r)
+		} else {
+			dropped = append(dropped, r)
+		}
+	}
+	return kept, dropped
+}
+
+// countedRolls returns Rolls as they count toward the total: AdjustedRolls
+// when the dice raised or lowered any.
+func (r *Result) countedRolls() []int {
+	if r.AdjustedRolls != nil {
+		return r.AdjustedRolls
+	}
+	return r.Rolls
+}
+
+// DroppedMask reports, for each of Rolls, whether that die was dropped.
+// Among equal values the later dice are the dropped ones, matching how
+// modifiers break ties.
+func (r *Result) DroppedMask() []bool {
+	dropped := make(map[int]int, len(r.Dropped))
+	for _, d := range r.Dropped {
+		dropped[d]++
+	}
+	rolls := r.countedRolls()
+	mask := make([]bool, len(rolls))
+	for i := len(rolls) - 1; i >= 0; i-- {
+		if dropped[rolls[i]] > 0 {
+			dropped[rolls[i]]--
+			mask[i] = true
+		}
+	}
+	return mask
+}
+
+func (r *Result) String() string {
+	if r.Summarized {
+		return r.summaryString() + r.nudgeString()
+	}
+	if len(r.Groups) > 0 {
+		return r.groupsString() + r.rerolledString() + r.nudgeString()
+	}
+	if len(r.Faces) > 0 {
+		return fmt.Sprintf("%s: Rolled: [%s] = %s", r.Expression, truncateFaces(r.Faces), r.tallyString())
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: Rolled: %s", r.Expression, r.rolledList())
+	switch {
+	case len(r.Dropped) > 0 && r.Fudge:
+		fmt.Fprintf(&b, " Dropped: %s", fudgeList(r.Dropped))
+	case len(r.Dropped) > 0:
+		fmt.Fprintf(&b, " Dropped: %s", diceList(r.Dropped))
+	}
+	for _, sub := range r.Substituted {
+		fmt.Fprintf(&b, " Substituted: %d→%d", sub.Original, sub.Value)
+	}
+	if r.Bonus != 0 {
+		fmt.Fprintf(&b, " %+d", r.Bonus)
+	}
+	b.WriteString(" = " + r.totalString())
+	return b.String() + r.rerolledString() + r.nudgeString()
+}
+
+// totalString returns r's total, as a number of successes for a success
+// pool, followed by the successes and failures it nets and any botch.
+func (r *Result) totalString() string {
+	if !r.SuccessPool {
+		return strconv.Itoa(r.Total)
+	}
+	s := quantity(r.Total, "success")
+	if r.Failures > 0 {
+		s += fmt.Sprintf(" (%s, %s)", quantity(r.Successes, "success"), quantity(r.Failures, "failure"))
+	}
+	if r.Botch {
+		s += " BOTCH"
+	}
+	return s
+}
+
+func (r *Result) rerolledString() string {
+	var b strings.Builder
+	for _, re := range r.Rerolled {
+		fmt.Fprintf(&b, " (%s die rerolled %d→%d)", ordinal(re.Index+1), re.Original, re.Value)
+	}
+	return b.String()
+}
+
+func (r *Result) nudgeString() string {
+	if r.Nudge == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (nudged %+gσ from %d)", r.Nudge.Sigma, r.Nudge.Unbiased)
+}
@@ -0,0 +1,151 @@
+package rolls
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// sortedRollModifier is the full-sort selection applyRollModifier replaced:
+// the n best dice by value, earlier dice winning ties, in rolled order.
+func sortedRollModifier(rolls []int, m Modifier, n int) ([]int, []int) {
+	if m == NoModifier || n >= len(rolls) {
+		return append([]int(nil), rolls...), nil
+	}
+	n = max(n, 0)
+	order := make([]int, len(rolls))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if m == KeepHighest {
+			return rolls[order[i]] > rolls[order[j]]
+		}
+		return rolls[order[i]] < rolls[order[j]]
+	})
+	keep := make([]bool, len(rolls))
+	for _, i := range order[:n] {
+		keep[i] = true
+	}
+	var kept, dropped []int
+	for i, r := range rolls {
+		if keep[i] {
+			kept = append(kept, r)
+		} else {
+			dropped = append(dropped, r)
+		}
+	}
+	return kept, dropped
+}
+
+func TestApplyRollModifierMatchesSort(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 5000; i++ {
+		rolls := make([]int, rng.IntN(40))
+		sides := 1 + rng.IntN(20)
+		for j := range rolls {
+			rolls[j] = 1 + rng.IntN(sides)
+		}
+		m := []Modifier{KeepHighest, KeepLowest}[rng.IntN(2)]
+		n := rng.IntN(len(rolls)+2) - 1
+
+		kept, dropped := applyRollModifier(rolls, m, n)
+		wantKept, wantDropped := sortedRollModifier(rolls, m, n)
+		if !slices.Equal(kept, wantKept) || !slices.Equal(dropped, wantDropped) {
+			t.Fatalf("applyRollModifier(%v, %d, %d) = %v, %v, want %v, %v", rolls, m, n, kept, dropped, wantKept, wantDropped)
+		}
+	}
+}
+
+func TestApplyRollModifierOrder(t *testing.T) {
+	tests := []struct {
+		rolls         []int
+		m             Modifier
+		n             int
+		kept, dropped []int
+	}{
+		{[]int{3, 6, 1, 6, 2}, KeepHighest, 2, []int{6, 6}, []int{3, 1, 2}},
+		{[]int{3, 6, 1, 6, 2}, KeepHighest, 4, []int{3, 6, 6, 2}, []int{1}},
+		{[]int{4, 4, 4, 4}, KeepHighest, 1, []int{4}, []int{4, 4, 4}},
+		{[]int{5, 2, 2, 6}, KeepLowest, 1, []int{2}, []int{5, 2, 6}},
+		{[]int{5, 2, 2, 6}, KeepLowest, 3, []int{5, 2, 2}, []int{6}},
+		{[]int{5, 2}, KeepLowest, 0, nil, []int{5, 2}},
+		{[]int{1, 2, 3, 4}, KeepMiddle, 2, []int{2, 3}, []int{1, 4}},
+		{[]int{1, 2, 3, 4, 5}, DropMiddle, 4, []int{1, 2, 4, 5}, []int{3}},
+		{[]int{5, 1, 3}, KeepMiddle, 1, []int{3}, []int{5, 1}},
+		{[]int{4, 1, 3, 2}, KeepMiddle, 1, []int{2}, []int{4, 1, 3}},
+		{[]int{5, 1, 3}, KeepMiddle, 2, []int{1, 3}, []int{5}},
+		{[]int{5, 1, 3}, DropMiddle, 2, []int{5, 1}, []int{3}},
+		{[]int{4, 1, 3, 2}, DropMiddle, 3, []int{4, 1, 3}, []int{2}},
+		{[]int{6, 1, 5, 2}, DropMiddle, 2, []int{6, 1}, []int{5, 2}},
+		{[]int{6, 1, 5, 2, 4}, DropMiddle, 3, []int{6, 1, 5}, []int{2, 4}},
+		{[]int{3, 3, 3, 3}, KeepMiddle, 1, []int{3}, []int{3, 3, 3}},
+		{[]int{5, 1, 3}, KeepMiddle, 0, nil, []int{5, 1, 3}},
+		{[]int{5, 1, 3}, KeepMiddle, 3, []int{5, 1, 3}, nil},
+		{[]int{5, 1, 3}, DropMiddle, 0, nil, []int{5, 1, 3}},
+		{[]int{5, 1, 3}, DropMiddle, 3, []int{5, 1, 3}, nil},
+	}
+	for _, tt := range tests {
+		kept, dropped := applyRollModifier(tt.rolls, tt.m, tt.n)
+		if !slices.Equal(kept, tt.kept) || !slices.Equal(dropped, tt.dropped) {
+			t.Errorf("applyRollModifier(%v, %d, %d) = %v, %v, want %v, %v", tt.rolls, tt.m, tt.n, kept, dropped, tt.kept, tt.dropped)
+		}
+	}
+}
+
+// benchmarkPool is the pool of 1000d6kh3.
+func benchmarkPool() []int {
+	rng := rand.New(rand.NewPCG(3, 4))
+	rolls := make([]int, 1000)
+	for i := range rolls {
+		rolls[i] = 1 + rng.IntN(6)
+	}
+	return rolls
+}
+
+func BenchmarkApplyRollModifier(b *testing.B) {
+	rolls := benchmarkPool()
+	for i := 0; i < b.N; i++ {
+		applyRollModifier(rolls, KeepHighest, 3)
+	}
+}
+
+func BenchmarkSortedRollModifier(b *testing.B) {
+	rolls := benchmarkPool()
+	for i := 0; i < b.N; i++ {
+		sortedRollModifier(rolls, KeepHighest, 3)
+	}
+}
+
+func TestSuccessPool(t *testing.T) {
+	tests := []struct {
+		expr                string
+		rolls               []int
+		successes, failures int
+		net, total          int
+		botch               bool
+	}{
+		{"5d10>=8f1", []int{1, 1, 1, 1, 1}, 0, 5, -5, -5, true},
+		{"5d10>=8f1", []int{1, 3, 5, 7, 1}, 0, 2, -2, -2, true},
+		{"5d10>=8f1", []int{2, 3, 5, 7, 4}, 0, 0, 0, 0, false},
+		{"5d10>=8f1", []int{8, 9, 10, 10, 8}, 5, 0, 5, 5, false},
+		{"5d10+2>=8f1", []int{8, 9, 10, 10, 8}, 5, 0, 5, 7, false},
+		{"5d10>=8f1", []int{10, 1, 4, 8, 1}, 2, 2, 0, 0, false},
+		{"5d10>=8f1", []int{9, 1, 4, 8, 10}, 3, 1, 2, 2, false},
+		{"5d10>=8f1", []int{9, 1, 1, 1, 4}, 1, 3, -2, -2, false},
+		{"4d10kh2>=8f1", []int{1, 1, 9, 2}, 1, 0, 1, 1, false},
+	}
+	for _, tt := range tests {
+		d, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.expr, err)
+		}
+		res := d.result(tt.rolls)
+		if res.Successes != tt.successes || res.Failures != tt.failures || res.NetSuccesses != tt.net || res.Total != tt.total || res.Botch != tt.botch {
+			t.Errorf("%s rolling %v = %d successes, %d failures, net %d, total %d, botch %t, want %d, %d, net %d, total %d, botch %t",
+				tt.expr, tt.rolls, res.Successes, res.Failures, res.NetSuccesses, res.Total, res.Botch,
+				tt.successes, tt.failures, tt.net, tt.total, tt.botch)
+		}
+	}
+}
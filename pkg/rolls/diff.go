@@ -0,0 +1,83 @@
+package rolls
+
+import "fmt"
+
+// Comparison describes how expression B differs from expression A.
+type Comparison struct {
+	A, B         *Expression
+	MinDelta     int
+	MaxDelta     int
+	AverageDelta float64
+	// BHigher is the probability that B rolls a higher total than A when
+	// both are rolled independently, and Tie the probability they are equal.
+	BHigher float64
+	Tie     float64
+}
+
+// CompareExpressions parses a and b, which may be sums of dice groups such
+// as 1d20+5-1d4, and compares their totals over every group.
+func CompareExpressions(a, b string) (*Comparison, error) {
+	da, err := ParseExpression(a)
+	if err != nil {
+		return nil, err
+	}
+	db, err := ParseExpression(b)
+	if err != nil {
+		return nil, err
+	}
+
+	distA, err := da.Distribution()
+	if err != nil {
+		return nil, err
+	}
+	distB, err := db.Distribution()
+	if err != nil {
+		return nil, err
+	}
+	avgA, err := da.Average()
+	if err != nil {
+		return nil, err
+	}
+	avgB, err := db.Average()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Comparison{
+		A:            da,
+		B:            db,
+		MinDelta:     db.Min() - da.Min(),
+		MaxDelta:     db.Max() - da.Max(),
+		AverageDelta: avgB - avgA,
+	}
+	for ta, pa := range distA {
+		for tb, pb := range distB {
+			switch {
+			case tb > ta:
+				c.BHigher += pa * pb
+			case tb == ta:
+				c.Tie += pa * pb
+			}
+		}
+	}
+
+	return c, nil
+}
+
+func diffGen(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("need to provide two expressions to compare (3d6+4 4d6+4)")
+	}
+	c, err := CompareExpressions(args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s → %s\n", c.A, c.B)
+	fmt.Printf("Average: %+.1f\n", c.AverageDelta)
+	fmt.Printf("Min: %+d\n", c.MinDelta)
+	fmt.Printf("Max: %+d\n", c.MaxDelta)
+	fmt.Printf("%s rolls higher: %.1f%% (tie %.1f%%)\n", c.B, c.BHigher*100, c.Tie*100)
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package rolls
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCompareExpressions(t *testing.T) {
+	tests := []struct {
+		a, b         string
+		minDelta     int
+		maxDelta     int
+		avgDelta     float64
+		bHigher, tie float64
+	}{
+		{"3d6", "4d6", 1, 6, 3.5, 0.7428, 0.0655},
+		{"1d20", "1d20", 0, 0, 0, 0.475, 0.05},
+		{"1d20+5-1d4", "1d20+5", 4, 1, 2.5, 0.59375, 0.04375},
+		{"1d6", "(1d6)*2", 1, 6, 3.5, 0.75, 0.0833},
+	}
+	for _, tt := range tests {
+		c, err := CompareExpressions(tt.a, tt.b)
+		if err != nil {
+			t.Fatalf("CompareExpressions(%q, %q): %v", tt.a, tt.b, err)
+		}
+		if c.MinDelta != tt.minDelta || c.MaxDelta != tt.maxDelta || math.Abs(c.AverageDelta-tt.avgDelta) > 1e-9 {
+			t.Errorf("CompareExpressions(%q, %q) = min %+d, max %+d, average %+g, want %+d, %+d, %+g",
+				tt.a, tt.b, c.MinDelta, c.MaxDelta, c.AverageDelta, tt.minDelta, tt.maxDelta, tt.avgDelta)
+		}
+		if math.Abs(c.BHigher-tt.bHigher) > 1e-4 || math.Abs(c.Tie-tt.tie) > 1e-4 {
+			t.Errorf("CompareExpressions(%q, %q) = higher %.4f, tie %.4f, want %.4f, %.4f",
+				tt.a, tt.b, c.BHigher, c.Tie, tt.bHigher, tt.tie)
+		}
+	}
+}
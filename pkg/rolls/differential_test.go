@@ -0,0 +1,30 @@
+package rolls_test
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/Domo929/roll/pkg/rolls/rolltest"
+)
+
+// FuzzParseDifferential checks Parse and ParseExpression agree on the
+// notation both read, and that neither panics. The corpus under
+// testdata/fuzz keeps every divergence the fuzzer has found, alongside the
+// rolltest.Fixtures it was seeded with.
+func FuzzParseDifferential(f *testing.F) {
+	for _, fx := range rolltest.Fixtures {
+		f.Add(fx.Expr)
+	}
+	rng := rand.New(rand.NewPCG(5, 6))
+	for i := 0; i < 200; i++ {
+		f.Add(rolltest.RandomExpression(rng))
+	}
+	f.Fuzz(func(t *testing.T, expr string) {
+		if err := rolltest.Differ(expr); err != nil {
+			t.Fatal(err)
+		}
+		if err := rolltest.RoundTrip(expr); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+)
+
+// DPROption configures DPR.
+type DPROption func(*Modifier)
+
+// WithAttackAdvantage rolls every attack with advantage.
+func WithAttackAdvantage() DPROption {
+	return func(m *Modifier) {
+		*m = KeepHighest
+	}
+}
+
+// WithAttackDisadvantage rolls every attack with disadvantage.
+func WithAttackDisadvantage() DPROption {
+	return func(m *Modifier) {
+		*m = KeepLowest
+	}
+}
+
+// AttackChances returns the chances that an attack with attackBonus hits
+// ac without a critical hit, and that it crits. Natural rolls of critRange
+// or more crit, and so hit whatever the AC; a natural 1 always misses. A
+// critRange of 0 crits on a 20 only.
+func AttackChances(attackBonus, ac, critRange int, opts ...DPROption) (hit, crit float64, err error) {
+	if critRange == 0 {
+		critRange = 20
+	}
+	if critRange < 2 || critRange > 20 {
+		return 0, 0, fmt.Errorf("passed illegal crit range: %d, want a natural roll from 2 to 20", critRange)
+	}
+	mode := NoModifier
+	for _, opt := range opts {
+		opt(&mode)
+	}
+
+	for face := 1; face <= 20; face++ {
+		p := faceChance(face, mode)
+		switch {
+		case face >= critRange:
+			crit += p
+		case face != 1 && face+attackBonus >= ac:
+			hit += p
+		}
+	}
+	return hit, crit, nil
+}
+
+// faceChance is the chance of a natural face on a d20, rolled with
+// advantage for KeepHighest and disadvantage for KeepLowest.
+func faceChance(face int, mode Modifier) float64 {
+	switch mode {
+	case KeepHighest:
+		return float64(face*face-(face-1)*(face-1)) / 400
+	case KeepLowest:
+		return float64((21-face)*(21-face)-(20-face)*(20-face)) / 400
+	}
+	return 1.0 / 20
+}
+
+// DPR returns the expected damage per round of attacks attacks, each with
+// attackBonus against ac, dealing damage on a hit. Critical hits double
+// the damage dice, as monster attacks do. See AttackChances for critRange.
+func DPR(attackBonus int, ac int, damage string, critRange int, attacks int, opts ...DPROption) (float64, error) {
+	e, err := ParseExpression(damage)
+	if err != nil {
+		return 0, err
+	}
+	hit, crit, err := AttackChances(attackBonus, ac, critRange, opts...)
+	if err != nil {
+		return 0, err
+	}
+	avg, err := e.Average()
+	if err != nil {
+		return 0, err
+	}
+	critAvg, err := e.critical().Average()
+	if err != nil {
+		return 0, err
+	}
+	return float64(attacks) * (hit*avg + crit*critAvg), nil
+}
+
+// critical returns e with the dice of every group doubled, those in
+// parentheses included.
+func (e *Expression) critical() *Expression {
+	out := &Expression{Bonus: e.Bonus}
+	for _, g := range e.Groups {
+		if g.Sub != nil {
+			g.Sub = g.Sub.critical()
+			out.Groups = append(out.Groups, g)
+			continue
+		}
+		d := *g.Dice
+		d.Count *= 2
+		if d.Modifier != NoModifier {
+			d.ModifierCount *= 2
+		}
+		g.Dice = &d
+		out.Groups = append(out.Groups, g)
+	}
+	return out
+}
+
+func dprGen(args []string) error {
+	fs := flag.NewFlagSet("dpr", flag.ContinueOnError)
+	hitBonus := fs.Int("hit", 0, "attack bonus")
+	ac := fs.Int("ac", 10, "target armor class")
+	damage := fs.String("dmg", "", "damage expression on a hit, e.g. 1d8+4")
+	attacks := fs.Int("attacks", 1, "attacks per round")
+	critRange := fs.Int("crit", 20, "lowest natural roll that crits")
+	adv := fs.Bool("adv", false, "attack with advantage")
+	dis := fs.Bool("dis", false, "attack with disadvantage")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *damage == "" {
+		return fmt.Errorf("need to provide the damage with --dmg")
+	}
+	if *attacks < 1 {
+		return fmt.Errorf("passed illegal attack count: %d", *attacks)
+	}
+
+	var opts []DPROption
+	switch {
+	case *adv && !*dis:
+		opts = append(opts, WithAttackAdvantage())
+	case *dis && !*adv:
+		opts = append(opts, WithAttackDisadvantage())
+	}
+	hit, crit, err := AttackChances(*hitBonus, *ac, *critRange, opts...)
+	if err != nil {
+		return err
+	}
+	dpr, err := DPR(*hitBonus, *ac, *damage, *critRange, *attacks, opts...)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Hit: %.1f%% Crit: %.1f%%\n", 100*(hit+crit), 100*crit)
+	fmt.Printf("DPR: %.2f\n", dpr)
+	return nil
+}
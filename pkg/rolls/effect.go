@@ -0,0 +1,182 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// SaveChance returns the chance that a save with saveMod meets dc.
+func SaveChance(dc, saveMod int) float64 {
+	faces := 0
+	for face := 1; face <= 20; face++ {
+		if face+saveMod >= dc {
+			faces++
+		}
+	}
+	return float64(faces) / 20
+}
+
+// ExpectedSaveDamage returns the expected damage of damage forced on a
+// creature saving with saveMod against dc. A successful save takes half,
+// rounded down, when halfOnSave is set and nothing otherwise. Totals below
+// zero deal no damage.
+func ExpectedSaveDamage(dc, saveMod int, damage string, halfOnSave bool) (float64, error) {
+	e, err := ParseExpression(damage)
+	if err != nil {
+		return 0, err
+	}
+	dist, err := e.Distribution()
+	if err != nil {
+		return 0, err
+	}
+	full, half := 0.0, 0.0
+	for total, p := range dist {
+		if total > 0 {
+			full += float64(total) * p
+			half += float64(total/2) * p
+		}
+	}
+	saved := SaveChance(dc, saveMod)
+	if !halfOnSave {
+		half = 0
+	}
+	return (1-saved)*full + saved*half, nil
+}
+
+// RepeatSave is when a creature under an effect repeats its save.
+type RepeatSave string
+
+const (
+	// RepeatNone never repeats the save: the effect lasts every round.
+	RepeatNone RepeatSave = "none"
+	// RepeatEndOfTurn repeats the save at the end of each affected turn,
+	// so the first turn is always lost.
+	RepeatEndOfTurn RepeatSave = "end-of-turn"
+	// RepeatStartOfTurn repeats the save at the start of each turn, and a
+	// success frees that turn.
+	RepeatStartOfTurn RepeatSave = "start-of-turn"
+)
+
+// ParseRepeatSave parses a RepeatSave.
+func ParseRepeatSave(s string) (RepeatSave, error) {
+	switch r := RepeatSave(s); r {
+	case RepeatNone, RepeatEndOfTurn, RepeatStartOfTurn:
+		return r, nil
+	}
+	return "", fmt.Errorf("passed illegal repeat save: %s, want none, end-of-turn or start-of-turn", s)
+}
+
+// ExpectedTurnsLost returns the expected number of turns, out of at most
+// maxRounds, that a creature saving with saveMod against dc loses to an
+// effect that ends on a repeated save. Each save succeeds independently
+// with chance p, so the turns lost after a failed first save follow a
+// geometric distribution cut off at maxRounds.
+func ExpectedTurnsLost(dc, saveMod int, repeat RepeatSave, maxRounds int) (float64, error) {
+	if maxRounds < 1 {
+		return 0, fmt.Errorf("need at least one round, got %d", maxRounds)
+	}
+	if _, err := ParseRepeatSave(string(repeat)); err != nil {
+		return 0, err
+	}
+	p := SaveChance(dc, saveMod)
+	// stay is the chance of losing one more turn: the effect holds through
+	// the next save, if any.
+	stay := 1 - p
+	if repeat == RepeatNone {
+		stay = 1
+	}
+
+	// After the failed first save, turn k is lost if the effect lasted
+	// through the k-1 saves before it, or k saves for start-of-turn saves.
+	lost, held := 0.0, 1.0
+	if repeat == RepeatStartOfTurn {
+		held = stay
+	}
+	for k := 0; k < maxRounds; k++ {
+		lost += held
+		held *= stay
+	}
+	return (1 - p) * lost, nil
+}
+
+// Effect is one effect compared by the sv subcommand: either damage, or a
+// named condition that costs turns while it lasts.
+type Effect struct {
+	Name       string
+	Damage     string
+	HalfOnSave bool
+}
+
+// ParseEffect parses an effect such as "8d6 half-on-save", "8d6" (no damage
+// on a save) or a condition name such as "hold-person".
+func ParseEffect(s string) (Effect, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return Effect{}, fmt.Errorf("passed illegal effect: empty")
+	}
+	if _, err := ParseExpression(fields[0]); err != nil {
+		if len(fields) > 1 {
+			return Effect{}, fmt.Errorf("passed illegal effect: %q, want damage such as 8d6 half-on-save or a condition name", s)
+		}
+		return Effect{Name: fields[0]}, nil
+	}
+	eff := Effect{Name: s, Damage: fields[0]}
+	for _, f := range fields[1:] {
+		switch f {
+		case "half-on-save":
+			eff.HalfOnSave = true
+		case "none-on-save":
+			eff.HalfOnSave = false
+		default:
+			return Effect{}, fmt.Errorf("passed illegal effect rule: %q, want half-on-save or none-on-save", f)
+		}
+	}
+	return eff, nil
+}
+
+func svGen(args []string) error {
+	fs := flag.NewFlagSet("sv", flag.ContinueOnError)
+	dc := fs.Int("dc", 10, "save DC")
+	saveMod := fs.Int("save", 0, "the target's saving throw modifier")
+	var effects stringList
+	fs.Var(&effects, "effect", "damage such as \"8d6 half-on-save\" or a condition name, may be repeated")
+	repeatName := fs.String("repeat-save", string(RepeatEndOfTurn), "when conditions are saved against again: none, end-of-turn or start-of-turn")
+	maxRounds := fs.Int("max-rounds", 10, "rounds a condition can last at most")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if len(effects) == 0 {
+		return fmt.Errorf("need to provide at least one --effect")
+	}
+	repeat, err := ParseRepeatSave(*repeatName)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Save: %.1f%% (%+d against DC %d)\n", 100*SaveChance(*dc, *saveMod), *saveMod, *dc)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Effect\tExpected")
+	for _, s := range effects {
+		eff, err := ParseEffect(s)
+		if err != nil {
+			return err
+		}
+		if eff.Damage != "" {
+			dmg, err := ExpectedSaveDamage(*dc, *saveMod, eff.Damage, eff.HalfOnSave)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "%s\t%.2f damage\n", eff.Name, dmg)
+			continue
+		}
+		turns, err := ExpectedTurnsLost(*dc, *saveMod, repeat, *maxRounds)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%.2f turns lost\n", eff.Name, turns)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,88 @@
+package rolls
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSaveChance(t *testing.T) {
+	tests := []struct {
+		dc, saveMod int
+		want        float64
+	}{
+		{16, 4, 9.0 / 20},
+		{11, 0, 10.0 / 20},
+		{1, 0, 1},
+		{25, 0, 0},
+		{10, -5, 6.0 / 20},
+	}
+	for _, tt := range tests {
+		if got := SaveChance(tt.dc, tt.saveMod); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("SaveChance(%d, %d) = %v, want %v", tt.dc, tt.saveMod, got, tt.want)
+		}
+	}
+}
+
+// TestExpectedSaveDamage checks small cases worked by hand: 1d4 averages
+// 2.5, and halved and rounded down reads 0, 1, 1, 2, averaging 1; 1d6-3
+// deals 0, 0, 0, 1, 2, 3, averaging 1, and halved 0, 0, 0, 0, 1, 1; and
+// 1d1+1 always deals 2, or 1 on a save.
+func TestExpectedSaveDamage(t *testing.T) {
+	tests := []struct {
+		dc, saveMod int
+		damage      string
+		halfOnSave  bool
+		want        float64
+	}{
+		{11, 0, "1d4", true, 0.5*2.5 + 0.5*1},
+		{11, 0, "1d4", false, 0.5 * 2.5},
+		{21, 0, "1d4", true, 2.5},
+		{1, 0, "1d4", true, 1},
+		{1, 0, "1d4", false, 0},
+		{21, 0, "1d6-3", true, 1},
+		{11, 0, "1d6-3", true, 0.5*1 + 0.5*2.0/6},
+		{16, 4, "1d1+1", true, 0.55*2 + 0.45*1},
+	}
+	for _, tt := range tests {
+		got, err := ExpectedSaveDamage(tt.dc, tt.saveMod, tt.damage, tt.halfOnSave)
+		if err != nil || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("ExpectedSaveDamage(%d, %d, %q, %t) = %v, %v, want %v", tt.dc, tt.saveMod, tt.damage, tt.halfOnSave, got, err, tt.want)
+		}
+	}
+}
+
+// TestExpectedTurnsLost checks small cases worked by hand. Saving at even
+// odds over 3 rounds, an end-of-turn save loses the first turn, then the
+// next with chance 1/2 and the last with 1/4, so 1.75 turns after the
+// failed first save and 0.875 in all; a start-of-turn save can free the
+// first turn too, so half that.
+func TestExpectedTurnsLost(t *testing.T) {
+	tests := []struct {
+		dc, saveMod int
+		repeat      RepeatSave
+		maxRounds   int
+		want        float64
+	}{
+		{11, 0, RepeatEndOfTurn, 3, 0.5 * (1 + 0.5 + 0.25)},
+		{11, 0, RepeatStartOfTurn, 3, 0.5 * (0.5 + 0.25 + 0.125)},
+		{11, 0, RepeatNone, 3, 0.5 * 3},
+		{11, 0, RepeatEndOfTurn, 1, 0.5},
+		{21, 0, RepeatEndOfTurn, 10, 10},
+		{21, 0, RepeatStartOfTurn, 10, 10},
+		{1, 0, RepeatEndOfTurn, 10, 0},
+		{16, 4, RepeatEndOfTurn, 2, 0.55 * (1 + 0.55)},
+	}
+	for _, tt := range tests {
+		got, err := ExpectedTurnsLost(tt.dc, tt.saveMod, tt.repeat, tt.maxRounds)
+		if err != nil || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("ExpectedTurnsLost(%d, %d, %s, %d) = %v, %v, want %v", tt.dc, tt.saveMod, tt.repeat, tt.maxRounds, got, err, tt.want)
+		}
+	}
+
+	if _, err := ExpectedTurnsLost(11, 0, RepeatEndOfTurn, 0); err == nil {
+		t.Error("ExpectedTurnsLost with no rounds succeeded")
+	}
+	if _, err := ExpectedTurnsLost(11, 0, "sometimes", 3); err == nil {
+		t.Error("ExpectedTurnsLost with an unknown repeat save succeeded")
+	}
+}
@@ -0,0 +1,188 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Difficulty is an encounter difficulty from the DMG.
+type Difficulty int
+
+const (
+	Easy Difficulty = iota
+	Medium
+	Hard
+	Deadly
+)
+
+var difficultyNames = []string{"easy", "medium", "hard", "deadly"}
+
+func (d Difficulty) String() string {
+	return difficultyNames[d]
+}
+
+// ParseDifficulty parses a difficulty name such as "hard".
+func ParseDifficulty(s string) (Difficulty, error) {
+	for i, name := range difficultyNames {
+		if strings.EqualFold(s, name) {
+			return Difficulty(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown difficulty %q, want one of %s", s, strings.Join(difficultyNames, ", "))
+}
+
+// xpThresholds holds the per-character XP thresholds by character level,
+// indexed by level-1 then Difficulty.
+var xpThresholds = [20][4]int{
+	{25, 50, 75, 100},
+	{50, 100, 150, 200},
+	{75, 150, 225, 400},
+	{125, 250, 375, 500},
+	{250, 500, 750, 1100},
+	{300, 600, 900, 1400},
+	{350, 750, 1100, 1700},
+	{450, 900, 1400, 2100},
+	{550, 1100, 1600, 2400},
+	{600, 1200, 1900, 2800},
+	{800, 1600, 2400, 3600},
+	{1000, 2000, 3000, 4500},
+	{1100, 2200, 3400, 5100},
+	{1250, 2500, 3800, 5700},
+	{1400, 2800, 4300, 6400},
+	{1600, 3200, 4800, 7200},
+	{2000, 3900, 5900, 8800},
+	{2100, 4200, 6300, 9500},
+	{2400, 4900, 7300, 10900},
+	{2800, 5700, 8500, 12700},
+}
+
+// multiplierLadder is the encounter multiplier ladder. The outer steps are
+// only reached through the party size adjustment.
+var multiplierLadder = []float64{0.5, 1, 1.5, 2, 2.5, 3, 4, 5}
+
+// XPBudget returns the XP threshold for a party of partySize characters of
+// the given level.
+func XPBudget(partySize, level int, diff Difficulty) (int, error) {
+	if partySize < 1 {
+		return 0, fmt.Errorf("party size must be at least 1, got %d", partySize)
+	}
+	if level < 1 || level > len(xpThresholds) {
+		return 0, fmt.Errorf("character level must be between 1 and %d, got %d", len(xpThresholds), level)
+	}
+	if diff < Easy || diff > Deadly {
+		return 0, fmt.Errorf("unknown difficulty %d", diff)
+	}
+	return partySize * xpThresholds[level-1][diff], nil
+}
+
+// EncounterMultiplier returns the multiplier applied to monster XP for the
+// number of monsters, shifted one step up for parties of fewer than three
+// characters and one step down for parties of six or more.
+func EncounterMultiplier(monsters, partySize int) float64 {
+	var step int
+	switch {
+	case monsters <= 1:
+		step = 1
+	case monsters == 2:
+		step = 2
+	case monsters <= 6:
+		step = 3
+	case monsters <= 10:
+		step = 4
+	case monsters <= 14:
+		step = 5
+	default:
+		step = 6
+	}
+
+	switch {
+	case partySize < 3:
+		step++
+	case partySize >= 6:
+		step--
+	}
+	return multiplierLadder[step]
+}
+
+// Encounter is a rolled encounter and its XP math.
+type Encounter struct {
+	PartySize  int
+	Level      int
+	Difficulty Difficulty
+	Budget     int
+	Monsters   *Result
+	Multiplier float64
+	// PerMonsterXP is the most XP each monster can be worth for the
+	// encounter to stay within budget once the multiplier is applied.
+	PerMonsterXP int
+}
+
+// RollEncounter computes the XP budget for the party and rolls the number of
+// monsters from the given dice.
+func RollEncounter(partySize, level int, diff Difficulty, monsters *Dice) (*Encounter, error) {
+	budget, err := XPBudget(partySize, level, diff)
+	if err != nil {
+		return nil, err
+	}
+
+	res := monsters.Roll()
+	if res.Total < 1 {
+		return nil, fmt.Errorf("rolled %s from %s", quantity(res.Total, "monster"), monsters)
+	}
+
+	multiplier := EncounterMultiplier(res.Total, partySize)
+	return &Encounter{
+		PartySize:    partySize,
+		Level:        level,
+		Difficulty:   diff,
+		Budget:       budget,
+		Monsters:     res,
+		Multiplier:   multiplier,
+		PerMonsterXP: int(float64(budget) / (float64(res.Total) * multiplier)),
+	}, nil
+}
+
+func encounterGen(args []string) error {
+	fs := flag.NewFlagSet("encounter", flag.ContinueOnError)
+	party := fs.String("party", "", "party size and level, e.g. 4x5 for four level 5 characters")
+	difficulty := fs.String("difficulty", "medium", "encounter difficulty: easy, medium, hard or deadly")
+	monsters := fs.String("monsters", "1d4", "dice rolled for the number of monsters")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	parts := strings.Split(*party, "x")
+	if len(parts) != 2 {
+		return fmt.Errorf("passed illegal party: %q, want SIZExLEVEL", *party)
+	}
+	size, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return err
+	}
+	level, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return err
+	}
+	diff, err := ParseDifficulty(*difficulty)
+	if err != nil {
+		return err
+	}
+	num, sides, err := parseNormDice(*monsters)
+	if err != nil {
+		return err
+	}
+
+	enc, err := RollEncounter(size, level, diff, &Dice{Count: num, Sides: sides})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Budget: %d XP (%s for %d level %d %s)\n", enc.Budget, enc.Difficulty, enc.PartySize, enc.Level, pluralize("character", enc.PartySize))
+	fmt.Println("Monsters:", enc.Monsters)
+	fmt.Printf("Multiplier: x%g\n", enc.Multiplier)
+	fmt.Printf("Each monster can be worth up to %d XP\n", enc.PerMonsterXP)
+
+	return nil
+}
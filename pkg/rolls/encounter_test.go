@@ -0,0 +1,94 @@
+package rolls
+
+import "testing"
+
+// TestXPBudget checks the budget against the per-character thresholds of the
+// Dungeon Master's Guide, p. 82.
+func TestXPBudget(t *testing.T) {
+	tests := []struct {
+		party, level int
+		want         [4]int
+	}{
+		{1, 1, [4]int{25, 50, 75, 100}},
+		{1, 3, [4]int{75, 150, 225, 400}},
+		{1, 5, [4]int{250, 500, 750, 1100}},
+		{1, 7, [4]int{350, 750, 1100, 1700}},
+		{1, 10, [4]int{600, 1200, 1900, 2800}},
+		{1, 13, [4]int{1100, 2200, 3400, 5100}},
+		{1, 17, [4]int{2000, 3900, 5900, 8800}},
+		{1, 20, [4]int{2800, 5700, 8500, 12700}},
+		{4, 5, [4]int{1000, 2000, 3000, 4400}},
+		{5, 3, [4]int{375, 750, 1125, 2000}},
+	}
+	for _, tt := range tests {
+		for diff := Easy; diff <= Deadly; diff++ {
+			got, err := XPBudget(tt.party, tt.level, diff)
+			if err != nil {
+				t.Fatalf("XPBudget(%d, %d, %s): %v", tt.party, tt.level, diff, err)
+			}
+			if got != tt.want[diff] {
+				t.Errorf("XPBudget(%d, %d, %s) = %d, want %d", tt.party, tt.level, diff, got, tt.want[diff])
+			}
+		}
+	}
+}
+
+func TestXPBudgetErrors(t *testing.T) {
+	tests := []struct {
+		party, level int
+		diff         Difficulty
+	}{
+		{0, 5, Easy},
+		{4, 0, Easy},
+		{4, 21, Easy},
+		{4, 5, Deadly + 1},
+		{4, 5, Easy - 1},
+	}
+	for _, tt := range tests {
+		if _, err := XPBudget(tt.party, tt.level, tt.diff); err == nil {
+			t.Errorf("XPBudget(%d, %d, %d) succeeded, want an error", tt.party, tt.level, tt.diff)
+		}
+	}
+}
+
+// TestEncounterMultiplier checks the DMG's multiplier table, p. 82, and its
+// shift for small and large parties.
+func TestEncounterMultiplier(t *testing.T) {
+	tests := []struct {
+		monsters int
+		want     [3]float64 // parties of 2, 4 and 6
+	}{
+		{1, [3]float64{1.5, 1, 0.5}},
+		{2, [3]float64{2, 1.5, 1}},
+		{3, [3]float64{2.5, 2, 1.5}},
+		{6, [3]float64{2.5, 2, 1.5}},
+		{7, [3]float64{3, 2.5, 2}},
+		{10, [3]float64{3, 2.5, 2}},
+		{11, [3]float64{4, 3, 2.5}},
+		{14, [3]float64{4, 3, 2.5}},
+		{15, [3]float64{5, 4, 3}},
+		{40, [3]float64{5, 4, 3}},
+	}
+	for _, tt := range tests {
+		for i, party := range []int{2, 4, 6} {
+			if got := EncounterMultiplier(tt.monsters, party); got != tt.want[i] {
+				t.Errorf("EncounterMultiplier(%d, %d) = %g, want %g", tt.monsters, party, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestRollEncounter(t *testing.T) {
+	// Three one-sided dice always roll three monsters.
+	enc, err := RollEncounter(4, 5, Hard, &Dice{Count: 3, Sides: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc.Budget != 3000 || enc.Monsters.Total != 3 || enc.Multiplier != 2 || enc.PerMonsterXP != 500 {
+		t.Errorf("RollEncounter(4, 5, hard, 3d1) = budget %d, %d monsters, x%g, %d XP each, want 3000, 3, x2, 500",
+			enc.Budget, enc.Monsters.Total, enc.Multiplier, enc.PerMonsterXP)
+	}
+	if _, err := RollEncounter(4, 5, Hard, &Dice{Count: 1, Sides: 1, Bonus: -1}); err == nil {
+		t.Error("RollEncounter with no monsters succeeded, want an error")
+	}
+}
@@ -0,0 +1,236 @@
+package rolls
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var smallNumbers = []string{
+	"zero", "one", "two", "three", "four", "five", "six", "seven", "eight",
+	"nine", "ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen",
+	"sixteen", "seventeen", "eighteen", "nineteen", "twenty",
+}
+
+// spell writes n as a word up to twenty and in digits above.
+func spell(n int) string {
+	if n >= 0 && n < len(smallNumbers) {
+		return smallNumbers[n]
+	}
+	return strconv.Itoa(n)
+}
+
+var thresholdWords = map[string]string{
+	">=": "%d or more",
+	"<=": "%d or less",
+	">":  "more than %d",
+	"<":  "less than %d",
+	"=":  "exactly %d",
+}
+
+// Explain describes expr in plain English without rolling it, along with
+// the range and average of its total, such as "Roll four six-sided dice,
+// drop the lowest one, add 2. Range 5–20, average 14.2." Conditionals such
+// as 1d20+7>=15?2d6+4 are explained too.
+func Explain(expr string) (string, error) {
+	if strings.Contains(expr, "?") {
+		c, err := ParseConditional(expr)
+		if err != nil {
+			return "", err
+		}
+		return explainConditional(c), nil
+	}
+	e, err := ParseExpression(expr)
+	if err != nil {
+		return "", err
+	}
+	if d, ok := e.Dice(); ok {
+		return ExplainDice(d), nil
+	}
+	return explainExpression(e), nil
+}
+
+// ExplainDice describes d in plain English.
+func ExplainDice(d *Dice) string {
+	return strings.Join([]string{sentence(d.describe()), d.stats()}, " ")
+}
+
+// explainExpression describes each group of e in turn, with negative
+// groups as dice to subtract.
+func explainExpression(e *Expression) string {
+	avg, err := e.Average()
+	return sentence(e.describe()) + " " + rangeText(e.Min(), e.Max(), "", avg, err)
+}
+
+// describe lists what rolling e does, clause by clause.
+func (e *Expression) describe() []string {
+	var clauses []string
+	for i, g := range e.Groups {
+		c := g.describe()
+		switch {
+		case g.Negative:
+			c[0] = "subtract " + strings.TrimPrefix(c[0], "roll ")
+		case i > 0:
+			c[0] = "add " + strings.TrimPrefix(c[0], "roll ")
+		}
+		clauses = append(clauses, c...)
+	}
+	return append(clauses, bonusClause(e.Bonus)...)
+}
+
+// describe lists what rolling g does. A scaled group or one in parentheses
+// is a single clause with its steps in brackets, so they are not read as
+// applying to the rest, as in "roll (two six-sided dice, add 3, multiply
+// by 2)".
+func (g Group) describe() []string {
+	if g.Sub == nil && len(g.Scale) == 0 {
+		return g.Dice.describe()
+	}
+	var c []string
+	if g.Sub != nil {
+		c = g.Sub.describe()
+	} else {
+		c = g.Dice.describe()
+	}
+	for _, s := range g.Scale {
+		c = append(c, s.describe())
+	}
+	return []string{"roll (" + strings.TrimPrefix(strings.Join(c, ", "), "roll ") + ")"}
+}
+
+// describe says what s does to a total.
+func (s ScaleStep) describe() string {
+	switch {
+	case s.Op == "*":
+		return fmt.Sprintf("multiply by %d", s.By)
+	case s.RoundUp:
+		return fmt.Sprintf("divide by %d, rounding up", s.By)
+	}
+	return fmt.Sprintf("divide by %d, rounding down", s.By)
+}
+
+func explainConditional(c *Conditional) string {
+	return fmt.Sprintf("%s If the total is %s, also: %s",
+		sentence(c.Check.describe()),
+		fmt.Sprintf(thresholdWords[c.Gate.Op], c.Gate.Target),
+		ExplainDice(c.OnSuccess))
+}
+
+// describe lists what rolling d does, clause by clause.
+func (d *Dice) describe() []string {
+	if len(d.Labels) > 0 {
+		die := fmt.Sprintf("%s dice", spell(d.Count))
+		if d.Count == 1 {
+			die = "one die"
+		}
+		return []string{fmt.Sprintf("roll %s with faces labeled %s", die, joinWithAnd(d.Labels)), "count the dice showing each label"}
+	}
+	die := fmt.Sprintf("%s %s-sided dice", spell(d.Count), spell(d.Sides))
+	switch {
+	case d.fudge() && d.Count == 1:
+		die = "one Fudge die, showing -1, 0 or +1"
+	case d.fudge():
+		die = fmt.Sprintf("%s Fudge dice, each showing -1, 0 or +1", spell(d.Count))
+	case len(d.FaceValues) > 0:
+		die = fmt.Sprintf("%s dice with faces of %s", spell(d.Count), joinWithAnd(strings.Fields(joinInts(d.FaceValues, " "))))
+	case d.Count == 1:
+		die = fmt.Sprintf("one %s-sided die", spell(d.Sides))
+	}
+	clauses := []string{"roll " + die}
+	switch d.Explode {
+	case Exploding:
+		clauses = append(clauses, fmt.Sprintf("roll another die for every %s rolled", spell(d.Sides)))
+	case Compounding:
+		clauses = append(clauses, fmt.Sprintf("roll a die again and add to it whenever it shows %s", spell(d.Sides)))
+	case Penetrating:
+		clauses = append(clauses, fmt.Sprintf("roll a die again whenever it shows %s, adding each new roll less one", spell(d.Sides)))
+	}
+	switch {
+	case d.Reroll == nil:
+	case d.RerollRecursive:
+		clauses = append(clauses, "reroll each die showing "+fmt.Sprintf(thresholdWords[d.Reroll.Op], d.Reroll.Target)+" until it does not")
+	default:
+		clauses = append(clauses, "reroll each die showing "+fmt.Sprintf(thresholdWords[d.Reroll.Op], d.Reroll.Target)+" once, keeping the new roll")
+	}
+	if d.MinPerDie > 1 {
+		clauses = append(clauses, fmt.Sprintf("count any die below %d as %d", d.MinPerDie, d.MinPerDie))
+	}
+	if d.MaxPerDie > 0 && (d.MaxPerDie < d.Sides || d.Explode != NoExplosion) {
+		clauses = append(clauses, fmt.Sprintf("count any die above %d as %d", d.MaxPerDie, d.MaxPerDie))
+	}
+
+	switch {
+	case d.Modifier == KeepMiddle && d.ModifierCount < d.Count:
+		clauses = append(clauses, fmt.Sprintf("keep the middle %s", spell(d.ModifierCount)))
+	case d.Modifier == DropMiddle && d.ModifierCount < d.Count:
+		clauses = append(clauses, fmt.Sprintf("drop the middle %s", spell(d.Count-d.ModifierCount)))
+	case d.Modifier != NoModifier && d.ModifierCount < d.Count:
+		keep, drop := "highest", "lowest"
+		if d.Modifier == KeepLowest {
+			keep, drop = drop, keep
+		}
+		switch dropped := d.Count - d.ModifierCount; {
+		case d.Count == 2 && d.Sides == 20 && d.ModifierCount == 1:
+			adv := "advantage"
+			if d.Modifier == KeepLowest {
+				adv = "disadvantage"
+			}
+			clauses[0] = fmt.Sprintf("roll one twenty-sided die with %s, keeping the %s of two", adv, keep)
+		case dropped <= d.ModifierCount:
+			clauses = append(clauses, fmt.Sprintf("drop the %s %s", drop, spell(dropped)))
+		default:
+			clauses = append(clauses, fmt.Sprintf("keep the %s %s", keep, spell(d.ModifierCount)))
+		}
+	}
+
+	if d.Success != nil {
+		clauses = append(clauses, "count each kept die showing "+fmt.Sprintf(thresholdWords[d.Success.Op], d.Success.Target)+" as a success")
+		if d.Failure != nil {
+			clauses = append(clauses, "take one away for each showing "+fmt.Sprintf(thresholdWords[d.Failure.Op], d.Failure.Target))
+		}
+		if bonus := bonusClause(d.Bonus); bonus != nil {
+			return append(clauses, bonus[0]+" "+pluralize("success", d.Bonus))
+		}
+	}
+	return append(clauses, bonusClause(d.Bonus)...)
+}
+
+func bonusClause(bonus int) []string {
+	switch {
+	case bonus > 0:
+		return []string{fmt.Sprintf("add %d", bonus)}
+	case bonus < 0:
+		return []string{fmt.Sprintf("subtract %d", -bonus)}
+	}
+	return nil
+}
+
+// stats describes the range and average of d's total.
+func (d *Dice) stats() string {
+	if len(d.Labels) > 0 {
+		return fmt.Sprintf("Each face has a 1 in %d chance.", d.Sides)
+	}
+	unit := ""
+	if d.Success != nil {
+		unit = " successes"
+	}
+	avg, err := d.Average()
+	return rangeText(d.Min(), d.Max(), unit, avg, err)
+}
+
+// rangeText describes a range of totals, counted in unit if set, and, unless
+// err is set, their average.
+func rangeText(lo, hi int, unit string, avg float64, err error) string {
+	s := fmt.Sprintf("Range %d–%d%s", lo, hi, unit)
+	if err == nil {
+		s += ", average " + strconv.FormatFloat(math.Round(avg*10)/10, 'f', -1, 64)
+	}
+	return s + "."
+}
+
+// sentence joins clauses into a capitalised sentence.
+func sentence(clauses []string) string {
+	s := strings.Join(clauses, ", ")
+	return strings.ToUpper(s[:1]) + s[1:] + "."
+}
@@ -0,0 +1,306 @@
+package rolls
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// FoundryMessage is a FoundryVTT chat message carrying a single roll.
+type FoundryMessage struct {
+	Type      int            `json:"type"`
+	Content   string         `json:"content"`
+	Flavor    string         `json:"flavor,omitempty"`
+	Timestamp int64          `json:"timestamp"`
+	Speaker   FoundrySpeaker `json:"speaker"`
+	// Rolls holds each roll serialized to a JSON string, as Foundry stores it.
+	Rolls []string `json:"rolls"`
+}
+
+// FoundrySpeaker names who sent a FoundryVTT chat message.
+type FoundrySpeaker struct {
+	Alias string `json:"alias"`
+}
+
+// FoundryRoll is the serialized form of a FoundryVTT Roll.
+type FoundryRoll struct {
+	Class     string        `json:"class"`
+	Formula   string        `json:"formula"`
+	Terms     []FoundryTerm `json:"terms"`
+	Total     int           `json:"total"`
+	Evaluated bool          `json:"evaluated"`
+}
+
+// FoundryTerm is a Die, OperatorTerm or NumericTerm of a FoundryVTT Roll.
+type FoundryTerm struct {
+	Class    string             `json:"class"`
+	Number   int                `json:"number,omitempty"`
+	Faces    int                `json:"faces,omitempty"`
+	Operator string             `json:"operator,omitempty"`
+	Results  []FoundryDieResult `json:"results,omitempty"`
+}
+
+// FoundryDieResult is one die of a FoundryVTT Die term.
+type FoundryDieResult struct {
+	Result    int  `json:"result"`
+	Active    bool `json:"active"`
+	Discarded bool `json:"discarded,omitempty"`
+}
+
+// Roll20Message is a Roll20 "rollresult" chat message.
+type Roll20Message struct {
+	Who  string `json:"who"`
+	Type string `json:"type"`
+	// Content holds the Roll20Content serialized to a JSON string.
+	Content  string `json:"content"`
+	OrigRoll string `json:"origRoll"`
+}
+
+// Roll20Content is the roll structure inside a Roll20 rollresult message.
+type Roll20Content struct {
+	Type       string       `json:"type"`
+	Rolls      []Roll20Roll `json:"rolls"`
+	ResultType string       `json:"resultType"`
+	Total      int          `json:"total"`
+}
+
+// Roll20Roll is a dice ("R"), math ("M") or comment ("C") part of a roll.
+type Roll20Roll struct {
+	Type    string         `json:"type"`
+	Dice    int            `json:"dice,omitempty"`
+	Sides   int            `json:"sides,omitempty"`
+	Results []Roll20Result `json:"results,omitempty"`
+	Expr    string         `json:"expr,omitempty"`
+	Text    string         `json:"text,omitempty"`
+}
+
+// Roll20Result is one die of a Roll20 dice roll; D marks a dropped die.
+type Roll20Result struct {
+	V int  `json:"v"`
+	D bool `json:"d,omitempty"`
+}
+
+// exportSpeaker is the speaker name exported messages are attributed to.
+const exportSpeaker = "roll"
+
+// splitExpression separates a result's formula from any trailing annotation,
+// such as "(advantage cancelled)".
+func splitExpression(expr string) (string, string) {
+	formula, note, _ := strings.Cut(expr, " ")
+	return formula, note
+}
+
+// unsupportedText describes the parts of a result neither VTT can represent
+// natively, such as the faces of symbol dice, so they can be carried as
+// text instead of dropped.
+func unsupportedText(res *Result) []string {
+	var text []string
+	for _, sub := range res.Substituted {
+		text = append(text, fmt.Sprintf("substituted %d→%d", sub.Original, sub.Value))
+	}
+	if res.Successes > 0 {
+		text = append(text, quantity(res.Successes, "success"))
+	}
+	if res.Failures > 0 {
+		text = append(text, quantity(res.Failures, "failure"))
+	}
+	if res.Botch {
+		text = append(text, "botch")
+	}
+	for _, g := range resultGroups(res) {
+		if len(g.Faces) > 0 {
+			text = append(text, fmt.Sprintf("faces %s (%s)", strings.Join(g.Faces, ", "), g.tallyString()))
+		}
+		for i, v := range g.AdjustedRolls {
+			switch {
+			case v > g.Rolls[i]:
+				text = append(text, fmt.Sprintf("raised %d→%d", g.Rolls[i], v))
+			case v < g.Rolls[i]:
+				text = append(text, fmt.Sprintf("lowered %d→%d", g.Rolls[i], v))
+			}
+		}
+	}
+	for _, g := range res.Groups {
+		if len(g.Scale) > 0 {
+			text = append(text, fmt.Sprintf("%d%s = %d", g.Unscaled, stepsString(g.Scale), g.Total))
+		}
+	}
+	return text
+}
+
+// ExportFoundry converts history entries to FoundryVTT chat messages. Parts of
+// a result Foundry cannot represent are added to the message flavor and
+// reported as warnings.
+func ExportFoundry(entries []HistoryEntry) ([]FoundryMessage, []string, error) {
+	var (
+		msgs     []FoundryMessage
+		warnings []string
+	)
+	for i, e := range entries {
+		res := e.Result
+		formula, note := splitExpression(res.Expression)
+
+		roll := FoundryRoll{Class: "Roll", Formula: formula, Total: res.Total, Evaluated: true}
+		for j, g := range resultGroups(res) {
+			switch {
+			case g.Negative:
+				roll.Terms = append(roll.Terms, FoundryTerm{Class: "OperatorTerm", Operator: "-"})
+			case j > 0:
+				roll.Terms = append(roll.Terms, FoundryTerm{Class: "OperatorTerm", Operator: "+"})
+			}
+			die := FoundryTerm{Class: "Die", Number: len(g.Rolls), Faces: g.Sides}
+			if g.Fudge {
+				die.Class = "FateDie"
+			}
+			dropped := g.DroppedMask()
+			for k, r := range g.countedRolls() {
+				die.Results = append(die.Results, FoundryDieResult{Result: r, Active: !dropped[k], Discarded: dropped[k]})
+			}
+			roll.Terms = append(roll.Terms, die)
+		}
+		if res.Bonus != 0 {
+			op, n := "+", res.Bonus
+			if n < 0 {
+				op, n = "-", -n
+			}
+			roll.Terms = append(roll.Terms, FoundryTerm{Class: "OperatorTerm", Operator: op}, FoundryTerm{Class: "NumericTerm", Number: n})
+		}
+		raw, err := json.Marshal(roll)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		flavor := []string{}
+		if note != "" {
+			flavor = append(flavor, note)
+		}
+		if text := unsupportedText(res); len(text) > 0 {
+			warnings = append(warnings, fmt.Sprintf("entry %d (%s): %s exported as text", i+1, res.Expression, strings.Join(text, ", ")))
+			flavor = append(flavor, text...)
+		}
+
+		msgs = append(msgs, FoundryMessage{
+			Type:      5,
+			Content:   fmt.Sprint(res.Total),
+			Flavor:    strings.Join(flavor, "; "),
+			Timestamp: e.Time.UnixMilli(),
+			Speaker:   FoundrySpeaker{Alias: exportSpeaker},
+			Rolls:     []string{string(raw)},
+		})
+	}
+	return msgs, warnings, nil
+}
+
+// ExportRoll20 converts history entries to Roll20 rollresult messages. Parts
+// of a result Roll20 cannot represent are added as comments and reported as
+// warnings.
+func ExportRoll20(entries []HistoryEntry) ([]Roll20Message, []string, error) {
+	var (
+		msgs     []Roll20Message
+		warnings []string
+	)
+	for i, e := range entries {
+		res := e.Result
+		formula, note := splitExpression(res.Expression)
+
+		content := Roll20Content{Type: "V", ResultType: "sum", Total: res.Total}
+		for j, g := range resultGroups(res) {
+			switch {
+			case g.Negative:
+				content.Rolls = append(content.Rolls, Roll20Roll{Type: "M", Expr: "-"})
+			case j > 0:
+				content.Rolls = append(content.Rolls, Roll20Roll{Type: "M", Expr: "+"})
+			}
+			dice := Roll20Roll{Type: "R", Dice: len(g.Rolls), Sides: g.Sides}
+			dropped := g.DroppedMask()
+			for k, r := range g.countedRolls() {
+				dice.Results = append(dice.Results, Roll20Result{V: r, D: dropped[k]})
+			}
+			content.Rolls = append(content.Rolls, dice)
+		}
+		if res.Bonus != 0 {
+			content.Rolls = append(content.Rolls, Roll20Roll{Type: "M", Expr: fmt.Sprintf("%+d", res.Bonus)})
+		}
+		if note != "" {
+			content.Rolls = append(content.Rolls, Roll20Roll{Type: "C", Text: note})
+		}
+		if text := unsupportedText(res); len(text) > 0 {
+			warnings = append(warnings, fmt.Sprintf("entry %d (%s): %s exported as text", i+1, res.Expression, strings.Join(text, ", ")))
+			content.Rolls = append(content.Rolls, Roll20Roll{Type: "C", Text: strings.Join(text, "; ")})
+		}
+		raw, err := json.Marshal(content)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		msgs = append(msgs, Roll20Message{Who: exportSpeaker, Type: "rollresult", Content: string(raw), OrigRoll: formula})
+	}
+	return msgs, warnings, nil
+}
+
+func exportGen(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "foundry", "export format: foundry or roll20")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	entries, skipped, err := LoadHistory()
+	if err != nil {
+		return err
+	}
+	if skipped > 0 {
+		log.Printf("skipped %d unreadable history lines", skipped)
+	}
+
+	var (
+		out      interface{}
+		warnings []string
+	)
+	switch *format {
+	case "foundry":
+		out, warnings, err = ExportFoundry(entries)
+	case "roll20":
+		out, warnings, err = ExportRoll20(entries)
+	default:
+		return fmt.Errorf("unknown export format %q, want foundry or roll20", *format)
+	}
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		log.Println("warning:", w)
+	}
+
+	raw, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(raw))
+	return nil
+}
+
+// resultGroups returns the dice groups of res, which is a group itself
+// unless it came from a multi-group Expression.
+func resultGroups(res *Result) []*Result {
+	if len(res.Groups) > 0 {
+		return res.Groups
+	}
+	return []*Result{res}
+}
+
+// DiceGroups returns the result of every dice group of r in order, those
+// in parentheses included, or r itself when it rolled a single group.
+func (r *Result) DiceGroups() []*Result {
+	var groups []*Result
+	for _, g := range resultGroups(r) {
+		if len(g.Groups) > 0 {
+			groups = append(groups, g.DiceGroups()...)
+		} else {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
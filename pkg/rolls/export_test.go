@@ -0,0 +1,69 @@
+package rolls
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files under testdata")
+
+// TestExportGolden exports testdata/export/history.jsonl, a history log
+// written by the roll command, and compares what each format makes of it
+// with the golden files beside it, as roll history export prints them.
+// Run go test -update to rewrite them after a deliberate change.
+func TestExportGolden(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "export", "history.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries, skipped, err := ReadHistory(f)
+	if err != nil || skipped > 0 {
+		t.Fatalf("ReadHistory: %v, skipping %d lines", err, skipped)
+	}
+
+	foundry, foundryWarnings, err := ExportFoundry(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roll20, roll20Warnings, err := ExportRoll20(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "foundry.json", foundry)
+	golden(t, "roll20.json", roll20)
+	golden(t, "warnings.json", foundryWarnings)
+	if strings.Join(roll20Warnings, "\n") != strings.Join(foundryWarnings, "\n") {
+		t.Errorf("roll20 warns %q, want the same warnings as foundry, %q", roll20Warnings, foundryWarnings)
+	}
+}
+
+// golden compares v, indented as JSON, with testdata/export/name, or
+// rewrites the file with -update.
+func golden(t *testing.T, name string, v interface{}) {
+	t.Helper()
+	got, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+	path := filepath.Join("testdata", "export", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("export differs from %s, run go test -update if that is intended:\n%s", path, got)
+	}
+}
@@ -0,0 +1,572 @@
+package rolls
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Expression is a sum of dice groups and a flat bonus, such as 1d20+5-1d4.
+//
+// A negative group is rolled like any other, keeping or dropping dice by
+// their faces, and only then are its kept dice subtracted: -2d6kh1 rolls
+// two d6 and subtracts the higher one. Its dice never fold into the flat
+// bonus, so 1d20-1d4 and 1d20-4 stay distinct in every result.
+type Expression struct {
+	Groups []Group
+	Bonus  int
+}
+
+// Group is one dice group of an Expression.
+type Group struct {
+	Dice *Dice
+	// Sub, set instead of Dice, is an expression in parentheses rolled as
+	// one group, as in (2d6+3)*2.
+	Sub      *Expression
+	Negative bool
+	// Scale multiplies and divides the group's total, in order, before it
+	// is added or subtracted, as in 2d6*100 or 4d6/2.
+	Scale []ScaleStep
+	// Label names a group added by an effect, such as bless.
+	Label string
+}
+
+// ScaleStep multiplies or divides a total by By, a positive whole number.
+// Division rounds down, or up with RoundUp, which is written /^ as in
+// 4d6/^2.
+type ScaleStep struct {
+	Op      string `json:"op"`
+	By      int    `json:"by"`
+	RoundUp bool   `json:"round_up,omitempty"`
+}
+
+func (s ScaleStep) String() string {
+	if s.RoundUp {
+		return fmt.Sprintf("/^%d", s.By)
+	}
+	return s.Op + strconv.Itoa(s.By)
+}
+
+// Apply returns total multiplied or divided by s. Division rounds toward
+// negative infinity, or with RoundUp toward positive infinity, so a
+// negative total rounds the same way as a positive one.
+func (s ScaleStep) Apply(total int) int {
+	if s.Op == "*" {
+		return total * s.By
+	}
+	q, r := total/s.By, total%s.By
+	switch {
+	case r < 0 && !s.RoundUp:
+		q--
+	case r > 0 && s.RoundUp:
+		q++
+	}
+	return q
+}
+
+// scaleTotal applies every step of steps to total, in order.
+func scaleTotal(total int, steps []ScaleStep) int {
+	for _, s := range steps {
+		total = s.Apply(total)
+	}
+	return total
+}
+
+// stepsString writes steps as they are written after a group, as in *2/^3.
+func stepsString(steps []ScaleStep) string {
+	var b strings.Builder
+	for _, s := range steps {
+		b.WriteString(s.String())
+	}
+	return b.String()
+}
+
+var labeledTerm = regexp.MustCompile(`\d*d\{[^{}]*\}`)
+
+// isDiceTerm reports whether term rolls dice. Any term with a d in it bar a
+// trailing adv or dis does, so a malformed one such as d or 3dx is worded by
+// Parse, as it would be alone, rather than refused as a modifier.
+func isDiceTerm(term string) bool {
+	term = strings.TrimSuffix(strings.TrimSuffix(term, "adv"), "dis")
+	return strings.Contains(term, "d")
+}
+
+// ParseExpression parses a sum of dice groups and flat modifiers, such as
+// 2d6+1d8+3-1d4. Each group takes the notation Parse does, apart from bonuses
+// and success thresholds; an expression with a single positive group is
+// parsed by Parse as a whole, so thresholds still work there. Labeled dice
+// have no total, so they are always rolled alone. Flat modifiers must be
+// whole numbers, and both each and their sum within MaxModifier. Spaces
+// between terms are ignored, so FormatExpression's 1d20 + 5 reads as 1d20+5.
+//
+// Groups and sums in parentheses, nested as deep as need be, may be
+// multiplied or divided by whole numbers, as in 2d6*100, 2*(1d6+1d8) or
+// ((1d4+1)*2+1d6)/2, with multiplication and division before addition and
+// subtraction. Division rounds down, and /^ rounds up, as in 4d6/^2; the
+// rounding applies to a group's own total, before its sign, so -1d6/2
+// subtracts half a d6 rounded down.
+func ParseExpression(expr string) (*Expression, error) {
+	if !strings.Contains(expr, "{") {
+		expr = percentileDice.ReplaceAllStringFunc(strings.Join(strings.Fields(expr), ""), percentileToD100)
+	}
+	expr = strings.TrimPrefix(expr, "+")
+	if loc := labeledTerm.FindStringIndex(expr); loc != nil {
+		if strings.ContainsAny(expr[:loc[0]]+expr[loc[1]:], "+-*/()") {
+			return nil, fmt.Errorf("passed illegal die command: %s, labeled dice cannot be combined with other terms", expr)
+		}
+		d, err := Parse(expr)
+		if err != nil {
+			return nil, err
+		}
+		return &Expression{Groups: []Group{{Dice: d}}}, nil
+	}
+	terms := splitTerms(expr)
+	groups := 0
+	for _, t := range terms {
+		if isDiceTerm(t) {
+			groups++
+		}
+	}
+	if groups <= 1 && isDiceTerm(terms[0]) && !strings.HasPrefix(terms[0], "-") && !strings.ContainsAny(expr, "*/()") {
+		d, err := Parse(expr)
+		if err != nil {
+			return nil, err
+		}
+		return &Expression{Groups: []Group{{Dice: d}}}, nil
+	}
+	return parseArithmetic(expr)
+}
+
+// splitTerms splits expr before every sign that does not follow a
+// threshold operator, keeping the sign with its term.
+func splitTerms(expr string) []string {
+	var terms []string
+	start := 0
+	for i := 1; i < len(expr); i++ {
+		if (expr[i] == '+' || expr[i] == '-') && !strings.ContainsRune("<>=", rune(expr[i-1])) {
+			terms = append(terms, expr[start:i])
+			start = i
+		}
+	}
+	return append(terms, expr[start:])
+}
+
+// Dice returns the expression as a single Dice when it is one positive group
+// plus a bonus, neither scaled nor in parentheses.
+func (e *Expression) Dice() (*Dice, bool) {
+	if len(e.Groups) != 1 || e.Groups[0].Negative || e.Groups[0].Sub != nil || len(e.Groups[0].Scale) > 0 {
+		return nil, false
+	}
+	d := *e.Groups[0].Dice
+	d.Bonus += e.Bonus
+	return &d, true
+}
+
+// AddBlessBane returns e with a 1d4 added for bless and subtracted for
+// bane, each in its own labelled group. It refuses expressions without a
+// d20, so a damage roll is never blessed by mistake.
+func AddBlessBane(e *Expression, bless, bane bool) (*Expression, error) {
+	if !bless && !bane {
+		return e, nil
+	}
+	if !e.hasD20() {
+		return nil, fmt.Errorf("cannot apply bless or bane to %s, it has no d20", e)
+	}
+
+	if e.countsSuccesses() {
+		return nil, fmt.Errorf("cannot apply bless or bane to %s, it counts successes", e)
+	}
+	out := e.flatten()
+	if bless {
+		out.Groups = append(out.Groups, Group{Dice: &Dice{Count: 1, Sides: 4}, Label: "bless"})
+	}
+	if bane {
+		out.Groups = append(out.Groups, Group{Dice: &Dice{Count: 1, Sides: 4}, Negative: true, Label: "bane"})
+	}
+	return out, nil
+}
+
+// countsSuccesses reports whether any group of e counts successes.
+func (e *Expression) countsSuccesses() bool {
+	return slices.ContainsFunc(e.AllDice(), func(d *Dice) bool { return d.Success != nil })
+}
+
+// AllDice returns the Dice of every group of e in order, those of groups in
+// parentheses included.
+func (e *Expression) AllDice() []*Dice {
+	var all []*Dice
+	for _, g := range e.Groups {
+		if g.Sub != nil {
+			all = append(all, g.Sub.AllDice()...)
+		} else {
+			all = append(all, g.Dice)
+		}
+	}
+	return all
+}
+
+// flatten returns a copy of e with the bonuses of its groups moved into
+// the expression's own, so that more groups can be added to it. Scaled
+// groups and those in parentheses keep theirs, which scale with them.
+func (e *Expression) flatten() *Expression {
+	out := &Expression{Bonus: e.Bonus}
+	for _, g := range e.Groups {
+		if g.Sub != nil || len(g.Scale) > 0 {
+			out.Groups = append(out.Groups, g)
+			continue
+		}
+		d := *g.Dice
+		out.Bonus += d.Bonus
+		d.Bonus = 0
+		g.Dice = &d
+		out.Groups = append(out.Groups, g)
+	}
+	return out
+}
+
+func (e *Expression) String() string {
+	var b strings.Builder
+	for i, g := range e.Groups {
+		switch {
+		case g.Negative:
+			b.WriteString("-")
+		case i > 0:
+			b.WriteString("+")
+		}
+		b.WriteString(g.String())
+	}
+	if e.Bonus != 0 {
+		fmt.Fprintf(&b, "%+d", e.Bonus)
+	}
+	return b.String()
+}
+
+// String writes g without its sign, as in 2d6, (2d6+3)*2 or 4d6/2.
+func (g Group) String() string {
+	if g.Sub != nil {
+		return "(" + g.Sub.String() + ")" + stepsString(g.Scale)
+	}
+	return g.Dice.String() + stepsString(g.Scale)
+}
+
+// span returns the lowest and highest totals of g before its sign. Scaling
+// by positive numbers keeps the order of totals, so the bounds scale too.
+func (g Group) span() (lo, hi int) {
+	if g.Sub != nil {
+		lo, hi = g.Sub.Min(), g.Sub.Max()
+	} else {
+		lo, hi = g.Dice.Min(), g.Dice.Max()
+	}
+	return scaleTotal(lo, g.Scale), scaleTotal(hi, g.Scale)
+}
+
+// average returns the expected total of g before its sign. Rounding makes
+// a division's average depend on every total, so it comes from the
+// distribution; multiplying alone scales the average.
+func (g Group) average() (float64, error) {
+	if slices.ContainsFunc(g.Scale, func(s ScaleStep) bool { return s.Op == "/" }) {
+		dist, err := g.distribution()
+		if err != nil {
+			return 0, err
+		}
+		avg := 0.0
+		for v, p := range dist {
+			avg += float64(v) * p
+		}
+		return avg, nil
+	}
+	var (
+		avg float64
+		err error
+	)
+	if g.Sub != nil {
+		avg, err = g.Sub.Average()
+	} else {
+		avg, err = g.Dice.Average()
+	}
+	for _, s := range g.Scale {
+		avg *= float64(s.By)
+	}
+	return avg, err
+}
+
+// distribution returns the probability of each total of g before its sign.
+func (g Group) distribution() (map[int]float64, error) {
+	var (
+		dist map[int]float64
+		err  error
+	)
+	if g.Sub != nil {
+		dist, err = g.Sub.Distribution()
+	} else {
+		dist, err = g.Dice.Distribution()
+	}
+	if err != nil || len(g.Scale) == 0 {
+		return dist, err
+	}
+	scaled := make(map[int]float64, len(dist))
+	for v, p := range dist {
+		scaled[scaleTotal(v, g.Scale)] += p
+	}
+	return scaled, nil
+}
+
+// Min returns the lowest total the expression can roll.
+func (e *Expression) Min() int {
+	total := e.Bonus
+	for _, g := range e.Groups {
+		lo, hi := g.span()
+		if g.Negative {
+			total -= hi
+		} else {
+			total += lo
+		}
+	}
+	return total
+}
+
+// Max returns the highest total the expression can roll.
+func (e *Expression) Max() int {
+	total := e.Bonus
+	for _, g := range e.Groups {
+		lo, hi := g.span()
+		if g.Negative {
+			total -= lo
+		} else {
+			total += hi
+		}
+	}
+	return total
+}
+
+// Average returns the expected total.
+func (e *Expression) Average() (float64, error) {
+	avg := float64(e.Bonus)
+	for _, g := range e.Groups {
+		a, err := g.average()
+		if err != nil {
+			return 0, err
+		}
+		if g.Negative {
+			a = -a
+		}
+		avg += a
+	}
+	return avg, nil
+}
+
+// Distribution returns the exact probability of rolling each total.
+func (e *Expression) Distribution() (map[int]float64, error) {
+	dist := map[int]float64{e.Bonus: 1}
+	for _, g := range e.Groups {
+		gd, err := g.distribution()
+		if err != nil {
+			return nil, err
+		}
+		next := make(map[int]float64, len(dist)*len(gd))
+		for total, p := range dist {
+			for v, q := range gd {
+				if g.Negative {
+					v = -v
+				}
+				next[total+v] += p * q
+			}
+		}
+		dist = next
+	}
+	return dist, nil
+}
+
+// RollExpression rolls e with the default roller.
+func RollExpression(e *Expression) *Result {
+	return defaultRoller.RollExpression(e)
+}
+
+// RollExpression rolls e. An expression that is a single Dice rolls exactly
+// as Roll does; otherwise the result keeps each group's own result, in
+// order, in Groups, with Rolls holding every die of every group.
+func (r *Roller) RollExpression(e *Expression) *Result {
+	res, _ := r.RollExpressionContext(context.Background(), e)
+	return res
+}
+
+// RollExpressionContext is like RollExpression but stops early with ctx's
+// error if ctx is done before every die has been rolled.
+func (r *Roller) RollExpressionContext(ctx context.Context, e *Expression) (*Result, error) {
+	return r.rollExpression(ctx, e, nil)
+}
+
+// RollExpressionWithObserver rolls e with the default roller, calling onDie
+// as each die lands.
+func RollExpressionWithObserver(e *Expression, onDie func(i, value int)) *Result {
+	return defaultRoller.RollExpressionWithObserver(e, onDie)
+}
+
+// RollExpressionWithObserver rolls e as RollWithObserver rolls Dice, calling
+// onDie with the index in the result's Rolls and the value of every die as
+// it lands, dice of subtracted groups included.
+func (r *Roller) RollExpressionWithObserver(e *Expression, onDie func(i, value int)) *Result {
+	res, _ := r.rollExpression(context.Background(), e, onDie)
+	return res
+}
+
+func (r *Roller) rollExpression(ctx context.Context, e *Expression, onDie func(i, value int)) (*Result, error) {
+	if d, ok := e.Dice(); ok {
+		return r.roll(ctx, d, onDie)
+	}
+	res, err := r.rollGroups(ctx, e, r.nudge, onDie)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range r.observers {
+		o.Observe(res)
+	}
+	return res, nil
+}
+
+// rollGroups rolls every group of e and sums them, leaving the roller's
+// observers to the caller so that a group in parentheses is only observed
+// as part of the whole. A scaled group's Total is scaled, with its sum
+// kept in Unscaled. Each group is nudged by sigma, the other way for one
+// that is subtracted, so the whole total moves the way the nudge says.
+func (r *Roller) rollGroups(ctx context.Context, e *Expression, sigma float64, onDie func(i, value int)) (*Result, error) {
+	groups := make([]*Result, len(e.Groups))
+	start := 0
+	for i, g := range e.Groups {
+		var onGroupDie func(i, value int)
+		if onDie != nil {
+			offset := start
+			onGroupDie = func(i, value int) { onDie(offset+i, value) }
+		}
+		var (
+			gr  *Result
+			err error
+		)
+		groupSigma := sigma
+		if g.Negative {
+			groupSigma = -sigma
+		}
+		if g.Sub != nil {
+			gr, err = r.rollGroups(ctx, g.Sub, groupSigma, onGroupDie)
+		} else if gr, err = r.rollContext(ctx, g.Dice, onGroupDie); err == nil {
+			r.applyFloor(gr, g.Dice)
+			r.applyPercentile(gr, g.Dice)
+			if groupSigma != 0 && len(g.Dice.Labels) == 0 {
+				applyNudge(gr, g.Dice, groupSigma)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		start += len(gr.Rolls)
+		if len(g.Scale) > 0 {
+			gr.Scale, gr.Unscaled = g.Scale, gr.Total
+			gr.Total = scaleTotal(gr.Total, g.Scale)
+		}
+		groups[i] = gr
+	}
+	res := e.combine(groups)
+	if sigma != 0 {
+		res.Nudge = &Nudge{Sigma: sigma, Unbiased: e.unbiasedTotal(groups)}
+	}
+	return res, nil
+}
+
+// unbiasedTotal sums groups, the results of e's groups, as they rolled
+// before any nudge.
+func (e *Expression) unbiasedTotal(groups []*Result) int {
+	total := e.Bonus
+	for i, gr := range groups {
+		v := gr.Total
+		if gr.Nudge != nil {
+			v = scaleTotal(gr.Nudge.Unbiased, e.Groups[i].Scale)
+		}
+		if e.Groups[i].Negative {
+			total -= v
+		} else {
+			total += v
+		}
+	}
+	return total
+}
+
+// combine sums the results of e's groups, in order, into one result.
+func (e *Expression) combine(groups []*Result) *Result {
+	res := &Result{Expression: e.String(), Bonus: e.Bonus, Total: e.Bonus}
+	for i, gr := range groups {
+		g := e.Groups[i]
+		gr.Negative, gr.Label = g.Negative, g.Label
+		if g.Negative {
+			res.Total -= gr.Total
+		} else {
+			res.Total += gr.Total
+		}
+		res.Rolls = append(res.Rolls, gr.Rolls...)
+		res.Groups = append(res.Groups, gr)
+	}
+	return res
+}
+
+// groupsString writes each group's dice, with a minus sign before the
+// negative ones, as in "1d20-1d4+5: Rolled: [14] - [3] +5 = 16". A scaled
+// group or one in parentheses is bracketed with its own total, as in
+// "(2d6+3)*2: Rolled: ([4 5] +3 = 12)*2 = 24".
+func (r *Result) groupsString() string {
+	return fmt.Sprintf("%s: Rolled:%s = %d", r.Expression, r.groupsBody(), r.Total)
+}
+
+// groupsBody writes the groups and bonus of r for groupsString, each term
+// after a space.
+func (r *Result) groupsBody() string {
+	var b strings.Builder
+	for i, g := range r.Groups {
+		switch {
+		case g.Negative && i == 0:
+			b.WriteString(" -")
+		case g.Negative:
+			b.WriteString(" - ")
+		case i > 0:
+			b.WriteString(" + ")
+		default:
+			b.WriteString(" ")
+		}
+		if g.Label != "" {
+			b.WriteString(g.Label + " ")
+		}
+		if len(g.Groups) == 0 && len(g.Scale) == 0 {
+			b.WriteString(g.diceString())
+			continue
+		}
+		inner, total := strings.TrimPrefix(g.groupsBody(), " "), g.Total
+		if len(g.Groups) == 0 {
+			inner = g.diceString()
+		}
+		if len(g.Scale) > 0 {
+			total = g.Unscaled
+		}
+		fmt.Fprintf(&b, "(%s = %d)%s", inner, total, stepsString(g.Scale))
+	}
+	if r.Bonus != 0 {
+		fmt.Fprintf(&b, " %+d", r.Bonus)
+	}
+	return b.String()
+}
+
+// diceString writes the dice of a group's result for groupsBody.
+func (r *Result) diceString() string {
+	if r.Summarized {
+		return fmt.Sprintf("[%s]", r.summaryString())
+	}
+	var b strings.Builder
+	b.WriteString(r.rolledList())
+	if len(r.Dropped) > 0 {
+		fmt.Fprintf(&b, " Dropped: %s", diceList(r.Dropped))
+	}
+	for _, sub := range r.Substituted {
+		fmt.Fprintf(&b, " Substituted: %d→%d", sub.Original, sub.Value)
+	}
+	return b.String()
+}
@@ -0,0 +1,136 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FormatExpression returns e in canonical form: every group as Dice.String
+// writes it, dropped dice as kept ones and advantage as the pool it rolls,
+// with a space either side of each sign between terms, as in
+// 2d20kh1 + 1d4 - 2. ParseExpression reads it back as the same expression.
+// A success pool keeps its bonus beside its dice, as in 8d6+1>=5, since the
+// bonus adds successes rather than to a sum. Groups in parentheses are
+// formatted the same way inside them, and multiplication and division are
+// written without spaces, as in (2d6 + 3)*2.
+func FormatExpression(e *Expression) string {
+	var b strings.Builder
+	for i, g := range e.Groups {
+		switch {
+		case g.Negative && i == 0:
+			b.WriteString("-")
+		case g.Negative:
+			b.WriteString(" - ")
+		case i > 0:
+			b.WriteString(" + ")
+		}
+		if g.Sub != nil {
+			b.WriteString("(" + FormatExpression(g.Sub) + ")" + stepsString(g.Scale))
+			continue
+		}
+		d := *g.Dice
+		bonus := 0
+		if d.Success == nil && len(g.Scale) == 0 {
+			bonus, d.Bonus = d.Bonus, 0
+		}
+		b.WriteString(d.String() + stepsString(g.Scale))
+		writeTerm(&b, bonus)
+	}
+	writeTerm(&b, e.Bonus)
+	return b.String()
+}
+
+// writeTerm writes a flat modifier after other terms, as " + 3" or " - 3".
+func writeTerm(b *strings.Builder, n int) {
+	switch {
+	case n > 0:
+		fmt.Fprintf(b, " + %d", n)
+	case n < 0:
+		fmt.Fprintf(b, " - %d", -n)
+	}
+}
+
+// ANSI colors HighlightExpression gives each kind of term.
+const (
+	colorDice      = "\x1b[36m"
+	colorModifier  = "\x1b[33m"
+	colorThreshold = "\x1b[35m"
+	colorKeyword   = "\x1b[1m"
+	colorReset     = "\x1b[0m"
+)
+
+// expressionToken matches the terms HighlightExpression colors: dice with
+// their explosions, rerolls and keep or drop modifiers, thresholds with any
+// failure count, the adv and dis keywords, and flat modifiers.
+var expressionToken = regexp.MustCompile(`(\d*d(?:\{[^{}]*\}|\d+|%|F)(?:!!|!p|!|r[ro]?(?:>=|<=|>|<|=)?\d+|(?:min|max)\d+|k[hlm]?\d+|d[hlm]\d+)*)|((?:>=|<=|>|<|=)\d+(?:f(?:>=|<=|>|<|=)?\d+)?)|(adv|dis)|(\d+)`)
+
+// HighlightExpression returns expr with ANSI colors for a terminal: dice in
+// cyan, flat modifiers in yellow, thresholds in magenta and adv and dis in
+// bold. Everything else, signs and spaces included, is left as it was, so
+// stripping the colors gives back expr.
+func HighlightExpression(expr string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range expressionToken.FindAllStringSubmatchIndex(expr, -1) {
+		b.WriteString(expr[last:m[0]])
+		color := colorModifier
+		switch {
+		case m[2] >= 0:
+			color = colorDice
+		case m[4] >= 0:
+			color = colorThreshold
+		case m[6] >= 0:
+			color = colorKeyword
+		}
+		b.WriteString(color + expr[m[0]:m[1]] + colorReset)
+		last = m[1]
+	}
+	b.WriteString(expr[last:])
+	return b.String()
+}
+
+// highlightPrefix colors expr at the start of line, as roll echoes each
+// expression before its result. Lines that do not start with expr are
+// returned as they are.
+func highlightPrefix(line, expr string) string {
+	if expr == "" || !strings.HasPrefix(line, expr) {
+		return line
+	}
+	return HighlightExpression(expr) + line[len(expr):]
+}
+
+func fmtGen(args []string) error {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	dialectName := dialectFlag(fs)
+	colorMode := colorFlag(fs)
+	exprs, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(exprs) == 0 {
+		return fmt.Errorf("need to provide an expression to format")
+	}
+	dialect, err := ParseDialectName(*dialectName)
+	if err != nil {
+		return err
+	}
+	color, err := useColor(*colorMode)
+	if err != nil {
+		return err
+	}
+
+	for _, expr := range exprs {
+		e, err := ParseDialect(expr, dialect)
+		if err != nil {
+			return err
+		}
+		s := FormatExpression(e)
+		if color {
+			s = HighlightExpression(s)
+		}
+		fmt.Println(s)
+	}
+	return nil
+}
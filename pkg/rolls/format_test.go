@@ -0,0 +1,33 @@
+package rolls
+
+import "testing"
+
+// TestFormatRoundTrip checks FormatExpression is lossless over the notation
+// the parse tests read: parsing the canonical form gives back the same
+// expression, and formatting it again changes nothing.
+func TestFormatRoundTrip(t *testing.T) {
+	var corpus []string
+	for _, tt := range parseBareDiceTests {
+		corpus = append(corpus, tt.expr)
+	}
+	for _, tt := range parseBonusTests {
+		corpus = append(corpus, tt.expr)
+	}
+	for _, expr := range corpus {
+		e, err := ParseExpression(expr)
+		if err != nil {
+			t.Errorf("ParseExpression(%q): %v", expr, err)
+			continue
+		}
+		formatted := FormatExpression(e)
+		again, err := ParseExpression(formatted)
+		switch {
+		case err != nil:
+			t.Errorf("%q formats as %q, which does not parse: %v", expr, formatted, err)
+		case again.String() != e.String():
+			t.Errorf("%q formats as %q, which parses as %s, want %s", expr, formatted, again, e)
+		case FormatExpression(again) != formatted:
+			t.Errorf("%q formats as %q, and then as %q", expr, formatted, FormatExpression(again))
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package rolls
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Domo929/roll/pkg/rolls/migrate"
+)
+
+// HistorySchema is the schema version written with every history entry.
+const HistorySchema = migrate.Current
+
+// HistoryEntry is a single line of the history log.
+type HistoryEntry struct {
+	Schema int       `json:"schema"`
+	Time   time.Time `json:"time"`
+	Result *Result   `json:"result"`
+	// Commitment is set for secret rolls, which can be revealed later.
+	Commitment *Commitment `json:"commitment,omitempty"`
+	// ID is set for rolls given an ID to refer back to them.
+	ID string `json:"id,omitempty"`
+	// Ref is a short hash of the entry's time and result that names any
+	// roll in the log. Entries written before refs were recorded have it
+	// computed as they are read.
+	Ref string `json:"ref,omitempty"`
+	// Correction is set, in place of Result, for a line that voids or
+	// annotates an earlier roll; the log is only ever appended to.
+	Correction *Correction `json:"correction,omitempty"`
+	// Chain hashes this entry together with the Chain of the entry before
+	// it, so editing or removing a past line breaks every later link.
+	Chain string `json:"chain,omitempty"`
+
+	// Voided and Notes gather the corrections made to the roll, as read
+	// back by ReadHistory.
+	Voided bool     `json:"-"`
+	Notes  []string `json:"-"`
+}
+
+// Correction actions.
+const (
+	CorrectionVoid     = "void"
+	CorrectionAnnotate = "annotate"
+)
+
+// Correction voids or annotates the roll whose Ref is Target.
+type Correction struct {
+	Target string `json:"target"`
+	Action string `json:"action"`
+	Note   string `json:"note,omitempty"`
+}
+
+// entryRef returns the Ref of a roll logged at t.
+func entryRef(t time.Time, res *Result) string {
+	b, _ := json.Marshal(res)
+	sum := sha256.Sum256(append([]byte(t.Format(time.RFC3339Nano)), b...))
+	return hex.EncodeToString(sum[:4])
+}
+
+// chainLink returns the Chain of e following the entry whose Chain is prev.
+func chainLink(prev string, e HistoryEntry) string {
+	e.Chain = ""
+	b, _ := json.Marshal(e)
+	sum := sha256.Sum256(append([]byte(prev+"\n"), b...))
+	return hex.EncodeToString(sum[:8])
+}
+
+// link sets the Ref and Chain of entries, to be appended after an entry
+// whose Chain is prev.
+func link(prev string, entries []HistoryEntry) {
+	for i := range entries {
+		if entries[i].Result != nil {
+			entries[i].Ref = entryRef(entries[i].Time, entries[i].Result)
+		}
+		entries[i].Chain = chainLink(prev, entries[i])
+		prev = entries[i].Chain
+	}
+}
+
+// FindEntry returns the roll among entries named by id, which is either
+// the ID it was logged under or a prefix of at least four characters of
+// its Ref. The latest roll logged under an ID wins, while a Ref prefix
+// must name a single roll.
+func FindEntry(entries []HistoryEntry, id string) (*HistoryEntry, error) {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].ID == id {
+			return &entries[i], nil
+		}
+	}
+	if len(id) < 4 {
+		return nil, fmt.Errorf("no roll with id %s in the history", id)
+	}
+	var found *HistoryEntry
+	for i := range entries {
+		if strings.HasPrefix(entries[i].Ref, id) {
+			if found != nil {
+				return nil, fmt.Errorf("%s names more than one roll in the history, give more of its ref", id)
+			}
+			found = &entries[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no roll with id %s in the history", id)
+	}
+	return found, nil
+}
+
+// AppendHistory appends results to the history log.
+func AppendHistory(results ...*Result) error {
+	now := time.Now()
+	entries := make([]HistoryEntry, len(results))
+	for i, res := range results {
+		entries[i] = HistoryEntry{Schema: HistorySchema, Time: now, Result: res}
+	}
+	return appendHistory(entries)
+}
+
+// AppendCommitted appends a secret result and its commitment to the history
+// log.
+func AppendCommitted(res *Result, c *Commitment) error {
+	return appendHistory([]HistoryEntry{{Schema: HistorySchema, Time: time.Now(), Result: res, Commitment: c}})
+}
+
+// AppendWithID appends res to the history log under id, so it can be found
+// again later, as roll reroll does.
+func AppendWithID(id string, res *Result) error {
+	return appendHistory([]HistoryEntry{{Schema: HistorySchema, Time: time.Now(), Result: res, ID: id}})
+}
+
+// AppendCorrection appends a correction to the history log. The roll it
+// names is left as it was logged.
+func AppendCorrection(c *Correction) error {
+	return appendHistory([]HistoryEntry{{Schema: HistorySchema, Time: time.Now(), Correction: c}})
+}
+
+// appendHistory appends entries to the history log in NamespaceHistory of
+// the current Storage, chained on from its last line.
+func appendHistory(entries []HistoryEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return logStorage(currentStorage()).AppendLog(NamespaceHistory, func(last []byte) ([][]byte, error) {
+		var prev struct {
+			Chain string `json:"chain"`
+		}
+		json.Unmarshal(last, &prev)
+		link(prev.Chain, entries)
+		lines := make([][]byte, len(entries))
+		for i, e := range entries {
+			line, err := json.Marshal(e)
+			if err != nil {
+				return nil, err
+			}
+			lines[i] = line
+		}
+		return lines, nil
+	})
+}
+
+// LoadHistory reads the history log of the current Storage. A log never
+// written to is empty.
+func LoadHistory() ([]HistoryEntry, int, error) {
+	r, err := logStorage(currentStorage()).ReadLog(NamespaceHistory)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer r.Close()
+
+	return ReadHistory(r)
+}
+
+// VerifyHistory checks the hash chain of the history log.
+func VerifyHistory() (*ChainReport, error) {
+	if off, err := historyOff(); err != nil {
+		return nil, err
+	} else if off {
+		return nil, errHistoryOff
+	}
+	r, err := logStorage(currentStorage()).ReadLog(NamespaceHistory)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return VerifyChain(r)
+}
+
+// MigrateHistory upgrades every entry of the history log to the current
+// schema in place. The old log is kept as a backup, and where it is kept
+// returned: beside the log file for the default Storage.
+func MigrateHistory() (*MigrateReport, string, error) {
+	if off, err := historyOff(); err != nil {
+		return nil, "", err
+	} else if off {
+		return nil, "", errHistoryOff
+	}
+	l := logStorage(currentStorage())
+	r, err := l.ReadLog(NamespaceHistory)
+	if err != nil {
+		return nil, "", err
+	}
+	old, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var report *MigrateReport
+	backup, err := l.ReplaceLog(NamespaceHistory, func(w io.Writer) error {
+		report, err = MigrateHistoryLog(bytes.NewReader(old), w)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return report, backup, nil
+}
+
+// errHistoryOff is returned for the history log when ROLL_HISTORY is off.
+var errHistoryOff = errors.New("the history log is off")
+
+func logHistory(results ...*Result) {
+	if err := AppendHistory(results...); err != nil {
+		log.Println("could not write history:", err)
+	}
+}
+
+// ReadHistory reads history entries from r and returns them along with the
+// number of lines it had to skip. Entries from older schema versions are
+// upgraded as they are read; lines that are not JSON, hold no result or
+// come from a newer schema are skipped. Corrections are not returned as
+// entries but applied to the rolls they name, setting Voided and Notes.
+func ReadHistory(r io.Reader) ([]HistoryEntry, int, error) {
+	var (
+		entries     []HistoryEntry
+		corrections []Correction
+		skipped     int
+	)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		e, _, err := upgradeEntry(sc.Bytes())
+		if err != nil {
+			skipped++
+			continue
+		}
+		if e.Correction != nil {
+			corrections = append(corrections, *e.Correction)
+			continue
+		}
+		if e.Ref == "" {
+			e.Ref = entryRef(e.Time, e.Result)
+		}
+		entries = append(entries, e)
+	}
+
+	byRef := make(map[string]*HistoryEntry, len(entries))
+	for i := range entries {
+		byRef[entries[i].Ref] = &entries[i]
+	}
+	for _, c := range corrections {
+		e, ok := byRef[c.Target]
+		if !ok {
+			continue
+		}
+		switch c.Action {
+		case CorrectionVoid:
+			e.Voided = true
+			if c.Note != "" {
+				e.Notes = append(e.Notes, c.Note)
+			}
+		case CorrectionAnnotate:
+			e.Notes = append(e.Notes, c.Note)
+		}
+	}
+	return entries, skipped, sc.Err()
+}
+
+// upgradeEntry decodes a history line of any schema, returning the schema
+// it was written with.
+func upgradeEntry(line []byte) (HistoryEntry, int, error) {
+	var e HistoryEntry
+	raw, version, err := migrate.Entry(line)
+	if err != nil {
+		return e, version, err
+	}
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return e, version, err
+	}
+	if e.Result == nil && e.Correction == nil {
+		return e, version, fmt.Errorf("history entry holds no result")
+	}
+	return e, version, nil
+}
+
+// MigrateReport counts the lines MigrateHistoryLog rewrote.
+type MigrateReport struct {
+	Entries  int
+	Upgraded int
+	// Unreadable lines are copied through unchanged.
+	Unreadable int
+}
+
+// MigrateHistoryLog copies the history log in r to w with every entry
+// upgraded to the current schema.
+func MigrateHistoryLog(r io.Reader, w io.Writer) (*MigrateReport, error) {
+	report := &MigrateReport{}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	enc := json.NewEncoder(w)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		e, version, err := upgradeEntry(sc.Bytes())
+		if err != nil {
+			report.Unreadable++
+			if _, err := fmt.Fprintf(w, "%s\n", sc.Bytes()); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		report.Entries++
+		if version != HistorySchema {
+			report.Upgraded++
+		}
+		if err := enc.Encode(e); err != nil {
+			return nil, err
+		}
+	}
+	return report, sc.Err()
+}
+
+// ChainReport describes the hash chain of a history log, as VerifyChain
+// found it.
+type ChainReport struct {
+	Entries int
+	// Unchained counts entries written before the log was chained.
+	Unchained int
+	// Broken lists the line numbers, counting from 1, whose Chain does not
+	// follow from the line before: each was edited, or lines before it
+	// were removed or edited.
+	Broken []int
+}
+
+// VerifyChain reads the history log in r and checks every link of its hash
+// chain.
+func VerifyChain(r io.Reader) (*ChainReport, error) {
+	report := &ChainReport{}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	prev, line := "", 0
+	for sc.Scan() {
+		line++
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		e, _, err := upgradeEntry(sc.Bytes())
+		if err != nil {
+			continue
+		}
+		report.Entries++
+		if e.Chain == "" {
+			report.Unchained++
+			if prev != "" {
+				report.Broken = append(report.Broken, line)
+			}
+			continue
+		}
+		if chainLink(prev, e) != e.Chain {
+			report.Broken = append(report.Broken, line)
+		}
+		prev = e.Chain
+	}
+	return report, sc.Err()
+}
@@ -0,0 +1,423 @@
+//go:build !js
+
+package rolls
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HistoryPath returns the location of the history log FileStorage keeps:
+// $ROLL_HISTORY if set, otherwise roll/history.jsonl under the user config
+// directory. Setting ROLL_HISTORY to "off" disables the log and returns an
+// empty path.
+func HistoryPath() (string, error) {
+	if p := os.Getenv("ROLL_HISTORY"); p != "" {
+		if p == "off" {
+			return "", nil
+		}
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "roll", "history.jsonl"), nil
+}
+
+// historyOff reports whether ROLL_HISTORY turned off the history log of the
+// default Storage. Other Storages always keep it.
+func historyOff() (bool, error) {
+	if _, ok := currentStorage().(FileStorage); !ok {
+		return false, nil
+	}
+	path, err := HistoryPath()
+	return path == "" && err == nil, err
+}
+
+// LoadTable reads a CSV table from the file at path.
+func LoadTable(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadTable(path, f)
+}
+
+// LoadBatchFile reads a TOML batch file from the file at path.
+func LoadBatchFile(path string) (*BatchFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadBatchFile(path, f)
+}
+
+// LoadBatchReport reads the batch report SaveBatchReport saved at path.
+func LoadBatchReport(path string) (*BatchReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadBatchReport(f)
+}
+
+// SaveBatchReport replaces the batch report saved at path.
+func SaveBatchReport(path string, report *BatchReport) error {
+	return saveState(path, func(w io.Writer) error {
+		return WriteBatchReport(w, report)
+	})
+}
+
+// LoadRoutine reads a JSON attack routine from the file at path.
+func LoadRoutine(path string) (*Routine, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadRoutine(f)
+}
+
+// LoadSheet reads a JSON character sheet from the file at path.
+func LoadSheet(path string) (*Sheet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadSheet(path, f)
+}
+
+// LoadMacroFile reads the macros in the TOML or JSON file at path, as
+// ReadMacroFile does. A missing file holds no macros.
+func LoadMacroFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return make(map[string]string), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadMacroFile(path, f)
+}
+
+// LoadGolden reads the results SaveGolden saved at path.
+func LoadGolden(path string) (map[string]*Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadGolden(f)
+}
+
+// SaveGolden replaces the results saved at path.
+func SaveGolden(path string, golden map[string]*Result) error {
+	return saveState(path, func(w io.Writer) error {
+		return WriteGolden(w, golden)
+	})
+}
+
+// ConfigPath returns the location of the config file: $ROLL_CONFIG if set,
+// otherwise roll/config.json under the user config directory.
+func ConfigPath() (string, error) {
+	return statePath("ROLL_CONFIG", "config.json")
+}
+
+// LoadConfig reads the config file at ConfigPath. A missing file is an empty
+// config.
+func LoadConfig() (*Config, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadConfig(f)
+}
+
+// FileStorage is the default Storage. Each namespace is a JSON file holding
+// an object of its keys, named after it in Dir, such as decks.json. An
+// empty Dir is roll under the user config directory, where $ROLL_DECKS and
+// the like name another file for their namespace. Logs are files of JSON
+// lines, such as history.jsonl; the history log is at HistoryPath unless
+// Dir is set.
+type FileStorage struct {
+	Dir string
+}
+
+// fileStorageMu serializes reading and rewriting namespace files.
+var fileStorageMu sync.Mutex
+
+func defaultStorage() Storage {
+	return FileStorage{}
+}
+
+func (s FileStorage) Get(namespace, key string) ([]byte, error) {
+	fileStorageMu.Lock()
+	defer fileStorageMu.Unlock()
+	obj, err := s.read(namespace)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := obj[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return value, nil
+}
+
+func (s FileStorage) Put(namespace, key string, value []byte) error {
+	if !json.Valid(value) {
+		return fmt.Errorf("cannot save %s in %s, its value is not JSON", key, namespace)
+	}
+	fileStorageMu.Lock()
+	defer fileStorageMu.Unlock()
+	obj, err := s.read(namespace)
+	if err != nil {
+		return err
+	}
+	obj[key] = value
+	return s.write(namespace, obj)
+}
+
+func (s FileStorage) List(namespace string) ([]string, error) {
+	fileStorageMu.Lock()
+	defer fileStorageMu.Unlock()
+	obj, err := s.read(namespace)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s FileStorage) Delete(namespace, key string) error {
+	fileStorageMu.Lock()
+	defer fileStorageMu.Unlock()
+	obj, err := s.read(namespace)
+	if err != nil {
+		return err
+	}
+	if _, ok := obj[key]; !ok {
+		return nil
+	}
+	delete(obj, key)
+	return s.write(namespace, obj)
+}
+
+func (s FileStorage) path(namespace string) (string, error) {
+	if s.Dir != "" {
+		return filepath.Join(s.Dir, namespace+".json"), nil
+	}
+	return statePath("ROLL_"+strings.ToUpper(namespace), namespace+".json")
+}
+
+// read returns the keys of namespace's file. A missing file has none.
+func (s FileStorage) read(namespace string) (map[string]json.RawMessage, error) {
+	obj := make(map[string]json.RawMessage)
+	path, err := s.path(namespace)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return obj, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(&obj); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return obj, nil
+}
+
+func (s FileStorage) write(namespace string, obj map[string]json.RawMessage) error {
+	path, err := s.path(namespace)
+	if err != nil {
+		return err
+	}
+	return saveState(path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(obj)
+	})
+}
+
+// logPath returns the file of namespace's log, or "" if it is off.
+func (s FileStorage) logPath(namespace string) (string, error) {
+	switch {
+	case s.Dir != "":
+		return filepath.Join(s.Dir, namespace+".jsonl"), nil
+	case namespace == NamespaceHistory:
+		return HistoryPath()
+	}
+	return statePath("ROLL_"+strings.ToUpper(namespace), namespace+".jsonl")
+}
+
+// AppendLog appends to the log file in a single write, so lines appended by
+// other processes never interleave with them. A log that is off takes
+// nothing.
+func (s FileStorage) AppendLog(namespace string, next func(last []byte) ([][]byte, error)) error {
+	path, err := s.logPath(namespace)
+	if err != nil || path == "" {
+		return err
+	}
+	fileStorageMu.Lock()
+	defer fileStorageMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	last, err := lastLine(f)
+	if err != nil {
+		return err
+	}
+	lines, err := next(last)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	_, err = f.Write(buf.Bytes())
+	return err
+}
+
+// ReadLog opens the log file. A missing log, or one that is off, is empty.
+func (s FileStorage) ReadLog(namespace string) (io.ReadCloser, error) {
+	path, err := s.logPath(namespace)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if path == "" || os.IsNotExist(err) {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	return f, err
+}
+
+// ReplaceLog keeps the old log file beside it, named after it and the time,
+// such as history.jsonl.20240301T190211.bak, and returns its path.
+func (s FileStorage) ReplaceLog(namespace string, write func(io.Writer) error) (string, error) {
+	path, err := s.logPath(namespace)
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "", fmt.Errorf("the %s log is off", namespace)
+	}
+	fileStorageMu.Lock()
+	defer fileStorageMu.Unlock()
+	old, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	backup := fmt.Sprintf("%s.%s.bak", path, time.Now().Format("20060102T150405"))
+	if err := os.WriteFile(backup, old, 0o644); err != nil {
+		return "", err
+	}
+	return backup, saveState(path, write)
+}
+
+// lastLine returns the last line of the log f, or nil if it has none. Lines
+// are at most as long as ReadHistory reads.
+func lastLine(f *os.File) ([]byte, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	const tail = 1024 * 1024
+	off := max(fi.Size()-tail, 0)
+	buf := make([]byte, fi.Size()-off)
+	if _, err := f.ReadAt(buf, off); err != nil && err != io.EOF {
+		return nil, err
+	}
+	lines := bytes.Split(bytes.TrimRight(buf, "\n"), []byte("\n"))
+	return lines[len(lines)-1], nil
+}
+
+// statePath returns $env if set, otherwise name under roll in the user
+// config directory.
+func statePath(env, name string) (string, error) {
+	if p := os.Getenv(env); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "roll", name), nil
+}
+
+// saveState replaces the file at path with what write writes. It writes a
+// temporary file in the same directory and renames it over path, so a
+// crash leaves either the old file or the new one, never half of either.
+func saveState(path string, write func(io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	err = write(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
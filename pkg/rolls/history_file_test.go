@@ -0,0 +1,100 @@
+//go:build !js
+
+package rolls
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStorageHistory(t *testing.T) {
+	dir := t.TempDir()
+	SetStorage(FileStorage{Dir: dir})
+	defer SetStorage(nil)
+
+	r := NewRoller(WithSeed(1))
+	for i := 0; i < 3; i++ {
+		if err := AppendHistory(r.Roll(&Dice{Count: 2, Sides: 6})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, "history.jsonl")
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(raw, []byte("\n")); n != 3 {
+		t.Errorf("history.jsonl has %d lines, want 3", n)
+	}
+	report, err := VerifyChain(bytes.NewReader(raw))
+	if err != nil || report.Entries != 3 || len(report.Broken) != 0 {
+		t.Errorf("VerifyChain = %+v, %v", report, err)
+	}
+
+	_, backup, err := MigrateHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old, err := os.ReadFile(backup); err != nil || !bytes.Equal(old, raw) || filepath.Dir(backup) != dir {
+		t.Errorf("MigrateHistory kept %s as the backup: %v", backup, err)
+	}
+}
+
+func TestHistoryOff(t *testing.T) {
+	t.Setenv("ROLL_HISTORY", "off")
+	SetStorage(nil)
+
+	if err := AppendHistory(NewRoller(WithSeed(1)).Roll(&Dice{Count: 1, Sides: 20})); err != nil {
+		t.Errorf("AppendHistory with the log off: %v", err)
+	}
+	if entries, _, err := LoadHistory(); err != nil || len(entries) != 0 {
+		t.Errorf("LoadHistory with the log off = %v, %v", entries, err)
+	}
+	if _, err := VerifyHistory(); !errors.Is(err, errHistoryOff) {
+		t.Errorf("VerifyHistory with the log off = %v", err)
+	}
+	if _, _, err := RollSecret("1d20"); err == nil {
+		t.Error("RollSecret with the log off did not fail")
+	}
+}
+
+func TestSaveState(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "decks.json")
+	write := func(s string) func(io.Writer) error {
+		return func(w io.Writer) error {
+			_, err := io.WriteString(w, s)
+			return err
+		}
+	}
+	if err := saveState(path, write("{}\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A write that fails halfway leaves the file as it was.
+	err := saveState(path, func(w io.Writer) error {
+		io.WriteString(w, `{"tarot": `)
+		return errors.New("disk full")
+	})
+	if err == nil {
+		t.Fatal("saveState hid the write's error")
+	}
+	if raw, _ := os.ReadFile(path); string(raw) != "{}\n" {
+		t.Errorf("a failed save left %q", raw)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 1 {
+		t.Errorf("saveState left %d files behind, want only decks.json", len(files))
+	}
+
+	if err := saveState(path, write(`{"tarot": []}`)); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(path)
+	if raw, _ := os.ReadFile(path); err != nil || string(raw) != `{"tarot": []}` || fi.Mode().Perm() != 0o644 {
+		t.Errorf("saveState wrote %q with mode %v, %v", raw, fi.Mode(), err)
+	}
+}
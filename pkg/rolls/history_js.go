@@ -0,0 +1,180 @@
+//go:build js
+
+package rolls
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"syscall/js"
+)
+
+// errNoFiles is returned by the file-backed features in js builds, which have
+// no file system to use.
+var errNoFiles = errors.New("files are not available in js builds")
+
+// HistoryPath returns an empty path: js builds keep the history log in the
+// Storage rather than a file.
+func HistoryPath() (string, error) {
+	return "", nil
+}
+
+// historyOff reports whether there is no Storage to keep the history log
+// in, as outside a browser.
+func historyOff() (bool, error) {
+	_, ok := currentStorage().(noStorage)
+	return ok, nil
+}
+
+// LoadTable is not supported in js builds; use ReadTable instead.
+func LoadTable(path string) (*Table, error) {
+	return nil, errNoFiles
+}
+
+// LoadBatchFile is not supported in js builds; use ReadBatchFile instead.
+func LoadBatchFile(path string) (*BatchFile, error) {
+	return nil, errNoFiles
+}
+
+// LoadBatchReport is not supported in js builds; use ReadBatchReport
+// instead.
+func LoadBatchReport(path string) (*BatchReport, error) {
+	return nil, errNoFiles
+}
+
+// SaveBatchReport is not supported in js builds; use WriteBatchReport
+// instead.
+func SaveBatchReport(path string, report *BatchReport) error {
+	return errNoFiles
+}
+
+// LoadRoutine is not supported in js builds; use ReadRoutine instead.
+func LoadRoutine(path string) (*Routine, error) {
+	return nil, errNoFiles
+}
+
+// LoadSheet is not supported in js builds; use ReadSheet instead.
+func LoadSheet(path string) (*Sheet, error) {
+	return nil, errNoFiles
+}
+
+// ConfigPath returns an empty path: js builds have no config file.
+func ConfigPath() (string, error) {
+	return "", nil
+}
+
+// LoadConfig returns an empty config: js builds have no config file.
+func LoadConfig() (*Config, error) {
+	return &Config{}, nil
+}
+
+// LoadMacroFile is not supported in js builds; use ReadMacroFile instead.
+func LoadMacroFile(path string) (map[string]string, error) {
+	return nil, errNoFiles
+}
+
+// LoadGolden is not supported in js builds; use ReadGolden instead.
+func LoadGolden(path string) (map[string]*Result, error) {
+	return nil, errNoFiles
+}
+
+// SaveGolden is not supported in js builds; use WriteGolden instead.
+func SaveGolden(path string, golden map[string]*Result) error {
+	return errNoFiles
+}
+
+// WebStorage is the default Storage of js builds run in a browser. Each key
+// is an item of Items, a Web Storage object such as window.localStorage,
+// named roll/<namespace>/<key>, so the items of other scripts on the page
+// are left alone.
+type WebStorage struct {
+	Items js.Value
+}
+
+// noStorage is the default Storage of js builds without localStorage, such
+// as under Node: it holds nothing and saves nothing.
+type noStorage struct{}
+
+func defaultStorage() Storage {
+	if items := js.Global().Get("localStorage"); items.Truthy() {
+		return WebStorage{Items: items}
+	}
+	return noStorage{}
+}
+
+func (s WebStorage) Get(namespace, key string) ([]byte, error) {
+	value := s.Items.Call("getItem", webStorageKey(namespace, key))
+	if value.Type() != js.TypeString {
+		return nil, ErrNotFound
+	}
+	return []byte(value.String()), nil
+}
+
+func (s WebStorage) Put(namespace, key string, value []byte) (err error) {
+	// setItem throws once the page's quota is used up.
+	defer func() {
+		if r := recover(); r != nil {
+			jsErr, ok := r.(js.Error)
+			if !ok {
+				panic(r)
+			}
+			err = fmt.Errorf("cannot save %s in %s: %v", key, namespace, jsErr)
+		}
+	}()
+	s.Items.Call("setItem", webStorageKey(namespace, key), string(value))
+	return nil
+}
+
+func (s WebStorage) List(namespace string) ([]string, error) {
+	prefix := webStorageKey(namespace, "")
+	var keys []string
+	for i := 0; i < s.Items.Get("length").Int(); i++ {
+		item := s.Items.Call("key", i)
+		if item.Type() == js.TypeString && strings.HasPrefix(item.String(), prefix) {
+			keys = append(keys, strings.TrimPrefix(item.String(), prefix))
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s WebStorage) Delete(namespace, key string) error {
+	s.Items.Call("removeItem", webStorageKey(namespace, key))
+	return nil
+}
+
+func webStorageKey(namespace, key string) string {
+	return "roll/" + namespace + "/" + key
+}
+
+func (noStorage) Get(namespace, key string) ([]byte, error) {
+	return nil, ErrNotFound
+}
+
+func (noStorage) Put(namespace, key string, value []byte) error {
+	return errNoFiles
+}
+
+func (noStorage) List(namespace string) ([]string, error) {
+	return nil, nil
+}
+
+func (noStorage) Delete(namespace, key string) error {
+	return nil
+}
+
+// AppendLog drops the lines: without a Storage, js builds keep no history
+// log.
+func (noStorage) AppendLog(namespace string, next func(last []byte) ([][]byte, error)) error {
+	return nil
+}
+
+func (noStorage) ReadLog(namespace string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+func (noStorage) ReplaceLog(namespace string, write func(io.Writer) error) (string, error) {
+	return "", errNoFiles
+}
@@ -0,0 +1,90 @@
+//go:build js
+
+package rolls
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"syscall/js"
+	"testing"
+)
+
+// newFakeWebStorage returns an object with the methods of localStorage,
+// which Node does not have, holding its items in a Map.
+func newFakeWebStorage() js.Value {
+	return js.Global().Call("eval", `(() => {
+		const items = new Map();
+		return {
+			getItem: (k) => items.has(k) ? items.get(k) : null,
+			setItem: (k, v) => { items.set(k, String(v)); },
+			removeItem: (k) => { items.delete(k); },
+			key: (i) => [...items.keys()][i] ?? null,
+			get length() { return items.size; },
+		};
+	})()`)
+}
+
+func TestWebStorage(t *testing.T) {
+	items := newFakeWebStorage()
+	items.Call("setItem", "theme", "dark")
+	s := WebStorage{Items: items}
+
+	if _, err := s.Get(NamespaceMacros, "fireball"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get of a missing key = %v, want ErrNotFound", err)
+	}
+	for _, key := range []string{"fireball", "attack"} {
+		if err := s.Put(NamespaceMacros, key, []byte(`"8d6"`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Put(NamespaceDecks, "tarot", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.Get(NamespaceMacros, "fireball"); err != nil || string(got) != `"8d6"` {
+		t.Errorf("Get(macros, fireball) = %s, %v, want \"8d6\"", got, err)
+	}
+	if got := items.Call("getItem", "roll/macros/fireball").String(); got != `"8d6"` {
+		t.Errorf("localStorage holds %q under roll/macros/fireball, want \"8d6\"", got)
+	}
+	if keys, _ := s.List(NamespaceMacros); !slices.Equal(keys, []string{"attack", "fireball"}) {
+		t.Errorf("List(macros) = %v, want [attack fireball]", keys)
+	}
+
+	if err := s.Delete(NamespaceMacros, "attack"); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := s.List(NamespaceMacros); !slices.Equal(keys, []string{"fireball"}) {
+		t.Errorf("List(macros) after deleting attack = %v, want [fireball]", keys)
+	}
+	if got := items.Call("getItem", "theme").String(); got != "dark" {
+		t.Errorf("the page's own theme item became %q", got)
+	}
+}
+
+func TestWebStorageRoundTrip(t *testing.T) {
+	SetStorage(WebStorage{Items: newFakeWebStorage()})
+	defer SetStorage(nil)
+
+	want := map[string]string{"fireball": "8d6", "sneak": "1d20+7 + 3d6"}
+	if err := SaveMacros(want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadMacros()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("LoadMacros() = %v, want %v", got, want)
+	}
+}
+
+func TestNoStorage(t *testing.T) {
+	// Node has no localStorage, so nothing is saved.
+	if _, ok := defaultStorage().(noStorage); !ok {
+		t.Fatalf("defaultStorage() under Node = %T, want noStorage", defaultStorage())
+	}
+	if err := SaveMacros(map[string]string{"fireball": "8d6"}); err == nil {
+		t.Error("SaveMacros without localStorage succeeded, want an error")
+	}
+}
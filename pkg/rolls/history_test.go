@@ -0,0 +1,62 @@
+package rolls
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestHistoryInStorage keeps the history log in a MemoryStorage, which
+// holds it as numbered keys of NamespaceHistory.
+func TestHistoryInStorage(t *testing.T) {
+	s := NewMemoryStorage()
+	SetStorage(s)
+	defer SetStorage(nil)
+
+	r := NewRoller(WithSeed(1))
+	d20 := &Dice{Count: 1, Sides: 20}
+	if err := AppendHistory(r.Roll(d20), r.Roll(d20)); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendWithID("stealth", r.Roll(d20)); err != nil {
+		t.Fatal(err)
+	}
+	entries, _, err := LoadHistory()
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("LoadHistory = %d entries, %v", len(entries), err)
+	}
+	if err := AppendCorrection(&Correction{Target: entries[0].Ref, Action: CorrectionVoid}); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, _ := s.List(NamespaceHistory)
+	if want := []string{"000000000000", "000000000001", "000000000002", "000000000003"}; !slices.Equal(keys, want) {
+		t.Errorf("the log is kept under %q, want %q", keys, want)
+	}
+	entries, skipped, err := LoadHistory()
+	if err != nil || skipped != 0 || len(entries) != 3 || !entries[0].Voided || entries[2].ID != "stealth" {
+		t.Fatalf("LoadHistory = %+v, %d skipped, %v", entries, skipped, err)
+	}
+	report, err := VerifyHistory()
+	if err != nil || report.Entries != 4 || len(report.Broken) != 0 {
+		t.Errorf("VerifyHistory = %+v, %v, want 4 intact entries", report, err)
+	}
+
+	// A line from before the schema was recorded is upgraded in place, and
+	// the old log kept in a namespace of its own.
+	s.Put(NamespaceHistory, "000000000004", []byte(`{"expression":"1d6","rolls":[4],"bonus":0,"total":4}`))
+	migrated, backup, err := MigrateHistory()
+	if err != nil || migrated.Entries != 5 || migrated.Upgraded != 1 {
+		t.Fatalf("MigrateHistory = %+v, %v", migrated, err)
+	}
+	if old, _ := s.List(backup); !strings.HasPrefix(backup, NamespaceHistory+"-") || len(old) != 5 {
+		t.Errorf("MigrateHistory kept %d lines in %q as the backup", len(old), backup)
+	}
+	raw, _ := s.Get(NamespaceHistory, "000000000004")
+	if !strings.Contains(string(raw), `"schema":1`) {
+		t.Errorf("the old line migrated to %s", raw)
+	}
+	if entries, _, _ := LoadHistory(); len(entries) != 4 || entries[3].Result.Sides != 6 {
+		t.Errorf("after migrating, LoadHistory = %+v", entries)
+	}
+}
@@ -0,0 +1,117 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HitDie is a class hit die and the number of levels taken in that class.
+type HitDie struct {
+	Sides  int
+	Levels int
+}
+
+// HPLevel is the hit points gained at a single character level.
+type HPLevel struct {
+	Level int
+	Sides int
+	// Die is the die value used: rolled, the maximum, or the fixed average.
+	Die    int
+	Method string
+	ConMod int
+	Gained int
+}
+
+// HPResult holds the per-level hit points and their total.
+type HPResult struct {
+	Levels []HPLevel
+	Total  int
+}
+
+// RollHP rolls hit points across the given class levels in order. The first
+// level takes the die maximum when firstLevelMax is set. Character levels
+// above avgAfter take the fixed average (half the die plus one) instead of
+// rolling; a negative avgAfter rolls every level. Every level gains at least
+// 1 hit point regardless of the Con modifier.
+func RollHP(levels []HitDie, conMod int, firstLevelMax bool, avgAfter int) (*HPResult, error) {
+	res := &HPResult{}
+	level := 0
+	for _, hd := range levels {
+		if hd.Sides < 1 || hd.Levels < 1 {
+			return nil, fmt.Errorf("illegal hit die d%d:%d", hd.Sides, hd.Levels)
+		}
+		for i := 0; i < hd.Levels; i++ {
+			level++
+			l := HPLevel{Level: level, Sides: hd.Sides, ConMod: conMod}
+			switch {
+			case level == 1 && firstLevelMax:
+				l.Die, l.Method = hd.Sides, "max"
+			case avgAfter >= 0 && level > avgAfter:
+				l.Die, l.Method = hd.Sides/2+1, "average"
+			default:
+				l.Die, l.Method = result(hd.Sides), "rolled"
+			}
+
+			l.Gained = l.Die + conMod
+			if l.Gained < 1 {
+				l.Gained = 1
+			}
+			res.Levels = append(res.Levels, l)
+			res.Total += l.Gained
+		}
+	}
+
+	return res, nil
+}
+
+func hpGen(args []string) error {
+	fs := flag.NewFlagSet("hp", flag.ContinueOnError)
+	con := fs.Int("con", 0, "constitution modifier applied per level")
+	avgAfter := fs.Int("avg-after", -1, "take the average hit points for levels after this one")
+	rollFirst := fs.Bool("roll-first", false, "roll the first level instead of taking the maximum")
+	maxHP := fs.Int("max", 0, "start tracking a creature at this many hit points")
+	damage := fs.String("damage", "", "damage dealt to a tracked creature, e.g. 2d6+3")
+	heal := fs.String("heal", "", "healing for a tracked creature, e.g. 2d4+2")
+	temp := fs.Int("temp", 0, "temporary hit points granted to a tracked creature")
+	hitDice, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(hitDice) == 1 && !strings.Contains(hitDice[0], ":") {
+		return trackCreature(hitDice[0], *maxHP, *damage, *heal, *temp)
+	}
+	if len(hitDice) == 0 {
+		return fmt.Errorf("need to provide hit dice and levels (d10:5 d8:3)")
+	}
+
+	levels := make([]HitDie, 0, len(hitDice))
+	for _, hd := range hitDice {
+		parts := strings.Split(strings.TrimPrefix(hd, "d"), ":")
+		if len(parts) != 2 {
+			return fmt.Errorf("passed illegal hit die: %s", hd)
+		}
+		sides, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return err
+		}
+		count, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return err
+		}
+		levels = append(levels, HitDie{Sides: sides, Levels: count})
+	}
+
+	res, err := RollHP(levels, *con, !*rollFirst, *avgAfter)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range res.Levels {
+		fmt.Printf("Level %d (d%d): %s %d %+d = %d\n", l.Level, l.Sides, l.Method, l.Die, l.ConMod, l.Gained)
+	}
+	fmt.Println("Total: ", res.Total)
+
+	return nil
+}
@@ -0,0 +1,361 @@
+package rolls
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Combatant is one creature in an initiative order.
+type Combatant struct {
+	Name       string `json:"name"`
+	Modifier   int    `json:"modifier"`
+	Initiative int    `json:"initiative"`
+	// TieBreak orders combatants with the same initiative and modifier. It
+	// is a d20 rolled, and rerolled until it differs, only when they tie.
+	TieBreak int `json:"tie_break,omitempty"`
+}
+
+// Combat is an initiative order, saved by name as an encounter, and whose turn it is.
+type Combat struct {
+	Name       string       `json:"name"`
+	Combatants []*Combatant `json:"combatants"`
+	// Turn is the index of the combatant acting now, -1 before the first
+	// turn.
+	Turn  int `json:"turn"`
+	Round int `json:"round"`
+	// Vacant is set when the combatant acting now was removed: Turn then
+	// points at whoever acted before it, and nobody acts until the next
+	// turn.
+	Vacant bool `json:"vacant,omitempty"`
+}
+
+// RollInitiative rolls initiative for every target, d20 plus its modifier,
+// and returns a combat in initiative order that has not started.
+func RollInitiative(name string, targets []SaveTarget) (*Combat, error) {
+	e := &Combat{Name: name, Turn: -1}
+	for _, t := range targets {
+		if err := e.add(t); err != nil {
+			return nil, err
+		}
+	}
+	e.order()
+	return e, nil
+}
+
+// Add rolls initiative for a late combatant and places it in the order.
+// It acts in the next round if it lands before whoever is acting now.
+func (e *Combat) Add(t SaveTarget) (*Combatant, error) {
+	if err := e.add(t); err != nil {
+		return nil, err
+	}
+	var last string
+	if e.Turn >= 0 {
+		last = e.Combatants[e.Turn].Name
+	}
+	e.order()
+	if last != "" {
+		e.Turn = e.index(last)
+	}
+	return e.Combatants[e.index(t.Name)], nil
+}
+
+func (e *Combat) add(t SaveTarget) error {
+	if e.index(t.Name) >= 0 {
+		return fmt.Errorf("%s is already in encounter %s", t.Name, e.Name)
+	}
+	c := &Combatant{Name: t.Name, Modifier: t.SaveMod}
+	c.Initiative = RollD20(t.SaveMod, false, false).Total
+	e.Combatants = append(e.Combatants, c)
+	return nil
+}
+
+// order breaks new ties and sorts the combatants: highest initiative
+// first, then highest modifier, then highest tie-break roll. Combatants
+// that already hold a tie-break roll keep it, so inserting a late
+// combatant never reorders those already placed.
+func (e *Combat) order() {
+	tied := make(map[[2]int][]*Combatant)
+	for _, c := range e.Combatants {
+		key := [2]int{c.Initiative, c.Modifier}
+		tied[key] = append(tied[key], c)
+	}
+	for _, group := range tied {
+		if len(group) < 2 {
+			continue
+		}
+		for _, c := range group {
+			if c.TieBreak == 0 {
+				c.TieBreak = result(20)
+			}
+		}
+		for {
+			seen := make(map[int]int)
+			for _, c := range group {
+				seen[c.TieBreak]++
+			}
+			rerolled := false
+			for _, c := range group {
+				if seen[c.TieBreak] > 1 && rerollTie(c, group) {
+					rerolled = true
+				}
+			}
+			if !rerolled {
+				break
+			}
+		}
+	}
+
+	sort.SliceStable(e.Combatants, func(i, j int) bool {
+		a, b := e.Combatants[i], e.Combatants[j]
+		if a.Initiative != b.Initiative {
+			return a.Initiative > b.Initiative
+		}
+		if a.Modifier != b.Modifier {
+			return a.Modifier > b.Modifier
+		}
+		return a.TieBreak > b.TieBreak
+	})
+}
+
+// rerollTie rerolls c's tie-break if it shares it with another member of
+// group. Of two tied combatants only the later one rerolls, so an earlier
+// roll-off is never undone.
+func rerollTie(c *Combatant, group []*Combatant) bool {
+	for _, other := range group {
+		if other == c {
+			return false
+		}
+		if other.TieBreak == c.TieBreak {
+			c.TieBreak = result(20)
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Combat) index(name string) int {
+	for i, c := range e.Combatants {
+		if c.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Current returns the combatant acting now, or nil before the first turn
+// or after the one acting was removed.
+func (e *Combat) Current() *Combatant {
+	if e.Vacant || e.Turn < 0 || e.Turn >= len(e.Combatants) {
+		return nil
+	}
+	return e.Combatants[e.Turn]
+}
+
+// Next advances to the next combatant's turn and reports whether that
+// started a new round. The first turn starts round 1.
+func (e *Combat) Next() (*Combatant, bool, error) {
+	if len(e.Combatants) == 0 {
+		return nil, false, fmt.Errorf("encounter %s has no combatants left", e.Name)
+	}
+	e.Turn++
+	e.Vacant = false
+	newRound := e.Round == 0
+	if e.Turn >= len(e.Combatants) {
+		e.Turn, newRound = 0, true
+	}
+	if newRound {
+		e.Round++
+	}
+	return e.Combatants[e.Turn], newRound, nil
+}
+
+// Remove takes a combatant out of the order, as when it dies. Removing the
+// combatant acting now passes the next turn to whoever followed it.
+func (e *Combat) Remove(name string) error {
+	i := e.index(name)
+	if i < 0 {
+		return fmt.Errorf("no combatant named %s in encounter %s", name, e.Name)
+	}
+	if i == e.Turn && !e.Vacant {
+		e.Vacant = true
+	}
+	e.Combatants = append(e.Combatants[:i], e.Combatants[i+1:]...)
+	if i <= e.Turn {
+		e.Turn--
+	}
+	return nil
+}
+
+// ReadCombats reads the saved encounters written by WriteCombats.
+func ReadCombats(r io.Reader) (map[string]*Combat, error) {
+	encounters := make(map[string]*Combat)
+	if err := json.NewDecoder(r).Decode(&encounters); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return encounters, nil
+}
+
+// WriteCombats writes encounters as JSON.
+func WriteCombats(w io.Writer, encounters map[string]*Combat) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(encounters)
+}
+
+// joinTargets joins command line arguments into a target list for
+// parseTargets, so "goblin:+2 x2 ogre:-1" reads as two groups.
+func joinTargets(args []string) string {
+	var b strings.Builder
+	for i, a := range args {
+		switch {
+		case i == 0:
+		case strings.HasPrefix(a, "x") || strings.HasSuffix(args[i-1], ","):
+			b.WriteString(" ")
+		default:
+			b.WriteString(", ")
+		}
+		b.WriteString(a)
+	}
+	return b.String()
+}
+
+func printOrder(e *Combat) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	current := e.Current()
+	for _, c := range e.Combatants {
+		marker := " "
+		if c == current {
+			marker = ">"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t(%+d)\n", marker, c.Name, c.Initiative, c.Modifier)
+	}
+	return w.Flush()
+}
+
+func initiativeGen(args []string) error {
+	fs := flag.NewFlagSet("initiative", flag.ContinueOnError)
+	save := fs.String("save", "", "save the order as a named encounter for roll turn")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		return fmt.Errorf("need to provide combatants and initiative modifiers, e.g. goblin:+2 x3 fighter:+4")
+	}
+	targets, err := parseTargets(joinTargets(rest))
+	if err != nil {
+		return err
+	}
+
+	e, err := RollInitiative(*save, targets)
+	if err != nil {
+		return err
+	}
+	if err := printOrder(e); err != nil {
+		return err
+	}
+	if *save == "" {
+		return nil
+	}
+	encounters, err := LoadCombats()
+	if err != nil {
+		return err
+	}
+	encounters[*save] = e
+	return SaveCombats(encounters)
+}
+
+func turnGen(args []string) error {
+	fs := flag.NewFlagSet("turn", flag.ContinueOnError)
+	name := fs.String("encounter", "", "the saved encounter, needed when more than one is saved")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		return fmt.Errorf("need to provide a turn command: next, add, remove, list or end")
+	}
+
+	encounters, err := LoadCombats()
+	if err != nil {
+		return err
+	}
+	if *name == "" && len(encounters) == 1 {
+		for n := range encounters {
+			*name = n
+		}
+	}
+	e, ok := encounters[*name]
+	if !ok && *name == "" && len(encounters) > 1 {
+		return fmt.Errorf("%d encounters are saved, choose one with --encounter", len(encounters))
+	}
+	if !ok {
+		return fmt.Errorf("no saved encounter named %q, save one with roll initiative --save", *name)
+	}
+
+	switch {
+	case rest[0] == "next" && len(rest) == 1:
+		c, newRound, err := e.Next()
+		if err != nil {
+			return err
+		}
+		if newRound {
+			fmt.Println("Round", e.Round)
+			if e.Round > 1 {
+				if err := tickConditions(); err != nil {
+					return err
+				}
+			}
+		}
+		fmt.Printf("%s's turn (initiative %d)\n", c.Name, c.Initiative)
+	case rest[0] == "add" && len(rest) > 1:
+		targets, err := parseTargets(joinTargets(rest[1:]))
+		if err != nil {
+			return err
+		}
+		for _, t := range targets {
+			c, err := e.Add(t)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Added %s (initiative %d)\n", c.Name, c.Initiative)
+		}
+	case rest[0] == "remove" && len(rest) == 2:
+		if err := e.Remove(rest[1]); err != nil {
+			return err
+		}
+		fmt.Println("Removed", rest[1])
+	case rest[0] == "list" && len(rest) == 1:
+		if e.Round > 0 {
+			fmt.Println("Round", e.Round)
+		}
+		return printOrder(e)
+	case rest[0] == "end" && len(rest) == 1:
+		delete(encounters, *name)
+	default:
+		return fmt.Errorf("unknown turn command %q, want next, add NAME:MOD, remove NAME, list or end", strings.Join(rest, " "))
+	}
+	return SaveCombats(encounters)
+}
+
+// tickConditions counts a round down on the saved conditions that last a
+// number of rounds and clears those that run out.
+func tickConditions() error {
+	s, err := LoadConditions()
+	if err != nil {
+		return err
+	}
+	if len(s) == 0 {
+		return nil
+	}
+	for _, c := range s.Tick() {
+		fmt.Println("Condition ended:", c.Name)
+	}
+	return SaveConditions(s)
+}
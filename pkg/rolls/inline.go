@@ -0,0 +1,78 @@
+package rolls
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// InlineRoll is a [[...]] roll found in prose. Start and End are the byte
+// offsets of the whole span, brackets included.
+type InlineRoll struct {
+	Start      int
+	End        int
+	Expression string
+	Result     *Result
+}
+
+// ExtractRolls finds every [[expr]] span in text, then parses and rolls it
+// as ParseExpression reads it, so sums such as [[1d20+5-1d4]] work too.
+// A span written as \[[ is escaped and left alone. Nested and unterminated
+// spans are errors.
+func ExtractRolls(text string) ([]InlineRoll, error) {
+	var rolls []InlineRoll
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && strings.HasPrefix(text[i+1:], "[[") {
+			i += 2
+			continue
+		}
+		if !strings.HasPrefix(text[i:], "[[") {
+			continue
+		}
+
+		end := strings.Index(text[i+2:], "]]")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated inline roll at byte %d", i)
+		}
+		expr := text[i+2 : i+2+end]
+		if strings.Contains(expr, "[[") {
+			return nil, fmt.Errorf("nested inline roll at byte %d", i)
+		}
+
+		e, err := ParseExpression(strings.TrimSpace(expr))
+		if err != nil {
+			return nil, fmt.Errorf("inline roll at byte %d: %w", i, err)
+		}
+		rolls = append(rolls, InlineRoll{Start: i, End: i + 4 + end, Expression: expr, Result: RollExpression(e)})
+		i += 3 + end
+	}
+	return rolls, nil
+}
+
+// ResolveInline rolls every inline roll in text and replaces each span with
+// its total. Escaped \[[ sequences lose their backslash.
+func ResolveInline(text string) (string, []*Result, error) {
+	rolls, err := ExtractRolls(text)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		b       strings.Builder
+		results = make([]*Result, 0, len(rolls))
+		last    = 0
+	)
+	for _, r := range rolls {
+		b.WriteString(unescapeInline(text[last:r.Start]))
+		b.WriteString(strconv.Itoa(r.Result.Total))
+		results = append(results, r.Result)
+		last = r.End
+	}
+	b.WriteString(unescapeInline(text[last:]))
+
+	return b.String(), results, nil
+}
+
+func unescapeInline(s string) string {
+	return strings.ReplaceAll(s, `\[[`, "[[")
+}
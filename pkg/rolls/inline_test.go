@@ -0,0 +1,67 @@
+package rolls
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractRolls(t *testing.T) {
+	text := "I attack [[1d20+7]] and deal [[ (2d6+3)*2 ]] damage, [[1d20+5-1d4]] to save"
+	rolls, err := ExtractRolls(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1d20+7", " (2d6+3)*2 ", "1d20+5-1d4"}
+	if len(rolls) != len(want) {
+		t.Fatalf("ExtractRolls found %d rolls, want %d", len(rolls), len(want))
+	}
+	for i, r := range rolls {
+		if r.Expression != want[i] {
+			t.Errorf("roll %d is %q, want %q", i, r.Expression, want[i])
+		}
+		if span := text[r.Start:r.End]; span != "[["+want[i]+"]]" {
+			t.Errorf("roll %d spans %q, want [[%s]]", i, span, want[i])
+		}
+		e, err := ParseExpression(strings.TrimSpace(want[i]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Result.Total < e.Min() || r.Result.Total > e.Max() {
+			t.Errorf("roll %d totals %d, outside %d-%d", i, r.Result.Total, e.Min(), e.Max())
+		}
+	}
+}
+
+func TestExtractRollsErrors(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"roll [[1d20", "unterminated inline roll at byte 5"},
+		{"roll [[1d20 [[1d6]] ]]", "nested inline roll at byte 5"},
+		{"roll [[1d20+bogus]]", "inline roll at byte 5"},
+	}
+	for _, tt := range tests {
+		_, err := ExtractRolls(tt.text)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ExtractRolls(%q) = %v, want an error containing %q", tt.text, err, tt.want)
+		}
+	}
+}
+
+func TestResolveInline(t *testing.T) {
+	got, results, err := ResolveInline(`say \[[1d6]] and roll [[1d1+2]] then [[2d1*3]]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "say [[1d6]] and roll 3 then 6"; got != want {
+		t.Errorf("ResolveInline = %q, want %q", got, want)
+	}
+	if len(results) != 2 || results[0].Total != 3 || results[1].Total != 6 {
+		t.Errorf("ResolveInline results = %v", results)
+	}
+
+	got, results, err = ResolveInline("no rolls here")
+	if err != nil || got != "no rolls here" || len(results) != 0 {
+		t.Errorf("ResolveInline without rolls = %q, %v, %v", got, results, err)
+	}
+}
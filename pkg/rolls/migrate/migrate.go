@@ -0,0 +1,115 @@
+// Package migrate upgrades history entries and results written by older
+// versions of package rolls to the current schema.
+//
+// Every history entry records the schema it was written with. Entries
+// before the schema was recorded, and bare results stored on their own by
+// bots and other tools, count as version 0. Each upgrade step works on the
+// decoded JSON, so it only depends on the shapes involved and never on the
+// current Go types.
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Current is the schema entries are upgraded to, rolls.HistorySchema.
+const Current = 1
+
+// steps[v] upgrades a decoded entry from version v to v+1.
+var steps = []func(entry map[string]any) error{
+	0: fromV0,
+}
+
+// Entry upgrades raw, a history entry or a bare result of any schema up to
+// Current, and returns it as a current history entry along with the
+// version it was read as.
+func Entry(raw []byte) ([]byte, int, error) {
+	var entry map[string]any
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return nil, 0, err
+	}
+	if entry == nil {
+		return nil, 0, fmt.Errorf("history entry is not a JSON object")
+	}
+
+	version := 0
+	if v, ok := entry["schema"].(float64); ok {
+		version = int(v)
+	}
+	if version > Current {
+		return nil, version, fmt.Errorf("history entry has schema %d, newer than %d", version, Current)
+	}
+	for v := version; v < Current; v++ {
+		if err := steps[v](entry); err != nil {
+			return nil, version, fmt.Errorf("upgrading schema %d: %w", v, err)
+		}
+	}
+	entry["schema"] = Current
+
+	out, err := json.Marshal(entry)
+	return out, version, err
+}
+
+var leadingDice = regexp.MustCompile(`^\d*d(\d+|%)`)
+
+// fromV0 wraps bare results in an entry, and fills in the die size and
+// kept dice that the first results did not record.
+func fromV0(entry map[string]any) error {
+	if _, ok := entry["result"]; !ok {
+		if _, ok := entry["expression"]; !ok {
+			return fmt.Errorf("neither an entry nor a result")
+		}
+		result := make(map[string]any, len(entry))
+		for k, v := range entry {
+			result[k] = v
+			delete(entry, k)
+		}
+		entry["result"] = result
+	}
+	if _, ok := entry["time"]; !ok {
+		entry["time"] = "0001-01-01T00:00:00Z"
+	}
+
+	result, ok := entry["result"].(map[string]any)
+	if !ok {
+		return fmt.Errorf("result is not a JSON object")
+	}
+	if sides, _ := result["sides"].(float64); sides == 0 {
+		expr, _ := result["expression"].(string)
+		if m := leadingDice.FindStringSubmatch(expr); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			if m[1] == "%" {
+				n = 100
+			}
+			result["sides"] = n
+		}
+	}
+	if _, ok := result["kept"]; !ok {
+		result["kept"] = keptDice(result)
+	}
+	return nil
+}
+
+// keptDice returns the rolls of result without its dropped dice.
+func keptDice(result map[string]any) []any {
+	rolls, _ := result["rolls"].([]any)
+	dropped, _ := result["dropped"].([]any)
+	left := make(map[float64]int, len(dropped))
+	for _, d := range dropped {
+		if f, ok := d.(float64); ok {
+			left[f]++
+		}
+	}
+	kept := []any{}
+	for i := len(rolls) - 1; i >= 0; i-- {
+		if f, ok := rolls[i].(float64); ok && left[f] > 0 {
+			left[f]--
+			continue
+		}
+		kept = append([]any{rolls[i]}, kept...)
+	}
+	return kept
+}
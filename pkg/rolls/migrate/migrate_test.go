@@ -0,0 +1,146 @@
+package migrate_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Domo929/roll/pkg/rolls"
+	"github.com/Domo929/roll/pkg/rolls/migrate"
+)
+
+// TestCorpus upgrades every document of testdata/v<N>, written with schema
+// N, and compares it with the .golden file beside it. Each upgraded entry
+// must then decode into a rolls.HistoryEntry and come back unchanged from
+// another pass through Entry.
+func TestCorpus(t *testing.T) {
+	docs, err := filepath.Glob("testdata/v*/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[int]bool{}
+	for _, doc := range docs {
+		want, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(filepath.Dir(doc)), "v"))
+		if err != nil {
+			t.Fatalf("%s: %v", doc, err)
+		}
+		seen[want] = true
+
+		raw, err := os.ReadFile(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, version, err := migrate.Entry(raw)
+		if err != nil {
+			t.Errorf("%s: %v", doc, err)
+			continue
+		}
+		if version != want {
+			t.Errorf("%s was read as schema %d, want %d", doc, version, want)
+		}
+		golden, err := os.ReadFile(strings.TrimSuffix(doc, ".json") + ".golden")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sameJSON(t, out, golden) {
+			t.Errorf("%s upgraded to\n%s\nwant\n%s", doc, out, bytes.TrimSpace(golden))
+		}
+
+		var entry rolls.HistoryEntry
+		if err := json.Unmarshal(out, &entry); err != nil {
+			t.Errorf("%s does not decode as a history entry: %v", doc, err)
+			continue
+		}
+		if entry.Schema != migrate.Current || (entry.Result == nil && entry.Correction == nil) {
+			t.Errorf("%s decoded as schema %d with no result", doc, entry.Schema)
+		}
+		again, err := json.Marshal(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, version, err = migrate.Entry(again)
+		if err != nil || version != migrate.Current || !sameJSON(t, out, again) {
+			t.Errorf("%s did not round-trip: %s read as schema %d, %v, gave %s", doc, again, version, err, out)
+		}
+	}
+	for v := 0; v <= migrate.Current; v++ {
+		if !seen[v] {
+			t.Errorf("testdata has no documents of schema %d", v)
+		}
+	}
+}
+
+func TestEntryErrors(t *testing.T) {
+	for _, raw := range []string{
+		``,
+		`null`,
+		`[1, 2]`,
+		`{"rolls": [3]}`,
+		`{"time": "2024-03-01T19:02:11Z", "result": 14}`,
+		`{"schema": 2, "result": {"expression": "1d20"}}`,
+	} {
+		if out, _, err := migrate.Entry([]byte(raw)); err == nil {
+			t.Errorf("Entry(%s) = %s, want an error", raw, out)
+		}
+	}
+}
+
+// TestMigrateHistoryLog upgrades a log of mixed schemas, copying through
+// the line it cannot read.
+func TestMigrateHistoryLog(t *testing.T) {
+	f, err := os.Open("testdata/history-v0.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var buf bytes.Buffer
+	report, err := rolls.MigrateHistoryLog(f, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *report != (rolls.MigrateReport{Entries: 4, Upgraded: 3, Unreadable: 1}) {
+		t.Errorf("MigrateHistoryLog = %+v, want 4 entries, 3 upgraded and 1 unreadable", *report)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 5 || lines[2] != "not json at all" {
+		t.Fatalf("MigrateHistoryLog wrote %q", lines)
+	}
+	totals := []int{19, 14, 0, 4, 12}
+	for i, line := range lines {
+		if i == 2 {
+			continue
+		}
+		var entry rolls.HistoryEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+		if entry.Schema != migrate.Current || entry.Result == nil || entry.Result.Total != totals[i] || entry.Result.Sides == 0 {
+			t.Errorf("line %d = %s, want a current entry totalling %d", i+1, line, totals[i])
+		}
+	}
+
+	// A migrated log has nothing left to upgrade.
+	report, err = rolls.MigrateHistoryLog(strings.NewReader(buf.String()), new(bytes.Buffer))
+	if err != nil || report.Upgraded != 0 {
+		t.Errorf("migrating again = %+v, %v, want nothing upgraded", report, err)
+	}
+}
+
+// sameJSON reports whether a and b hold the same JSON value.
+func sameJSON(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var va, vb any
+	if err := json.Unmarshal(a, &va); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		t.Fatal(err)
+	}
+	return reflect.DeepEqual(va, vb)
+}
@@ -0,0 +1,185 @@
+package rolls
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+)
+
+// Routine is a monster's attack routine, such as an owlbear's multiattack.
+type Routine struct {
+	Name    string   `json:"name"`
+	Attacks []Attack `json:"attacks"`
+}
+
+// Attack is one entry of a Routine.
+type Attack struct {
+	Name string `json:"name"`
+	// ToHit is the attack bonus. It is ignored when SaveDC is set.
+	ToHit  int    `json:"to_hit"`
+	Damage string `json:"damage"`
+	// Count is how many times the attack is made; 0 is treated as 1.
+	Count int `json:"count,omitempty"`
+	// SaveDC makes the attack a saving throw, such as a breath weapon,
+	// that deals its damage on a failure instead of rolling to hit.
+	SaveDC int `json:"save_dc,omitempty"`
+	// Recharge is the lowest d6 roll that lets the attack be used, 5 for
+	// "Recharge 5-6". 0 means the attack needs no recharge.
+	Recharge int `json:"recharge,omitempty"`
+	// Uses limits how often the attack can be made, for "3/day" abilities.
+	// RollRoutine spends a use each time it makes the attack.
+	Uses *Uses `json:"uses,omitempty"`
+}
+
+// AttackResult is a single use of an Attack.
+type AttackResult struct {
+	Attack *Attack
+	// Recharge is the d6 rolled to see if the attack could be used.
+	Recharge *Result
+	// Skipped explains why the attack was not made.
+	Skipped    string
+	AttackRoll *Result
+	Hit        bool
+	Crit       bool
+	Damage     *Result
+}
+
+// RoutineResult holds every attack made by a routine and the damage dealt.
+type RoutineResult struct {
+	Routine *Routine
+	AC      int
+	Attacks []AttackResult
+	// Total is the damage dealt by attacks that hit. Damage from saving
+	// throw attacks is counted in full.
+	Total int
+}
+
+// ReadRoutine reads a JSON routine from r and checks its damage expressions.
+func ReadRoutine(r io.Reader) (*Routine, error) {
+	var rt Routine
+	if err := json.NewDecoder(r).Decode(&rt); err != nil {
+		return nil, fmt.Errorf("reading routine: %w", err)
+	}
+	for _, a := range rt.Attacks {
+		if _, err := Parse(a.Damage); err != nil {
+			return nil, fmt.Errorf("attack %s: %w", a.Name, err)
+		}
+		if a.Uses != nil && a.Uses.Remaining > a.Uses.Max {
+			return nil, fmt.Errorf("attack %s: %s remaining exceeds the maximum of %d", a.Name, quantity(a.Uses.Remaining, "use"), a.Uses.Max)
+		}
+		if a.Recharge < 0 || a.Recharge > 6 {
+			return nil, fmt.Errorf("attack %s: recharge must be between 1 and 6, got %d", a.Name, a.Recharge)
+		}
+	}
+	return &rt, nil
+}
+
+// RollRoutine makes every attack in r against a target with the given armor
+// class. A natural 20 hits and doubles the damage dice; a natural 1 misses.
+// Attacks with a recharge roll a d6 first and are skipped if it comes up
+// short; limited-use attacks are skipped once their uses are spent.
+func RollRoutine(r Routine, ac int) *RoutineResult {
+	res := &RoutineResult{Routine: &r, AC: ac}
+	for i := range r.Attacks {
+		a := &r.Attacks[i]
+		for n := 0; n < max(a.Count, 1); n++ {
+			ar := rollAttack(a, ac)
+			if ar.Hit && ar.Damage != nil {
+				res.Total += ar.Damage.Total
+			}
+			res.Attacks = append(res.Attacks, ar)
+		}
+	}
+	return res
+}
+
+func rollAttack(a *Attack, ac int) AttackResult {
+	ar := AttackResult{Attack: a}
+	if a.Uses != nil && a.Uses.Remaining <= 0 {
+		ar.Skipped = fmt.Sprintf("expended (%s)", a.Uses)
+		return ar
+	}
+	if a.Recharge > 0 {
+		var ok bool
+		if ar.Recharge, ok = CheckRecharge(a.Recharge); !ok {
+			ar.Skipped = fmt.Sprintf("did not recharge (rolled %d, needs %d)", ar.Recharge.Total, a.Recharge)
+			return ar
+		}
+	}
+	if a.Uses != nil {
+		if err := a.Uses.Spend(); err != nil {
+			ar.Skipped = err.Error()
+			return ar
+		}
+	}
+
+	d, err := Parse(a.Damage)
+	if err != nil {
+		ar.Skipped = err.Error()
+		return ar
+	}
+
+	if a.SaveDC > 0 {
+		ar.Hit = true
+	} else {
+		ar.AttackRoll = RollD20(a.ToHit, false, false)
+		nat := ar.AttackRoll.Rolls[0]
+		ar.Crit = nat == 20
+		ar.Hit = ar.Crit || (nat != 1 && ar.AttackRoll.Total >= ac)
+	}
+	if !ar.Hit {
+		return ar
+	}
+	if ar.Crit {
+		d.Count *= 2
+	}
+	ar.Damage = d.Roll()
+	return ar
+}
+
+func (ar AttackResult) String() string {
+	switch {
+	case ar.Skipped != "":
+		return fmt.Sprintf("%s: %s", ar.Attack.Name, ar.Skipped)
+	case ar.AttackRoll == nil:
+		return fmt.Sprintf("%s: DC %d save, %s on a failure", ar.Attack.Name, ar.Attack.SaveDC, ar.Damage)
+	case ar.Crit:
+		return fmt.Sprintf("%s: %s crits for %s", ar.Attack.Name, ar.AttackRoll, ar.Damage)
+	case ar.Hit:
+		return fmt.Sprintf("%s: %s hits for %s", ar.Attack.Name, ar.AttackRoll, ar.Damage)
+	}
+	return fmt.Sprintf("%s: %s misses", ar.Attack.Name, ar.AttackRoll)
+}
+
+func monsterGen(args []string) error {
+	fs := flag.NewFlagSet("monster", flag.ContinueOnError)
+	ac := fs.Int("ac", 10, "armor class of the target")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return fmt.Errorf("need to provide one routine file (owlbear.json)")
+	}
+
+	rt, err := LoadRoutine(files[0])
+	if err != nil {
+		return err
+	}
+	res := RollRoutine(*rt, *ac)
+
+	var rolled []*Result
+	for _, ar := range res.Attacks {
+		fmt.Println(ar)
+		for _, r := range []*Result{ar.AttackRoll, ar.Damage} {
+			if r != nil {
+				rolled = append(rolled, r)
+			}
+		}
+	}
+	logHistory(rolled...)
+	fmt.Printf("Total: %d damage against AC %d\n", res.Total, res.AC)
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package rolls
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var numberWords = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+	"six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11,
+	"twelve": 12, "thirteen": 13, "fourteen": 14, "fifteen": 15,
+	"sixteen": 16, "seventeen": 17, "eighteen": 18, "nineteen": 19,
+	"twenty": 20,
+}
+
+// ParseNatural parses a constrained English phrase such as "roll three d6
+// plus two" or "roll a d20 with advantage" into the Dice that Parse returns
+// for the equivalent notation. It understands spelled-out numbers up to
+// twenty, "plus N", "minus N", "with advantage", "with disadvantage" and
+// "drop the lowest" or "drop the highest". Anything else is refused with the
+// notation to use instead.
+func ParseNatural(s string) (*Dice, error) {
+	words := strings.Fields(strings.ToLower(strings.Trim(s, " .!?")))
+	if len(words) > 0 && words[0] == "roll" {
+		words = words[1:]
+	}
+	p := &naturalParser{words: words, count: 1}
+	if err := p.parse(); err != nil {
+		return nil, fmt.Errorf("cannot read %q: %v; write it as notation such as %s", s, err, p.notation())
+	}
+	return Parse(p.notation())
+}
+
+type naturalParser struct {
+	words []string
+	count int
+	sides int
+	bonus int
+	// keep is the notation suffix for a drop phrase, such as kh2.
+	keep      string
+	advantage string
+}
+
+func (p *naturalParser) next() (string, bool) {
+	if len(p.words) == 0 {
+		return "", false
+	}
+	w := p.words[0]
+	p.words = p.words[1:]
+	return w, true
+}
+
+func (p *naturalParser) parse() error {
+	w, ok := p.next()
+	if !ok {
+		return fmt.Errorf("no dice")
+	}
+	if i := strings.Index(w, "d"); i > 0 {
+		// Notation such as 4d6 inside the phrase.
+		n, ok := naturalNumber(w[:i])
+		if !ok {
+			return fmt.Errorf("%q is not a die", w)
+		}
+		p.count, w = n, w[i:]
+	} else if n, ok := naturalNumber(w); ok {
+		p.count = n
+		if w, ok = p.next(); !ok {
+			return fmt.Errorf("no die after %d", n)
+		}
+	}
+	sides, err := naturalDie(w)
+	if err != nil {
+		return err
+	}
+	p.sides = sides
+
+	for {
+		w, ok := p.next()
+		if !ok {
+			return nil
+		}
+		switch w {
+		case "and":
+		case "plus", "minus":
+			n, err := p.number(w)
+			if err != nil {
+				return err
+			}
+			if w == "minus" {
+				n = -n
+			}
+			p.bonus += n
+		case "with":
+			if err := p.withAdvantage(); err != nil {
+				return err
+			}
+		case "drop", "dropping":
+			if err := p.drop(); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown word %q", w)
+		}
+	}
+}
+
+// number reads the number after op, refusing dice such as "plus two d4".
+func (p *naturalParser) number(op string) (int, error) {
+	w, ok := p.next()
+	if !ok {
+		return 0, fmt.Errorf("no number after %q", op)
+	}
+	n, ok := naturalNumber(w)
+	if !ok || n == 1 && (w == "a" || w == "an") {
+		return 0, fmt.Errorf("%q after %q is not a number", w, op)
+	}
+	if len(p.words) > 0 {
+		if _, err := naturalDie(p.words[0]); err == nil {
+			return 0, fmt.Errorf("%q adds dice, not a number", op+" "+w+" "+p.words[0])
+		}
+	}
+	return n, nil
+}
+
+func (p *naturalParser) withAdvantage() error {
+	w, _ := p.next()
+	if w != "advantage" && w != "disadvantage" {
+		return fmt.Errorf("%q after \"with\" is not advantage or disadvantage", w)
+	}
+	if p.keep != "" || p.advantage != "" {
+		return fmt.Errorf("only one drop or advantage phrase is allowed")
+	}
+	if p.count != 1 {
+		return fmt.Errorf("%s on %s is ambiguous", w, quantity(p.count, "die"))
+	}
+	p.advantage = w[:3]
+	return nil
+}
+
+func (p *naturalParser) drop() error {
+	w, _ := p.next()
+	if w == "the" {
+		w, _ = p.next()
+	}
+	if p.keep != "" || p.advantage != "" {
+		return fmt.Errorf("only one drop or advantage phrase is allowed")
+	}
+	if p.count < 2 {
+		return fmt.Errorf("cannot drop the only die")
+	}
+	switch w {
+	case "lowest":
+		p.keep = fmt.Sprintf("kh%d", p.count-1)
+	case "highest":
+		p.keep = fmt.Sprintf("kl%d", p.count-1)
+	default:
+		return fmt.Errorf("%q after \"drop\" is not lowest or highest", w)
+	}
+	// Allow "drop the lowest die" and "drop the lowest one".
+	if len(p.words) > 0 && (p.words[0] == "die" || p.words[0] == "one") {
+		p.words = p.words[1:]
+	}
+	return nil
+}
+
+// notation returns what has been parsed so far as dice notation.
+func (p *naturalParser) notation() string {
+	if p.sides == 0 {
+		return "3d6+2"
+	}
+	s := fmt.Sprintf("%dd%d%s", p.count, p.sides, p.keep)
+	if p.bonus != 0 {
+		s += fmt.Sprintf("%+d", p.bonus)
+	}
+	return s + p.advantage
+}
+
+// naturalNumber reads a spelled-out number up to twenty or digits.
+func naturalNumber(w string) (int, bool) {
+	if n, ok := numberWords[w]; ok {
+		return n, true
+	}
+	n, err := strconv.Atoi(w)
+	return n, err == nil && n >= 0
+}
+
+// naturalDie reads a die such as d6, d20 or d%.
+func naturalDie(w string) (int, error) {
+	w = strings.TrimSuffix(strings.TrimSuffix(w, "s"), "'")
+	if !strings.HasPrefix(w, "d") {
+		return 0, fmt.Errorf("%q is not a die", w)
+	}
+	if w == "d%" {
+		return 100, nil
+	}
+	sides, err := strconv.Atoi(w[1:])
+	if err != nil || sides < 1 {
+		return 0, fmt.Errorf("%q is not a die", w)
+	}
+	return sides, nil
+}
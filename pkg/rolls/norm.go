@@ -1,53 +1,263 @@
 package rolls
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"strconv"
 	"strings"
+	"time"
 )
 
-func normGen(dieGens []string) {
-	total := 0
+func normGen(args []string) error {
+	fs := flag.NewFlagSet("roll", flag.ContinueOnError)
+	applyTo := fs.String("apply-to", "", "apply the total as damage to hit points written CURRENT/MAX, e.g. 45/45")
+	rulesName := fs.String("rules", "instant-death", "damage rules for --apply-to: instant-death or system-shock")
+	tempHP := fs.Int("temp-hp", 0, "temporary hit points for --apply-to")
+	bless := fs.Bool("bless", false, "add a 1d4 to every expression with a d20")
+	bane := fs.Bool("bane", false, "subtract a 1d4 from every expression with a d20")
+	dialectName := dialectFlag(fs)
+	id := fs.String("id", "", "log the roll under this id, so roll reroll can find it")
+	reliable := fs.Bool("reliable", false, "treat any kept d20 below 10 as a 10")
+	nudgeBy := fs.String("nudge", "", "openly shift each total by a number of standard deviations, e.g. +2sigma")
+	full := fs.Bool("full", false, "print every die of long rolls instead of the first and last few")
+	receipt := fs.Bool("receipt", false, "print a signed receipt for each roll, for roll verify to check")
+	colorMode := colorFlag(fs)
+	percentile := fs.Bool("percentile", false, "show every d100 as a tens and a ones die, e.g. [70 + 4] = 74")
+	times := fs.Int("n", 1, "roll each expression this many times")
+	summary := fs.Bool("summary", false, "follow each expression's rolls with a sparkline of their totals and a five-number summary")
+	quiet := fs.Bool("quiet", false, "print no rolls or receipts, only their --summary")
+	plain := fs.Bool("plain", false, "draw --summary sparklines in ASCII")
+	a11y := fs.Bool("a11y", false, "describe each roll in a plain sentence for screen readers, without brackets or symbols; set it once with a roll default in the config")
+	streaks := fs.Bool("streaks", false, "after rolling d20s, check the latest natural d20s in the history for an unusual run of 1s or 20s")
+	profile := fs.String("profile", "", "only allow the dice and notation of a system profile, e.g. pbta, 5e or fudge, or one from the config")
+	secret := fs.Bool("secret", false, "roll the expressions secretly, printing only a commitment to reveal later")
+	takeForward := fs.Bool("take-forward", false, "apply and use up banked forward and ongoing modifiers on the roll")
+	dieGens, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	dialect, err := ParseDialectName(*dialectName)
+	if err != nil {
+		return err
+	}
+	color, err := useColor(*colorMode)
+	if err != nil {
+		return err
+	}
+	prof, err := LoadProfile(*profile)
+	if err != nil {
+		return err
+	}
+	if *full {
+		TruncateDice = 0
+	}
+	if *id != "" && len(dieGens) != 1 {
+		return fmt.Errorf("--id names a single roll, got %d expressions", len(dieGens))
+	}
+	switch {
+	case *times < 1:
+		return fmt.Errorf("passed illegal -n %d, need to roll at least once", *times)
+	case *id != "" && *times > 1:
+		return fmt.Errorf("--id names a single roll, got -n %d", *times)
+	case *applyTo != "" && *times > 1:
+		return fmt.Errorf("--apply-to takes a single roll of each expression, got -n %d", *times)
+	case *quiet && !*summary:
+		return fmt.Errorf("--quiet prints only the --summary, so it needs one")
+	case *secret && *nudgeBy != "":
+		return fmt.Errorf("--nudge cannot be combined with --secret")
+	case *takeForward && (len(dieGens) != 1 || *times > 1):
+		return fmt.Errorf("--take-forward adds to a single roll, got %d expressions rolled %d times", len(dieGens), *times)
+	}
+
+	if *secret {
+		for _, dieGen := range dieGens {
+			res, c, err := RollSecret(dieGen)
+			if err != nil {
+				return err
+			}
+			fmt.Println(Redact(res, c))
+		}
+		return nil
+	}
+
+	var opts []RollerOption
+	if *nudgeBy != "" {
+		sigma, err := parseSigma(*nudgeBy)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, WithNudge(sigma))
+	}
+	if *reliable {
+		opts = append(opts, WithD20Floor(ReliableTalentFloor))
+	}
+	if *percentile {
+		opts = append(opts, WithPercentileDice())
+	}
+	roller := defaultRoller
+	if len(opts) > 0 {
+		roller = NewRoller(opts...)
+	}
+	var key []byte
+	if *receipt {
+		if key, err = receiptKey(); err != nil {
+			return err
+		}
+	}
+
+	var (
+		current, maxHP int
+		rules          DamageRules
+	)
+	if *applyTo != "" {
+		if current, maxHP, err = parseHP(*applyTo); err != nil {
+			return err
+		}
+		if rules, err = parseDamageRules(*rulesName); err != nil {
+			return err
+		}
+		rules.TempHP = *tempHP
+	}
+
+	total, labeled, width := 0, 0, terminalWidth()
+	results := make([]*Result, 0, len(dieGens))
 	for _, dieGen := range dieGens {
-		num, sides, err := parseNormDice(dieGen)
+		e, err := ParseDialect(dieGen, dialect)
+		if err == nil {
+			err = prof.Check(e)
+		}
+		if err == nil {
+			e, err = AddBlessBane(e, *bless, *bane)
+		}
+		if err == nil {
+			e, err = applyConditions(e)
+		}
+		if err == nil && *takeForward {
+			err = takeForwardOn(e)
+		}
 		if err != nil {
 			log.Println(err)
 			continue
 		}
-		resMsg := fmt.Sprintf("%s: ", dieGen)
-		for i := 0; i < num; i++ {
-			res := result(sides)
-			total += res
-			resMsg = fmt.Sprintf("%s %d", resMsg, res)
+		d, single := e.Dice()
+		totals := make([]int, 0, *times)
+		for i := 0; i < *times; i++ {
+			res := roller.RollExpression(e)
+			results = append(results, res)
+			totals = append(totals, res.Total)
+			total += res.Total
+			if len(res.Faces) > 0 {
+				labeled++
+			}
+			if *quiet {
+				continue
+			}
+
+			echo, line := res.Expression, ""
+			switch {
+			case *a11y:
+				line = res.Accessible()
+			case len(res.Faces) == 1:
+				line = res.Faces[0]
+			case !single || res.Summarized || !d.plain() || res.Nudge != nil || len(res.Percentiles) > 0:
+				line = wrap(res.String(), width)
+			default:
+				echo, line = dieGen, wrap(fmt.Sprintf("%s:  %s", dieGen, diceNumbers(res.Rolls)), width)
+			}
+			if color && !*a11y {
+				line = highlightPrefix(line, echo)
+			}
+			fmt.Println(line)
+			if *receipt {
+				blob, err := SignReceipt(res, time.Now(), key)
+				if err != nil {
+					return err
+				}
+				fmt.Println("receipt:", blob)
+			}
 		}
-		fmt.Println(resMsg)
+		switch {
+		case *summary && *a11y:
+			fmt.Println(spokenBatchSummary(dieGen, totals))
+		case *summary:
+			fmt.Println(batchSummary(dieGen, totals, *plain))
+		}
+	}
+	if *id != "" && len(results) == 1 {
+		if err := AppendWithID(*id, results[0]); err != nil {
+			log.Println("could not write history:", err)
+		}
+	} else {
+		logHistory(results...)
 	}
 
 	if len(dieGens) == 0 {
 		fmt.Println("no die combos provided")
-		return
+		return nil
+	}
+
+	switch {
+	case labeled == len(results), *times > 1:
+	case *a11y:
+		// A single roll has already said its total.
+		if len(results) > 1 {
+			fmt.Printf("Total of all rolls: %s.\n", spokenNumber(total))
+		}
+	default:
+		fmt.Println("total: ", total)
+	}
+	if *streaks {
+		printStreak(results)
+	}
+
+	if *applyTo != "" {
+		fmt.Println(ApplyDamage(current, maxHP, &Result{Total: total}, rules))
 	}
 
-	fmt.Println("total: ", total)
+	return nil
+}
 
+// plain reports whether d is a bare NdM pool.
+func (d *Dice) plain() bool {
+	return d.Modifier == NoModifier && d.Bonus == 0 && d.Success == nil && d.Explode == NoExplosion && d.Reroll == nil && !d.clamped() && len(d.Labels) == 0 && len(d.FaceValues) == 0
 }
 
+// parseSigma parses a nudge such as +2sigma, -1σ or 0.5.
+func parseSigma(s string) (float64, error) {
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "sigma"), "σ")
+	sigma, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("passed illegal nudge: %s, want a number of standard deviations such as +2sigma", s)
+	}
+	return sigma, nil
+}
+
+// parseNormDice parses plain dice such as 3d6 or d20, whose count defaults
+// to one.
 func parseNormDice(dieGen string) (int, int, error) {
 	parts := strings.Split(dieGen, "d")
 	if len(parts) != 2 {
 		return 0, 0, fmt.Errorf("passed illegal die command: %s", dieGen)
 	}
 
-	num, err := strconv.ParseInt(parts[0], 10, 64)
-	if err != nil {
-		return 0, 0, err
+	num := 1
+	if parts[0] != "" {
+		n, err := parseNumber(parts[0], "number of dice", dieGen)
+		if err != nil {
+			return 0, 0, err
+		}
+		num = n
 	}
 
-	sides, err := strconv.ParseInt(parts[1], 10, 64)
+	sides, err := parseNumber(parts[1], "number of sides", dieGen)
 	if err != nil {
 		return 0, 0, err
 	}
+	if sides < 1 {
+		return 0, 0, fmt.Errorf("passed illegal die command: %s, dice need at least one side", dieGen)
+	}
 
-	return int(num), int(sides), nil
+	return num, sides, nil
 }
@@ -0,0 +1,35 @@
+package rolls
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormGenNudge(t *testing.T) {
+	SetStorage(NewMemoryStorage())
+	defer SetStorage(nil)
+
+	if err := normGen([]string{"--nudge", "+1sigma", "1d20+5-1d4"}); err != nil {
+		t.Fatal(err)
+	}
+	entries, _, _ := LoadHistory()
+	if len(entries) != 1 || entries[0].Result.Nudge == nil {
+		t.Fatalf("a nudged sum of groups logged %+v, want one nudged roll", entries)
+	}
+
+	for _, args := range [][]string{
+		{"--secret", "--nudge", "+1sigma", "1d20"},
+		{"1d20", "--nudge=+1", "--secret"},
+	} {
+		if err := normGen(args); err == nil || !strings.Contains(err.Error(), "--nudge cannot be combined with --secret") {
+			t.Errorf("roll %v = %v, want the nudge refused", args, err)
+		}
+	}
+	if err := normGen([]string{"--secret", "1d20+5-1d4"}); err != nil {
+		t.Fatal(err)
+	}
+	entries, _, _ = LoadHistory()
+	if len(entries) != 2 || entries[1].Commitment == nil || entries[1].Result.Nudge != nil {
+		t.Errorf("a secret sum of groups logged %+v, want a committed roll", entries[1:])
+	}
+}
@@ -0,0 +1,91 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+)
+
+// OddResult is an attack resolved under Into the Odd style rules.
+type OddResult struct {
+	Damage *Result
+	// Dealt is the damage left after armor.
+	Dealt int
+	HP    int
+	// AbilityDamage is the damage that overflowed past 0 HP into the
+	// ability score, and Ability the score that is left.
+	AbilityDamage int
+	Ability       int
+	// Save is the critical damage save, rolled when damage overflowed into
+	// the ability score and the target survived.
+	SaveNeeded bool
+	Save       *Result
+	// CriticalDamage is set when the save failed: the target is out of
+	// action.
+	CriticalDamage bool
+	// Dead is set when the ability score was reduced to 0.
+	Dead bool
+}
+
+// ResolveOddAttack resolves an attack that always hits: damage is rolled,
+// reduced by armor, and taken from hp. Damage past 0 HP reduces the ability
+// score instead, after which the target saves by rolling a d20 equal to or
+// under the reduced score or takes critical damage.
+func ResolveOddAttack(damage *Dice, armor, hp, abilityScore int) *OddResult {
+	res := &OddResult{Damage: damage.Roll(), HP: hp, Ability: abilityScore}
+	res.Dealt = max(res.Damage.Total-armor, 0)
+
+	overflow := res.Dealt - max(hp, 0)
+	res.HP = max(hp-res.Dealt, 0)
+	if overflow <= 0 {
+		return res
+	}
+
+	res.AbilityDamage = min(overflow, abilityScore)
+	res.Ability -= res.AbilityDamage
+	if res.Ability <= 0 {
+		res.Dead = true
+		return res
+	}
+	res.SaveNeeded = true
+	res.Save = (&Dice{Count: 1, Sides: 20}).Roll()
+	res.CriticalDamage = res.Save.Total > res.Ability
+	return res
+}
+
+func oddGen(args []string) error {
+	fs := flag.NewFlagSet("odd", flag.ContinueOnError)
+	armor := fs.Int("armor", 0, "armor of the target")
+	hp := fs.Int("hp", 0, "hit protection of the target")
+	str := fs.Int("str", 10, "strength score of the target")
+	exprs, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(exprs) != 1 {
+		return fmt.Errorf("need to provide one damage expression (1d6)")
+	}
+	d, err := Parse(exprs[0])
+	if err != nil {
+		return err
+	}
+
+	res := ResolveOddAttack(d, *armor, *hp, *str)
+	logHistory(res.Damage)
+
+	fmt.Println("Damage:", res.Damage)
+	fmt.Printf("Dealt: %d after %d armor, %d HP left\n", res.Dealt, *armor, res.HP)
+	if res.AbilityDamage == 0 {
+		return nil
+	}
+	fmt.Printf("STR: -%d, %d left\n", res.AbilityDamage, res.Ability)
+	switch {
+	case res.Dead:
+		fmt.Println("Dead")
+	case res.CriticalDamage:
+		fmt.Println("Save:", res.Save, "failed, critical damage")
+	default:
+		fmt.Println("Save:", res.Save, "passed")
+	}
+
+	return nil
+}
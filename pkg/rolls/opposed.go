@@ -0,0 +1,181 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// Tiebreak selects how RollOpposed settles a tie.
+type Tiebreak int
+
+const (
+	// TiebreakNone leaves a tie standing.
+	TiebreakNone Tiebreak = iota
+	// TiebreakReroll rerolls both sides until one wins, up to
+	// MaxTiebreakRounds rounds in all.
+	TiebreakReroll
+	// TiebreakModifier gives the win to the side with the higher bonus.
+	TiebreakModifier
+	// TiebreakDefender gives the win to b.
+	TiebreakDefender
+	// TiebreakCoin flips a coin.
+	TiebreakCoin
+)
+
+var tiebreakNames = []string{"none", "reroll", "modifier", "defender", "coin"}
+
+func (t Tiebreak) String() string {
+	return tiebreakNames[t]
+}
+
+// ParseTiebreak parses a tiebreak name such as "reroll".
+func ParseTiebreak(s string) (Tiebreak, error) {
+	for i, name := range tiebreakNames {
+		if strings.EqualFold(s, name) {
+			return Tiebreak(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown tiebreak %q, want one of %s", s, strings.Join(tiebreakNames, ", "))
+}
+
+// MaxTiebreakRounds caps how many rounds TiebreakReroll rolls.
+const MaxTiebreakRounds = 10
+
+// Side is a side of an opposed check.
+type Side int
+
+const (
+	NoSide Side = iota
+	SideA
+	SideB
+)
+
+// OpposedRound is one roll of both sides.
+type OpposedRound struct {
+	A, B *Result
+}
+
+// OpposedResult is a settled opposed check.
+type OpposedResult struct {
+	// Rounds holds every round rolled, the first being the check itself.
+	Rounds   []OpposedRound
+	Tiebreak Tiebreak
+	Winner   Side
+	// Resolution explains how a tie was settled, empty if there was none.
+	Resolution string
+}
+
+// OpposedOption configures RollOpposed.
+type OpposedOption func(*Tiebreak)
+
+// WithTiebreak settles ties with t.
+func WithTiebreak(t Tiebreak) OpposedOption {
+	return func(tb *Tiebreak) {
+		*tb = t
+	}
+}
+
+// RollOpposed rolls the expressions a and b against each other, the higher
+// total winning. Ties stand unless a tiebreak is given.
+func RollOpposed(a, b string, opts ...OpposedOption) (*OpposedResult, error) {
+	da, err := Parse(a)
+	if err != nil {
+		return nil, err
+	}
+	db, err := Parse(b)
+	if err != nil {
+		return nil, err
+	}
+	res := &OpposedResult{}
+	for _, opt := range opts {
+		opt(&res.Tiebreak)
+	}
+
+	res.roll(da, db)
+	if res.Winner != NoSide {
+		return res, nil
+	}
+
+	switch res.Tiebreak {
+	case TiebreakReroll:
+		for len(res.Rounds) < MaxTiebreakRounds && res.Winner == NoSide {
+			res.roll(da, db)
+		}
+		if res.Winner == NoSide {
+			res.Resolution = fmt.Sprintf("still tied after %s", quantity(len(res.Rounds), "round"))
+		} else {
+			res.Resolution = fmt.Sprintf("rerolled %s", quantity(len(res.Rounds)-1, "time"))
+		}
+	case TiebreakModifier:
+		switch {
+		case da.Bonus > db.Bonus:
+			res.Winner = SideA
+		case db.Bonus > da.Bonus:
+			res.Winner = SideB
+		}
+		res.Resolution = fmt.Sprintf("higher modifier wins (%+d vs %+d)", da.Bonus, db.Bonus)
+	case TiebreakDefender:
+		res.Winner = SideB
+		res.Resolution = "defender wins ties"
+	case TiebreakCoin:
+		res.Winner = SideA
+		if result(2) == 2 {
+			res.Winner = SideB
+		}
+		res.Resolution = "coin flip"
+	}
+	return res, nil
+}
+
+func (res *OpposedResult) roll(da, db *Dice) {
+	round := OpposedRound{A: da.Roll(), B: db.Roll()}
+	res.Rounds = append(res.Rounds, round)
+	switch {
+	case round.A.Total > round.B.Total:
+		res.Winner = SideA
+	case round.B.Total > round.A.Total:
+		res.Winner = SideB
+	}
+}
+
+func opposedGen(args []string) error {
+	fs := flag.NewFlagSet("opposed", flag.ContinueOnError)
+	tiebreak := fs.String("tiebreak", "none", "how to settle ties: "+strings.Join(tiebreakNames, ", "))
+	exprs, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(exprs) != 2 {
+		return fmt.Errorf("need to provide two expressions to oppose (1d20+5 1d20+3)")
+	}
+	tb, err := ParseTiebreak(*tiebreak)
+	if err != nil {
+		return err
+	}
+
+	res, err := RollOpposed(exprs[0], exprs[1], WithTiebreak(tb))
+	if err != nil {
+		return err
+	}
+
+	var rolled []*Result
+	for i, round := range res.Rounds {
+		fmt.Printf("Round %d: %s vs %s\n", i+1, round.A, round.B)
+		rolled = append(rolled, round.A, round.B)
+	}
+	logHistory(rolled...)
+	if res.Resolution != "" {
+		fmt.Println("Tie:", res.Resolution)
+	}
+	switch res.Winner {
+	case SideA:
+		fmt.Println("Winner: A,", exprs[0])
+	case SideB:
+		fmt.Println("Winner: B,", exprs[1])
+	default:
+		fmt.Println("Tie")
+	}
+
+	return nil
+}
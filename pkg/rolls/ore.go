@@ -0,0 +1,123 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ORESet is a One-Roll Engine match: Width dice all showing Height.
+type ORESet struct {
+	Width  int
+	Height int
+}
+
+func (s ORESet) String() string {
+	return fmt.Sprintf("%dx%d", s.Width, s.Height)
+}
+
+// OREResult is a resolved One-Roll Engine pool.
+type OREResult struct {
+	Rolls []int
+	// Sets are ordered widest first, then highest.
+	Sets []ORESet
+	// Loose are the unmatched dice, highest first.
+	Loose []int
+}
+
+// OREOption adds special dice to a One-Roll Engine pool.
+type OREOption func(*[]int)
+
+// WithHardDice adds n hard dice, which always show 10.
+func WithHardDice(n int) OREOption {
+	return func(fixed *[]int) {
+		for i := 0; i < n; i++ {
+			*fixed = append(*fixed, 10)
+		}
+	}
+}
+
+// WithExpertDie adds an expert die set to face before rolling.
+func WithExpertDie(face int) OREOption {
+	return func(fixed *[]int) {
+		*fixed = append(*fixed, face)
+	}
+}
+
+// RollORE rolls pool d10s along with any hard and expert dice and groups
+// them into matched sets.
+func RollORE(pool int, opts ...OREOption) (*OREResult, error) {
+	var fixed []int
+	for _, opt := range opts {
+		opt(&fixed)
+	}
+	for _, face := range fixed {
+		if face < 1 || face > 10 {
+			return nil, fmt.Errorf("expert die face %d must be between 1 and 10", face)
+		}
+	}
+	if pool < 0 || pool+len(fixed) < 1 {
+		return nil, fmt.Errorf("passed illegal ORE pool: %s", quantity(pool+len(fixed), "die"))
+	}
+
+	res := &OREResult{Rolls: make([]int, 0, pool+len(fixed))}
+	for i := 0; i < pool; i++ {
+		res.Rolls = append(res.Rolls, result(10))
+	}
+	res.Rolls = append(res.Rolls, fixed...)
+
+	var counts [11]int
+	for _, r := range res.Rolls {
+		counts[r]++
+	}
+	for face := 10; face >= 1; face-- {
+		switch {
+		case counts[face] > 1:
+			res.Sets = append(res.Sets, ORESet{Width: counts[face], Height: face})
+		case counts[face] == 1:
+			res.Loose = append(res.Loose, face)
+		}
+	}
+	sort.SliceStable(res.Sets, func(i, j int) bool {
+		return res.Sets[i].Width > res.Sets[j].Width
+	})
+	return res, nil
+}
+
+func oreGen(args []string) error {
+	fs := flag.NewFlagSet("ore", flag.ContinueOnError)
+	hard := fs.Int("hard", 0, "number of hard dice, which always show 10")
+	expert := fs.Int("expert", 0, "add an expert die set to this face")
+	pools, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pools) != 1 {
+		return fmt.Errorf("need to provide the number of d10s in the pool")
+	}
+	pool, err := strconv.Atoi(pools[0])
+	if err != nil {
+		return err
+	}
+
+	opts := []OREOption{WithHardDice(*hard)}
+	if *expert != 0 {
+		opts = append(opts, WithExpertDie(*expert))
+	}
+	res, err := RollORE(pool, opts...)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Rolled:", res.Rolls)
+	if len(res.Sets) == 0 {
+		fmt.Println("Sets: none")
+	}
+	for _, s := range res.Sets {
+		fmt.Printf("Set: %s (width %d, height %d)\n", s, s.Width, s.Height)
+	}
+	fmt.Println("Loose:", res.Loose)
+
+	return nil
+}
@@ -0,0 +1,459 @@
+package rolls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Parse parses a die expression such as 3d6, 3d6+4, 4d6kh3, 4d6dl1, 3d6!,
+// 2d6r1 or 1d20>=18, whose count defaults to one, as in d20. Dropping dice
+// is stored as keeping the rest, so 4d6dl1 is 4d6kh3. A km or dm keeps or
+// drops the middle dice, as in 3d20km1 or 5d6dm1; see KeepMiddle for pools
+// without a single middle. An r or ro after the sides, with an optional
+// comparison, as in 2d6r1 or 4d6ro<3, rerolls each matching die once before
+// keep and drop modifiers apply, and rr rerolls it until it stops matching,
+// as in 1d8rr<3. A min after the sides or the reroll counts every die below
+// it as it, as in 8d6min2, and may not be more than the sides; a max counts
+// every die above it as it, as in 3d6max4, and may follow or come before a
+// min no lower than it. A ! after the sides makes the dice explode, and keep
+// and drop modifiers then choose from the whole pool, extra dice included;
+// !! makes them compound instead, and modifiers choose among the compounded
+// dice, and !p makes them penetrate, compounding with one less for every
+// extra roll. A trailing adv or dis rolls the pool twice over and keeps the
+// highest or lowest half, so 1d20+7adv is 2d20kh1+7. Labeled dice list their
+// faces in braces, as in 3d{red,blue,green}. A trailing comparison, as in
+// 8d6>=5, makes a success pool that totals the kept dice meeting it; a
+// bonus, written before the comparison as in 8d6+1>=5, adds successes. An f
+// after it, with an optional comparison, counts matching dice as failures
+// that each take a success away, as in 10d10>=8f1. Percentile dice are
+// written d%, as in d% or 2d%, and read as d100 with a count of one unless
+// given. Fudge dice are written dF, as in 4dF+2, with faces of -1, 0 and +1,
+// and cannot explode or reroll. Spaces are ignored. Bonuses add up, as in
+// 1d20+5+2, and each and their sum must be a whole number within
+// MaxModifier.
+func Parse(expr string) (*Dice, error) {
+	if strings.Contains(expr, "{") {
+		return parseLabeled(expr)
+	}
+	expr = percentileDice.ReplaceAllStringFunc(strings.Join(strings.Fields(expr), ""), percentileToD100)
+	src := expr
+	fudge := fudgeDice.MatchString(expr)
+	if fudge {
+		expr = fudgeDice.ReplaceAllStringFunc(expr, fudgeToD3)
+	}
+	d := &Dice{}
+	advantage := NoModifier
+	switch {
+	case strings.HasSuffix(expr, "adv"):
+		advantage, expr = KeepHighest, strings.TrimSuffix(expr, "adv")
+	case strings.HasSuffix(expr, "dis"):
+		advantage, expr = KeepLowest, strings.TrimSuffix(expr, "dis")
+	}
+	if strings.Contains(expr, "adv") || strings.Contains(expr, "dis") {
+		return nil, fmt.Errorf("passed illegal die command: %s, adv and dis go at the end, as in 1d20+5adv", src)
+	}
+	if m := rerollMark.FindStringSubmatchIndex(expr); m != nil {
+		if !rerollSides.MatchString(expr[:m[0]]) {
+			return nil, fmt.Errorf("passed illegal die command: %s, %s must follow the sides", expr, expr[m[0]:m[1]])
+		}
+		op := "="
+		if m[4] >= 0 {
+			op = expr[m[4]:m[5]]
+		}
+		target, err := parseNumber(expr[m[6]:m[7]], "reroll target", src)
+		if err != nil {
+			return nil, err
+		}
+		d.Reroll = &Threshold{Op: op, Target: target}
+		d.RerollRecursive = m[2] >= 0 && expr[m[2]:m[3]] == "r"
+		expr = expr[:m[0]] + expr[m[1]:]
+	}
+
+	// A minimum and a maximum may come in either order, so each is taken
+	// out in turn, the first always straight after the sides.
+	for m := clampMark.FindStringSubmatchIndex(expr); m != nil; m = clampMark.FindStringSubmatchIndex(expr) {
+		if !rerollSides.MatchString(expr[:m[0]]) {
+			return nil, fmt.Errorf("passed illegal die command: %s, %s must follow the sides", expr, expr[m[0]:m[1]])
+		}
+		limit, name := &d.MinPerDie, "minimum"
+		if expr[m[2]:m[3]] == "max" {
+			limit, name = &d.MaxPerDie, "maximum"
+		}
+		n, err := parseNumber(expr[m[4]:m[5]], name, src)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case n < 1:
+			return nil, fmt.Errorf("passed illegal die command: %s, a %s must be at least 1", src, name)
+		case *limit > 0:
+			return nil, fmt.Errorf("passed illegal die command: %s, dice take a single %s", src, name)
+		}
+		*limit = n
+		expr = expr[:m[0]] + expr[m[1]:]
+	}
+
+	if m := failureMark.FindStringSubmatchIndex(expr); m != nil {
+		op := "="
+		if m[2] >= 0 {
+			op = expr[m[2]:m[3]]
+		}
+		target, err := parseNumber(expr[m[4]:m[5]], "failure target", src)
+		if err != nil {
+			return nil, err
+		}
+		d.Failure = &Threshold{Op: op, Target: target}
+		expr = expr[:m[0]]
+	}
+
+	dice := expr
+	for _, op := range thresholdOps {
+		if i := strings.Index(expr, op); i >= 0 {
+			target, err := parseNumber(expr[i+len(op):], "success target", src)
+			if err != nil {
+				return nil, err
+			}
+			d.Success = &Threshold{Op: op, Target: target}
+			dice = expr[:i]
+			break
+		}
+	}
+	if d.Failure != nil && d.Success == nil {
+		return nil, fmt.Errorf("passed illegal die command: %s, failures need a success threshold to count against, as in 10d10>=8f1", src)
+	}
+
+	if i := strings.IndexAny(dice, "+-"); i >= 0 {
+		for _, term := range splitTerms(dice[i:]) {
+			bonus, err := parseBonus(term, src)
+			if err != nil {
+				return nil, err
+			}
+			if d.Bonus, err = addBonuses(d.Bonus, bonus, src); err != nil {
+				return nil, err
+			}
+		}
+		dice = dice[:i]
+	}
+
+	if i := strings.Index(dice, "!"); i >= 0 {
+		mark := "!"
+		d.Explode = Exploding
+		switch {
+		case strings.HasPrefix(dice[i:], "!!"):
+			mark, d.Explode = "!!", Compounding
+		case strings.HasPrefix(dice[i:], "!p"):
+			mark, d.Explode = "!p", Penetrating
+		}
+		if rest := dice[i+len(mark):]; !explodingSides.MatchString(dice[:i]) || rest != "" && rest[0] != 'k' && rest[0] != 'd' {
+			return nil, fmt.Errorf("passed illegal die command: %s, %s must follow the sides", expr, mark)
+		}
+		dice = dice[:i] + dice[i+len(mark):]
+	}
+
+	var keep, drop string
+	if i := strings.Index(dice, "k"); i >= 0 {
+		dice, keep = dice[:i], dice[i:]
+	} else if i := strings.LastIndex(dice, "d"); i > 0 && strings.ContainsAny(dice[i:], "lhm") {
+		dice, drop = dice[:i], dice[i:]
+	}
+
+	num, sides, err := parseNormDice(dice)
+	if err != nil {
+		return nil, err
+	}
+	d.Count, d.Sides = num, sides
+	if fudge {
+		switch {
+		case d.Sides != len(FudgeFaces):
+			return nil, fmt.Errorf("passed illegal die command: %s, want Fudge dice such as 4dF", src)
+		case d.Explode != NoExplosion || d.Reroll != nil:
+			return nil, fmt.Errorf("passed illegal die command: %s, Fudge dice cannot explode or reroll", src)
+		case d.clamped():
+			return nil, fmt.Errorf("passed illegal die command: %s, Fudge dice take no minimum or maximum", src)
+		}
+		d.FaceValues = FudgeFaces
+	}
+	switch {
+	case d.MinPerDie > d.Sides:
+		return nil, fmt.Errorf("passed illegal die command: %s, a d%d cannot roll a minimum of %d", src, d.Sides, d.MinPerDie)
+	case d.MaxPerDie > d.Sides && d.Explode == NoExplosion:
+		return nil, fmt.Errorf("passed illegal die command: %s, a d%d never rolls above %d, so a maximum of %d does nothing", src, d.Sides, d.Sides, d.MaxPerDie)
+	case d.MaxPerDie > 0 && d.MinPerDie > d.MaxPerDie:
+		return nil, fmt.Errorf("passed illegal die command: %s, a minimum of %d is above the maximum of %d", src, d.MinPerDie, d.MaxPerDie)
+	}
+	if d.Explode != NoExplosion && d.Sides < 2 {
+		return nil, fmt.Errorf("passed illegal die command: %s, dice need at least two sides to explode", expr)
+	}
+	if d.Reroll != nil {
+		switch matching := d.rerolledFaces(); {
+		case matching == 0:
+			return nil, fmt.Errorf("passed illegal die command: %s, no face of a d%d can be rerolled", src, d.Sides)
+		case matching == d.Sides && d.RerollRecursive:
+			return nil, fmt.Errorf("passed illegal die command: %s, every face of a d%d rerolls, so it would never stop", src, d.Sides)
+		}
+	}
+
+	if d.Failure != nil {
+		for face := 1; face <= d.Sides; face++ {
+			if v := d.face(face); d.Success.Matches(v) && d.Failure.Matches(v) {
+				return nil, fmt.Errorf("passed illegal die command: %s, a %d would both succeed and fail", src, v)
+			}
+		}
+	}
+
+	switch {
+	case keep != "":
+		if err := parseKeep(d, keep); err != nil {
+			return nil, err
+		}
+	case drop != "":
+		if err := parseDrop(d, drop); err != nil {
+			return nil, err
+		}
+	}
+
+	if advantage != NoModifier {
+		if d.Modifier != NoModifier {
+			return nil, fmt.Errorf("passed illegal die command: %s, cannot keep dice and roll with advantage", expr)
+		}
+		// Advantage on no dice keeps nothing from nothing, so it is left
+		// as it is rather than written as an unreadable kh0.
+		if d.Count > 0 {
+			d.Modifier, d.ModifierCount = advantage, d.Count
+			d.Count *= 2
+		}
+	}
+
+	return d, nil
+}
+
+// percentileToD100 rewrites percentile dice such as d% or 2d% as d100.
+func percentileToD100(m string) string {
+	count := strings.TrimSuffix(m, "d%")
+	if count == "" {
+		count = "1"
+	}
+	return count + "d100"
+}
+
+// fudgeToD3 rewrites Fudge dice such as dF or 4dF as the three-sided dice
+// whose faces they relabel.
+func fudgeToD3(m string) string {
+	count := strings.TrimSuffix(m, "dF")
+	if count == "" {
+		count = "1"
+	}
+	return count + "d3"
+}
+
+// MaxModifier bounds the flat modifiers of an expression, each on its own
+// and summed, so a bonus pasted from a spreadsheet, such as 1d20+1000000000,
+// is refused rather than rolled. Zero allows any modifier that fits in an
+// int.
+var MaxModifier = 1_000_000
+
+// LimitError reports a number in an expression beyond what it may be.
+type LimitError struct {
+	Expr string
+	// What names the number, such as "modifier".
+	What  string
+	Value string
+	Limit int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("passed illegal die command: %s, %s %s is beyond the limit of %d", e.Expr, e.What, e.Value, e.Limit)
+}
+
+// parseBonus parses s, a signed flat modifier of expr such as +3 or -12,
+// refusing fractions, scientific notation and modifiers beyond MaxModifier
+// or an int.
+func parseBonus(s, expr string) (int, error) {
+	switch {
+	case wholeNumber.MatchString(s):
+	case strings.ContainsAny(s, ".eE"):
+		return 0, fmt.Errorf("passed illegal die command: %s, modifiers must be whole numbers, not %s", expr, s)
+	default:
+		return 0, fmt.Errorf("passed illegal die command: %s, %q is not a modifier", expr, s)
+	}
+	n, err := strconv.ParseInt(s, 10, 0)
+	if err != nil {
+		return 0, &LimitError{Expr: expr, What: "modifier", Value: s, Limit: modifierLimit()}
+	}
+	return checkBonus(int(n), expr)
+}
+
+// modifierLimit returns the limit a modifier is reported beyond: MaxModifier,
+// or the largest int when MaxModifier is zero.
+func modifierLimit() int {
+	if MaxModifier > 0 {
+		return MaxModifier
+	}
+	return math.MaxInt
+}
+
+// parseNumber parses s, the what of expr such as its reroll target, wording
+// a missing, malformed or overlarge number in the parser's own terms rather
+// than strconv's.
+func parseNumber(s, what, expr string) (int, error) {
+	n, err := strconv.Atoi(s)
+	switch {
+	case err == nil:
+		return n, nil
+	case s == "":
+		return 0, fmt.Errorf("passed illegal die command: %s, the %s is missing", expr, what)
+	case errors.Is(err, strconv.ErrRange):
+		return 0, fmt.Errorf("passed illegal die command: %s, the %s %s is too large", expr, what, s)
+	}
+	return 0, fmt.Errorf("passed illegal die command: %s, %q is not a %s", expr, s, what)
+}
+
+var wholeNumber = regexp.MustCompile(`^[+-]?\d+$`)
+
+// checkBonus returns n, or a *LimitError if it is beyond MaxModifier.
+func checkBonus(n int, expr string) (int, error) {
+	if MaxModifier > 0 && (n > MaxModifier || n < -MaxModifier) {
+		return 0, &LimitError{Expr: expr, What: "modifier", Value: strconv.Itoa(n), Limit: MaxModifier}
+	}
+	return n, nil
+}
+
+// addBonuses returns a+b, or a *LimitError if the sum overflows an int or
+// is beyond MaxModifier.
+func addBonuses(a, b int, expr string) (int, error) {
+	sum := a + b
+	if (b > 0 && sum < a) || (b < 0 && sum > a) {
+		return 0, &LimitError{Expr: expr, What: "total modifier", Value: fmt.Sprintf("%d%+d", a, b), Limit: modifierLimit()}
+	}
+	if _, err := checkBonus(sum, expr); err != nil {
+		err.(*LimitError).What = "total modifier"
+		return 0, err
+	}
+	return sum, nil
+}
+
+var (
+	explodingSides = regexp.MustCompile(`d\d+$`)
+	rerollMark     = regexp.MustCompile(`r(r|o)?(>=|<=|>|<|=)?(\d+)`)
+	clampMark      = regexp.MustCompile(`(min|max)(\d+)`)
+	rerollSides    = regexp.MustCompile(`d\d+(!!|!p|!)?$`)
+	failureMark    = regexp.MustCompile(`f(>=|<=|>|<|=)?(\d+)$`)
+	percentileDice = regexp.MustCompile(`\d*d%`)
+	fudgeDice      = regexp.MustCompile(`^\d*dF`)
+	labeledDice    = regexp.MustCompile(`^(\d*)d\{([^{}]*)\}(.*)$`)
+)
+
+// rerolledFaces returns how many faces of d match its Reroll.
+func (d *Dice) rerolledFaces() int {
+	n := 0
+	for v := 1; v <= d.Sides; v++ {
+		if d.Reroll.Matches(v) {
+			n++
+		}
+	}
+	return n
+}
+
+// parseLabeled parses labeled dice such as d{red,blue,green} or
+// 3d{hit,hit,miss}. The count defaults to one, and a label may name several
+// faces.
+func parseLabeled(expr string) (*Dice, error) {
+	m := labeledDice.FindStringSubmatch(expr)
+	if m == nil {
+		return nil, fmt.Errorf("passed illegal die command: %s, want labeled dice such as 3d{red,blue,green}", expr)
+	}
+	if m[3] != "" {
+		return nil, fmt.Errorf("passed illegal die command: %s, labeled dice take no modifiers, bonuses or thresholds", expr)
+	}
+	count := 1
+	if m[1] != "" {
+		n, err := parseNumber(m[1], "number of dice", expr)
+		if err != nil {
+			return nil, err
+		}
+		count = n
+	}
+	labels := strings.Split(m[2], ",")
+	for i, label := range labels {
+		if labels[i] = strings.TrimSpace(label); labels[i] == "" {
+			return nil, fmt.Errorf("passed illegal die command: %s, every face of labeled dice needs a label", expr)
+		}
+	}
+	return &Dice{Count: count, Sides: len(labels), Labels: labels}, nil
+}
+
+// parseDrop parses a drop modifier such as dl1, dh2 or dm1 into d, whose
+// Count must already be set. The count defaults to 1.
+func parseDrop(d *Dice, drop string) error {
+	rest := strings.TrimPrefix(drop, "d")
+	switch {
+	case strings.HasPrefix(rest, "l"):
+		d.Modifier = KeepHighest
+	case strings.HasPrefix(rest, "h"):
+		d.Modifier = KeepLowest
+	case strings.HasPrefix(rest, "m"):
+		d.Modifier = DropMiddle
+	default:
+		return fmt.Errorf("passed illegal drop modifier: %s", drop)
+	}
+
+	n := 1
+	if rest = rest[1:]; rest != "" {
+		var err error
+		if n, err = strconv.Atoi(rest); err != nil {
+			return fmt.Errorf("passed illegal drop modifier: %s, want a number of dice to drop", drop)
+		}
+	}
+	if n < 1 || n >= d.Count {
+		return fmt.Errorf("passed illegal drop modifier: %s, must drop between 1 and %s", drop, quantity(d.Count-1, "die"))
+	}
+	d.ModifierCount = d.Count - n
+	return nil
+}
+
+// parseKeep parses a keep modifier such as kh3, kl1 or km1 into d. A bare k
+// keeps the highest, and the count defaults to 1.
+func parseKeep(d *Dice, keep string) error {
+	rest := strings.TrimPrefix(keep, "k")
+	d.Modifier = KeepHighest
+	switch {
+	case strings.HasPrefix(rest, "h"):
+		rest = rest[1:]
+	case strings.HasPrefix(rest, "l"):
+		d.Modifier, rest = KeepLowest, rest[1:]
+	case strings.HasPrefix(rest, "m"):
+		d.Modifier, rest = KeepMiddle, rest[1:]
+	}
+
+	d.ModifierCount = 1
+	if rest != "" {
+		n, err := strconv.Atoi(rest)
+		if err != nil {
+			return fmt.Errorf("passed illegal keep count: %s, want a number of dice to keep", keep)
+		}
+		if n < 1 {
+			return fmt.Errorf("passed illegal keep count: %s", keep)
+		}
+		d.ModifierCount = n
+	}
+	return nil
+}
+
+// RollString parses expr, which may hold several dice groups, and rolls it.
+func RollString(expr string) (*Result, error) {
+	return RollStringContext(context.Background(), expr)
+}
+
+// RollStringContext parses expr and rolls it, stopping early if ctx is done.
+func RollStringContext(ctx context.Context, expr string) (*Result, error) {
+	e, err := ParseExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+	return defaultRoller.RollExpressionContext(ctx, e)
+}
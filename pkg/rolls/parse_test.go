@@ -0,0 +1,138 @@
+package rolls
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// parseBareDiceTests are dice written without a count, and the group each
+// reads as.
+var parseBareDiceTests = []struct {
+	expr string
+	want string
+}{
+	{"d20", "1d20"},
+	{"d6+2", "1d6+2"},
+	{"d20adv", "2d20kh1"},
+	{"d8!", "1d8!"},
+	{"d%", "1d100"},
+	{"dF", "1dF"},
+}
+
+func TestParseBareDice(t *testing.T) {
+	for _, tt := range parseBareDiceTests {
+		d, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := d.String(); got != tt.want || d.Count != 1 && !strings.HasSuffix(tt.expr, "adv") {
+			t.Errorf("Parse(%q) = %s with a count of %d, want %s", tt.expr, got, d.Count, tt.want)
+		}
+	}
+}
+
+// parseBonusTests are lone groups with repeated flat modifiers, and the
+// bonus they add up to.
+var parseBonusTests = []struct {
+	expr  string
+	bonus int
+}{
+	{"1d20+5+3", 8},
+	{"1d20+5-3", 2},
+	{"1d20-5-3", -8},
+	{"1d20+3-5", -2},
+	{"2d6 + 1 + 1", 2},
+	{"4d6kh3+1+1", 2},
+	{"8d6+1+1>=5", 2},
+	{"1d20+1000000-1", 999999},
+}
+
+// TestParseBonuses checks a lone group's repeated flat modifiers add up the
+// same through Parse and ParseExpression, which hands such a group to Parse.
+func TestParseBonuses(t *testing.T) {
+	for _, tt := range parseBonusTests {
+		d, err := Parse(tt.expr)
+		if err != nil || d.Bonus != tt.bonus {
+			t.Errorf("Parse(%q) = %v, %v, want a bonus of %d", tt.expr, d, err, tt.bonus)
+		}
+		e, err := ParseExpression(tt.expr)
+		if err != nil {
+			t.Errorf("ParseExpression(%q): %v", tt.expr, err)
+			continue
+		}
+		if d, ok := e.Dice(); !ok || d.Bonus != tt.bonus {
+			t.Errorf("ParseExpression(%q) = %s, want one group with a bonus of %d", tt.expr, e, tt.bonus)
+		}
+	}
+
+	for _, expr := range []string{"1d20+1000000+1", "1d20-1000000-1"} {
+		var limit *LimitError
+		if _, err := Parse(expr); !errors.As(err, &limit) || limit.What != "total modifier" {
+			t.Errorf("Parse(%q) = %v, want a *LimitError on the total modifier", expr, err)
+		}
+		if _, err := ParseExpression(expr); !errors.As(err, &limit) {
+			t.Errorf("ParseExpression(%q) = %v, want a *LimitError", expr, err)
+		}
+	}
+	if _, err := Parse("1d20+5+x"); err == nil || !strings.Contains(err.Error(), `"+x" is not a modifier`) {
+		t.Errorf(`Parse("1d20+5+x") = %v, want "+x" refused as a modifier`, err)
+	}
+}
+
+// TestParseErrors checks every refusal of Parse is worded by the parser
+// itself rather than by strconv, and that ParseExpression refuses the same
+// notation with the same words.
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"1d20>=", "the success target is missing"},
+		{"1d20<", "the success target is missing"},
+		{"10d10>=8f", `"8f" is not a success target`},
+		{"2d6r", `"6r" is not a number of sides`},
+		{"d", "the number of sides is missing"},
+		{"3dx", `"x" is not a number of sides`},
+		{"xd6", `"x" is not a number of dice`},
+		{"1d6khx", "want a number of dice to keep"},
+		{"4d6dlx", "want a number of dice to drop"},
+		{"99999999999999999999d6", "the number of dice 99999999999999999999 is too large"},
+		{"1d99999999999999999999", "the number of sides 99999999999999999999 is too large"},
+		{"2d6r99999999999999999999", "the reroll target 99999999999999999999 is too large"},
+		{"8d6min99999999999999999999", "the minimum 99999999999999999999 is too large"},
+		{"10d10>=8f99999999999999999999", "the failure target 99999999999999999999 is too large"},
+		{"99999999999999999999d{a,b}", "the number of dice 99999999999999999999 is too large"},
+		{"1d20adv+5", "adv and dis go at the end"},
+		{"1d20dis-1", "adv and dis go at the end"},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.expr); err == nil || !strings.Contains(err.Error(), tt.want) || strings.Contains(err.Error(), "strconv") {
+			t.Errorf("Parse(%q) = %v, want an error saying %s", tt.expr, err, tt.want)
+		}
+		if _, err := ParseExpression(tt.expr); err == nil || !strings.Contains(err.Error(), tt.want) || strings.Contains(err.Error(), "strconv") {
+			t.Errorf("ParseExpression(%q) = %v, want the same error saying %s", tt.expr, err, tt.want)
+		}
+	}
+}
+
+func TestParseModifierLimit(t *testing.T) {
+	for _, expr := range []string{"1d20+99999999999999999999", "1d20+1000001", "1d20-1000001"} {
+		_, err := Parse(expr)
+		var limit *LimitError
+		if !errors.As(err, &limit) || limit.Limit != MaxModifier || !strings.HasSuffix(err.Error(), "beyond the limit of 1000000") {
+			t.Errorf("Parse(%q) = %v, want a *LimitError at MaxModifier", expr, err)
+		}
+	}
+
+	defer func(n int) { MaxModifier = n }(MaxModifier)
+	MaxModifier = 0
+	if _, err := Parse("1d20+1000001"); err != nil {
+		t.Errorf("with no MaxModifier, Parse(1d20+1000001): %v", err)
+	}
+	var limit *LimitError
+	if _, err := Parse("1d20+99999999999999999999"); !errors.As(err, &limit) {
+		t.Errorf("with no MaxModifier, Parse(1d20+99999999999999999999) = %v, want a *LimitError", err)
+	}
+}
@@ -0,0 +1,218 @@
+package rolls
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PoolResult is a dice pool rolled under a game system's rules.
+type PoolResult struct {
+	System    string
+	Size      int
+	Rolls     []int
+	Successes int
+	// Note describes any special outcome, such as a botch.
+	Note string
+}
+
+// PoolRoller rolls a pool of size dice.
+type PoolRoller func(size int) *PoolResult
+
+// PoolSystems maps a game system name to its pool roller.
+var PoolSystems = map[string]PoolRoller{
+	"wod":      rollWoDPool,
+	"cofd":     rollCofDPool,
+	"yearzero": rollYearZeroPool,
+}
+
+// rollWoDPool rolls d10s against difficulty 6. Each 1 cancels a success,
+// and a roll with 1s and no successes is a botch.
+func rollWoDPool(size int) *PoolResult {
+	res := &PoolResult{System: "wod", Size: size}
+	ones := 0
+	for i := 0; i < size; i++ {
+		r := result(10)
+		res.Rolls = append(res.Rolls, r)
+		switch {
+		case r >= 6:
+			res.Successes++
+		case r == 1:
+			ones++
+		}
+	}
+	if res.Successes == 0 && ones > 0 {
+		res.Note = "botch"
+	}
+	res.Successes = max(res.Successes-ones, 0)
+	return res
+}
+
+// rollCofDPool rolls d10s succeeding on 8 and up, with every 10 adding
+// another die. A pool of 0 or less rolls a single chance die instead, which
+// only succeeds on a 10 and is a dramatic failure on a 1.
+func rollCofDPool(size int) *PoolResult {
+	res := &PoolResult{System: "cofd", Size: size}
+	if size < 1 {
+		r := result(10)
+		res.Rolls = []int{r}
+		res.Note = "chance die"
+		switch r {
+		case 10:
+			res.Successes = 1
+		case 1:
+			res.Note = "dramatic failure"
+		}
+		return res
+	}
+	for left := size; left > 0; left-- {
+		r := result(10)
+		res.Rolls = append(res.Rolls, r)
+		if r >= 8 {
+			res.Successes++
+		}
+		if r == 10 {
+			left++
+		}
+	}
+	if res.Successes >= 5 {
+		res.Note = "exceptional success"
+	}
+	return res
+}
+
+// rollYearZeroPool rolls d6s succeeding on each 6.
+func rollYearZeroPool(size int) *PoolResult {
+	res := &PoolResult{System: "yearzero", Size: size}
+	for i := 0; i < size; i++ {
+		r := result(6)
+		res.Rolls = append(res.Rolls, r)
+		if r == 6 {
+			res.Successes++
+		}
+	}
+	return res
+}
+
+// Sheet is a character sheet: named trait ratings under a game system.
+type Sheet struct {
+	// Name is where the sheet came from, used in errors.
+	Name   string         `json:"-"`
+	System string         `json:"system"`
+	Traits map[string]int `json:"traits"`
+}
+
+// ReadSheet reads a JSON sheet from r.
+func ReadSheet(name string, r io.Reader) (*Sheet, error) {
+	s := &Sheet{Name: name}
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return nil, fmt.Errorf("reading sheet %s: %w", name, err)
+	}
+	return s, nil
+}
+
+// PoolSize evaluates a trait expression such as strength+brawl+2 against
+// the sheet. Trait names are matched case-insensitively.
+func (s *Sheet) PoolSize(expr string) (int, error) {
+	if strings.TrimSpace(expr) == "" {
+		return 0, fmt.Errorf("empty trait expression for sheet %s", s.Name)
+	}
+	size, sign, term := 0, 1, ""
+	for i := 0; i <= len(expr); i++ {
+		if i < len(expr) && expr[i] != '+' && expr[i] != '-' {
+			term += string(expr[i])
+			continue
+		}
+		term = strings.TrimSpace(term)
+		if term == "" {
+			return 0, fmt.Errorf("passed illegal trait expression: %q", expr)
+		}
+		v, err := s.trait(term)
+		if err != nil {
+			return 0, err
+		}
+		size += sign * v
+		if i < len(expr) && expr[i] == '-' {
+			sign = -1
+		} else {
+			sign = 1
+		}
+		term = ""
+	}
+	return size, nil
+}
+
+func (s *Sheet) trait(term string) (int, error) {
+	if n, err := strconv.Atoi(term); err == nil {
+		return n, nil
+	}
+	for name, v := range s.Traits {
+		if strings.EqualFold(name, term) {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("sheet %s has no trait %q", s.Name, term)
+}
+
+// RollPool sizes a pool from the trait expression and rolls it under the
+// sheet's system.
+func (s *Sheet) RollPool(expr string) (*PoolResult, error) {
+	roll, ok := PoolSystems[s.System]
+	if !ok {
+		return nil, fmt.Errorf("sheet %s uses unknown system %q, want one of %s", s.Name, s.System, strings.Join(poolSystemNames(), ", "))
+	}
+	size, err := s.PoolSize(expr)
+	if err != nil {
+		return nil, err
+	}
+	return roll(size), nil
+}
+
+func poolSystemNames() []string {
+	names := make([]string, 0, len(PoolSystems))
+	for name := range PoolSystems {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func poolGen(args []string) error {
+	fs := flag.NewFlagSet("pool", flag.ContinueOnError)
+	sheetPath := fs.String("sheet", "", "JSON character sheet to take traits and system from")
+	system := fs.String("system", "", "game system, overriding the sheet's: "+strings.Join(poolSystemNames(), ", "))
+	exprs, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(exprs) != 1 {
+		return fmt.Errorf("need to provide a trait expression (strength+brawl)")
+	}
+
+	sheet := &Sheet{Name: "command line"}
+	if *sheetPath != "" {
+		if sheet, err = LoadSheet(*sheetPath); err != nil {
+			return err
+		}
+	}
+	if *system != "" {
+		sheet.System = *system
+	}
+
+	res, err := sheet.RollPool(exprs[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s pool of %d: %v\n", res.System, res.Size, res.Rolls)
+	fmt.Println("Successes:", res.Successes)
+	if res.Note != "" {
+		fmt.Println(strings.ToUpper(res.Note[:1]) + res.Note[1:])
+	}
+
+	return nil
+}
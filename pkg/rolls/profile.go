@@ -0,0 +1,216 @@
+package rolls
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Profile restricts the dice and notation a game system's table may roll,
+// so that a newcomer to it cannot roll a d20 in PbtA or keep dice in FATE
+// by mistake. Parse knows nothing of profiles: Check is applied to what it
+// returns.
+type Profile struct {
+	Name string `json:"-"`
+	// Sides lists the dice sizes the profile allows, such as [6]. Empty
+	// allows any. Fudge and labeled dice are held to Features instead.
+	Sides []int `json:"sides,omitempty"`
+	// Features lists the notation features the profile allows, by the
+	// names Capabilities reports them under, such as "dice" and "bonus".
+	// Empty allows them all.
+	Features []string `json:"features,omitempty"`
+}
+
+// BuiltinProfiles are the profiles every config has, which one of the same
+// name in Config.Profiles replaces.
+var BuiltinProfiles = map[string]*Profile{
+	"5e":    {Name: "5e"},
+	"pbta":  {Name: "pbta", Sides: []int{6}, Features: []string{"dice", "bonus", "expression"}},
+	"fudge": {Name: "fudge", Features: []string{"fudge", "bonus"}},
+}
+
+// keepFeatures are the features Parse reads into KeepHighest and
+// KeepLowest alike, as 4d6dl1 is 4d6kh3 and 1d20adv is 2d20kh1 once
+// parsed. A profile allowing any of them allows all three.
+var keepFeatures = []string{"keep", "drop", "advantage"}
+
+// Profile returns the profile named name, from c or else the built-ins.
+func (c *Config) Profile(name string) (*Profile, error) {
+	if p, ok := c.Profiles[name]; ok {
+		p.Name = name
+		return p, nil
+	}
+	if p, ok := BuiltinProfiles[name]; ok {
+		return p, nil
+	}
+	names := make([]string, 0, len(BuiltinProfiles)+len(c.Profiles))
+	for n := range BuiltinProfiles {
+		names = append(names, n)
+	}
+	for n := range c.Profiles {
+		if _, ok := BuiltinProfiles[n]; !ok {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("no profile named %q, only %s", name, joinWithAnd(names))
+}
+
+// LoadProfile returns the profile named name from the config file or the
+// built-ins, or nil for an empty name.
+func LoadProfile(name string) (*Profile, error) {
+	if name == "" {
+		return nil, nil
+	}
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Profile(name)
+}
+
+// Check returns an error naming p and the first die size or feature of e
+// it does not allow, or nil. A nil profile allows everything.
+func (p *Profile) Check(e *Expression) error {
+	if p == nil {
+		return nil
+	}
+	if len(p.Sides) > 0 {
+		for _, d := range e.AllDice() {
+			if d.fudge() || len(d.Labels) > 0 || slices.Contains(p.Sides, d.Sides) {
+				continue
+			}
+			allowed := make([]string, len(p.Sides))
+			for i, s := range p.Sides {
+				allowed[i] = fmt.Sprintf("d%ds", s)
+			}
+			return fmt.Errorf("passed illegal die command: %s, the %s profile does not allow d%ds, only %s", e, p.Name, d.Sides, joinWithAnd(allowed))
+		}
+	}
+	if len(p.Features) == 0 {
+		return nil
+	}
+	for _, f := range e.features() {
+		if p.allows(f) {
+			continue
+		}
+		name := strings.ReplaceAll(f, "_", " ")
+		if f == "dice" {
+			name = "numbered dice"
+		}
+		for _, nf := range notationFeatures {
+			if nf.Name == f {
+				return fmt.Errorf("passed illegal die command: %s, the %s profile does not allow %s, such as %s", e, p.Name, name, nf.Example)
+			}
+		}
+		return fmt.Errorf("passed illegal die command: %s, the %s profile does not allow %s", e, p.Name, name)
+	}
+	return nil
+}
+
+// allows reports whether p allows the feature named f.
+func (p *Profile) allows(f string) bool {
+	if slices.Contains(p.Features, f) {
+		return true
+	}
+	if slices.Contains(keepFeatures, f) {
+		for _, k := range keepFeatures {
+			if slices.Contains(p.Features, k) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// features returns the names of the notation features e uses, in the order
+// notationFeatures lists them. Keeping, dropping and advantage all come
+// back as keep, as Parse cannot tell them apart afterwards, and percentile
+// dice as the d100s they are read as.
+func (e *Expression) features() []string {
+	used := make(map[string]bool)
+	e.markFeatures(used)
+	var names []string
+	for _, nf := range notationFeatures {
+		if used[nf.Name] {
+			names = append(names, nf.Name)
+		}
+	}
+	return names
+}
+
+func (e *Expression) markFeatures(used map[string]bool) {
+	if len(e.Groups) > 1 {
+		used["expression"] = true
+	}
+	if e.Bonus != 0 {
+		used["bonus"] = true
+	}
+	for _, g := range e.Groups {
+		if g.Negative {
+			used["expression"] = true
+		}
+		for _, s := range g.Scale {
+			if s.Op == "*" {
+				used["multiply"] = true
+			} else {
+				used["divide"] = true
+			}
+		}
+		if g.Sub != nil {
+			used["parentheses"] = true
+			g.Sub.markFeatures(used)
+			continue
+		}
+		g.Dice.markFeatures(used)
+	}
+}
+
+func (d *Dice) markFeatures(used map[string]bool) {
+	switch {
+	case len(d.Labels) > 0:
+		used["labels"] = true
+	case d.fudge():
+		used["fudge"] = true
+	default:
+		used["dice"] = true
+	}
+	if d.Bonus != 0 {
+		used["bonus"] = true
+	}
+	switch d.Modifier {
+	case KeepHighest, KeepLowest:
+		used["keep"] = true
+	case KeepMiddle:
+		used["keep_middle"] = true
+	case DropMiddle:
+		used["drop_middle"] = true
+	}
+	switch d.Explode {
+	case Exploding:
+		used["explode"] = true
+	case Compounding:
+		used["compound"] = true
+	case Penetrating:
+		used["penetrate"] = true
+	}
+	switch {
+	case d.Reroll != nil && d.RerollRecursive:
+		used["reroll_recursive"] = true
+	case d.Reroll != nil:
+		used["reroll"] = true
+	}
+	if d.MinPerDie > 0 {
+		used["minimum"] = true
+	}
+	if d.MaxPerDie > 0 {
+		used["maximum"] = true
+	}
+	if d.Success != nil {
+		used["success_pool"] = true
+	}
+	if d.Failure != nil {
+		used["failures"] = true
+	}
+}
@@ -0,0 +1,106 @@
+package rolls
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// receiptVersion is the version of the receipt encoding written by
+// SignReceipt.
+const receiptVersion = 1
+
+// Receipt is a roll signed after the fact, so a player in an asynchronous
+// game can share what they rolled and when. Unlike a Commitment it proves
+// nothing about the roll being made honestly, only that whoever holds the
+// key vouched for it; anyone with the key can both verify and forge one.
+type Receipt struct {
+	Version int       `json:"v"`
+	Time    time.Time `json:"t"`
+	Result  *Result   `json:"r"`
+}
+
+// SignReceipt returns a receipt for res rolled at t, as a single line of
+// URL-safe base64: the receipt's JSON followed by its HMAC-SHA256 under key.
+// The time is kept to the second.
+func SignReceipt(res *Result, t time.Time, key []byte) (string, error) {
+	if len(key) == 0 {
+		return "", fmt.Errorf("cannot sign a receipt without a key")
+	}
+	body, err := json.Marshal(Receipt{Version: receiptVersion, Time: t.UTC().Truncate(time.Second), Result: res})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(append(body, receiptMAC(body, key)...)), nil
+}
+
+// VerifyReceipt decodes a receipt written by SignReceipt and checks its
+// HMAC under key, failing if any part of the blob was changed.
+func VerifyReceipt(blob string, key []byte) (*Receipt, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("cannot verify a receipt without a key")
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(blob))
+	if err != nil {
+		return nil, fmt.Errorf("receipt is not valid base64: %w", err)
+	}
+	if len(raw) <= sha256.Size {
+		return nil, fmt.Errorf("receipt is too short, %s", quantity(len(raw), "byte"))
+	}
+	body, mac := raw[:len(raw)-sha256.Size], raw[len(raw)-sha256.Size:]
+	if !hmac.Equal(mac, receiptMAC(body, key)) {
+		return nil, fmt.Errorf("receipt does not match the key, it was changed or signed with another key")
+	}
+
+	r := &Receipt{}
+	if err := json.Unmarshal(body, r); err != nil {
+		return nil, fmt.Errorf("reading receipt: %w", err)
+	}
+	switch {
+	case r.Version != receiptVersion:
+		return nil, fmt.Errorf("receipt has version %d, want %d", r.Version, receiptVersion)
+	case r.Result == nil:
+		return nil, fmt.Errorf("receipt holds no roll")
+	}
+	return r, nil
+}
+
+func receiptMAC(body, key []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// receiptKey returns the receipt key from the config file.
+func receiptKey() ([]byte, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ReceiptKey == "" {
+		path, _ := ConfigPath()
+		return nil, fmt.Errorf("receipts need a receipt_key in the config file %s", path)
+	}
+	return []byte(cfg.ReceiptKey), nil
+}
+
+func verifyGen(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("need to provide the receipt to verify")
+	}
+	key, err := receiptKey()
+	if err != nil {
+		return err
+	}
+	r, err := VerifyReceipt(args[0], key)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s (rolled %s)\n", r.Result, r.Time.Local().Format("2006-01-02 15:04:05"))
+	fmt.Println("Receipt verified")
+	return nil
+}
@@ -0,0 +1,295 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// TruncateDice is how many dice Result.String shows from each end of a list
+// of more than twice as many, replacing the middle with a marker such as
+// "… (+186 more)". Zero shows every die. JSON and CSV output always hold
+// every die.
+var TruncateDice = 7
+
+// diceList renders dice as fmt's %v does, truncated to TruncateDice dice at
+// each end: [6 2 3 … (+194 more) 1 4 5].
+func diceList(dice []int) string {
+	return "[" + diceNumbers(dice) + "]"
+}
+
+// diceNumbers renders dice separated by spaces, truncated as diceList does.
+func diceNumbers(dice []int) string {
+	faces := make([]string, len(dice))
+	for i, v := range dice {
+		faces[i] = strconv.Itoa(v)
+	}
+	return truncateFaces(faces)
+}
+
+// rolledList renders r's Rolls as diceList does, with each exploded die
+// followed by the dice it added, [6→[6,4] 3 2], each compounded die by its
+// chain, [16[6+6+4] 3 2], and each penetrating die by its raw rolls and
+// what they count for, [14[6,6,4 → 6+5+3] 3 2], and each rerolled die by
+// the rolls before it, [1→4 3] or [1→2→5 3]. Truncation counts an exploded
+// die and its extra dice as one.
+func (r *Result) rolledList() string {
+	if len(r.Percentiles) > 0 {
+		return r.percentileList()
+	}
+	if r.Fudge {
+		return fudgeList(r.Rolls)
+	}
+	if len(r.Exploded) == 0 && len(r.Compounded) == 0 && len(r.Penetrated) == 0 && len(r.RerolledOnce) == 0 && len(r.RerollChains) == 0 && r.AdjustedRolls == nil {
+		return diceList(r.Rolls)
+	}
+	firsts := make(map[int]string, len(r.RerolledOnce)+len(r.RerollChains))
+	for _, re := range r.RerolledOnce {
+		firsts[re.Index] = strconv.Itoa(re.Original)
+	}
+	for _, c := range r.RerollChains {
+		firsts[c.Index] = joinInts(c.Chain[:len(c.Chain)-1], "→")
+	}
+	added := make(map[int]int, len(r.Exploded))
+	for _, ex := range r.Exploded {
+		added[ex.Index] = ex.Count
+	}
+	chains := make(map[int]string, len(r.Compounded)+len(r.Penetrated))
+	for _, c := range r.Compounded {
+		chains[c.Index] = joinInts(c.Chain, "+")
+	}
+	for _, c := range r.Penetrated {
+		penalized := append([]int{c.Chain[0]}, c.Chain[1:]...)
+		for j := 1; j < len(penalized); j++ {
+			penalized[j]--
+		}
+		chains[c.Index] = joinInts(c.Chain, ",") + " → " + joinInts(penalized, "+")
+	}
+	// A die raised to a minimum shows what it counts as after an up arrow,
+	// and one lowered to a maximum after a down arrow.
+	value := func(i int) string {
+		v := strconv.Itoa(r.Rolls[i])
+		switch {
+		case r.AdjustedRolls == nil || r.AdjustedRolls[i] == r.Rolls[i]:
+		case r.AdjustedRolls[i] > r.Rolls[i]:
+			v += "↑" + strconv.Itoa(r.AdjustedRolls[i])
+		default:
+			v += "↓" + strconv.Itoa(r.AdjustedRolls[i])
+		}
+		return v
+	}
+	var faces []string
+	for i := 0; i < len(r.Rolls); i++ {
+		face := value(i)
+		if first, ok := firsts[i]; ok {
+			face = first + "→" + face
+		}
+		if chain, ok := chains[i]; ok {
+			face += "[" + chain + "]"
+		}
+		if n := added[i]; n > 0 {
+			extra := make([]string, n)
+			for j := range extra {
+				extra[j] = value(i + 1 + j)
+			}
+			face += "→[" + strings.Join(extra, ",") + "]"
+			i += n
+		}
+		faces = append(faces, face)
+	}
+	return "[" + truncateFaces(faces) + "]"
+}
+
+// percentileList renders r's Percentiles as [70 + 4, 00 + 5], truncated
+// as diceList does.
+func (r *Result) percentileList() string {
+	faces := make([]string, len(r.Percentiles))
+	for i, p := range r.Percentiles {
+		faces[i] = fmt.Sprintf("%02d + %d", p.Tens, p.Ones)
+		if i < len(r.Percentiles)-1 {
+			faces[i] += ","
+		}
+	}
+	return "[" + truncateFaces(faces) + "]"
+}
+
+// fudgeList renders Fudge dice as [+][-][0][+], truncated as diceList does.
+func fudgeList(dice []int) string {
+	faces := make([]string, len(dice))
+	for i, v := range dice {
+		faces[i] = "[" + fudgeSymbols[v+1] + "]"
+	}
+	return strings.ReplaceAll(truncateFaces(faces), "] [", "][")
+}
+
+var fudgeSymbols = []string{"-", "0", "+"}
+
+// tallyString renders r's Tally as "2 red, 1 blue, 0 green".
+func (r *Result) tallyString() string {
+	counts := make([]string, len(r.Tally))
+	for i, c := range r.Tally {
+		counts[i] = fmt.Sprintf("%d %s", c.Count, c.Label)
+	}
+	return strings.Join(counts, ", ")
+}
+
+func joinInts(ints []int, sep string) string {
+	s := make([]string, len(ints))
+	for i, v := range ints {
+		s[i] = strconv.Itoa(v)
+	}
+	return strings.Join(s, sep)
+}
+
+// truncateFaces joins faces with spaces, keeping TruncateDice at each end.
+func truncateFaces(faces []string) string {
+	k := TruncateDice
+	if k == 0 || len(faces) <= 2*k {
+		return strings.Join(faces, " ")
+	}
+	return fmt.Sprintf("%s … (+%d more) %s", strings.Join(faces[:k], " "), len(faces)-2*k, strings.Join(faces[len(faces)-k:], " "))
+}
+
+// wrap breaks s at spaces into lines of at most width columns, indenting
+// every line after the first. Words longer than a line are left whole, and
+// a width of zero or less leaves s as it is.
+func wrap(s string, width int) string {
+	const indent = "    "
+	if width <= 0 || len([]rune(s)) <= width {
+		return s
+	}
+	var b strings.Builder
+	line := 0
+	for i, word := range strings.Split(s, " ") {
+		n := len([]rune(word))
+		switch {
+		case i == 0:
+		case line+1+n > width:
+			b.WriteString("\n" + indent)
+			line = len(indent)
+		default:
+			b.WriteByte(' ')
+			line++
+		}
+		b.WriteString(word)
+		line += n
+	}
+	return b.String()
+}
+
+// terminalWidth returns the width to wrap output to: $COLUMNS when stdout is
+// a terminal, 80 if COLUMNS is unset, and zero, for no wrapping, when
+// output goes to a file or pipe.
+func terminalWidth() int {
+	fi, err := os.Stdout.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return 0
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return 80
+}
+
+// colorFlag adds the --color flag to fs.
+func colorFlag(fs *flag.FlagSet) *string {
+	return fs.String("color", "auto", "highlight expressions: auto, always or never; auto colors a terminal unless $NO_COLOR is set")
+}
+
+// useColor reports whether output should be colored for a --color mode.
+func useColor(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		fi, err := os.Stdout.Stat()
+		return err == nil && fi.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, fmt.Errorf("passed illegal color mode: %s, want auto, always or never", mode)
+}
+
+// sparkBlocks are the bars of a sparkline from lowest to highest, and
+// sparkASCII the same for terminals without block characters.
+var (
+	sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+	sparkASCII  = []rune("_.-:=+*#")
+)
+
+// SparkWidth is the most columns a sparkline takes. Longer series are
+// shown by the mean of each run of values sharing a column.
+var SparkWidth = 60
+
+// sparkline draws values in order as a row of bars scaled from the lowest
+// to the highest, ▁▃█▅, or in ASCII when plain is set, _-#=. A series of a
+// single value, or of one value repeated, draws every bar at half height.
+func sparkline(values []int, plain bool) string {
+	bars := sparkBlocks
+	if plain {
+		bars = sparkASCII
+	}
+	columns := len(values)
+	if SparkWidth > 0 && columns > SparkWidth {
+		columns = SparkWidth
+	}
+	means := make([]float64, columns)
+	for c := range means {
+		run := values[c*len(values)/columns : (c+1)*len(values)/columns]
+		sum := 0.0
+		for _, v := range run {
+			sum += float64(v)
+		}
+		means[c] = sum / float64(len(run))
+	}
+	if len(means) == 0 {
+		return ""
+	}
+
+	lo, hi := slices.Min(means), slices.Max(means)
+	var b strings.Builder
+	for _, m := range means {
+		i := len(bars)/2 - 1
+		if hi > lo {
+			i = int(math.Round((m - lo) / (hi - lo) * float64(len(bars)-1)))
+		}
+		b.WriteRune(bars[i])
+	}
+	return b.String()
+}
+
+// fiveNumbers returns the lowest, lower quartile, median, upper quartile and
+// highest of values, the quartiles interpolated between the values around
+// them.
+func fiveNumbers(values []int) [5]float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	quantile := func(q float64) float64 {
+		at := q * float64(len(sorted)-1)
+		i := int(at)
+		if i+1 >= len(sorted) {
+			return float64(sorted[i])
+		}
+		return float64(sorted[i]) + (at-float64(i))*float64(sorted[i+1]-sorted[i])
+	}
+	return [5]float64{quantile(0), quantile(0.25), quantile(0.5), quantile(0.75), quantile(1)}
+}
+
+// batchSummary describes the totals of rolling expr again and again, in
+// the order they were rolled, as a sparkline and a five-number summary:
+// "2d6 over 20 rolls: ▃▅▁█▄ min 3, q1 5.75, median 7, q3 9, max 12".
+func batchSummary(expr string, totals []int, plain bool) string {
+	if len(totals) == 0 {
+		return fmt.Sprintf("%s: no rolls", expr)
+	}
+	f := fiveNumbers(totals)
+	return fmt.Sprintf("%s over %s: %s min %g, q1 %g, median %g, q3 %g, max %g",
+		expr, quantity(len(totals), "roll"), sparkline(totals, plain), f[0], f[1], f[2], f[3], f[4])
+}
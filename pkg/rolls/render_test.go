@@ -0,0 +1,191 @@
+package rolls
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// dieFaces returns the dice 1, 2, … n, wrapping past 6 so a face never runs
+// to two digits.
+func dieFaces(n int) []int {
+	dice := make([]int, n)
+	for i := range dice {
+		dice[i] = i%6 + 1
+	}
+	return dice
+}
+
+func TestDiceListTruncation(t *testing.T) {
+	defer func(k int) { TruncateDice = k }(TruncateDice)
+	tests := []struct {
+		k, n int
+		want string
+	}{
+		{3, 0, "[]"},
+		{3, 1, "[1]"},
+		{3, 5, "[1 2 3 4 5]"},
+		{3, 6, "[1 2 3 4 5 6]"},
+		{3, 7, "[1 2 3 … (+1 more) 5 6 1]"},
+		{3, 8, "[1 2 3 … (+2 more) 6 1 2]"},
+		{1, 2, "[1 2]"},
+		{1, 3, "[1 … (+1 more) 3]"},
+		{7, 14, "[1 2 3 4 5 6 1 2 3 4 5 6 1 2]"},
+		{7, 15, "[1 2 3 4 5 6 1 … (+1 more) 3 4 5 6 1 2 3]"},
+		{7, 200, "[1 2 3 4 5 6 1 … (+186 more) 2 3 4 5 6 1 2]"},
+		{0, 15, "[1 2 3 4 5 6 1 2 3 4 5 6 1 2 3]"},
+	}
+	for _, tt := range tests {
+		TruncateDice = tt.k
+		if got := diceList(dieFaces(tt.n)); got != tt.want {
+			t.Errorf("%d dice, truncated to %d = %s, want %s", tt.n, tt.k, got, tt.want)
+		}
+	}
+}
+
+func TestRolledListTruncation(t *testing.T) {
+	defer func(k int) { TruncateDice = k }(TruncateDice)
+	TruncateDice = 2
+	tests := []struct {
+		name string
+		res  *Result
+		want string
+	}{
+		{
+			// Each exploded die counts as one with the dice it added.
+			"exploded at the boundary",
+			&Result{Rolls: []int{6, 6, 2, 3, 1, 6, 4}, Exploded: []Explosion{{Index: 0, Count: 2}, {Index: 5, Count: 1}}},
+			"[6→[6,2] 3 1 6→[4]]",
+		},
+		{
+			"exploded past the boundary",
+			&Result{Rolls: []int{6, 1, 3, 1, 2, 6, 4}, Exploded: []Explosion{{Index: 0, Count: 1}, {Index: 5, Count: 1}}},
+			"[6→[1] 3 … (+1 more) 2 6→[4]]",
+		},
+		{"fudge at the boundary", &Result{Rolls: []int{1, -1, 0, 1}, Fudge: true}, "[+][-][0][+]"},
+		{"fudge past the boundary", &Result{Rolls: []int{1, -1, 0, 0, 1}, Fudge: true}, "[+][-] … (+1 more) [0][+]"},
+		{
+			"percentiles past the boundary",
+			&Result{Rolls: []int{74, 5, 100, 31, 12}, Percentiles: []PercentileRoll{{70, 4}, {0, 5}, {0, 0}, {30, 1}, {10, 2}}},
+			"[70 + 4, 00 + 5, … (+1 more) 30 + 1, 10 + 2]",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.res.rolledList(); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestResultJSONNeverTruncates(t *testing.T) {
+	defer func(k int) { TruncateDice = k }(TruncateDice)
+	TruncateDice = 1
+	res := NewRoller(WithSeed(1)).Roll(&Dice{Count: 200, Sides: 6})
+	if !strings.Contains(res.String(), "(+198 more)") {
+		t.Fatalf("200d6 was not truncated: %s", res)
+	}
+	var back Result
+	raw, err := json.Marshal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(raw, &back); err != nil {
+		t.Fatal(err)
+	}
+	if len(back.Rolls) != 200 || len(back.Kept) != 200 {
+		t.Errorf("200d6 marshals %d rolls and %d kept dice", len(back.Rolls), len(back.Kept))
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"3d6: Rolled: [1 2 3]", 0, "3d6: Rolled: [1 2 3]"},
+		{"3d6: Rolled: [1 2 3]", 20, "3d6: Rolled: [1 2 3]"},
+		{"3d6: Rolled: [1 2 3]", 19, "3d6: Rolled: [1 2\n    3]"},
+		{"3d6: Rolled: [1 2 3]", 16, "3d6: Rolled: [1\n    2 3]"},
+		{"aaaaaaaaaa bb", 6, "aaaaaaaaaa\n    bb"},
+		{"… (+186 more) 3", 10, "… (+186\n    more)\n    3"},
+	}
+	for _, tt := range tests {
+		if got := wrap(tt.s, tt.width); got != tt.want {
+			t.Errorf("wrap(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	defer func(w int) { SparkWidth = w }(SparkWidth)
+	tests := []struct {
+		values      []int
+		width       int
+		want, plain string
+	}{
+		{[]int{1, 3, 8, 5}, 60, "▁▃█▅", "_-#="},
+		{[]int{5}, 60, "▄", ":"},
+		{[]int{4, 4, 4}, 60, "▄▄▄", ":::"},
+		{nil, 60, "", ""},
+		{[]int{1, 2, 3, 4, 5, 6, 7, 8}, 60, "▁▂▃▄▅▆▇█", "_.-:=+*#"},
+		// Longer series take the mean of the values in each column.
+		{[]int{1, 2, 3, 4, 5, 6}, 3, "▁▅█", "_=#"},
+		{[]int{1, 2, 9}, 2, "▁█", "_#"},
+		{[]int{9, 1, 1, 1, 9}, 2, "█▁", "#_"},
+		{[]int{2, 1, 3}, 0, "▅▁█", "=_#"},
+	}
+	for _, tt := range tests {
+		SparkWidth = tt.width
+		if got := sparkline(tt.values, false); got != tt.want {
+			t.Errorf("sparkline(%v) at width %d = %q, want %q", tt.values, tt.width, got, tt.want)
+		}
+		if got := sparkline(tt.values, true); got != tt.plain {
+			t.Errorf("plain sparkline(%v) at width %d = %q, want %q", tt.values, tt.width, got, tt.plain)
+		}
+	}
+
+	SparkWidth = 60
+	if got := []rune(sparkline(dieFaces(1000), false)); len(got) != 60 {
+		t.Errorf("a sparkline of 1000 values is %d columns, want SparkWidth", len(got))
+	}
+	SparkWidth = 0
+	if got := []rune(sparkline(dieFaces(1000), false)); len(got) != 1000 {
+		t.Errorf("a sparkline of 1000 values with no SparkWidth is %d columns", len(got))
+	}
+}
+
+func TestBatchSummary(t *testing.T) {
+	tests := []struct {
+		expr   string
+		totals []int
+		plain  bool
+		want   string
+		spoken string
+	}{
+		{
+			"2d6", []int{3, 7, 12, 5, 9}, false,
+			"2d6 over 5 rolls: ▁▄█▃▆ min 3, q1 5, median 7, q3 9, max 12",
+			"Rolled 2d6 five times: lowest three, lower quartile five, median seven, upper quartile nine, highest twelve.",
+		},
+		{
+			"1d4", []int{1, 2, 3, 4}, true,
+			"1d4 over 4 rolls: _-+# min 1, q1 1.75, median 2.5, q3 3.25, max 4",
+			"Rolled 1d4 four times: lowest one, lower quartile 1.75, median 2.5, upper quartile 3.25, highest four.",
+		},
+		{
+			"1d20", []int{17}, false,
+			"1d20 over 1 roll: ▄ min 17, q1 17, median 17, q3 17, max 17",
+			"Rolled 1d20 one time: lowest seventeen, lower quartile seventeen, median seventeen, upper quartile seventeen, highest seventeen.",
+		},
+		{"2d6", nil, false, "2d6: no rolls", "Rolled 2d6 no times."},
+	}
+	for _, tt := range tests {
+		if got := batchSummary(tt.expr, tt.totals, tt.plain); got != tt.want {
+			t.Errorf("batchSummary(%s, %v) =\n%s\nwant\n%s", tt.expr, tt.totals, got, tt.want)
+		}
+		if got := spokenBatchSummary(tt.expr, tt.totals); got != tt.spoken {
+			t.Errorf("spokenBatchSummary(%s, %v) =\n%s\nwant\n%s", tt.expr, tt.totals, got, tt.spoken)
+		}
+	}
+}
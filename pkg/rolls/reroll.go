@@ -0,0 +1,140 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+)
+
+// Reroll records a die that ForceReroll rolled again.
+type Reroll struct {
+	// Index is the die's position in Rolls.
+	Index    int `json:"index"`
+	Original int `json:"original"`
+	Value    int `json:"value"`
+}
+
+// ForceReroll rolls the die at dieIndex of res's Rolls again with roller,
+// or the default roller if it is nil, and returns a new result with keep
+// and drop modifiers, bonuses and thresholds applied afresh, since the new
+// value can change which dice are kept. The replaced value is recorded in
+// Rerolled, after any earlier rerolls of res, and dice the expression
+// rerolled as they landed keep their records, bar the one rolled again.
+// Only results whose expression
+// parses can be rerolled, and not summarized, nudged, substituted or
+// exploded ones, whose dice no longer add up to their total or match their
+// expression, nor labeled ones, whose rerolls could only be recorded by
+// face number. Expressions that multiply, divide or bracket their groups are
+// refused as well.
+func ForceReroll(res *Result, dieIndex int, roller *Roller) (*Result, error) {
+	switch {
+	case res.Summarized:
+		return nil, fmt.Errorf("cannot reroll a die of %s, its dice were summarized", res.Expression)
+	case res.Nudge != nil:
+		return nil, fmt.Errorf("cannot reroll a die of %s, its total was nudged", res.Expression)
+	case len(res.Substituted) > 0:
+		return nil, fmt.Errorf("cannot reroll a die of %s, it has substituted dice", res.Expression)
+	case len(res.Exploded) > 0 || len(res.Compounded) > 0 || len(res.Penetrated) > 0:
+		return nil, fmt.Errorf("cannot reroll a die of %s, its dice exploded", res.Expression)
+	case len(res.Faces) > 0:
+		return nil, fmt.Errorf("cannot reroll a die of %s, its dice are labeled", res.Expression)
+	case dieIndex < 0 || dieIndex >= len(res.Rolls):
+		return nil, fmt.Errorf("no %s die in %s, it rolled %s", ordinal(dieIndex+1), res.Expression, quantity(len(res.Rolls), "die"))
+	}
+	e, err := ParseExpression(res.Expression)
+	if err != nil {
+		return nil, fmt.Errorf("cannot reroll a die of %s: %w", res.Expression, err)
+	}
+	for _, g := range e.Groups {
+		if g.Sub != nil || len(g.Scale) > 0 {
+			return nil, fmt.Errorf("cannot reroll a die of %s, it multiplies, divides or brackets its dice", res.Expression)
+		}
+	}
+	if roller == nil {
+		roller = defaultRoller
+	}
+
+	rolls := append([]int(nil), res.Rolls...)
+	var (
+		groups []*Result
+		start  int
+	)
+	for i, g := range e.Groups {
+		end := start + g.Dice.Count
+		if end > len(rolls) {
+			return nil, fmt.Errorf("cannot reroll a die of %s, its dice do not match the expression", res.Expression)
+		}
+		if dieIndex >= start && dieIndex < end {
+			rolls[dieIndex] = g.Dice.face(roller.die(g.Dice.Sides))
+		}
+		gr := g.Dice.result(rolls[start:end:end])
+		prior := res
+		if len(res.Groups) == len(e.Groups) {
+			prior = res.Groups[i]
+		}
+		for _, re := range prior.RerolledOnce {
+			if re.Index != dieIndex-start {
+				gr.RerolledOnce = append(gr.RerolledOnce, re)
+			}
+		}
+		for _, c := range prior.RerollChains {
+			if c.Index != dieIndex-start {
+				gr.RerollChains = append(gr.RerollChains, c)
+			}
+		}
+		groups = append(groups, gr)
+		start = end
+	}
+	if start != len(rolls) {
+		return nil, fmt.Errorf("cannot reroll a die of %s, its dice do not match the expression", res.Expression)
+	}
+
+	var out *Result
+	if len(e.Groups) == 1 && !e.Groups[0].Negative {
+		out = groups[0]
+		out.Total += e.Bonus
+		out.Bonus += e.Bonus
+	} else {
+		for i, g := range res.Groups {
+			e.Groups[i].Label = g.Label
+		}
+		out = e.combine(groups)
+	}
+	out.Rerolled = append(append([]Reroll(nil), res.Rerolled...), Reroll{Index: dieIndex, Original: res.Rolls[dieIndex], Value: rolls[dieIndex]})
+	return out, nil
+}
+
+func rerollGen(args []string) error {
+	fs := flag.NewFlagSet("reroll", flag.ContinueOnError)
+	die := fs.Int("die", 1, "which die of the roll to reroll, counting from 1")
+	ids, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(ids) != 1 {
+		return fmt.Errorf("need to provide the id of the roll to reroll")
+	}
+
+	entries, _, err := LoadHistory()
+	if err != nil {
+		return err
+	}
+	var prior *Result
+	for i := len(entries) - 1; i >= 0 && prior == nil; i-- {
+		if entries[i].ID == ids[0] {
+			prior = entries[i].Result
+		}
+	}
+	if prior == nil {
+		return fmt.Errorf("no roll with id %s in the history", ids[0])
+	}
+
+	res, err := ForceReroll(prior, *die-1, nil)
+	if err != nil {
+		return err
+	}
+	if err := AppendWithID(ids[0], res); err != nil {
+		return err
+	}
+	fmt.Println(res)
+	return nil
+}
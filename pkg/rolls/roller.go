@@ -0,0 +1,523 @@
+package rolls
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	oldrand "math/rand"
+	"math/rand/v2"
+)
+
+// cancelCheckInterval is how many dice are rolled between checks of the
+// context passed to RollContext.
+const cancelCheckInterval = 1 << 10
+
+// Roller rolls dice from a random source. The zero value, like Dice.Roll,
+// uses the global math/rand/v2 source, which is safe for concurrent use; a
+// Roller with its own source must only be used by one goroutine at a time.
+type Roller struct {
+	rng       *rand.Rand
+	seed      int64
+	seeded    bool
+	weighted  map[int]*WeightedDie
+	observers []Observer
+	nudge     float64
+	d20Floor  int
+	percent   bool
+	stats     *rollTally
+}
+
+// Observer is notified of every result a Roller produces.
+type Observer interface {
+	Observe(res *Result)
+}
+
+// ObserverFunc adapts a function to an Observer.
+type ObserverFunc func(res *Result)
+
+func (f ObserverFunc) Observe(res *Result) {
+	f(res)
+}
+
+// RollerOption configures a Roller.
+type RollerOption func(*Roller)
+
+var defaultRoller = &Roller{}
+
+// NewRoller returns a Roller configured by opts.
+func NewRoller(opts ...RollerOption) *Roller {
+	r := &Roller{}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// WithSource makes the Roller draw from src, such as a *rand.PCG or
+// *rand.ChaCha8, instead of the global source.
+func WithSource(src rand.Source) RollerOption {
+	return func(r *Roller) {
+		r.rng = rand.New(src)
+	}
+}
+
+// WithLegacySource makes the Roller draw from a math/rand source.
+func WithLegacySource(src oldrand.Source) RollerOption {
+	return WithSource(legacySource{src})
+}
+
+// WithSeed makes the Roller draw from a ChaCha8 source keyed from seed. The
+// same seed always produces the same rolls, both from the Roller and from
+// any rollers split from it, for a given version of this package.
+func WithSeed(seed int64) RollerOption {
+	return func(r *Roller) {
+		r.rng = rand.New(newSeededSource(uint64(seed)))
+		r.seed, r.seeded = seed, true
+	}
+}
+
+// SetRandomSource makes Dice.Roll and the other package-level rolls draw from
+// src, a math/rand source. A nil src restores the global source. Unlike the
+// global source, src is not safe for concurrent use.
+func SetRandomSource(src oldrand.Source) {
+	if src == nil {
+		defaultRoller.rng = nil
+		return
+	}
+	defaultRoller.rng = rand.New(legacySource{src})
+}
+
+// WithObserver notifies o of every result the Roller produces.
+func WithObserver(o Observer) RollerOption {
+	return func(r *Roller) {
+		r.observers = append(r.observers, o)
+	}
+}
+
+// WithWeightedDie makes the Roller roll w in place of every plain die with
+// the same number of faces.
+func WithWeightedDie(w *WeightedDie) RollerOption {
+	return func(r *Roller) {
+		if r.weighted == nil {
+			r.weighted = make(map[int]*WeightedDie)
+		}
+		r.weighted[w.Sides()] = w
+	}
+}
+
+// WithNudge shifts every total the Roller produces by sigma standard
+// deviations of its expression, clamped to the totals the dice can roll. It
+// is meant for solo and narrative play: nudged results say so when printed
+// and keep the unbiased total in Result.Nudge.
+func WithNudge(sigma float64) RollerOption {
+	return func(r *Roller) {
+		r.nudge = sigma
+	}
+}
+
+// WithD20Floor raises every kept d20 below floor to floor after keep and
+// drop modifiers are applied, as Reliable Talent does. The raise is
+// recorded in Result.Substituted and the natural roll stays in Rolls, so a
+// natural 1 is still a natural 1.
+func WithD20Floor(floor int) RollerOption {
+	return func(r *Roller) {
+		r.d20Floor = floor
+	}
+}
+
+// WithPercentileDice records every d100 the Roller rolls as the tens and
+// ones dice of a physical pair in Result.Percentiles, so 74 reads as 70 + 4
+// and 100 as 00 + 0. Compounding and penetrating d100s total more than one
+// pair can show, so they are left as they are.
+func WithPercentileDice() RollerOption {
+	return func(r *Roller) {
+		r.percent = true
+	}
+}
+
+// Split derives n child rollers with independent streams, one per worker.
+// Each child gets its own ChaCha8 source keyed from a SplitMix64 hash of the
+// parent seed and the child's index, so children never share the correlated
+// neighbouring seeds that seeding with seed+i produces. Children of a Roller
+// created WithSeed are reproducible and do not advance the parent; otherwise
+// the parent seed is drawn from the Roller's source. Children keep the
+// parent's weighted dice, observers, nudge, d20 floor and percentile dice,
+// and add to its stats.
+func (r *Roller) Split(n int) []*Roller {
+	base := r.seed
+	if !r.seeded {
+		base = r.int64()
+	}
+
+	children := make([]*Roller, n)
+	for i := range children {
+		seed := int64(splitMix64(uint64(base) + uint64(i+1)*0x9e3779b97f4a7c15))
+		children[i] = &Roller{
+			rng:       rand.New(newSeededSource(uint64(seed))),
+			seed:      seed,
+			seeded:    true,
+			weighted:  r.weighted,
+			observers: r.observers,
+			nudge:     r.nudge,
+			d20Floor:  r.d20Floor,
+			percent:   r.percent,
+			stats:     r.stats,
+		}
+	}
+	return children
+}
+
+// newSeededSource expands seed into a ChaCha8 key.
+func newSeededSource(seed uint64) *rand.ChaCha8 {
+	var key [32]byte
+	for i := 0; i < len(key); i += 8 {
+		seed = splitMix64(seed + 0x9e3779b97f4a7c15)
+		binary.LittleEndian.PutUint64(key[i:], seed)
+	}
+	return rand.NewChaCha8(key)
+}
+
+// splitMix64 is the SplitMix64 output function, a bijective mix that spreads
+// nearby inputs across the whole output range.
+func splitMix64(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// legacySource adapts a math/rand source to math/rand/v2.
+type legacySource struct {
+	src oldrand.Source
+}
+
+func (s legacySource) Uint64() uint64 {
+	if s64, ok := s.src.(oldrand.Source64); ok {
+		return s64.Uint64()
+	}
+	// Int63 only yields 63 bits, so build the value from two draws.
+	return uint64(s.src.Int63())>>31 | uint64(s.src.Int63())<<32
+}
+
+func (r *Roller) intn(n int) int {
+	if r.rng == nil {
+		return rand.IntN(n)
+	}
+	return r.rng.IntN(n)
+}
+
+func (r *Roller) int64() int64 {
+	if r.rng == nil {
+		return rand.Int64()
+	}
+	return r.rng.Int64()
+}
+
+func (r *Roller) float64() float64 {
+	if r.rng == nil {
+		return rand.Float64()
+	}
+	return r.rng.Float64()
+}
+
+// die rolls a single die with the given number of sides.
+func (r *Roller) die(sides int) int {
+	v := 0
+	if w, ok := r.weighted[sides]; ok {
+		v = w.roll(r)
+	} else {
+		v = r.intn(sides) + 1
+	}
+	if r.stats != nil {
+		r.stats.record(sides, v)
+	}
+	return v
+}
+
+// Roll rolls d.
+func (r *Roller) Roll(d *Dice) *Result {
+	res, _ := r.RollContext(context.Background(), d)
+	return res
+}
+
+// RollContext rolls d, stopping early with ctx's error if ctx is done before
+// every die has been rolled. Observers see only completed results.
+func (r *Roller) RollContext(ctx context.Context, d *Dice) (*Result, error) {
+	return r.roll(ctx, d, nil)
+}
+
+// RollWithObserver rolls d, calling onDie with the index and value of every
+// die as it lands, in roll order, before the result is complete. Pools are
+// never summarized while observed, however large.
+func (r *Roller) RollWithObserver(d *Dice, onDie func(i, value int)) *Result {
+	res, _ := r.roll(context.Background(), d, onDie)
+	return res
+}
+
+func (r *Roller) roll(ctx context.Context, d *Dice, onDie func(i, value int)) (*Result, error) {
+	res, err := r.rollContext(ctx, d, onDie)
+	if err != nil {
+		return nil, err
+	}
+	r.applyFloor(res, d)
+	r.applyPercentile(res, d)
+	if r.nudge != 0 && len(d.Labels) == 0 {
+		applyNudge(res, d, r.nudge)
+	}
+	for _, o := range r.observers {
+		o.Observe(res)
+	}
+	return res, nil
+}
+
+func (r *Roller) rollContext(ctx context.Context, d *Dice, onDie func(i, value int)) (*Result, error) {
+	if onDie == nil && d.Explode == NoExplosion && d.Reroll == nil && len(d.Labels) == 0 && len(d.FaceValues) == 0 && SummarizeAbove > 0 && d.Count > SummarizeAbove {
+		if res, ok, err := r.rollSummarized(ctx, d); ok || err != nil {
+			return res, err
+		}
+	}
+
+	rolls := make([]int, 0, d.Count)
+	var (
+		exploded   []Explosion
+		compounded []Compound
+		penetrated []Compound
+		rerolled   []Reroll
+		chains     []RerollChain
+	)
+	land := func(v int) {
+		v = d.face(v)
+		if onDie != nil {
+			onDie(len(rolls), v)
+		}
+		rolls = append(rolls, v)
+	}
+	for i := 0; i < d.Count; i++ {
+		if i%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		v := r.die(d.Sides)
+		switch {
+		case d.Reroll == nil || !d.Reroll.Matches(v):
+		case d.RerollRecursive:
+			chain := []int{v}
+			for d.Reroll.Matches(v) && len(chain) <= RerollLimit {
+				v = r.die(d.Sides)
+				chain = append(chain, v)
+			}
+			chains = append(chains, RerollChain{Index: len(rolls), Chain: chain})
+		default:
+			first := v
+			v = r.die(d.Sides)
+			rerolled = append(rerolled, Reroll{Index: len(rolls), Original: first, Value: v})
+		}
+		switch d.Explode {
+		case Exploding:
+			at := len(rolls)
+			land(v)
+			n := 0
+			for ; v == d.Sides && n < ExplodeLimit; n++ {
+				v = r.die(d.Sides)
+				land(v)
+			}
+			if n > 0 {
+				exploded = append(exploded, Explosion{Index: at, Count: n})
+			}
+		case Compounding, Penetrating:
+			chain := []int{v}
+			total := v
+			for v == d.Sides && len(chain) <= ExplodeLimit {
+				v = r.die(d.Sides)
+				chain = append(chain, v)
+				total += v
+				if d.Explode == Penetrating {
+					total--
+				}
+			}
+			switch {
+			case len(chain) == 1:
+			case d.Explode == Penetrating:
+				penetrated = append(penetrated, Compound{Index: len(rolls), Chain: chain})
+			default:
+				compounded = append(compounded, Compound{Index: len(rolls), Chain: chain})
+			}
+			land(total)
+		default:
+			land(v)
+		}
+	}
+
+	res := d.result(rolls)
+	res.Exploded, res.Compounded, res.Penetrated = exploded, compounded, penetrated
+	res.RerolledOnce, res.RerollChains = rerolled, chains
+	return res, nil
+}
+
+// result applies d's modifier, bonus and threshold to rolls, or for labeled
+// dice looks up and tallies their faces.
+func (d *Dice) result(rolls []int) *Result {
+	if len(d.Labels) > 0 {
+		return d.labeledResult(rolls)
+	}
+	adjusted := rolls
+	if d.clamped() {
+		adjusted = make([]int, len(rolls))
+		for i, v := range rolls {
+			adjusted[i] = d.adjust(v)
+		}
+	}
+	kept, dropped := applyRollModifier(adjusted, d.Modifier, d.ModifierCount)
+	res := &Result{
+		Expression:  d.String(),
+		Sides:       d.Sides,
+		Rolls:       rolls,
+		Kept:        kept,
+		Dropped:     dropped,
+		Bonus:       d.Bonus,
+		Total:       d.Bonus,
+		SuccessPool: d.Success != nil,
+		Fudge:       d.fudge(),
+	}
+	if d.clamped() {
+		res.AdjustedRolls = adjusted
+	}
+	for _, k := range kept {
+		res.Total += k
+		res.countSuccess(d, k, 1)
+	}
+	if res.SuccessPool {
+		res.netSuccesses()
+	}
+	return res
+}
+
+// countSuccess counts a kept die v of d as a success or failure, adding by
+// to the count, which is -1 to take it back.
+func (res *Result) countSuccess(d *Dice, v, by int) {
+	switch {
+	case d.Success != nil && d.Success.Matches(v):
+		res.Successes += by
+	case d.Failure != nil && d.Failure.Matches(v):
+		res.Failures += by
+	}
+}
+
+// netSuccesses totals a success pool from its counted successes and
+// failures.
+func (res *Result) netSuccesses() {
+	res.NetSuccesses = res.Successes - res.Failures
+	res.Botch = res.Successes == 0 && res.Failures > 0
+	res.Total = res.NetSuccesses + res.Bonus
+}
+
+func (d *Dice) labeledResult(rolls []int) *Result {
+	res := &Result{
+		Expression: d.String(),
+		Sides:      d.Sides,
+		Rolls:      rolls,
+		Kept:       rolls,
+		Faces:      make([]string, len(rolls)),
+	}
+	index := make(map[string]int, len(d.Labels))
+	for _, label := range d.Labels {
+		if _, ok := index[label]; !ok {
+			index[label] = len(res.Tally)
+			res.Tally = append(res.Tally, FaceCount{Label: label})
+		}
+	}
+	for i, v := range rolls {
+		res.Faces[i] = d.Labels[v-1]
+		res.Tally[index[res.Faces[i]]].Count++
+	}
+	return res
+}
+
+// applyFloor raises the kept dice of a d20 result to the Roller's floor.
+func (r *Roller) applyFloor(res *Result, d *Dice) {
+	if r.d20Floor == 0 || d.Sides != 20 || res.Summarized || len(d.Labels) > 0 {
+		return
+	}
+	for i, k := range res.Kept {
+		if k >= r.d20Floor {
+			continue
+		}
+		res.Substituted = append(res.Substituted, Substitution{Original: k, Value: r.d20Floor})
+		res.Kept[i] = r.d20Floor
+		res.Total += r.d20Floor - k
+		res.countSuccess(d, k, -1)
+		res.countSuccess(d, r.d20Floor, 1)
+	}
+	if res.SuccessPool {
+		res.netSuccesses()
+	}
+}
+
+// applyPercentile splits the rolls of a d100 result into tens and ones.
+func (r *Roller) applyPercentile(res *Result, d *Dice) {
+	if !r.percent || d.Sides != 100 || res.Summarized || len(d.Labels) > 0 || d.Explode == Compounding || d.Explode == Penetrating {
+		return
+	}
+	res.Percentiles = make([]PercentileRoll, len(res.Rolls))
+	for i, v := range res.Rolls {
+		res.Percentiles[i] = splitPercentile(v)
+	}
+}
+
+// applyNudge shifts res's total by sigma standard deviations of d. Pools too
+// large for an exact deviation use that of their kept dice rolled plainly.
+func applyNudge(res *Result, d *Dice, sigma float64) {
+	sd, err := d.StdDev()
+	if err != nil {
+		sd, _ = (&Dice{Count: d.keptCount(), Sides: d.Sides, Success: d.Success, Failure: d.Failure}).StdDev()
+	}
+	res.Nudge = &Nudge{Sigma: sigma, Unbiased: res.Total}
+	res.Total += int(math.Round(sigma * sd))
+	res.Total = min(max(res.Total, d.Min()), d.Max())
+}
+
+// WeightedDie is a die whose faces come up in proportion to their weights.
+type WeightedDie struct {
+	// cumulative[i] is the summed weight of faces 1 through i+1.
+	cumulative []int
+}
+
+// NewWeightedDie returns a die with one face per weight, weights[0] being the
+// weight of face 1. Every weight must be positive.
+func NewWeightedDie(weights []int) (*WeightedDie, error) {
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("weighted die needs at least one face")
+	}
+	w := &WeightedDie{cumulative: make([]int, len(weights))}
+	total := 0
+	for i, weight := range weights {
+		if weight <= 0 {
+			return nil, fmt.Errorf("weight %d of face %d must be positive", weight, i+1)
+		}
+		total += weight
+		w.cumulative[i] = total
+	}
+	return w, nil
+}
+
+// Sides returns the number of faces of the die.
+func (w *WeightedDie) Sides() int {
+	return len(w.cumulative)
+}
+
+// roll picks a face by drawing uniformly from the total weight, which keeps
+// the face probabilities exact.
+func (w *WeightedDie) roll(r *Roller) int {
+	n := r.intn(w.cumulative[len(w.cumulative)-1])
+	lo, hi := 0, len(w.cumulative)-1
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if w.cumulative[mid] > n {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lo + 1
+}
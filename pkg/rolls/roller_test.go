@@ -0,0 +1,210 @@
+package rolls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	oldrand "math/rand"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestRollContextCancel cancels a roll of ten million dice a few
+// milliseconds in and checks it stops with the context's error long before
+// it could have finished, summarized or rolled die by die.
+func TestRollContextCancel(t *testing.T) {
+	if runtime.GOOS == "js" {
+		t.Skip("js runs the deadline's timer on the same thread as the roll, so it cannot fire mid-roll")
+	}
+	for _, expr := range []string{"10000000d6", "10000000d6!", "10000000d20+10000000d4"} {
+		e, err := ParseExpression(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		start := time.Now()
+		res, err := NewRoller(WithSeed(1)).RollExpressionContext(ctx, e)
+		elapsed := time.Since(start)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) || res != nil {
+			t.Errorf("RollExpressionContext(%s) = %v, %v, want %v", expr, res, err, context.DeadlineExceeded)
+		}
+		if elapsed > 500*time.Millisecond {
+			t.Errorf("RollExpressionContext(%s) took %s to notice the cancellation", expr, elapsed)
+		}
+	}
+}
+
+func TestNewWeightedDieErrors(t *testing.T) {
+	for _, weights := range [][]int{nil, {}, {1, 0, 1}, {2, -1}} {
+		if _, err := NewWeightedDie(weights); err == nil {
+			t.Errorf("NewWeightedDie(%v) succeeded, want an error", weights)
+		}
+	}
+}
+
+// TestWeightedDie rolls a d6 whose 1 is twice as likely as each other face
+// and checks each face comes up as often as its weight says, by a
+// chi-squared test at the 0.001 level.
+func TestWeightedDie(t *testing.T) {
+	weights := []int{2, 1, 1, 1, 1, 1}
+	w, err := NewWeightedDie(weights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRoller(WithSeed(42), WithWeightedDie(w))
+	counts := make([]int, len(weights))
+	const rolls = 70000
+	for i := 0; i < rolls/500; i++ {
+		for _, v := range r.Roll(&Dice{Count: 500, Sides: 6}).Rolls {
+			counts[v-1]++
+		}
+	}
+	chi2 := 0.0
+	for face, weight := range weights {
+		want := float64(rolls*weight) / 7
+		chi2 += (float64(counts[face]) - want) * (float64(counts[face]) - want) / want
+	}
+	// 20.52 is the critical value for five degrees of freedom.
+	if chi2 > 20.52 {
+		t.Errorf("weighted d6 rolled faces %v in %d rolls, chi-squared %.2f against weights %v", counts, rolls, chi2, weights)
+	}
+
+	for _, v := range r.Roll(&Dice{Count: 500, Sides: 8}).Rolls {
+		if v < 1 || v > 8 {
+			t.Fatalf("d8 rolled %d beside a weighted d6", v)
+		}
+	}
+}
+
+// sequence returns n d20s rolled by r.
+func sequence(r *Roller, n int) []int {
+	return r.Roll(&Dice{Count: n, Sides: 20}).Rolls
+}
+
+func TestSplit(t *testing.T) {
+	children := NewRoller(WithSeed(7)).Split(4)
+	again := NewRoller(WithSeed(7)).Split(4)
+	seen := make(map[string]int)
+	for i := range children {
+		got := sequence(children[i], 50)
+		if want := sequence(again[i], 50); !slices.Equal(got, want) {
+			t.Errorf("child %d of seed 7 rolled %v, then %v", i, got, want)
+		}
+		key := fmt.Sprint(got)
+		if j, ok := seen[key]; ok {
+			t.Errorf("children %d and %d of seed 7 rolled the same %v", j, i, got)
+		}
+		seen[key] = i
+	}
+
+	parent := NewRoller(WithSeed(7))
+	parent.Split(4)
+	if got, want := sequence(parent, 50), sequence(NewRoller(WithSeed(7)), 50); !slices.Equal(got, want) {
+		t.Errorf("Split advanced its seeded parent: rolled %v, want %v", got, want)
+	}
+	if a, b := sequence(NewRoller(WithSeed(8)).Split(1)[0], 50), sequence(children[0], 50); slices.Equal(a, b) {
+		t.Errorf("the first children of seeds 7 and 8 both rolled %v", a)
+	}
+}
+
+func TestSplitKeepsOptions(t *testing.T) {
+	parent := NewRoller(WithSeed(1), WithD20Floor(20), WithPercentileDice(), WithNudge(1))
+	for i, child := range parent.Split(3) {
+		if res := child.Roll(&Dice{Count: 1, Sides: 20}); res.Total != 20 {
+			t.Errorf("child %d rolled %d on a d20 floored at 20", i, res.Total)
+		}
+		if res := child.Roll(&Dice{Count: 1, Sides: 100}); len(res.Percentiles) != 1 {
+			t.Errorf("child %d rolled a d100 without its percentile dice: %+v", i, res)
+		}
+		if res := child.Roll(&Dice{Count: 2, Sides: 6}); res.Nudge == nil {
+			t.Errorf("child %d rolled 2d6 without its nudge", i)
+		}
+	}
+}
+
+// TestCompoundingKeep rolls compounding dice with keep and drop modifiers at
+// fixed seeds and checks they choose among the compounded totals, not the
+// dice that make them up.
+func TestCompoundingKeep(t *testing.T) {
+	tests := []struct {
+		expr        string
+		seed        int64
+		rolls, kept []int
+		total       int
+	}{
+		{"4d3!!kh2", 2, []int{1, 1, 2, 10}, []int{2, 10}, 12},
+		{"4d3!!dl1", 3, []int{5, 8, 5, 1}, []int{5, 8, 5}, 18},
+		{"4d3!!dh1", 2, []int{1, 1, 2, 10}, []int{1, 1, 2}, 4},
+		{"5d4!!kl2", 3, []int{9, 3, 9, 1, 1}, []int{1, 1}, 2},
+		{"3d6!!kh1", 3, []int{5, 4, 16}, []int{16}, 16},
+		{"3d6!!kh1+2", 1, []int{4, 3, 8}, []int{8}, 10},
+	}
+	for _, tt := range tests {
+		d, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := NewRoller(WithSeed(tt.seed)).Roll(d)
+		if !slices.Equal(res.Rolls, tt.rolls) || !slices.Equal(res.Kept, tt.kept) || res.Total != tt.total {
+			t.Errorf("%s at seed %d = rolls %v, kept %v, total %d, want %v, %v, %d", tt.expr, tt.seed, res.Rolls, res.Kept, res.Total, tt.rolls, tt.kept, tt.total)
+		}
+		if len(res.Compounded) == 0 {
+			t.Errorf("%s at seed %d compounded no dice", tt.expr, tt.seed)
+		}
+		got, all := append(slices.Clone(res.Kept), res.Dropped...), slices.Clone(res.Rolls)
+		slices.Sort(got)
+		slices.Sort(all)
+		if !slices.Equal(got, all) {
+			t.Errorf("%s at seed %d kept %v and dropped %v of %v", tt.expr, tt.seed, res.Kept, res.Dropped, res.Rolls)
+		}
+	}
+}
+
+// TestSeededRolls pins the rolls of each kind of source, so a change to how
+// seeds key ChaCha8 or how legacy sources are adapted shows up here before
+// it changes anyone's reproducible rolls.
+func TestSeededRolls(t *testing.T) {
+	tests := []struct {
+		name string
+		r    *Roller
+		want []int
+	}{
+		{"WithSeed(42)", NewRoller(WithSeed(42)), []int{9, 4, 11, 1, 2, 14, 10, 11, 7, 18}},
+		{"WithSource(PCG(42, 54))", NewRoller(WithSource(rand.NewPCG(42, 54))), []int{13, 8, 9, 6, 6, 13, 15, 11, 1, 8}},
+		{"WithLegacySource(42)", NewRoller(WithLegacySource(oldrand.NewSource(42))), []int{14, 11, 7, 3, 11, 4, 9, 14, 14, 17}},
+	}
+	for _, tt := range tests {
+		if got := sequence(tt.r, 10); !slices.Equal(got, tt.want) {
+			t.Errorf("%s rolled %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSetRandomSource(t *testing.T) {
+	defer SetRandomSource(nil)
+	want := []int{14, 11, 7, 3, 11, 4, 9, 14, 14, 17}
+	SetRandomSource(oldrand.NewSource(42))
+	if got := (&Dice{Count: 10, Sides: 20}).Roll().Rolls; !slices.Equal(got, want) {
+		t.Errorf("Dice.Roll after SetRandomSource(42) rolled %v, want %v", got, want)
+	}
+
+	// A source without Uint64 is adapted from two Int63 draws.
+	SetRandomSource(int63Source{oldrand.NewSource(42)})
+	first := (&Dice{Count: 10, Sides: 20}).Roll().Rolls
+	SetRandomSource(int63Source{oldrand.NewSource(42)})
+	if again := (&Dice{Count: 10, Sides: 20}).Roll().Rolls; !slices.Equal(first, again) {
+		t.Errorf("the same Int63 source rolled %v, then %v", first, again)
+	}
+}
+
+// int63Source hides the Uint64 method of a math/rand source.
+type int63Source struct {
+	src oldrand.Source
+}
+
+func (s int63Source) Int63() int64    { return s.src.Int63() }
+func (s int63Source) Seed(seed int64) { s.src.Seed(seed) }
@@ -2,21 +2,128 @@ package rolls
 
 import (
 	"flag"
-	"log"
-	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
-func Roll(args []string) {
-	if args[0] == "age" {
-		if err := ageGen(flag.Args()); err != nil {
-			log.Println(err)
-		}
-		return
+// commands are the subcommands Roll dispatches to by name, each given the
+// arguments after it. Anything else is rolled as expressions.
+var commands map[string]func(args []string) error
+
+func init() {
+	// commands is filled here rather than where it is declared, as roll
+	// capabilities lists it.
+	commands = map[string]func([]string) error{
+		"age":           func([]string) error { return ageGen(flag.Args()) },
+		"hp":            hpGen,
+		"encounter":     encounterGen,
+		"days":          daysGen,
+		"diff":          diffGen,
+		"history":       historyGen,
+		"chance":        chanceGen,
+		"macro":         macroGen,
+		"opposed":       opposedGen,
+		"stepdown":      stepDownGen,
+		"pool":          poolGen,
+		"pbta":          pbtaGen,
+		"forward":       forwardGen,
+		"hold":          holdGen,
+		"odd":           oddGen,
+		"stress":        stressGen,
+		"traveller":     travellerGen,
+		"bw":            bwGen,
+		"ore":           oreGen,
+		"set":           setGen,
+		"passive":       passiveGen,
+		"aoe":           aoeGen,
+		"reveal":        revealGen,
+		"deck":          deckGen,
+		"saves":         savesGen,
+		"contest":       contestGen,
+		"monster":       monsterGen,
+		"dialects":      dialectsGen,
+		"initiative":    initiativeGen,
+		"turn":          turnGen,
+		"condition":     conditionGen,
+		"reroll":        rerollGen,
+		"sv":            svGen,
+		"dpr":           dprGen,
+		"check":         checkGen,
+		"config":        configGen,
+		"concentration": concentrationGen,
+		"verify":        verifyGen,
+		"batch":         batchGen,
+		"delay":         delayGen,
+		"fmt":           fmtGen,
+		"capabilities":  capabilitiesGen,
 	}
+}
 
-	normGen(args)
+// Roll runs the subcommand named by args[0], or rolls args as expressions,
+// and returns what went wrong for the caller to report. An error wrapping
+// ErrMacroTest should fail the shell.
+func Roll(args []string) error {
+	switch gen, ok := commands[args[0]]; {
+	case ok:
+		return gen(args[1:])
+	case strings.Contains(args[0], "?"):
+		return conditionalGen(args)
+	case isChance(args[0]):
+		return chanceGen(args)
+	}
+	return normGen(args)
 }
 
 func result(sides int) int {
-	return rand.Intn(sides) + 1
+	return defaultRoller.die(sides)
+}
+
+// parseArgs parses fs from args, allowing flags to appear between the
+// positional arguments, which are returned in order, after applying the
+// defaults the config file and environment set for them. Negative numbers and
+// dice groups, such as -2 or -1d4, are treated as positional rather than as
+// flags.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var flags, positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			positional = append(positional, args[i+1:]...)
+			i = len(args)
+		case strings.HasPrefix(arg, "-") && len(arg) > 1 && !isNumber(arg) && !negativeDice.MatchString(arg):
+			flags = append(flags, arg)
+			name := strings.TrimLeft(arg, "-")
+			if strings.Contains(name, "=") {
+				continue
+			}
+			if f := fs.Lookup(name); f != nil && !isBoolFlag(f) && i+1 < len(args) {
+				i++
+				flags = append(flags, args[i])
+			}
+		default:
+			positional = append(positional, arg)
+		}
+	}
+
+	if err := ApplyDefaults(fs, fs.Name()); err != nil {
+		return nil, err
+	}
+	if err := fs.Parse(flags); err != nil {
+		return nil, err
+	}
+	return positional, nil
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	bf, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && bf.IsBoolFlag()
+}
+
+var negativeDice = regexp.MustCompile(`^-\d+d\d`)
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
 }
@@ -0,0 +1,347 @@
+// Package rolltest checks the invariants every rolls.Result keeps, for
+// property tests of package rolls and of programs that embed it.
+//
+// A typical property test rolls many random dice at a fixed seed:
+//
+//	rng := rand.New(rand.NewPCG(1, 2))
+//	roller := rolls.NewRoller(rolls.WithSeed(1))
+//	for i := 0; i < 10000; i++ {
+//		d := rolltest.RandomDice(rng)
+//		if err := rolltest.Check(d, roller.Roll(d)); err != nil {
+//			t.Fatal(err)
+//		}
+//	}
+package rolltest
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/Domo929/roll/pkg/rolls"
+)
+
+// Check runs every check on r, a result of rolling d.
+func Check(d *rolls.Dice, r *rolls.Result) error {
+	if err := CheckBounds(d, r); err != nil {
+		return err
+	}
+	if err := CheckTotal(r); err != nil {
+		return err
+	}
+	return CheckPartition(r)
+}
+
+// CheckBounds checks that r rolled d.Count dice, plus any its exploding
+// dice added, each from 1 to d.Sides or one of d.FaceValues, that every
+// compounded or penetrating die adds up its chain, that only dice matching
+// d.Reroll were rerolled, recursive rerolls until they stopped matching,
+// that dice below d.MinPerDie or above d.MaxPerDie count as it, that a
+// success pool counted its kept dice matching d.Success and d.Failure and
+// netted them, and that any percentile dice read as the rolls they split.
+// Summarized results are checked through their summary.
+func CheckBounds(d *rolls.Dice, r *rolls.Result) error {
+	if r.Summarized {
+		s := r.Summary
+		switch {
+		case s == nil:
+			return fmt.Errorf("%s: summarized without a summary", r.Expression)
+		case s.Count != d.Count:
+			return fmt.Errorf("%s: summary counts %d dice, want %d", r.Expression, s.Count, d.Count)
+		case s.Min < 1 || s.Max > d.Sides || s.Min > s.Max:
+			return fmt.Errorf("%s: summary range %d-%d is outside 1-%d", r.Expression, s.Min, s.Max, d.Sides)
+		}
+		return nil
+	}
+	want := d.Count
+	for _, ex := range r.Exploded {
+		if d.Explode != rolls.Exploding || ex.Index+ex.Count >= len(r.Rolls) || r.Rolls[ex.Index] != d.Sides {
+			return fmt.Errorf("%s: explosion %+v does not follow a highest face", r.Expression, ex)
+		}
+		want += ex.Count
+	}
+	if len(r.Rolls) != want {
+		return fmt.Errorf("%s: rolled %d dice, want %d", r.Expression, len(r.Rolls), want)
+	}
+
+	// A compounded die is the sum of its chain, so only the chain's rolls
+	// are faces.
+	lowest, highest := 1, d.Sides
+	if len(d.FaceValues) > 0 {
+		lowest, highest = slices.Min(d.FaceValues), slices.Max(d.FaceValues)
+	}
+	chains := make(map[int]bool, len(r.Compounded))
+	for _, c := range append(r.Compounded, r.Penetrated...) {
+		if err := checkChain(d, r, c); err != nil {
+			return err
+		}
+		chains[c.Index] = true
+		highest = d.Sides * (rolls.ExplodeLimit + 1)
+	}
+	for i, v := range r.Rolls {
+		switch {
+		case chains[i]:
+		case len(d.FaceValues) > 0 && !slices.Contains(d.FaceValues, v):
+			return fmt.Errorf("%s: die %d is not a face of %v", r.Expression, v, d.FaceValues)
+		case v < lowest || v > d.Sides:
+			return fmt.Errorf("%s: die %d is outside %d-%d", r.Expression, v, lowest, d.Sides)
+		}
+	}
+	if (r.AdjustedRolls != nil) != (d.MinPerDie > 0 || d.MaxPerDie > 0) || r.AdjustedRolls != nil && len(r.AdjustedRolls) != len(r.Rolls) {
+		return fmt.Errorf("%s: adjusted rolls %v do not fit rolls %v", r.Expression, r.AdjustedRolls, r.Rolls)
+	}
+	for i, v := range r.AdjustedRolls {
+		want := max(r.Rolls[i], d.MinPerDie)
+		if d.MaxPerDie > 0 {
+			want = min(want, d.MaxPerDie)
+		}
+		if v != want {
+			return fmt.Errorf("%s: die %d counts as %d, want %d", r.Expression, r.Rolls[i], v, want)
+		}
+	}
+	for _, dice := range [][]int{r.Kept, r.Dropped} {
+		for _, v := range dice {
+			if v < lowest || v > highest {
+				return fmt.Errorf("%s: die %d is outside %d-%d", r.Expression, v, lowest, highest)
+			}
+		}
+	}
+	if r.Fudge != slices.Equal(d.FaceValues, rolls.FudgeFaces) {
+		return fmt.Errorf("%s: fudge is %t for faces %v", r.Expression, r.Fudge, d.FaceValues)
+	}
+	for _, re := range r.RerolledOnce {
+		if d.Reroll == nil || d.RerollRecursive || !d.Reroll.Matches(re.Original) || re.Index >= len(r.Rolls) || re.Value < 1 || re.Value > d.Sides {
+			return fmt.Errorf("%s: reroll %+v does not fit the roll", r.Expression, re)
+		}
+	}
+	for _, c := range r.RerollChains {
+		if err := checkRerollChain(d, r, c); err != nil {
+			return err
+		}
+	}
+	for i, p := range r.Percentiles {
+		v := p.Tens + p.Ones
+		if v == 0 {
+			v = 100
+		}
+		if len(r.Percentiles) != len(r.Rolls) || r.Rolls[i] != v {
+			return fmt.Errorf("%s: percentile dice %02d + %d do not read as roll %d", r.Expression, p.Tens, p.Ones, i+1)
+		}
+	}
+	if r.SuccessPool != (d.Success != nil) {
+		return fmt.Errorf("%s: success pool is %t, want %t", r.Expression, r.SuccessPool, d.Success != nil)
+	}
+	if d.Success != nil {
+		successes, failures := 0, 0
+		for _, k := range r.Kept {
+			switch {
+			case d.Success.Matches(k):
+				successes++
+			case d.Failure != nil && d.Failure.Matches(k):
+				failures++
+			}
+		}
+		switch {
+		case r.Successes != successes || r.Failures != failures:
+			return fmt.Errorf("%s: counted %d successes and %d failures, want %d and %d", r.Expression, r.Successes, r.Failures, successes, failures)
+		case r.NetSuccesses != successes-failures:
+			return fmt.Errorf("%s: nets %d successes, want %d", r.Expression, r.NetSuccesses, successes-failures)
+		case r.Botch != (successes == 0 && failures > 0):
+			return fmt.Errorf("%s: botch is %t with %d successes and %d failures", r.Expression, r.Botch, successes, failures)
+		}
+	}
+	return nil
+}
+
+// checkRerollChain checks that c is a recursive reroll of d that stopped as
+// soon as a roll no longer matched, or at RerollLimit.
+func checkRerollChain(d *rolls.Dice, r *rolls.Result, c rolls.RerollChain) error {
+	if d.Reroll == nil || !d.RerollRecursive || c.Index >= len(r.Rolls) || len(c.Chain) < 2 || len(c.Chain) > rolls.RerollLimit+1 {
+		return fmt.Errorf("%s: reroll chain %+v does not fit the roll", r.Expression, c)
+	}
+	last := len(c.Chain) - 1
+	for i, v := range c.Chain {
+		if v < 1 || v > d.Sides || i < last && !d.Reroll.Matches(v) || i == last && last < rolls.RerollLimit && d.Reroll.Matches(v) {
+			return fmt.Errorf("%s: reroll chain %+v does not stop at the first roll that no longer matches", r.Expression, c)
+		}
+	}
+	if d.Explode == rolls.NoExplosion && r.Rolls[c.Index] != c.Chain[last] {
+		return fmt.Errorf("%s: reroll chain %+v ends on %d, not %d", r.Expression, c, c.Chain[last], r.Rolls[c.Index])
+	}
+	return nil
+}
+
+// checkChain checks that c is a compounding or penetrating chain of d that
+// adds up to its die in r: every roll but the last on the highest face.
+func checkChain(d *rolls.Dice, r *rolls.Result, c rolls.Compound) error {
+	if d.Explode != rolls.Compounding && d.Explode != rolls.Penetrating || c.Index >= len(r.Rolls) || len(c.Chain) < 2 || len(c.Chain) > rolls.ExplodeLimit+1 {
+		return fmt.Errorf("%s: compound %+v does not fit the roll", r.Expression, c)
+	}
+	sum := 0
+	for i, v := range c.Chain {
+		if v < 1 || v > d.Sides || i < len(c.Chain)-1 && v != d.Sides {
+			return fmt.Errorf("%s: compound %+v does not roll again only on a highest face", r.Expression, c)
+		}
+		sum += v
+		if i > 0 && d.Explode == rolls.Penetrating {
+			sum--
+		}
+	}
+	if sum != r.Rolls[c.Index] {
+		return fmt.Errorf("%s: compound %+v adds up to %d, not %d", r.Expression, c, sum, r.Rolls[c.Index])
+	}
+	return nil
+}
+
+// CheckTotal checks that r's total is the sum of its kept dice and bonus,
+// before any nudge, or for a success pool its net successes and bonus. The
+// total of a result with dice groups must be the signed sum of its groups'
+// totals and its bonus. A scaled group is checked before its scaling, which
+// must give its Total.
+func CheckTotal(r *rolls.Result) error {
+	total := r.Total
+	if r.Nudge != nil {
+		total = r.Nudge.Unbiased
+	}
+	if len(r.Scale) > 0 {
+		scaled := r.Unscaled
+		for _, s := range r.Scale {
+			scaled = s.Apply(scaled)
+		}
+		if scaled != total {
+			return fmt.Errorf("%s: total %d, want %d scaled by %v", r.Expression, total, r.Unscaled, r.Scale)
+		}
+		total = r.Unscaled
+	}
+	want := r.Bonus
+	if len(r.Groups) > 0 {
+		for _, g := range r.Groups {
+			if err := CheckTotal(g); err != nil {
+				return err
+			}
+			if g.Negative {
+				want -= g.Total
+			} else {
+				want += g.Total
+			}
+		}
+	} else {
+		switch {
+		case r.SuccessPool:
+			want += r.Successes - r.Failures
+		case r.Summarized:
+			return nil
+		default:
+			for _, k := range r.Kept {
+				want += k
+			}
+		}
+	}
+	if total != want {
+		return fmt.Errorf("%s: total %d, want %d", r.Expression, total, want)
+	}
+	return nil
+}
+
+// CheckPartition checks that r's kept and dropped dice are exactly its
+// rolls, counted as multisets, with substituted dice counted as they were
+// rolled and raised dice as they were raised. A result with dice groups
+// must hold every group's rolls, in order, as its own. Summarized results
+// do not keep their rolls and always pass.
+func CheckPartition(r *rolls.Result) error {
+	if len(r.Groups) > 0 {
+		var all []int
+		for _, g := range r.Groups {
+			if err := CheckPartition(g); err != nil {
+				return err
+			}
+			all = append(all, g.Rolls...)
+		}
+		if !slices.Equal(all, r.Rolls) {
+			return fmt.Errorf("%s: rolls %v are not its groups' rolls %v", r.Expression, r.Rolls, all)
+		}
+		return nil
+	}
+	if r.Summarized {
+		return nil
+	}
+
+	kept := slices.Clone(r.Kept)
+	for _, sub := range r.Substituted {
+		i := slices.Index(kept, sub.Value)
+		if i < 0 {
+			return fmt.Errorf("%s: substituted value %d is not kept", r.Expression, sub.Value)
+		}
+		kept[i] = sub.Original
+	}
+
+	got := append(kept, r.Dropped...)
+	slices.Sort(got)
+	want := slices.Clone(r.Rolls)
+	if r.AdjustedRolls != nil {
+		want = slices.Clone(r.AdjustedRolls)
+	}
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		return fmt.Errorf("%s: kept %v and dropped %v are not rolls %v", r.Expression, r.Kept, r.Dropped, r.Rolls)
+	}
+	return nil
+}
+
+var sides = []int{2, 4, 6, 8, 10, 12, 20, 100}
+
+// RandomDice returns valid random dice drawn from rng: up to twelve dice of
+// a common size, a keep modifier a third of the time, a bonus from -5 to 5,
+// exploding, compounding or penetrating a quarter of the time, rerolling low
+// dice, once or recursively, a sixth of the time, a minimum and a maximum
+// per die each an eighth of the time and a success threshold a quarter of
+// the time, a third of those counting ones as failures. One in twelve are
+// Fudge dice, which neither explode, reroll nor take a minimum or maximum.
+func RandomDice(rng *rand.Rand) *rolls.Dice {
+	d := &rolls.Dice{
+		Count: 1 + rng.IntN(12),
+		Sides: sides[rng.IntN(len(sides))],
+		Bonus: rng.IntN(11) - 5,
+	}
+	if rng.IntN(3) == 0 {
+		d.Modifier = []rolls.Modifier{rolls.KeepHighest, rolls.KeepLowest, rolls.KeepMiddle, rolls.DropMiddle}[rng.IntN(4)]
+		d.ModifierCount = 1 + rng.IntN(d.Count)
+		if d.Modifier == rolls.DropMiddle {
+			// Dropping the middle drops at least one die and keeps one.
+			if d.Count == 1 {
+				d.Modifier = rolls.KeepMiddle
+			} else {
+				d.ModifierCount = 1 + rng.IntN(d.Count-1)
+			}
+		}
+	}
+	switch rng.IntN(12) {
+	case 0:
+		d.Explode = rolls.Exploding
+	case 1:
+		d.Explode = rolls.Compounding
+	case 2:
+		d.Explode = rolls.Penetrating
+	}
+	if rng.IntN(12) == 0 {
+		d.Sides, d.FaceValues, d.Explode = len(rolls.FudgeFaces), rolls.FudgeFaces, rolls.NoExplosion
+	}
+	if rng.IntN(6) == 0 && len(d.FaceValues) == 0 {
+		d.Reroll = &rolls.Threshold{Op: []string{"=", "<="}[rng.IntN(2)], Target: 1 + rng.IntN(min(3, d.Sides-1))}
+		d.RerollRecursive = rng.IntN(2) == 0
+	}
+	if rng.IntN(8) == 0 && len(d.FaceValues) == 0 {
+		d.MinPerDie = 1 + rng.IntN(min(3, d.Sides))
+	}
+	if rng.IntN(8) == 0 && len(d.FaceValues) == 0 {
+		d.MaxPerDie = max(d.MinPerDie, d.Sides-rng.IntN(min(3, d.Sides)))
+	}
+	if rng.IntN(4) == 0 {
+		ops := []string{">=", "<=", ">", "<", "="}
+		d.Success = &rolls.Threshold{Op: ops[rng.IntN(len(ops))], Target: 1 + rng.IntN(d.Sides)}
+		if rng.IntN(3) == 0 && !d.Success.Matches(1) {
+			d.Failure = &rolls.Threshold{Op: "=", Target: 1}
+		}
+	}
+	return d
+}
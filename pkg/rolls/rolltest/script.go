@@ -0,0 +1,67 @@
+package rolltest
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// Script is a random source that makes a Roller roll chosen faces, for tests
+// that check exact results:
+//
+//	src := rolltest.NewScript().Roll(20, 17).Roll(4, 3)
+//	res := rolls.NewRoller(rolls.WithSource(src)).RollExpression(e) // 1d20+1d4
+//
+// Each draw the Roller makes for a die of n faces must be scripted with
+// Roll(n, face), in the order the dice are rolled: rerolls and exploding
+// dice draw again, and a d100 is a single draw even when read as a
+// percentile pair. Fudge dice draw a face of three, where 1, 2 and 3 land
+// on -, 0 and +. Running out of script panics, listing the scripted dice.
+type Script struct {
+	draws []uint64
+	dice  []string
+	next  int
+}
+
+// NewScript returns an empty script.
+func NewScript() *Script {
+	return &Script{}
+}
+
+// Roll adds a die of sides faces landing on each of faces, in order, and
+// returns s.
+func (s *Script) Roll(sides int, faces ...int) *Script {
+	for _, face := range faces {
+		if sides < 1 || face < 1 || face > sides {
+			panic(fmt.Sprintf("rolltest: a d%d cannot land on %d", sides, face))
+		}
+		s.draws = append(s.draws, draw(uint64(sides), uint64(face-1)))
+		s.dice = append(s.dice, fmt.Sprintf("%d on a d%d", face, sides))
+	}
+	return s
+}
+
+// Left returns how many scripted dice are still to be rolled, so a test can
+// check a roll drew every die it should have and no more.
+func (s *Script) Left() int {
+	return len(s.draws) - s.next
+}
+
+// Uint64 implements rand.Source.
+func (s *Script) Uint64() uint64 {
+	if s.next == len(s.draws) {
+		panic(fmt.Sprintf("rolltest: the roll drew more than the %d scripted dice, %v", len(s.draws), s.dice))
+	}
+	s.next++
+	return s.draws[s.next-1]
+}
+
+// draw returns the value that math/rand/v2 reduces to k of [0, n): the
+// low bits for a power of two, and otherwise the value whose product with
+// n has k as its high word and a low word too large to be rejected.
+func draw(n, k uint64) uint64 {
+	if n&(n-1) == 0 {
+		return k
+	}
+	x, _ := bits.Div64(k, 1<<63, n)
+	return x
+}
@@ -0,0 +1,41 @@
+package rolltest
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+// TestScript checks every face of dice of many sizes reduces to itself
+// through math/rand/v2, as a Roller draws it.
+func TestScript(t *testing.T) {
+	for _, sides := range []int{1, 2, 3, 4, 6, 7, 8, 10, 12, 20, 100, 1000, 1 << 20, 1<<31 - 1} {
+		faces := []int{1, sides, (sides + 1) / 2}
+		if sides <= 100 {
+			faces = faces[:0]
+			for face := 1; face <= sides; face++ {
+				faces = append(faces, face)
+			}
+		}
+		src := NewScript().Roll(sides, faces...)
+		rng := rand.New(src)
+		for _, face := range faces {
+			if got := rng.IntN(sides) + 1; got != face {
+				t.Fatalf("a d%d scripted to land on %d landed on %d", sides, face, got)
+			}
+		}
+		if src.Left() != 0 {
+			t.Errorf("d%d script has %d dice left", sides, src.Left())
+		}
+	}
+}
+
+func TestScriptRunsOut(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("drawing past the script did not panic")
+		}
+	}()
+	rng := rand.New(NewScript().Roll(6, 3))
+	rng.IntN(6)
+	rng.IntN(6)
+}
@@ -0,0 +1,217 @@
+package rolls_test
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Domo929/roll/pkg/rolls"
+	"github.com/Domo929/roll/pkg/rolls/rolltest"
+)
+
+// TestRandomDice rolls random dice at a fixed seed and checks every result
+// keeps the rolltest invariants.
+func TestRandomDice(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	roller := rolls.NewRoller(rolls.WithSeed(1))
+	for i := 0; i < 20000; i++ {
+		d := rolltest.RandomDice(rng)
+		if err := rolltest.Check(d, roller.Roll(d)); err != nil {
+			t.Fatalf("%s: %v", d, err)
+		}
+	}
+}
+
+// TestRandomExpressions rolls the random expressions that parse within
+// SafeRoll's dice limit and checks the total and partition of each, groups
+// included.
+func TestRandomExpressions(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	roller := rolls.NewRoller(rolls.WithSeed(3))
+	rolled := 0
+	for i := 0; i < 20000; i++ {
+		expr := rolltest.RandomExpression(rng)
+		e, err := rolls.ParseExpression(expr)
+		if err != nil || !withinDice(e, rolls.DefaultSafeMaxDice) {
+			continue
+		}
+		res := roller.RollExpression(e)
+		if err := rolltest.CheckTotal(res); err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		if err := rolltest.CheckPartition(res); err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		rolled++
+	}
+	if rolled < 5000 {
+		t.Errorf("only %d of 20000 random expressions parsed", rolled)
+	}
+}
+
+// withinDice reports whether e rolls at most max dice before any explode,
+// as RandomExpression writes counts far past what a test can roll.
+func withinDice(e *rolls.Expression, max int) bool {
+	for _, d := range e.AllDice() {
+		if d.Count < 0 || d.Count > max {
+			return false
+		}
+		max -= d.Count
+	}
+	return true
+}
+
+// TestScriptedRolls rolls chosen faces and checks the exact result.
+func TestScriptedRolls(t *testing.T) {
+	tests := []struct {
+		expr   string
+		script *rolltest.Script
+		rolls  []int
+		kept   []int
+		total  int
+	}{
+		{"4d6kh3", rolltest.NewScript().Roll(6, 3, 6, 1, 5), []int{3, 6, 1, 5}, []int{3, 6, 5}, 14},
+		{"4d6dl1", rolltest.NewScript().Roll(6, 3, 6, 1, 5), []int{3, 6, 1, 5}, []int{3, 6, 5}, 14},
+		{"2d20kl1+2", rolltest.NewScript().Roll(20, 4, 18), []int{4, 18}, []int{4}, 6},
+		{"1d20+5-1d4", rolltest.NewScript().Roll(20, 17).Roll(4, 3), []int{17, 3}, nil, 19},
+		{"-2d6kh1", rolltest.NewScript().Roll(6, 2, 5), []int{2, 5}, nil, -5},
+		{"1d20-2d6kh1", rolltest.NewScript().Roll(20, 12).Roll(6, 2, 5), []int{12, 2, 5}, nil, 7},
+		{"1d20-2d6kl1+1", rolltest.NewScript().Roll(20, 12).Roll(6, 2, 5), []int{12, 2, 5}, nil, 11},
+		{"(1d6+1)*2", rolltest.NewScript().Roll(6, 4), []int{4}, nil, 10},
+		{"(2d6+1d8)/2", rolltest.NewScript().Roll(6, 2, 3).Roll(8, 6), []int{2, 3, 6}, nil, 5},
+		{"1d6!", rolltest.NewScript().Roll(6, 6, 6, 2), []int{6, 6, 2}, []int{6, 6, 2}, 14},
+		{"4dF", rolltest.NewScript().Roll(3, 1, 2, 3, 3), []int{-1, 0, 1, 1}, []int{-1, 0, 1, 1}, 1},
+		{"5d10>=7", rolltest.NewScript().Roll(10, 7, 3, 10, 6, 8), []int{7, 3, 10, 6, 8}, []int{7, 3, 10, 6, 8}, 3},
+		{"3d6min3", rolltest.NewScript().Roll(6, 1, 2, 6), []int{1, 2, 6}, []int{3, 3, 6}, 12},
+		{"3d6max4", rolltest.NewScript().Roll(6, 1, 5, 6), []int{1, 5, 6}, []int{1, 4, 4}, 9},
+		{"1d100", rolltest.NewScript().Roll(100, 74), []int{74}, []int{74}, 74},
+		{"1d%+1", rolltest.NewScript().Roll(100, 100), []int{100}, []int{100}, 101},
+	}
+	for _, tt := range tests {
+		e, err := rolls.ParseExpression(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := rolls.NewRoller(rolls.WithSource(tt.script)).RollExpression(e)
+		if !slices.Equal(res.Rolls, tt.rolls) || res.Total != tt.total || (tt.kept != nil && !slices.Equal(res.Kept, tt.kept)) {
+			t.Errorf("%s = rolls %v, kept %v, total %d, want %v, %v, %d", tt.expr, res.Rolls, res.Kept, res.Total, tt.rolls, tt.kept, tt.total)
+		}
+		if d, ok := e.Dice(); ok {
+			err = rolltest.Check(d, res)
+		} else if err = rolltest.CheckTotal(res); err == nil {
+			err = rolltest.CheckPartition(res)
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+		}
+		if n := tt.script.Left(); n != 0 {
+			t.Errorf("%s left %d scripted dice unrolled", tt.expr, n)
+		}
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	tests := []struct {
+		expr   string
+		script *rolltest.Script
+		value  int
+		total  int
+	}{
+		{"1d20+5", rolltest.NewScript().Roll(20, 3), 17, 22},
+		{"2d20kh1+3", rolltest.NewScript().Roll(20, 4, 9), 20, 23},
+		{"1d20+5-1d4", rolltest.NewScript().Roll(20, 2).Roll(4, 3), 19, 21},
+		{"-1d6+10", rolltest.NewScript().Roll(6, 2), 5, 5},
+		{"(1d6)*2", rolltest.NewScript().Roll(6, 3), 5, 10},
+		{"(1d20+2)*2", rolltest.NewScript().Roll(20, 7), 15, 34},
+		{"-(1d6+1)*2+20", rolltest.NewScript().Roll(6, 3), 6, 6},
+		{"((1d8)*2+1)/2", rolltest.NewScript().Roll(8, 1), 8, 8},
+	}
+	for _, tt := range tests {
+		e, err := rolls.ParseExpression(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := rolls.NewRoller(rolls.WithSource(tt.script)).RollExpression(e)
+		if err := res.Substitute(tt.value); err != nil {
+			t.Fatalf("%s: %v", tt.expr, err)
+		}
+		if res.Total != tt.total {
+			t.Errorf("%s with a %d substituted = %d, want %d", tt.expr, tt.value, res.Total, tt.total)
+		}
+		if err := rolltest.CheckTotal(res); err != nil {
+			t.Errorf("%s with a %d substituted: %v", tt.expr, tt.value, err)
+		}
+	}
+}
+
+// TestNudgeGroups nudges expressions of several groups, each group by its
+// own standard deviation and a subtracted one the other way, so the total
+// always moves the way of the nudge.
+func TestNudgeGroups(t *testing.T) {
+	tests := []struct {
+		expr            string
+		sigma           float64
+		script          *rolltest.Script
+		total, unbiased int
+	}{
+		// A d20 moves by 6, a d6 by 2 and a d4 by 1, within their faces.
+		{"1d20+5-1d4", 1, rolltest.NewScript().Roll(20, 10).Roll(4, 3), 16 + 5 - 2, 12},
+		{"1d20+1d4", -1, rolltest.NewScript().Roll(20, 10).Roll(4, 3), 4 + 2, 13},
+		{"1d20+1d20", 1, rolltest.NewScript().Roll(20, 18, 2), 20 + 8, 20},
+		{"(1d6+1)*2", 1, rolltest.NewScript().Roll(6, 3), 12, 8},
+		{"-(1d6)+10", 1, rolltest.NewScript().Roll(6, 4), 8, 6},
+	}
+	for _, tt := range tests {
+		e, err := rolls.ParseExpression(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := rolls.NewRoller(rolls.WithSource(tt.script), rolls.WithNudge(tt.sigma)).RollExpression(e)
+		if res.Total != tt.total || res.Nudge == nil || res.Nudge.Unbiased != tt.unbiased || res.Nudge.Sigma != tt.sigma {
+			t.Errorf("%s nudged %+gσ = %d, %+v, want %d from %d", tt.expr, tt.sigma, res.Total, res.Nudge, tt.total, tt.unbiased)
+		}
+		if want := fmt.Sprintf("(nudged %+gσ from %d)", tt.sigma, tt.unbiased); !strings.HasSuffix(res.String(), want) {
+			t.Errorf("%s nudged reads %q, want it to end %q", tt.expr, res, want)
+		}
+	}
+}
+
+// TestD20Floor checks the floor applies to the d20 advantage or disadvantage
+// keeps, not to each die before the keep, and that a natural 1 raised by it
+// is still counted as a natural 1.
+func TestD20Floor(t *testing.T) {
+	tests := []struct {
+		expr   string
+		script *rolltest.Script
+		rolls  []int
+		kept   []int
+		total  int
+		raised []rolls.Substitution
+	}{
+		{"2d20kh1+3", rolltest.NewScript().Roll(20, 4, 7), []int{4, 7}, []int{10}, 13, []rolls.Substitution{{Original: 7, Value: 10}}},
+		{"2d20kh1+3", rolltest.NewScript().Roll(20, 3, 14), []int{3, 14}, []int{14}, 17, nil},
+		{"2d20kl1", rolltest.NewScript().Roll(20, 15, 2), []int{15, 2}, []int{10}, 10, []rolls.Substitution{{Original: 2, Value: 10}}},
+		{"2d20kl1", rolltest.NewScript().Roll(20, 12, 18), []int{12, 18}, []int{12}, 12, nil},
+		{"1d20+5", rolltest.NewScript().Roll(20, 1), []int{1}, []int{10}, 15, []rolls.Substitution{{Original: 1, Value: 10}}},
+		{"2d20kh1", rolltest.NewScript().Roll(20, 1, 1), []int{1, 1}, []int{10}, 10, []rolls.Substitution{{Original: 1, Value: 10}}},
+	}
+	for _, tt := range tests {
+		d, err := rolls.Parse(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := rolls.NewRoller(rolls.WithSource(tt.script), rolls.WithD20Floor(rolls.ReliableTalentFloor)).Roll(d)
+		if !slices.Equal(res.Rolls, tt.rolls) || !slices.Equal(res.Kept, tt.kept) || res.Total != tt.total || !slices.Equal(res.Substituted, tt.raised) {
+			t.Errorf("%s floored = rolls %v, kept %v, total %d, raised %v, want %v, %v, %d, %v", tt.expr, res.Rolls, res.Kept, res.Total, res.Substituted, tt.rolls, tt.kept, tt.total, tt.raised)
+		}
+		naturals := rolls.RecentD20s([]rolls.HistoryEntry{{Result: res}}, rolls.StreakWindow)
+		if !slices.Equal(naturals, tt.rolls) {
+			t.Errorf("%s floored counts natural d20s %v, want %v", tt.expr, naturals, tt.rolls)
+		}
+		if n := tt.script.Left(); n != 0 {
+			t.Errorf("%s left %d scripted dice unrolled", tt.expr, n)
+		}
+	}
+}
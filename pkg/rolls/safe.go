@@ -0,0 +1,140 @@
+package rolls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Defaults applied by SafeRoll when no Option overrides them.
+const (
+	DefaultSafeTimeout   = time.Second
+	DefaultSafeMaxLength = 256
+	DefaultSafeMaxDice   = 1000
+	DefaultSafeMaxSides  = 1000
+)
+
+// ErrOverLimit is wrapped by SafeRoll errors for expressions that break its
+// limits.
+var ErrOverLimit = errors.New("expression is over the limits")
+
+// Option configures SafeRoll.
+type Option func(*safeConfig)
+
+type safeConfig struct {
+	timeout   time.Duration
+	maxLength int
+	maxDice   int
+	maxSides  int
+	roller    *Roller
+}
+
+// WithTimeout bounds how long SafeRoll may take.
+func WithTimeout(d time.Duration) Option {
+	return func(c *safeConfig) {
+		c.timeout = d
+	}
+}
+
+// WithMaxLength bounds the length of the expression in bytes.
+func WithMaxLength(n int) Option {
+	return func(c *safeConfig) {
+		c.maxLength = n
+	}
+}
+
+// WithMaxDice bounds how many dice an expression may roll, across all its
+// groups.
+func WithMaxDice(n int) Option {
+	return func(c *safeConfig) {
+		c.maxDice = n
+	}
+}
+
+// WithMaxSides bounds how many sides each die may have.
+func WithMaxSides(n int) Option {
+	return func(c *safeConfig) {
+		c.maxSides = n
+	}
+}
+
+// UsingRoller makes SafeRoll roll with r instead of the default roller.
+func UsingRoller(r *Roller) Option {
+	return func(c *safeConfig) {
+		c.roller = r
+	}
+}
+
+// SafeRoll parses and rolls expr for callers that take expressions from
+// untrusted input. Unlike the rest of the package, which may panic on
+// programmer error such as a nil Dice, SafeRoll never panics: panics are
+// recovered into errors, expressions over the length, dice or sides limits
+// are refused with an error wrapping ErrOverLimit, and rolls that outlast the
+// timeout fail with context.DeadlineExceeded.
+func SafeRoll(expr string, opts ...Option) (res *Result, err error) {
+	return SafeRollContext(context.Background(), expr, opts...)
+}
+
+// SafeRollContext is like SafeRoll but also stops when ctx is done.
+func SafeRollContext(ctx context.Context, expr string, opts ...Option) (res *Result, err error) {
+	c := safeConfig{
+		timeout:   DefaultSafeTimeout,
+		maxLength: DefaultSafeMaxLength,
+		maxDice:   DefaultSafeMaxDice,
+		maxSides:  DefaultSafeMaxSides,
+		roller:    defaultRoller,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	type outcome struct {
+		res *Result
+		err error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- outcome{err: fmt.Errorf("rolling %q panicked: %v", expr, p)}
+			}
+		}()
+		res, err := c.roll(ctx, expr)
+		done <- outcome{res, err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.res, o.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *safeConfig) roll(ctx context.Context, expr string) (*Result, error) {
+	if len(expr) > c.maxLength {
+		return nil, fmt.Errorf("%w: longer than %d characters", ErrOverLimit, c.maxLength)
+	}
+	e, err := ParseExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+	dice := 0
+	for _, d := range e.AllDice() {
+		if d.Count < 0 {
+			return nil, fmt.Errorf("%w: %s rolls %s", ErrOverLimit, expr, quantity(d.Count, "die"))
+		}
+		if d.Sides < 1 || d.Sides > c.maxSides {
+			return nil, fmt.Errorf("%w: %s has dice of %s, want 1 to %d", ErrOverLimit, expr, quantity(d.Sides, "side"), c.maxSides)
+		}
+		dice += d.Count
+	}
+	if dice > c.maxDice {
+		return nil, fmt.Errorf("%w: %s rolls %s, the limit is %d", ErrOverLimit, expr, quantity(dice, "die"), c.maxDice)
+	}
+	return c.roller.RollExpressionContext(ctx, e)
+}
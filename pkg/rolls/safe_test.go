@@ -0,0 +1,102 @@
+package rolls
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSafeRoll(t *testing.T) {
+	tests := []struct {
+		expr  string
+		total int
+	}{
+		{"3d1+2", 5},
+		{"1d1+5-1d1", 5},
+		{"(1d1)*2", 2},
+		{"(2d1+3)*2-1d1", 9},
+		{"500d1+500d1", 1000},
+	}
+	for _, tt := range tests {
+		res, err := SafeRoll(tt.expr, UsingRoller(NewRoller(WithSeed(1))))
+		if err != nil {
+			t.Errorf("SafeRoll(%q): %v", tt.expr, err)
+			continue
+		}
+		if res.Total != tt.total {
+			t.Errorf("SafeRoll(%q) = %d, want %d", tt.expr, res.Total, tt.total)
+		}
+	}
+}
+
+func TestSafeRollLimits(t *testing.T) {
+	tests := []struct {
+		expr string
+		opts []Option
+	}{
+		{"1001d6", nil},
+		{"600d6+600d6", nil},
+		{"(600d6+1)*2+401d4", nil},
+		{"1d1001", nil},
+		{"1d20+1d1001", nil},
+		{"(1d20-1d2000)*2", nil},
+		{"11d6", []Option{WithMaxDice(10)}},
+		{"1d20+1d20", []Option{WithMaxSides(12)}},
+		{strings.Repeat("1d4+", 64) + "1", nil},
+		{"1d20+5", []Option{WithMaxLength(5)}},
+	}
+	for _, tt := range tests {
+		if _, err := SafeRoll(tt.expr, tt.opts...); !errors.Is(err, ErrOverLimit) {
+			t.Errorf("SafeRoll(%q) = %v, want ErrOverLimit", tt.expr, err)
+		}
+	}
+}
+
+func TestSafeRollRecovers(t *testing.T) {
+	// A nil Roller is a programmer error the core panics on.
+	_, err := SafeRoll("1d20", UsingRoller(nil))
+	if err == nil || !strings.Contains(err.Error(), "panicked") {
+		t.Errorf("SafeRoll with a nil roller = %v, want the panic as an error", err)
+	}
+}
+
+func TestSafeRollTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := SafeRollContext(ctx, "1d20"); !errors.Is(err, context.Canceled) {
+		t.Errorf("SafeRollContext with a cancelled context = %v, want context.Canceled", err)
+	}
+	if _, err := SafeRoll("1d20", WithTimeout(-time.Second)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SafeRoll past its timeout = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+// FuzzSafeRoll checks SafeRoll never panics, even in its own goroutine, and
+// only rolls what ParseExpression reads. The corpus under testdata/fuzz
+// holds inputs the fuzzer found reaching new paths of the parser.
+func FuzzSafeRoll(f *testing.F) {
+	for _, expr := range []string{
+		"1d20", "4d6kh3", "1d20+5-1d4", "(1d6)*2", "2d6+1d8+3", "10d10!>8",
+		"4dF", "3d{a,b}", "1d%", "1d20adv", "8d6r<2", "5d10>=7f1", "((1d4))",
+		"1d6/0", "3d6min2max5", "", "d", "1d", "(", ")", "-", "1d20+", "0d0",
+	} {
+		f.Add(expr)
+	}
+	f.Fuzz(func(t *testing.T, expr string) {
+		res, err := SafeRoll(expr, WithTimeout(time.Second), UsingRoller(NewRoller(WithSeed(1))))
+		if err != nil {
+			if strings.Contains(err.Error(), "panicked") {
+				t.Fatalf("SafeRoll(%q): %v", expr, err)
+			}
+			return
+		}
+		if res == nil {
+			t.Fatalf("SafeRoll(%q) returned neither a result nor an error", expr)
+		}
+		if _, err := ParseExpression(expr); err != nil {
+			t.Fatalf("SafeRoll(%q) rolled an expression ParseExpression refuses: %v", expr, err)
+		}
+	})
+}
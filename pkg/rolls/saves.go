@@ -0,0 +1,102 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+)
+
+// SaveTrials is how many fights SimulateSaves simulates.
+const SaveTrials = 10000
+
+// SaveSimulation is the outcome of repeatedly forcing saves on a creature
+// with legendary resistances.
+type SaveSimulation struct {
+	DC        int
+	SaveMod   int
+	Attempts  int
+	Legendary int
+	Trials    int
+	// Failed[k] is the fraction of fights in which exactly k saves failed
+	// after legendary resistances were spent.
+	Failed []float64
+	// LandedOn[i] is the fraction of fights in which attempt i+1 was a
+	// failed save that stuck.
+	LandedOn []float64
+	// ResistsUsed is the mean number of legendary resistances spent.
+	ResistsUsed float64
+}
+
+// SimulateSaves simulates fights in which a creature makes attempts saves
+// against dc in turn. It turns failed saves into successes while it has
+// legendary resistances left, spending them on the first failures.
+func SimulateSaves(dc, saveMod, attempts, legendaryResists int) (*SaveSimulation, error) {
+	if attempts < 1 {
+		return nil, fmt.Errorf("need at least one attempt, got %d", attempts)
+	}
+	if legendaryResists < 0 {
+		return nil, fmt.Errorf("legendary resistances must not be negative, got %d", legendaryResists)
+	}
+
+	sim := &SaveSimulation{
+		DC:        dc,
+		SaveMod:   saveMod,
+		Attempts:  attempts,
+		Legendary: legendaryResists,
+		Trials:    SaveTrials,
+		Failed:    make([]float64, attempts+1),
+		LandedOn:  make([]float64, attempts),
+	}
+	used := 0
+	for t := 0; t < sim.Trials; t++ {
+		resists, failed := legendaryResists, 0
+		for i := 0; i < attempts; i++ {
+			if result(20)+saveMod >= dc {
+				continue
+			}
+			if resists > 0 {
+				resists--
+				continue
+			}
+			failed++
+			sim.LandedOn[i]++
+		}
+		sim.Failed[failed]++
+		used += legendaryResists - resists
+	}
+
+	for k := range sim.Failed {
+		sim.Failed[k] /= float64(sim.Trials)
+	}
+	for i := range sim.LandedOn {
+		sim.LandedOn[i] /= float64(sim.Trials)
+	}
+	sim.ResistsUsed = float64(used) / float64(sim.Trials)
+	return sim, nil
+}
+
+func savesGen(args []string) error {
+	fs := flag.NewFlagSet("saves", flag.ContinueOnError)
+	dc := fs.Int("dc", 10, "save DC")
+	mod := fs.Int("mod", 0, "the target's saving throw modifier")
+	attempts := fs.Int("attempts", 1, "number of saves forced over the fight")
+	legendary := fs.Int("legendary", 0, "legendary resistances the target can spend")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	sim, err := SimulateSaves(*dc, *mod, *attempts, *legendary)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s against DC %d at %+d with %s, %s\n", quantity(sim.Attempts, "save"), sim.DC, sim.SaveMod, quantity(sim.Legendary, "legendary resistance"), quantity(sim.Trials, "fight"))
+	for k, p := range sim.Failed {
+		fmt.Printf("%d failed: %.2f%%\n", k, p*100)
+	}
+	for i, p := range sim.LandedOn {
+		fmt.Printf("Attempt %d lands: %.2f%%\n", i+1, p*100)
+	}
+	fmt.Printf("Resistances spent: %.2f\n", sim.ResistsUsed)
+
+	return nil
+}
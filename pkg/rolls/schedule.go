@@ -0,0 +1,118 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ScheduleEntry is a single day of a rolled schedule.
+type ScheduleEntry struct {
+	Day    int
+	Chance *Result
+	Event  bool
+	// Face and Entry are the table roll, set only on event days.
+	Face  int
+	Entry string
+}
+
+// Schedule is a day-by-day event log along with any warnings about its inputs.
+type Schedule struct {
+	Entries  []ScheduleEntry
+	Warnings []string
+}
+
+// RollSchedule rolls chance for each of days days, rolling on table whenever
+// every kept die of chance meets its Success threshold.
+func RollSchedule(days int, chance *Dice, table *Table) (*Schedule, error) {
+	if days < 1 {
+		return nil, fmt.Errorf("need at least one day, got %d", days)
+	}
+
+	s := &Schedule{Entries: make([]ScheduleEntry, 0, days)}
+	if !chance.CanSucceed() {
+		s.Warnings = append(s.Warnings, fmt.Sprintf("event chance %s can never succeed", chance))
+	}
+	if len(table.Entries) == 0 {
+		s.Warnings = append(s.Warnings, fmt.Sprintf("table %s has no entries", table.Name))
+	}
+
+	for day := 1; day <= days; day++ {
+		res := chance.Roll()
+		e := ScheduleEntry{Day: day, Chance: res}
+		e.Event = chance.Success == nil || res.Successes == len(res.Kept)
+		if e.Event && len(table.Entries) > 0 {
+			face, entry, err := table.Roll()
+			if err != nil {
+				return nil, err
+			}
+			e.Face, e.Entry = face, entry
+		}
+		s.Entries = append(s.Entries, e)
+	}
+
+	return s, nil
+}
+
+func daysGen(args []string) error {
+	fs := flag.NewFlagSet("days", flag.ContinueOnError)
+	chance := fs.String("event-chance", "1d20>=18", "roll made each day, an event happens when it succeeds")
+	tablePath := fs.String("table", "", "CSV file of events, one per row")
+	start := fs.String("start", "", "date of the first day, as YYYY-MM-DD")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("need to provide the number of days")
+	}
+	days, err := strconv.Atoi(rest[0])
+	if err != nil {
+		return err
+	}
+	if *tablePath == "" {
+		return fmt.Errorf("need to provide an event --table")
+	}
+
+	d, err := Parse(*chance)
+	if err != nil {
+		return err
+	}
+	table, err := LoadTable(*tablePath)
+	if err != nil {
+		return err
+	}
+	var first time.Time
+	if *start != "" {
+		if first, err = time.Parse("2006-01-02", *start); err != nil {
+			return err
+		}
+	}
+
+	schedule, err := RollSchedule(days, d, table)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range schedule.Warnings {
+		fmt.Println("warning:", w)
+	}
+	for _, e := range schedule.Entries {
+		label := fmt.Sprintf("Day %d", e.Day)
+		if !first.IsZero() {
+			label = first.AddDate(0, 0, e.Day-1).Format("2006-01-02")
+		}
+		if !e.Event {
+			fmt.Printf("%s: quiet (%v)\n", label, e.Chance.Rolls)
+			continue
+		}
+		if e.Entry == "" {
+			fmt.Printf("%s: event, but the table is empty (%v)\n", label, e.Chance.Rolls)
+			continue
+		}
+		fmt.Printf("%s: %s (%v, table %d)\n", label, e.Entry, e.Chance.Rolls, e.Face)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package rolls
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Commitment binds a secret result to a hash that can be shown before the
+// result is revealed. The hash covers a random salt and the result's JSON,
+// so it cannot be brute-forced from the handful of possible totals.
+type Commitment struct {
+	Hash string `json:"hash"`
+	Salt string `json:"salt"`
+}
+
+// Commit returns a fresh commitment to res.
+func Commit(res *Result) (*Commitment, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	c := &Commitment{Salt: hex.EncodeToString(salt)}
+	hash, err := c.hash(res)
+	if err != nil {
+		return nil, err
+	}
+	c.Hash = hash
+	return c, nil
+}
+
+// Verify reports whether res is the result c was made for.
+func (c *Commitment) Verify(res *Result) bool {
+	hash, err := c.hash(res)
+	return err == nil && subtle.ConstantTimeCompare([]byte(hash), []byte(c.Hash)) == 1
+}
+
+func (c *Commitment) hash(res *Result) (string, error) {
+	salt, err := hex.DecodeString(c.Salt)
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(res)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write(salt)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Redact renders res for a secret roll: the expression and commitment hash,
+// with the dice and total withheld.
+func Redact(res *Result, c *Commitment) string {
+	return fmt.Sprintf("%s: [secret] commitment %s", res.Expression, c.Hash)
+}
+
+// RollSecret rolls expr and records the result with a commitment in the
+// history log, from which it can be revealed later. It fails without
+// rolling when the history log is disabled.
+func RollSecret(expr string) (*Result, *Commitment, error) {
+	if off, err := historyOff(); err != nil {
+		return nil, nil, err
+	} else if off {
+		return nil, nil, fmt.Errorf("secret rolls need the history log to be revealed, but it is off")
+	}
+
+	res, err := RollString(expr)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := Commit(res)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := AppendCommitted(res, c); err != nil {
+		return nil, nil, err
+	}
+	return res, c, nil
+}
+
+func revealGen(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("need to provide the commitment hash, or a prefix of it, to reveal")
+	}
+	entries, _, err := LoadHistory()
+	if err != nil {
+		return err
+	}
+
+	var found []HistoryEntry
+	for _, e := range entries {
+		if e.Commitment != nil && strings.HasPrefix(e.Commitment.Hash, args[0]) {
+			found = append(found, e)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return fmt.Errorf("no secret roll with commitment %s", args[0])
+	case 1:
+	default:
+		return fmt.Errorf("%d secret rolls match commitment %s, give more of the hash", len(found), args[0])
+	}
+
+	e := found[0]
+	fmt.Printf("%s (rolled %s)\n", e.Result, e.Time.Format("2006-01-02 15:04:05"))
+	if !e.Commitment.Verify(e.Result) {
+		return fmt.Errorf("commitment %s does not match the stored result", e.Commitment.Hash)
+	}
+	fmt.Println("Commitment verified")
+
+	return nil
+}
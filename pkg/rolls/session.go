@@ -0,0 +1,289 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// minVerdictD20s is the number of d20s needed before the chi-square
+	// test means anything: at least five expected per face.
+	minVerdictD20s = 100
+	// chiSquareCritical is the 95th percentile of chi-square with 19 degrees
+	// of freedom, one less than the faces of a d20.
+	chiSquareCritical = 30.144
+)
+
+// SessionStats summarises a set of logged results.
+type SessionStats struct {
+	Rolls       int
+	Expressions map[string]int
+	// Naturals counts every d20 face rolled, kept or dropped, indexed by
+	// face-1.
+	Naturals  [20]int
+	D20s      int
+	ChiSquare float64
+	// Damage is the summed total of every roll that was not a d20 roll.
+	Damage  int
+	Crits   int
+	Fumbles int
+}
+
+// AnalyzeHistory computes session statistics over results.
+func AnalyzeHistory(results []*Result) *SessionStats {
+	s := &SessionStats{Expressions: make(map[string]int)}
+	for _, res := range results {
+		s.Rolls++
+		s.Expressions[res.Expression]++
+
+		groups := d20Groups(res)
+		if len(groups) == 0 {
+			s.Damage += res.Total
+			continue
+		}
+		for _, g := range groups {
+			for _, r := range naturals(g) {
+				s.Naturals[r-1]++
+				s.D20s++
+			}
+			for _, k := range g.Kept {
+				switch k {
+				case 20:
+					s.Crits++
+				case 1:
+					s.Fumbles++
+				}
+			}
+		}
+	}
+
+	if s.D20s > 0 {
+		expected := float64(s.D20s) / 20
+		for _, n := range s.Naturals {
+			diff := float64(n) - expected
+			s.ChiSquare += diff * diff / expected
+		}
+	}
+	return s
+}
+
+// d20Groups returns the dice groups of res that rolled d20s, so that the
+// d20 of a blessed attack, 1d20+5+1d4, is found beside its d4. Results
+// logged before the die size was recorded have it recovered from their
+// expression.
+func d20Groups(res *Result) []*Result {
+	var groups []*Result
+	for _, g := range res.DiceGroups() {
+		sides := g.Sides
+		if sides == 0 && g == res {
+			if d, err := Parse(res.Expression); err == nil {
+				sides = d.Sides
+			}
+		}
+		if sides == 20 {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// naturals returns the faces g's d20s landed on, kept or dropped.
+func naturals(g *Result) []int {
+	var faces []int
+	for _, r := range g.Rolls {
+		if r >= 1 && r <= 20 {
+			faces = append(faces, r)
+		}
+	}
+	return faces
+}
+
+// naturalD20s returns the faces res's d20s landed on, kept or dropped, and
+// whether it rolled d20s at all.
+func naturalD20s(res *Result) ([]int, bool) {
+	groups := d20Groups(res)
+	var faces []int
+	for _, g := range groups {
+		faces = append(faces, naturals(g)...)
+	}
+	return faces, len(groups) > 0
+}
+
+// Verdict describes whether the natural d20 results look fair.
+func (s *SessionStats) Verdict() string {
+	if s.D20s < minVerdictD20s {
+		return fmt.Sprintf("only %s rolled, need %d for a verdict", quantity(s.D20s, "d20"), minVerdictD20s)
+	}
+	if s.ChiSquare < chiSquareCritical {
+		return "your dice were fine"
+	}
+	return "your dice were unusually streaky (p < 0.05)"
+}
+
+func historyGen(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("need to provide a history command: stats, list, annotate, void, verify, export or migrate")
+	}
+	switch args[0] {
+	case "stats":
+		return historyStats()
+	case "list":
+		return historyList(args[1:])
+	case "annotate":
+		if len(args) != 3 {
+			return fmt.Errorf("need to provide the id of a roll and a note, e.g. roll history annotate 3f9a \"used the wrong modifier\"")
+		}
+		return historyCorrect(args[1], CorrectionAnnotate, args[2])
+	case "void":
+		if len(args) != 2 && len(args) != 3 {
+			return fmt.Errorf("need to provide the id of the roll to void, and an optional note")
+		}
+		return historyCorrect(args[1], CorrectionVoid, strings.Join(args[2:], " "))
+	case "verify":
+		return historyVerify()
+	case "export":
+		return exportGen(args[1:])
+	case "migrate":
+		return historyMigrate()
+	}
+	return fmt.Errorf("unknown history command %q, want stats, list, annotate, void, verify, export or migrate", args[0])
+}
+
+// historyCorrect appends a correction to the roll named by id.
+func historyCorrect(id, action, note string) error {
+	entries, _, err := LoadHistory()
+	if err != nil {
+		return err
+	}
+	e, err := FindEntry(entries, id)
+	if err != nil {
+		return err
+	}
+	if action == CorrectionVoid && e.Voided {
+		return fmt.Errorf("roll %s is already void", e.Ref)
+	}
+	if err := AppendCorrection(&Correction{Target: e.Ref, Action: action, Note: note}); err != nil {
+		return err
+	}
+	if action == CorrectionVoid {
+		fmt.Printf("Voided %s: %s\n", e.Ref, e.Result)
+	} else {
+		fmt.Printf("Annotated %s: %s\n", e.Ref, e.Result)
+	}
+	return nil
+}
+
+func historyList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	last := fs.Int("last", 20, "how many of the latest rolls to list, or 0 for all")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	entries, skipped, err := LoadHistory()
+	if err != nil {
+		return err
+	}
+	if *last > 0 && len(entries) > *last {
+		entries = entries[len(entries)-*last:]
+	}
+	for _, e := range entries {
+		line := fmt.Sprintf("%s  %s  %s", e.Ref, e.Time.Format("2006-01-02 15:04"), e.Result)
+		if e.Voided {
+			line += "  [void]"
+		}
+		for _, n := range e.Notes {
+			line += fmt.Sprintf("  (%s)", n)
+		}
+		fmt.Println(line)
+	}
+	if skipped > 0 {
+		fmt.Printf("Skipped %s\n", quantity(skipped, "unreadable history line"))
+	}
+	return nil
+}
+
+func historyVerify() error {
+	report, err := VerifyHistory()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Checked %s", quantity(report.Entries, "entry"))
+	if report.Unchained > 0 {
+		fmt.Printf(", %d written before the log was chained", report.Unchained)
+	}
+	fmt.Println()
+	if len(report.Broken) == 0 {
+		fmt.Println("The log is intact")
+		return nil
+	}
+	for _, line := range report.Broken {
+		fmt.Printf("Line %d does not follow from the line before it: it, or a line before it, was edited or removed\n", line)
+	}
+	return fmt.Errorf("the history log has been altered")
+}
+
+func historyMigrate() error {
+	report, backup, err := MigrateHistory()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Migrated %s, %d from older schemas\n", quantity(report.Entries, "entry"), report.Upgraded)
+	if report.Unreadable > 0 {
+		fmt.Printf("Kept %s as they were\n", quantity(report.Unreadable, "unreadable line"))
+	}
+	fmt.Println("Backup:", backup)
+	return nil
+}
+
+func historyStats() error {
+	entries, skipped, err := LoadHistory()
+	if err != nil {
+		return err
+	}
+	results := make([]*Result, 0, len(entries))
+	voided := 0
+	for _, e := range entries {
+		if e.Voided {
+			voided++
+			continue
+		}
+		results = append(results, e.Result)
+	}
+	s := AnalyzeHistory(results)
+
+	fmt.Printf("Rolls: %d\n", s.Rolls)
+	if voided > 0 {
+		fmt.Printf("Left out %s\n", quantity(voided, "voided roll"))
+	}
+	if skipped > 0 {
+		fmt.Printf("Skipped %s\n", quantity(skipped, "unreadable history line"))
+	}
+	exprs := make([]string, 0, len(s.Expressions))
+	for e := range s.Expressions {
+		exprs = append(exprs, e)
+	}
+	sort.Slice(exprs, func(i, j int) bool {
+		if s.Expressions[exprs[i]] != s.Expressions[exprs[j]] {
+			return s.Expressions[exprs[i]] > s.Expressions[exprs[j]]
+		}
+		return exprs[i] < exprs[j]
+	})
+	for _, e := range exprs {
+		fmt.Printf("  %s: %d\n", e, s.Expressions[e])
+	}
+
+	if s.D20s > 0 {
+		fmt.Print("Natural d20s:")
+		for face, n := range s.Naturals {
+			fmt.Printf(" %d:%d", face+1, n)
+		}
+		fmt.Println()
+		fmt.Printf("Chi-square %.1f over %s: %s\n", s.ChiSquare, quantity(s.D20s, "d20"), s.Verdict())
+	}
+	fmt.Println("Total damage: ", s.Damage)
+	fmt.Printf("Crits: %d Fumbles: %d\n", s.Crits, s.Fumbles)
+
+	return nil
+}
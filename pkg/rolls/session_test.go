@@ -0,0 +1,42 @@
+package rolls
+
+import "testing"
+
+func TestAnalyzeHistory(t *testing.T) {
+	blessed, err := ParseExpression("1d20+5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blessed, err = AddBlessBane(blessed, true, false); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := ParseExpression("(2d20kh1+1d4)*2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	roller := NewRoller(WithSeed(1))
+	results := []*Result{
+		roller.RollExpression(blessed),
+		roller.RollExpression(sub),
+		// Logged before the die size was recorded.
+		{Expression: "1d20", Rolls: []int{20}, Kept: []int{20}, Total: 20},
+		{Expression: "2d6", Sides: 6, Rolls: []int{3, 4}, Kept: []int{3, 4}, Total: 7},
+	}
+
+	s := AnalyzeHistory(results)
+	if s.Rolls != 4 || s.D20s != 4 || s.Damage != 7 || s.Crits < 1 {
+		t.Errorf("AnalyzeHistory = %d rolls, %d d20s, %d damage, %d crits, want 4, 4, 7 and at least 1", s.Rolls, s.D20s, s.Damage, s.Crits)
+	}
+	faces := 0
+	for _, n := range s.Naturals {
+		faces += n
+	}
+	if faces != s.D20s {
+		t.Errorf("AnalyzeHistory counted %d naturals for %d d20s", faces, s.D20s)
+	}
+	for i, res := range results[:2] {
+		if _, ok := naturalD20s(res); !ok {
+			t.Errorf("naturalD20s(%s) found no d20s", results[i].Expression)
+		}
+	}
+}
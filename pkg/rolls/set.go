@@ -0,0 +1,138 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Percentile is the number of sides RollSet treats as d%, rolled as a tens
+// die and a ones die.
+const Percentile = 100
+
+// RollSet rolls every die of a physical dice set once. Results are keyed by
+// die name, such as d6 or d%, with repeated dice numbered d6#2, d6#3 and so
+// on.
+func RollSet(set []int) map[string]*Result {
+	results := make(map[string]*Result, len(set))
+	for i, name := range setNames(set) {
+		sides := set[i]
+		if sides == Percentile {
+			results[name] = rollPercentile()
+			continue
+		}
+		results[name] = (&Dice{Count: 1, Sides: sides}).Roll()
+	}
+	return results
+}
+
+// setNames returns the RollSet key of each die in set.
+func setNames(set []int) []string {
+	names := make([]string, len(set))
+	seen := make(map[int]int)
+	for i, sides := range set {
+		seen[sides]++
+		names[i] = dieName(sides)
+		if seen[sides] > 1 {
+			names[i] = fmt.Sprintf("%s#%d", names[i], seen[sides])
+		}
+	}
+	return names
+}
+
+func dieName(sides int) string {
+	if sides == Percentile {
+		return "d%"
+	}
+	return fmt.Sprintf("d%d", sides)
+}
+
+// rollPercentile rolls d% as a tens die showing 00 to 90 and a ones die
+// showing 0 to 9, where 00 and 0 together read as 100.
+func rollPercentile() *Result {
+	tens, ones := (result(10)-1)*10, result(10)-1
+	total := tens + ones
+	if total == 0 {
+		total = 100
+	}
+	return &Result{
+		Expression:  "d%",
+		Sides:       Percentile,
+		Rolls:       []int{total},
+		Kept:        []int{total},
+		Total:       total,
+		Percentiles: []PercentileRoll{{Tens: tens, Ones: ones}},
+	}
+}
+
+// ParseSet parses a dice set such as "d4 d6 d8 d10 d% d12 d20".
+func ParseSet(s string) ([]int, error) {
+	var set []int
+	for _, die := range strings.Fields(s) {
+		if die == "d%" {
+			set = append(set, Percentile)
+			continue
+		}
+		sides, err := strconv.Atoi(strings.TrimPrefix(die, "d"))
+		if err != nil || !strings.HasPrefix(die, "d") || sides < 1 {
+			return nil, fmt.Errorf("passed illegal die in set: %s", die)
+		}
+		set = append(set, sides)
+	}
+	return set, nil
+}
+
+func setGen(args []string) error {
+	fs := flag.NewFlagSet("set", flag.ContinueOnError)
+	list := fs.Bool("list", false, "list the dice sets in the config file")
+	names, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	if *list {
+		sets := make([]string, 0, len(cfg.Sets))
+		for name := range cfg.Sets {
+			sets = append(sets, name)
+		}
+		sort.Strings(sets)
+		for _, name := range sets {
+			fmt.Printf("%s: %s\n", name, cfg.Sets[name])
+		}
+		return nil
+	}
+	if len(names) != 1 {
+		return fmt.Errorf("need to provide a dice set name, or --list")
+	}
+
+	dice, ok := cfg.Sets[names[0]]
+	if !ok {
+		return fmt.Errorf("no dice set named %q in the config file", names[0])
+	}
+	set, err := ParseSet(dice)
+	if err != nil {
+		return err
+	}
+
+	results := RollSet(set)
+	rolled := make([]*Result, 0, len(set))
+	for i, name := range setNames(set) {
+		res := results[name]
+		if set[i] == Percentile {
+			p := res.Percentiles[0]
+			fmt.Printf("%s: %d (%02d + %d)\n", name, res.Total, p.Tens, p.Ones)
+		} else {
+			fmt.Printf("%s: %d\n", name, res.Total)
+		}
+		rolled = append(rolled, res)
+	}
+	logHistory(rolled...)
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package rolls
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// statBlockDamage matches stat block damage such as "11 (2d6 + 4) slashing".
+var statBlockDamage = regexp.MustCompile(`(\d+)\s*\(\s*([^)]*?)\s*\)\s*([^.,;]*)`)
+
+// AverageMismatchError reports a stat block whose printed average disagrees
+// with the average of its dice, which happens in books with errata.
+type AverageMismatchError struct {
+	Dice     *Dice
+	Printed  int
+	Computed int
+}
+
+func (e *AverageMismatchError) Error() string {
+	return fmt.Sprintf("stat block average %d does not match %s, which averages %d", e.Printed, e.Dice, e.Computed)
+}
+
+// ParseStatBlockDamage extracts the dice, printed average and damage type
+// from stat block damage such as "Hit: 11 (2d6 + 4) slashing damage." The
+// average is checked against the dice, rounded down as the books do; on a
+// mismatch every value is still returned along with an *AverageMismatchError.
+func ParseStatBlockDamage(s string) (d *Dice, avg int, dtype string, err error) {
+	m := statBlockDamage.FindStringSubmatch(s)
+	if m == nil {
+		return nil, 0, "", fmt.Errorf("passed illegal stat block damage: %s", s)
+	}
+
+	avg, err = strconv.Atoi(m[1])
+	if err != nil {
+		return nil, 0, "", err
+	}
+	d, err = Parse(strings.Join(strings.Fields(m[2]), ""))
+	if err != nil {
+		return nil, 0, "", err
+	}
+	dtype, _, _ = strings.Cut(m[3], " plus ")
+	dtype = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(dtype), "damage"))
+
+	want, err := d.Average()
+	if err != nil {
+		return nil, 0, "", err
+	}
+	if computed := int(math.Floor(want)); computed != avg {
+		return d, avg, dtype, &AverageMismatchError{Dice: d, Printed: avg, Computed: computed}
+	}
+	return d, avg, dtype, nil
+}
@@ -0,0 +1,300 @@
+package rolls
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// maxEnumeration bounds how many outcomes Distribution will enumerate for
+// pools whose modifier rules out convolution.
+const maxEnumeration = 1 << 20
+
+// Min returns the lowest total the dice can roll, for a success pool with
+// a Failure threshold every die it can keep failing.
+func (d *Dice) Min() int {
+	switch {
+	case len(d.Labels) > 0:
+		return 0
+	case d.Failure != nil:
+		return d.Bonus - d.mostKept()
+	case d.Success != nil:
+		return d.Bonus
+	}
+	lo, _ := d.faceRange()
+	return d.keptCount()*lo + d.Bonus
+}
+
+// Max returns the highest total the dice can roll. Exploding dice reach it
+// only by every die exploding ExplodeLimit times, and compounding dice by
+// every kept die compounding as often, bar a MaxPerDie capping them. A
+// success pool's highest total is every die it can keep succeeding.
+func (d *Dice) Max() int {
+	if len(d.Labels) > 0 {
+		return 0
+	}
+	dice := d.mostKept()
+	switch {
+	case d.Success != nil:
+		return dice + d.Bonus
+	case d.MaxPerDie > 0:
+	case d.Explode == Compounding:
+		return dice*(ExplodeLimit+1)*d.Sides + d.Bonus
+	case d.Explode == Penetrating:
+		return dice*(d.Sides+ExplodeLimit*(d.Sides-1)) + d.Bonus
+	}
+	_, hi := d.faceRange()
+	return dice*hi + d.Bonus
+}
+
+// faceRange returns the lowest and highest values a die of d counts as.
+func (d *Dice) faceRange() (lo, hi int) {
+	if len(d.FaceValues) > 0 {
+		return slices.Min(d.FaceValues), slices.Max(d.FaceValues)
+	}
+	return d.adjust(1), d.adjust(d.Sides)
+}
+
+// mostKept returns the most dice d can keep, counting those exploding dice
+// add.
+func (d *Dice) mostKept() int {
+	if d.Explode != Exploding {
+		return d.keptCount()
+	}
+	dice := d.Count * (ExplodeLimit + 1)
+	if d.Modifier != NoModifier && d.ModifierCount < dice {
+		dice = d.ModifierCount
+	}
+	return dice
+}
+
+func (d *Dice) keptCount() int {
+	if d.Modifier != NoModifier && d.ModifierCount < d.Count {
+		return d.ModifierCount
+	}
+	return d.Count
+}
+
+// Average returns the expected total.
+func (d *Dice) Average() (float64, error) {
+	if len(d.Labels) > 0 {
+		return 0, fmt.Errorf("no average for %s, labeled dice have no total", d)
+	}
+	if d.Explode != NoExplosion {
+		if d.Modifier != NoModifier || d.Reroll != nil || d.Success != nil || d.clamped() {
+			return 0, fmt.Errorf("no average for %s, exploding dice with a modifier, reroll, minimum, maximum or threshold", d)
+		}
+		mean, _ := d.explodingMoments()
+		return float64(d.Count)*mean + float64(d.Bonus), nil
+	}
+	if d.Modifier == NoModifier && d.Reroll == nil && d.Success == nil && !d.clamped() && len(d.FaceValues) == 0 {
+		return float64(d.Count)*float64(d.Sides+1)/2 + float64(d.Bonus), nil
+	}
+	if d.Modifier == NoModifier {
+		mean, _ := d.faceMoments()
+		return float64(d.Count)*mean + float64(d.Bonus), nil
+	}
+
+	dist, err := d.Distribution()
+	if err != nil {
+		return 0, err
+	}
+	avg := 0.0
+	for total, p := range dist {
+		avg += float64(total) * p
+	}
+	return avg, nil
+}
+
+// StdDev returns the standard deviation of the total.
+func (d *Dice) StdDev() (float64, error) {
+	if len(d.Labels) > 0 {
+		return 0, fmt.Errorf("no deviation for %s, labeled dice have no total", d)
+	}
+	if d.Explode != NoExplosion {
+		if d.Modifier != NoModifier || d.Reroll != nil || d.Success != nil || d.clamped() {
+			return 0, fmt.Errorf("no deviation for %s, exploding dice with a modifier, reroll, minimum, maximum or threshold", d)
+		}
+		mean, square := d.explodingMoments()
+		return math.Sqrt(float64(d.Count) * (square - mean*mean)), nil
+	}
+	if d.Modifier == NoModifier && d.Reroll == nil && d.Success == nil && !d.clamped() && len(d.FaceValues) == 0 {
+		return math.Sqrt(float64(d.Count) * float64(d.Sides*d.Sides-1) / 12), nil
+	}
+	if d.Modifier == NoModifier {
+		mean, square := d.faceMoments()
+		return math.Sqrt(float64(d.Count) * (square - mean*mean)), nil
+	}
+
+	dist, err := d.Distribution()
+	if err != nil {
+		return 0, err
+	}
+	mean, variance := 0.0, 0.0
+	for total, p := range dist {
+		mean += float64(total) * p
+	}
+	for total, p := range dist {
+		variance += (float64(total) - mean) * (float64(total) - mean) * p
+	}
+	return math.Sqrt(variance), nil
+}
+
+// explodingMoments returns the mean and mean square of a single exploding
+// die, or of a compounding one, which totals the same, ignoring
+// ExplodeLimit. A die X is a face Y plus, on the highest face,
+// another exploding die, so E[X] = E[Y] + E[X]/s and E[X²] = E[Y²] + 2E[X]
+// + E[X²]/s. A penetrating die continues with Z, a face less one plus, on
+// the highest face, Z again, so X = Y + Z on the highest face.
+func (d *Dice) explodingMoments() (mean, square float64) {
+	s := float64(d.Sides)
+	y, y2 := (s+1)/2, (s+1)*(2*s+1)/6
+	if d.Explode != Penetrating {
+		mean = y * s / (s - 1)
+		square = (y2 + 2*mean) * s / (s - 1)
+		return mean, square
+	}
+	w, w2 := y-1, y2-2*y+1
+	z := w * s / (s - 1)
+	z2 := (w2 + 2*(s-1)/s*z) * s / (s - 1)
+	return y + z/s, y2 + 2*z + z2/s
+}
+
+// Distribution returns the exact probability of rolling each total.
+func (d *Dice) Distribution() (map[int]float64, error) {
+	if d.Count < 1 || d.Sides < 1 {
+		return nil, fmt.Errorf("no distribution for %s", d)
+	}
+	if len(d.Labels) > 0 {
+		return nil, fmt.Errorf("no distribution for %s, labeled dice have no total", d)
+	}
+	if d.Explode != NoExplosion {
+		return nil, fmt.Errorf("no exact distribution for %s, exploding dice have no highest total", d)
+	}
+	if d.Modifier == NoModifier {
+		return d.convolve(), nil
+	}
+	return d.enumerate()
+}
+
+// faceChances returns the chance of a single die of d landing on each
+// face, from 1: even, unless faces matching Reroll are rolled again. A
+// recursive reroll lands evenly on the faces that do not match, ignoring
+// RerollLimit.
+func (d *Dice) faceChances() []float64 {
+	face := 1 / float64(d.Sides)
+	chances := make([]float64, d.Sides)
+	rerolled := 0.0
+	for v := 1; v <= d.Sides; v++ {
+		if d.Reroll != nil && d.Reroll.Matches(v) {
+			rerolled += face
+			continue
+		}
+		chances[v-1] = face
+	}
+	for i := range chances {
+		switch {
+		case !d.RerollRecursive:
+			chances[i] += rerolled * face
+		case chances[i] > 0:
+			chances[i] = 1 / float64(d.Sides-d.rerolledFaces())
+		}
+	}
+	return chances
+}
+
+// faceMoments returns the mean and mean square of a single die of d, in
+// successes for a success pool.
+func (d *Dice) faceMoments() (mean, square float64) {
+	for i, p := range d.faceChances() {
+		v := float64(d.faceValue(i + 1))
+		mean += v * p
+		square += v * v * p
+	}
+	return mean, square
+}
+
+// faceValue returns what a kept die landing on its face-th face adds to the
+// total.
+func (d *Dice) faceValue(face int) int {
+	return d.keptValue(d.adjust(d.face(face)))
+}
+
+// keptValue returns what a kept die showing v adds to the total: v, or for
+// a success pool one if it succeeds and minus one if it fails.
+func (d *Dice) keptValue(v int) int {
+	switch {
+	case d.Success == nil:
+		return v
+	case d.Success.Matches(v):
+		return 1
+	case d.Failure != nil && d.Failure.Matches(v):
+		return -1
+	}
+	return 0
+}
+
+// convolve builds the distribution of a pool without a modifier one die at
+// a time.
+func (d *Dice) convolve() map[int]float64 {
+	dist := map[int]float64{0: 1}
+	chances := d.faceChances()
+	for i := 0; i < d.Count; i++ {
+		next := make(map[int]float64, len(dist)+d.Sides)
+		for total, p := range dist {
+			for s := 1; s <= d.Sides; s++ {
+				next[total+d.faceValue(s)] += p * chances[s-1]
+			}
+		}
+		dist = next
+	}
+
+	shifted := make(map[int]float64, len(dist))
+	for total, p := range dist {
+		shifted[total+d.Bonus] = p
+	}
+	return shifted
+}
+
+// enumerate walks every possible roll of the pool, applying the modifier.
+func (d *Dice) enumerate() (map[int]float64, error) {
+	outcomes := 1
+	for i := 0; i < d.Count; i++ {
+		outcomes *= d.Sides
+		if outcomes > maxEnumeration {
+			return nil, fmt.Errorf("%s has too many outcomes to enumerate", d)
+		}
+	}
+
+	dist := make(map[int]float64)
+	chances := d.faceChances()
+	rolls := make([]int, d.Count)
+	for i := range rolls {
+		rolls[i] = 1
+	}
+	shown := make([]int, d.Count)
+	for {
+		for i, v := range rolls {
+			shown[i] = d.adjust(d.face(v))
+		}
+		kept, _ := applyRollModifier(shown, d.Modifier, d.ModifierCount)
+		total, p := d.Bonus, 1.0
+		for _, k := range kept {
+			total += d.keptValue(k)
+		}
+		for _, v := range rolls {
+			p *= chances[v-1]
+		}
+		dist[total] += p
+
+		// Advance rolls like an odometer.
+		i := 0
+		for ; i < len(rolls) && rolls[i] == d.Sides; i++ {
+			rolls[i] = 1
+		}
+		if i == len(rolls) {
+			return dist, nil
+		}
+		rolls[i]++
+	}
+}
@@ -0,0 +1,122 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultStepChain is the standard polyhedral set, largest die first.
+var DefaultStepChain = []int{20, 12, 10, 8, 6, 4}
+
+// StepRoll is a single die of a step-down cascade.
+type StepRoll struct {
+	Sides int
+	Value int
+}
+
+// StepDownResult is a die whose explosions step down the chain.
+type StepDownResult struct {
+	// Cascade holds every die rolled, starting with the original.
+	Cascade []StepRoll
+	Total   int
+}
+
+func (r *StepDownResult) String() string {
+	parts := make([]string, len(r.Cascade))
+	for i, s := range r.Cascade {
+		parts[i] = fmt.Sprintf("d%d:%d", s.Sides, s.Value)
+	}
+	return fmt.Sprintf("%s = %d", strings.Join(parts, " → "), r.Total)
+}
+
+// RollStepDown rolls a die of sides. On its highest face the die explodes
+// into the next smaller die of chain, which can explode in turn; the
+// smallest die of the chain never explodes. A nil chain is
+// DefaultStepChain.
+func RollStepDown(sides int, chain []int) (*StepDownResult, error) {
+	if chain == nil {
+		chain = DefaultStepChain
+	}
+	step := -1
+	for i, s := range chain {
+		if i > 0 && s >= chain[i-1] || s < 1 {
+			return nil, fmt.Errorf("step chain %v must go from largest to smallest die", chain)
+		}
+		if s == sides {
+			step = i
+		}
+	}
+	if step < 0 {
+		return nil, fmt.Errorf("d%d is not in the step chain %v", sides, chain)
+	}
+
+	res := &StepDownResult{}
+	for ; step < len(chain); step++ {
+		r := result(chain[step])
+		res.Cascade = append(res.Cascade, StepRoll{Sides: chain[step], Value: r})
+		res.Total += r
+		if r < chain[step] {
+			break
+		}
+	}
+	return res, nil
+}
+
+// RollStepDownPool rolls each die of pool with RollStepDown.
+func RollStepDownPool(pool []int, chain []int) ([]*StepDownResult, int, error) {
+	results := make([]*StepDownResult, 0, len(pool))
+	total := 0
+	for _, sides := range pool {
+		res, err := RollStepDown(sides, chain)
+		if err != nil {
+			return nil, 0, err
+		}
+		results = append(results, res)
+		total += res.Total
+	}
+	return results, total, nil
+}
+
+func stepDownGen(args []string) error {
+	fs := flag.NewFlagSet("stepdown", flag.ContinueOnError)
+	chainFlag := fs.String("chain", "", "comma separated step chain, largest die first (default 20,12,10,8,6,4)")
+	dice, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(dice) == 0 {
+		return fmt.Errorf("need to provide dice to roll (d12 d8)")
+	}
+
+	var chain []int
+	if *chainFlag != "" {
+		for _, s := range strings.Split(*chainFlag, ",") {
+			sides, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(s), "d"))
+			if err != nil {
+				return err
+			}
+			chain = append(chain, sides)
+		}
+	}
+	pool := make([]int, 0, len(dice))
+	for _, d := range dice {
+		sides, err := strconv.Atoi(strings.TrimPrefix(d, "d"))
+		if err != nil {
+			return fmt.Errorf("passed illegal die: %s", d)
+		}
+		pool = append(pool, sides)
+	}
+
+	results, total, err := RollStepDownPool(pool, chain)
+	if err != nil {
+		return err
+	}
+	for _, res := range results {
+		fmt.Println(res)
+	}
+	fmt.Println("Total: ", total)
+
+	return nil
+}
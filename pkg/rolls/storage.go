@@ -0,0 +1,411 @@
+package rolls
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by a Storage for a key it does not hold.
+var ErrNotFound = errors.New("not found")
+
+// Storage persists the saved state of the stateful commands, such as macros,
+// decks and hit point trackers, as JSON values under keys grouped into
+// namespaces. A Storage must be safe for concurrent use if the program using
+// it is concurrent.
+type Storage interface {
+	// Get returns the value of key in namespace, or ErrNotFound.
+	Get(namespace, key string) ([]byte, error)
+	// Put replaces the value of key in namespace.
+	Put(namespace, key string, value []byte) error
+	// List returns the keys of namespace in order. A namespace never
+	// written to has none.
+	List(namespace string) ([]string, error)
+	// Delete removes key from namespace. Deleting a missing key is not an
+	// error.
+	Delete(namespace, key string) error
+}
+
+// The namespaces the stateful commands save to. Each key of a namespace is
+// a name, such as a macro's or a creature's, except in NamespaceBank, whose
+// keys are the fields of a ModifierBank, and NamespaceHistory, which is the
+// history log.
+const (
+	NamespaceMacros     = "macros"
+	NamespaceDecks      = "decks"
+	NamespaceCreatures  = "creatures"
+	NamespaceBank       = "bank"
+	NamespaceConditions = "conditions"
+	NamespaceCombats    = "combats"
+	NamespaceDelays     = "delays"
+	NamespaceHistory    = "history"
+)
+
+// LogStorage is a Storage that keeps append-only logs of JSON lines its own
+// way, as FileStorage keeps the history log in a file. A Storage that is
+// not a LogStorage keeps each log as a namespace whose keys number its
+// lines in order.
+type LogStorage interface {
+	Storage
+	// AppendLog appends the lines next returns to the log of namespace.
+	// next is given the last line of the log, or nil if it has none, so
+	// that each append can follow on from the one before.
+	AppendLog(namespace string, next func(last []byte) ([][]byte, error)) error
+	// ReadLog returns the log of namespace, one JSON value per line. A log
+	// never appended to is empty.
+	ReadLog(namespace string) (io.ReadCloser, error)
+	// ReplaceLog replaces the log of namespace with what write writes,
+	// keeping the old log as a backup, and returns where the backup is.
+	ReplaceLog(namespace string, write func(io.Writer) error) (string, error)
+}
+
+// logStorage returns s as a LogStorage, keeping its logs as numbered keys
+// if it does not keep them itself.
+func logStorage(s Storage) LogStorage {
+	if l, ok := s.(LogStorage); ok {
+		return l
+	}
+	return keyedLog{s}
+}
+
+// keyedLog keeps each log in a namespace of its Storage, under keys that
+// are the position of each line, zero-padded so they list in order.
+type keyedLog struct {
+	Storage
+}
+
+// keyedLogMu serializes appends to keyed logs, each of which reads the log
+// to find the next key.
+var keyedLogMu sync.Mutex
+
+func (l keyedLog) AppendLog(namespace string, next func(last []byte) ([][]byte, error)) error {
+	keyedLogMu.Lock()
+	defer keyedLogMu.Unlock()
+	keys, err := l.List(namespace)
+	if err != nil {
+		return err
+	}
+	var last []byte
+	if len(keys) > 0 {
+		if last, err = l.Get(namespace, keys[len(keys)-1]); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	lines, err := next(last)
+	if err != nil {
+		return err
+	}
+	for i, line := range lines {
+		if err := l.Put(namespace, logKey(len(keys)+i), line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l keyedLog) ReadLog(namespace string) (io.ReadCloser, error) {
+	keys, err := l.List(namespace)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for _, key := range keys {
+		line, err := l.Get(namespace, key)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return io.NopCloser(&buf), nil
+}
+
+// ReplaceLog keeps the old log in a namespace named after it and the time,
+// such as history-20240301T190211, and returns that namespace.
+func (l keyedLog) ReplaceLog(namespace string, write func(io.Writer) error) (string, error) {
+	keyedLogMu.Lock()
+	defer keyedLogMu.Unlock()
+	old, err := l.List(namespace)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		return "", err
+	}
+
+	backup := namespace + "-" + time.Now().Format("20060102T150405")
+	for _, key := range old {
+		line, err := l.Get(namespace, key)
+		if err != nil {
+			return "", err
+		}
+		if err := l.Put(backup, key, line); err != nil {
+			return "", err
+		}
+	}
+	n := 0
+	sc := bufio.NewScanner(&buf)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for ; sc.Scan(); n++ {
+		if err := l.Put(namespace, logKey(n), sc.Bytes()); err != nil {
+			return "", err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return "", err
+	}
+	for _, key := range old[min(n, len(old)):] {
+		if err := l.Delete(namespace, key); err != nil {
+			return "", err
+		}
+	}
+	return backup, nil
+}
+
+func logKey(i int) string {
+	return fmt.Sprintf("%012d", i)
+}
+
+var (
+	storeMu sync.RWMutex
+	store   = defaultStorage()
+)
+
+// SetStorage makes the stateful commands and the Load and Save functions
+// save through s, such as a bot's own database. A nil s restores the
+// default, JSON files under the user config directory.
+func SetStorage(s Storage) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	if s == nil {
+		s = defaultStorage()
+	}
+	store = s
+}
+
+func currentStorage() Storage {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+	return store
+}
+
+// LoadState reads every key of namespace in s into v, a pointer to a map or
+// struct whose JSON object has those keys, the way LoadDecks reads decks.
+func LoadState(s Storage, namespace string, v any) error {
+	keys, err := s.List(namespace)
+	if err != nil {
+		return err
+	}
+	obj := make(map[string]json.RawMessage, len(keys))
+	for _, key := range keys {
+		value, err := s.Get(namespace, key)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		obj[key] = value
+	}
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", namespace, err)
+	}
+	return json.Unmarshal(b, v)
+}
+
+// SaveState replaces namespace in s with v, which must encode as a JSON
+// object: each of its keys is put, and keys it no longer has are deleted.
+func SaveState(s Storage, namespace string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return fmt.Errorf("saving %s: want a JSON object: %w", namespace, err)
+	}
+	old, err := s.List(namespace)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := s.Put(namespace, key, obj[key]); err != nil {
+			return err
+		}
+	}
+	for _, key := range old {
+		if _, ok := obj[key]; !ok {
+			if err := s.Delete(namespace, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// MemoryStorage is a Storage that keeps everything in memory, for tests and
+// for programs that save nothing between runs.
+type MemoryStorage struct {
+	mu sync.Mutex
+	// namespaces maps a namespace to its keys and values.
+	namespaces map[string]map[string][]byte
+}
+
+// NewMemoryStorage returns an empty MemoryStorage.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{namespaces: make(map[string]map[string][]byte)}
+}
+
+func (m *MemoryStorage) Get(namespace, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.namespaces[namespace][key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return slices.Clone(value), nil
+}
+
+func (m *MemoryStorage) Put(namespace, key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.namespaces[namespace] == nil {
+		m.namespaces[namespace] = make(map[string][]byte)
+	}
+	m.namespaces[namespace][key] = slices.Clone(value)
+	return nil
+}
+
+func (m *MemoryStorage) List(namespace string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0, len(m.namespaces[namespace]))
+	for key := range m.namespaces[namespace] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (m *MemoryStorage) Delete(namespace, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.namespaces[namespace], key)
+	return nil
+}
+
+// LoadTrackers reads the trackers saved in NamespaceCreatures.
+func LoadTrackers() (map[string]*HPTracker, error) {
+	trackers := make(map[string]*HPTracker)
+	if err := LoadState(currentStorage(), NamespaceCreatures, &trackers); err != nil {
+		return nil, err
+	}
+	return trackers, nil
+}
+
+// SaveTrackers replaces the trackers saved in NamespaceCreatures.
+func SaveTrackers(trackers map[string]*HPTracker) error {
+	return SaveState(currentStorage(), NamespaceCreatures, trackers)
+}
+
+// LoadDecks reads the decks saved in NamespaceDecks.
+func LoadDecks() (map[string]*Deck, error) {
+	decks := make(map[string]*Deck)
+	if err := LoadState(currentStorage(), NamespaceDecks, &decks); err != nil {
+		return nil, err
+	}
+	return decks, nil
+}
+
+// SaveDecks replaces the decks saved in NamespaceDecks.
+func SaveDecks(decks map[string]*Deck) error {
+	return SaveState(currentStorage(), NamespaceDecks, decks)
+}
+
+// LoadBank reads the modifier bank saved in NamespaceBank. Nothing saved is
+// an empty bank.
+func LoadBank() (*ModifierBank, error) {
+	b := &ModifierBank{}
+	if err := LoadState(currentStorage(), NamespaceBank, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// SaveBank replaces the modifier bank saved in NamespaceBank.
+func SaveBank(b *ModifierBank) error {
+	return SaveState(currentStorage(), NamespaceBank, b)
+}
+
+// LoadMacros reads the macros saved in NamespaceMacros.
+func LoadMacros() (map[string]string, error) {
+	macros := make(map[string]string)
+	if err := LoadState(currentStorage(), NamespaceMacros, &macros); err != nil {
+		return nil, err
+	}
+	return macros, nil
+}
+
+// SaveMacros replaces the macros saved in NamespaceMacros.
+func SaveMacros(macros map[string]string) error {
+	return SaveState(currentStorage(), NamespaceMacros, macros)
+}
+
+// LoadConditions reads the conditions saved in NamespaceConditions.
+func LoadConditions() (ConditionSet, error) {
+	s := ConditionSet{}
+	if err := LoadState(currentStorage(), NamespaceConditions, &s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SaveConditions replaces the conditions saved in NamespaceConditions.
+func SaveConditions(s ConditionSet) error {
+	return SaveState(currentStorage(), NamespaceConditions, s)
+}
+
+// LoadDelays reads the delayed rolls saved in NamespaceDelays, by ID.
+func LoadDelays() (map[string]*DelayedRoll, error) {
+	delays := make(map[string]*DelayedRoll)
+	if err := LoadState(currentStorage(), NamespaceDelays, &delays); err != nil {
+		return nil, err
+	}
+	return delays, nil
+}
+
+// SaveDelays replaces the delayed rolls saved in NamespaceDelays.
+func SaveDelays(delays map[string]*DelayedRoll) error {
+	return SaveState(currentStorage(), NamespaceDelays, delays)
+}
+
+// LoadCombats reads the combats saved in NamespaceCombats.
+func LoadCombats() (map[string]*Combat, error) {
+	combats := make(map[string]*Combat)
+	if err := LoadState(currentStorage(), NamespaceCombats, &combats); err != nil {
+		return nil, err
+	}
+	return combats, nil
+}
+
+// SaveCombats replaces the combats saved in NamespaceCombats.
+func SaveCombats(combats map[string]*Combat) error {
+	return SaveState(currentStorage(), NamespaceCombats, combats)
+}
@@ -0,0 +1,132 @@
+package rolls
+
+import (
+	"fmt"
+	"log"
+)
+
+// StreakWindow is how many of the latest natural d20s CheckStreak looks at.
+const StreakWindow = 20
+
+// StreakAlpha is the chance below which a Streak is unusual. It is stricter
+// than the usual 0.05 as the streak is checked after every roll, and one
+// roll in twenty would otherwise be flagged by chance alone.
+const StreakAlpha = 0.01
+
+// Streak is the more surprising run of natural 1s or 20s among a series of
+// d20s.
+type Streak struct {
+	// Face is 1 or 20.
+	Face  int
+	Count int
+	D20s  int
+	// P is the chance of at least Count of Face among D20s fair d20s.
+	P float64
+}
+
+// Unusual reports whether s is less likely than StreakAlpha.
+func (s *Streak) Unusual() bool {
+	return s.P < StreakAlpha
+}
+
+// FindStreak returns the less likely of the runs of natural 1s and natural
+// 20s among naturals, by the binomial tail of each, with 1s winning a tie.
+// Too few of either, cold streaks included, are not looked for.
+func FindStreak(naturals []int) *Streak {
+	var best *Streak
+	for _, face := range []int{1, 20} {
+		s := &Streak{Face: face, D20s: len(naturals)}
+		for _, v := range naturals {
+			if v == face {
+				s.Count++
+			}
+		}
+		s.P = binomialTail(s.D20s, s.Count, 1.0/20)
+		if best == nil || s.P < best.P {
+			best = s
+		}
+	}
+	return best
+}
+
+// binomialTail returns the chance of at least k successes in n trials that
+// each succeed with chance p.
+func binomialTail(n, k int, p float64) float64 {
+	if k <= 0 {
+		return 1
+	}
+	// Each term of the distribution follows from the one before it, so
+	// there are no factorials to overflow for long windows.
+	term, tail := 1.0, 0.0
+	for i := 0; i < n; i++ {
+		term *= 1 - p
+	}
+	for i := 0; i <= n; i++ {
+		if i >= k {
+			tail += term
+		}
+		term *= float64(n-i) / float64(i+1) * p / (1 - p)
+	}
+	return min(tail, 1)
+}
+
+// RecentD20s returns the faces of the last n natural d20s results rolled,
+// oldest first, kept or dropped, leaving voided rolls out.
+func RecentD20s(entries []HistoryEntry, n int) []int {
+	var naturals []int
+	for i := len(entries) - 1; i >= 0 && len(naturals) < n; i-- {
+		if entries[i].Voided {
+			continue
+		}
+		faces, _ := naturalD20s(entries[i].Result)
+		naturals = append(append([]int(nil), faces...), naturals...)
+	}
+	if len(naturals) > n {
+		naturals = naturals[len(naturals)-n:]
+	}
+	return naturals
+}
+
+// CheckStreak finds the streak among the last StreakWindow natural d20s in
+// the history log, or nil when it has none.
+func CheckStreak() (*Streak, error) {
+	entries, _, err := LoadHistory()
+	if err != nil {
+		return nil, err
+	}
+	naturals := RecentD20s(entries, StreakWindow)
+	if len(naturals) == 0 {
+		return nil, nil
+	}
+	return FindStreak(naturals), nil
+}
+
+// printStreak prints the streak in the history log once results, already
+// logged, rolled a d20.
+func printStreak(results []*Result) {
+	for _, res := range results {
+		if _, ok := naturalD20s(res); !ok {
+			continue
+		}
+		s, err := CheckStreak()
+		if err != nil {
+			log.Println("could not check for streaks:", err)
+		} else if s != nil {
+			fmt.Println(s)
+		}
+		return
+	}
+}
+
+// String reports s in a line, as a run worth a look or one well within
+// chance: "5 natural 1s in the last 12 d20s: p≈0.00018, an unusual run".
+func (s *Streak) String() string {
+	if s.Count == 0 {
+		return fmt.Sprintf("No natural 1s or 20s in the last %s, nothing unusual", quantity(s.D20s, "d20"))
+	}
+	verdict := "well within chance"
+	if s.Unusual() {
+		verdict = "an unusual run"
+	}
+	return fmt.Sprintf("%s in the last %s: p≈%.2g, %s", quantity(s.Count, fmt.Sprintf("natural %d", s.Face)), quantity(s.D20s, "d20"), s.P, verdict)
+}
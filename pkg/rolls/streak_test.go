@@ -0,0 +1,112 @@
+package rolls
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+// TestBinomialTail checks the tail against exact sums of the binomial
+// distribution, long windows included.
+func TestBinomialTail(t *testing.T) {
+	tests := []struct {
+		n, k int
+		p    float64
+		want float64
+	}{
+		{20, 0, 0.05, 1},
+		{20, -1, 0.05, 1},
+		{20, 1, 0.05, 0.6415140775914577},
+		{20, 2, 0.05, 0.26416047505615015},
+		{20, 3, 0.05, 0.07548367378849634},
+		{20, 5, 0.05, 0.0025739403346522814},
+		{12, 5, 0.05, 0.0001839462704272461},
+		{20, 20, 0.05, 9.5367431640625e-27},
+		{20, 21, 0.05, 0},
+		{10, 5, 0.5, 0.623046875},
+		{1000, 50, 0.05, 0.5202589429126794},
+		{1000, 80, 0.05, 3.48864497579347e-05},
+	}
+	for _, tt := range tests {
+		got := binomialTail(tt.n, tt.k, tt.p)
+		if math.Abs(got-tt.want) > 1e-9*tt.want {
+			t.Errorf("binomialTail(%d, %d, %g) = %g, want %g", tt.n, tt.k, tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestFindStreak(t *testing.T) {
+	tests := []struct {
+		naturals    []int
+		face, count int
+		unusual     bool
+		want        string
+	}{
+		{
+			[]int{1, 7, 1, 1, 12, 1, 3, 1, 18, 9, 4, 15}, 1, 5, true,
+			"5 natural 1s in the last 12 d20s: p≈0.00018, an unusual run",
+		},
+		{
+			[]int{20, 5, 20, 11, 8, 20, 2, 14, 17, 6, 9, 3, 16, 13, 10, 4, 7, 15, 12, 19}, 20, 3, false,
+			"3 natural 20s in the last 20 d20s: p≈0.075, well within chance",
+		},
+		{
+			[]int{20, 5, 1, 11}, 1, 1, false,
+			"1 natural 1 in the last 4 d20s: p≈0.19, well within chance",
+		},
+		{
+			[]int{2, 3, 19}, 1, 0, false,
+			"No natural 1s or 20s in the last 3 d20s, nothing unusual",
+		},
+		{
+			[]int{20}, 20, 1, false,
+			"1 natural 20 in the last 1 d20: p≈0.05, well within chance",
+		},
+	}
+	for _, tt := range tests {
+		s := FindStreak(tt.naturals)
+		if s.Face != tt.face || s.Count != tt.count || s.D20s != len(tt.naturals) || s.Unusual() != tt.unusual {
+			t.Errorf("FindStreak(%v) = %+v, want %d of %d, unusual %v", tt.naturals, *s, tt.count, tt.face, tt.unusual)
+		}
+		if got := s.String(); got != tt.want {
+			t.Errorf("FindStreak(%v) reads %q, want %q", tt.naturals, got, tt.want)
+		}
+	}
+}
+
+func TestCheckStreak(t *testing.T) {
+	SetStorage(NewMemoryStorage())
+	defer SetStorage(nil)
+
+	if s, err := CheckStreak(); s != nil || err != nil {
+		t.Errorf("CheckStreak with no history = %v, %v", s, err)
+	}
+	d20 := func(face int) *Result {
+		return &Result{Expression: "1d20", Sides: 20, Rolls: []int{face}, Kept: []int{face}, Total: face}
+	}
+	// Five old 20s fall outside the window behind twenty plain rolls.
+	var results []*Result
+	for i := 0; i < 5; i++ {
+		results = append(results, d20(20))
+	}
+	for i := 0; i < StreakWindow; i++ {
+		results = append(results, d20(2+i%18))
+	}
+	results = append(results, &Result{Expression: "2d6", Sides: 6, Rolls: []int{1, 1}, Kept: []int{1, 1}, Total: 2})
+	if err := AppendHistory(results...); err != nil {
+		t.Fatal(err)
+	}
+	s, err := CheckStreak()
+	if err != nil || s == nil || s.D20s != StreakWindow || s.Count != 0 {
+		t.Errorf("CheckStreak = %v, %v, want none in the last %d d20s", s, err, StreakWindow)
+	}
+
+	entries, _, _ := LoadHistory()
+	if got := RecentD20s(entries, 3); !slices.Equal(got, []int{19, 2, 3}) {
+		t.Errorf("RecentD20s(3) = %v, want the three latest d20s", got)
+	}
+	entries[len(entries)-2].Voided = true
+	if got := RecentD20s(entries, 3); !slices.Equal(got, []int{18, 19, 2}) {
+		t.Errorf("RecentD20s(3) with the latest d20 voided = %v", got)
+	}
+}
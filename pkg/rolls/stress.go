@@ -0,0 +1,111 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+)
+
+// panicEntry is a row of the panic table, used for panic rolls up to Max.
+type panicEntry struct {
+	Max  int
+	Text string
+}
+
+// panicTable is the panic table, ordered by Max. The last row covers every
+// higher roll.
+var panicTable = []panicEntry{
+	{6, "Keeping it together: you hold your nerve."},
+	{7, "Nervous twitch: your stress and that of every nearby character rises by one."},
+	{8, "Tremble: you shake uncontrollably, -2 to skills needing a steady hand until your stress drops."},
+	{9, "Drop item: you drop a weapon or whatever else you are holding."},
+	{10, "Freeze: you are frozen in place and lose your next slow action."},
+	{11, "Seek cover: you must use your next action to get away from the danger."},
+	{12, "Scream: you scream, raising the stress of everyone nearby by one, and lose your next slow action."},
+	{13, "Flee: you run from the danger and will not come back willingly."},
+	{14, "Frenzy: you attack the nearest person or creature."},
+	{15, "Catatonic: you collapse and cannot act until snapped out of it."},
+}
+
+// StressResult is a roll of base and stress dice.
+type StressResult struct {
+	Base   []int
+	Stress []int
+	// Successes counts the 6s across both pools.
+	Successes int
+	// Panic is set when any stress die showed a 1.
+	Panic      bool
+	PanicRoll  *Result
+	PanicEntry string
+}
+
+// RollStress rolls base d6s and stress d6s, counting 6s as successes. A 1 on
+// any stress die forces a panic roll of 1d6 plus the stress level, which is
+// looked up on the panic table.
+func RollStress(base, stress int) (*StressResult, error) {
+	if base < 0 || stress < 0 || base+stress < 1 {
+		return nil, fmt.Errorf("passed illegal stress roll: %d base and %d stress dice", base, stress)
+	}
+
+	res := &StressResult{}
+	for i := 0; i < base+stress; i++ {
+		r := result(6)
+		if r == 6 {
+			res.Successes++
+		}
+		if i < base {
+			res.Base = append(res.Base, r)
+			continue
+		}
+		res.Stress = append(res.Stress, r)
+		if r == 1 {
+			res.Panic = true
+		}
+	}
+
+	if res.Panic {
+		res.PanicRoll = (&Dice{Count: 1, Sides: 6, Bonus: stress}).Roll()
+		res.PanicEntry = lookupPanic(res.PanicRoll.Total)
+	}
+	return res, nil
+}
+
+func lookupPanic(total int) string {
+	for _, e := range panicTable {
+		if total <= e.Max {
+			return e.Text
+		}
+	}
+	return panicTable[len(panicTable)-1].Text
+}
+
+func stressGen(args []string) error {
+	fs := flag.NewFlagSet("stress", flag.ContinueOnError)
+	stress := fs.Int("stress", 0, "number of stress dice, the character's stress level")
+	pools, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pools) != 1 {
+		return fmt.Errorf("need to provide the number of base dice")
+	}
+	base, err := strconv.Atoi(pools[0])
+	if err != nil {
+		return err
+	}
+
+	res, err := RollStress(base, *stress)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Base:", res.Base)
+	fmt.Println("Stress:", res.Stress)
+	fmt.Println("Successes:", res.Successes)
+	if res.Panic {
+		fmt.Println("Panic!", res.PanicRoll)
+		fmt.Println(res.PanicEntry)
+	}
+
+	return nil
+}
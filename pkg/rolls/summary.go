@@ -0,0 +1,196 @@
+package rolls
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SummarizeAbove is the pool size above which Dice.Roll summarizes the dice
+// instead of keeping every roll. Zero disables summarizing.
+var SummarizeAbove = 1000
+
+// RollSummary describes every die of a summarized pool.
+type RollSummary struct {
+	Count int     `json:"count"`
+	Min   int     `json:"min"`
+	Max   int     `json:"max"`
+	Mean  float64 `json:"mean"`
+}
+
+type rankedDie struct {
+	index int
+	value int
+}
+
+// dieHeap is a heap of dice ordered by less, so its root is the die that is
+// next to be evicted.
+type dieHeap struct {
+	dice []rankedDie
+	less func(a, b rankedDie) bool
+}
+
+func (h *dieHeap) Len() int           { return len(h.dice) }
+func (h *dieHeap) Less(i, j int) bool { return h.less(h.dice[i], h.dice[j]) }
+func (h *dieHeap) Swap(i, j int)      { h.dice[i], h.dice[j] = h.dice[j], h.dice[i] }
+func (h *dieHeap) Push(x interface{}) { h.dice = append(h.dice, x.(rankedDie)) }
+func (h *dieHeap) Pop() interface{} {
+	last := h.dice[len(h.dice)-1]
+	h.dice = h.dice[:len(h.dice)-1]
+	return last
+}
+
+// offer adds d to the heap, replacing the root once the heap already holds
+// limit dice and d should be held over it.
+func (h *dieHeap) offer(d rankedDie, limit int) {
+	switch {
+	case len(h.dice) < limit:
+		h.dice = append(h.dice, d)
+		heap.Fix(h, len(h.dice)-1)
+	case limit > 0 && h.less(h.dice[0], d):
+		h.dice[0] = d
+		heap.Fix(h, 0)
+	}
+}
+
+// keepsBefore returns the order in which a modifier prefers to keep dice:
+// by value, then earlier rolled dice first.
+func keepsBefore(m Modifier) func(a, b rankedDie) bool {
+	return func(a, b rankedDie) bool {
+		if a.value != b.value {
+			if m == KeepHighest {
+				return a.value > b.value
+			}
+			return a.value < b.value
+		}
+		return a.index < b.index
+	}
+}
+
+// values returns the held dice in the order they were rolled.
+func (h *dieHeap) values() []int {
+	if len(h.dice) == 0 {
+		return nil
+	}
+	sort.Slice(h.dice, func(i, j int) bool { return h.dice[i].index < h.dice[j].index })
+	values := make([]int, 0, len(h.dice))
+	for _, d := range h.dice {
+		values = append(values, d.value)
+	}
+	return values
+}
+
+// rollSummarized rolls the pool without keeping every die. A modifier is
+// applied by holding only the smaller of the kept or dropped dice in a
+// bounded heap; when even that would exceed SummarizeAbove it reports false
+// so the pool is rolled in full. Ties are broken as applyRollModifier does,
+// in favour of keeping the earlier die. Dice below a MinPerDie or above a
+// MaxPerDie are summarized as counting it.
+func (r *Roller) rollSummarized(ctx context.Context, d *Dice) (*Result, bool, error) {
+	var (
+		h         *dieHeap
+		held      int
+		holdsKept bool
+	)
+	if d.Modifier == KeepMiddle || d.Modifier == DropMiddle {
+		// The middle of a pool is only known once every die is in.
+		return nil, false, nil
+	}
+	if d.Modifier != NoModifier && d.ModifierCount < d.Count {
+		keep := d.ModifierCount
+		if keep < 0 {
+			keep = 0
+		}
+		better := keepsBefore(d.Modifier)
+		holdsKept = keep <= d.Count-keep
+		if holdsKept {
+			// Hold the best dice; the root is the worst of them.
+			held = keep
+			h = &dieHeap{less: func(a, b rankedDie) bool { return better(b, a) }}
+		} else {
+			// Hold the worst dice; the root is the best of them.
+			held = d.Count - keep
+			h = &dieHeap{less: better}
+		}
+		if held > SummarizeAbove {
+			return nil, false, nil
+		}
+	}
+
+	sum := &RollSummary{Count: d.Count, Min: d.Sides, Max: 1}
+	// counted holds the successes and failures of every die.
+	total, counted := 0, &Result{}
+	for i := 0; i < d.Count; i++ {
+		if i%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, true, err
+			}
+		}
+		v := d.adjust(r.die(d.Sides))
+		total += v
+		if v < sum.Min {
+			sum.Min = v
+		}
+		if v > sum.Max {
+			sum.Max = v
+		}
+		counted.countSuccess(d, v, 1)
+
+		if h != nil {
+			h.offer(rankedDie{index: i, value: v}, held)
+		}
+	}
+	if d.Count > 0 {
+		sum.Mean = float64(total) / float64(d.Count)
+	}
+
+	res := &Result{
+		Expression:  d.String(),
+		Sides:       d.Sides,
+		Bonus:       d.Bonus,
+		Summarized:  true,
+		Summary:     sum,
+		SuccessPool: d.Success != nil,
+	}
+	switch {
+	case h == nil:
+		res.Total, res.Successes, res.Failures = total, counted.Successes, counted.Failures
+	case holdsKept:
+		res.Kept = h.values()
+		for _, k := range res.Kept {
+			res.Total += k
+			res.countSuccess(d, k, 1)
+		}
+	default:
+		res.Dropped = h.values()
+		res.Total, res.Successes, res.Failures = total, counted.Successes, counted.Failures
+		for _, dr := range res.Dropped {
+			res.Total -= dr
+			res.countSuccess(d, dr, -1)
+		}
+	}
+	res.Total += d.Bonus
+	if res.SuccessPool {
+		res.netSuccesses()
+	}
+
+	return res, true, nil
+}
+
+func (r *Result) summaryString() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: Summary: %s, min %d, max %d, mean %.2f", r.Expression, quantity(r.Summary.Count, "die"), r.Summary.Min, r.Summary.Max, r.Summary.Mean)
+	switch {
+	case r.Kept != nil:
+		fmt.Fprintf(&b, " Kept: %s", diceList(r.Kept))
+	case r.Dropped != nil:
+		fmt.Fprintf(&b, " Dropped: %s", quantity(len(r.Dropped), "die"))
+	}
+	if r.Bonus != 0 {
+		fmt.Fprintf(&b, " %+d", r.Bonus)
+	}
+	b.WriteString(" = " + r.totalString())
+	return b.String()
+}
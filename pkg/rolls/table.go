@@ -0,0 +1,43 @@
+package rolls
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Table is a list of entries rolled on with a single die, one entry per face.
+type Table struct {
+	Name    string
+	Entries []string
+}
+
+// ReadTable reads a table from CSV, taking the first column of each row as an
+// entry. Blank rows are skipped.
+func ReadTable(name string, r io.Reader) (*Table, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading table %s: %w", name, err)
+	}
+
+	t := &Table{Name: name}
+	for _, record := range records {
+		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		t.Entries = append(t.Entries, strings.TrimSpace(record[0]))
+	}
+	return t, nil
+}
+
+// Roll rolls a die with one face per entry and returns the face and entry.
+func (t *Table) Roll() (int, string, error) {
+	if len(t.Entries) == 0 {
+		return 0, "", fmt.Errorf("table %s has no entries", t.Name)
+	}
+	face := result(len(t.Entries))
+	return face, t.Entries[face-1], nil
+}
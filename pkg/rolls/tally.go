@@ -0,0 +1,113 @@
+package rolls
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RollStats is a snapshot of the dice a Roller has rolled since its stats
+// were enabled or last reset.
+type RollStats struct {
+	Since time.Time  `json:"since"`
+	Dice  []DieStats `json:"dice"`
+}
+
+// DieStats counts the dice of one size in a RollStats. A fair die rolled
+// often enough has a mean near (Sides+1)/2 and lands on each of its faces
+// about Rolls/Sides times.
+type DieStats struct {
+	Sides int     `json:"sides"`
+	Rolls uint64  `json:"rolls"`
+	Mean  float64 `json:"mean"`
+	// NaturalMax and NaturalOne count the dice that landed on their highest
+	// face and on a 1.
+	NaturalMax uint64 `json:"natural_max"`
+	NaturalOne uint64 `json:"natural_one"`
+}
+
+// WithStats makes the Roller count every die it rolls by size, for
+// Roller.Stats. Counting is done with atomic adds, so it is cheap enough
+// for the hot path and safe however the Roller is shared. Rollers split
+// from it add to the same counts.
+func WithStats() RollerOption {
+	return func(r *Roller) {
+		r.stats = &rollTally{}
+		r.stats.reset()
+	}
+}
+
+// Stats returns what the Roller has rolled since its stats were enabled or
+// last reset, ordered by die size. It is empty unless the Roller was
+// created WithStats.
+func (r *Roller) Stats() RollStats {
+	if r.stats == nil {
+		return RollStats{}
+	}
+	return r.stats.snapshot()
+}
+
+// ResetStats clears the Roller's stats and restarts them from now.
+func (r *Roller) ResetStats() {
+	if r.stats != nil {
+		r.stats.reset()
+	}
+}
+
+// rollTally holds a Roller's counts. Reset swaps in a fresh table rather
+// than zeroing counters, so a roll racing a reset lands wholly in one table.
+type rollTally struct {
+	table atomic.Pointer[tallyTable]
+}
+
+type tallyTable struct {
+	since time.Time
+	sizes sync.Map // sides -> *dieTally
+}
+
+type dieTally struct {
+	rolls, sum, max, one atomic.Uint64
+}
+
+func (t *rollTally) reset() {
+	t.table.Store(&tallyTable{since: time.Now()})
+}
+
+func (t *rollTally) record(sides, v int) {
+	table := t.table.Load()
+	dt, ok := table.sizes.Load(sides)
+	if !ok {
+		dt, _ = table.sizes.LoadOrStore(sides, &dieTally{})
+	}
+	c := dt.(*dieTally)
+	c.rolls.Add(1)
+	c.sum.Add(uint64(v))
+	if v == sides {
+		c.max.Add(1)
+	}
+	if v == 1 {
+		c.one.Add(1)
+	}
+}
+
+func (t *rollTally) snapshot() RollStats {
+	table := t.table.Load()
+	stats := RollStats{Since: table.since, Dice: []DieStats{}}
+	table.sizes.Range(func(k, v any) bool {
+		c := v.(*dieTally)
+		ds := DieStats{
+			Sides:      k.(int),
+			Rolls:      c.rolls.Load(),
+			NaturalMax: c.max.Load(),
+			NaturalOne: c.one.Load(),
+		}
+		if ds.Rolls > 0 {
+			ds.Mean = float64(c.sum.Load()) / float64(ds.Rolls)
+		}
+		stats.Dice = append(stats.Dice, ds)
+		return true
+	})
+	slices.SortFunc(stats.Dice, func(a, b DieStats) int { return a.Sides - b.Sides })
+	return stats
+}
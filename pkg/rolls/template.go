@@ -0,0 +1,491 @@
+package rolls
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PlaceholderType is the kind of value a template placeholder accepts.
+type PlaceholderType string
+
+const (
+	// IntPlaceholder accepts an integer, such as 7 or -1.
+	IntPlaceholder PlaceholderType = "int"
+	// DicePlaceholder accepts a dice expression, such as 1d8.
+	DicePlaceholder PlaceholderType = "dice"
+)
+
+// sampleValues are substituted for placeholders to check that a template
+// yields a valid expression.
+var sampleValues = map[PlaceholderType]string{
+	IntPlaceholder:  "0",
+	DicePlaceholder: "1d4",
+}
+
+// Placeholder is a named hole in a Template, written {name} or {name:type}.
+type Placeholder struct {
+	Name string
+	Type PlaceholderType
+}
+
+// Template is an expression with placeholders filled at roll time, such as
+// 1d20+{mod}.
+type Template struct {
+	Source       string
+	Placeholders []Placeholder
+}
+
+// ParseTemplate parses src and checks that it yields a valid expression once
+// its placeholders are filled, so a mistyped template fails when it is saved
+// rather than when it is rolled. Placeholders without a type take integers.
+func ParseTemplate(src string) (*Template, error) {
+	t := &Template{Source: src}
+	seen := make(map[string]bool)
+	rest := src
+	for {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			break
+		}
+		if rest[open] == '}' {
+			return nil, fmt.Errorf("template %s has an unmatched }", src)
+		}
+		end := strings.IndexAny(rest[open+1:], "{}")
+		if end < 0 || rest[open+1+end] == '{' {
+			return nil, fmt.Errorf("template %s has an unmatched {", src)
+		}
+
+		p, err := parsePlaceholder(rest[open+1 : open+1+end])
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", src, err)
+		}
+		if !seen[p.Name] {
+			seen[p.Name] = true
+			t.Placeholders = append(t.Placeholders, p)
+		}
+		rest = rest[open+end+2:]
+	}
+
+	sample := make(map[string]string, len(t.Placeholders))
+	for _, p := range t.Placeholders {
+		sample[p.Name] = sampleValues[p.Type]
+	}
+	if _, err := ParseExpression(t.substitute(sample)); err != nil {
+		return nil, fmt.Errorf("template %s is not a valid expression: %w", src, err)
+	}
+	return t, nil
+}
+
+func parsePlaceholder(s string) (Placeholder, error) {
+	name, typ, _ := strings.Cut(s, ":")
+	p := Placeholder{Name: name, Type: PlaceholderType(typ)}
+	if p.Type == "" {
+		p.Type = IntPlaceholder
+	}
+	if _, ok := sampleValues[p.Type]; !ok {
+		return p, fmt.Errorf("placeholder %s has unknown type %q, want int or dice", name, typ)
+	}
+	if name == "" {
+		return p, fmt.Errorf("placeholder {%s} has no name", s)
+	}
+	for _, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return p, fmt.Errorf("placeholder name %q may only hold letters, digits and _", name)
+		}
+	}
+	return p, nil
+}
+
+// Fill substitutes values into the template, checking each against its
+// placeholder's type, and returns the expression. Every placeholder must
+// be filled.
+func (t *Template) Fill(values map[string]string) (string, error) {
+	var missing []string
+	for _, p := range t.Placeholders {
+		v, ok := values[p.Name]
+		if !ok {
+			missing = append(missing, p.Name)
+			continue
+		}
+		switch p.Type {
+		case IntPlaceholder:
+			if _, err := strconv.Atoi(v); err != nil {
+				return "", fmt.Errorf("placeholder %s needs an integer, got %q", p.Name, v)
+			}
+		case DicePlaceholder:
+			if _, err := ParseExpression(v); err != nil {
+				return "", fmt.Errorf("placeholder %s needs dice, got %q: %w", p.Name, v, err)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("template %s is missing values for %s", t.Source, strings.Join(missing, ", "))
+	}
+
+	expr := t.substitute(values)
+	if _, err := ParseExpression(expr); err != nil {
+		return "", err
+	}
+	return expr, nil
+}
+
+// substitute replaces every placeholder with its value. Integers are
+// written with their sign so 1d20+{mod} reads 1d20-1 rather than 1d20+-1.
+func (t *Template) substitute(values map[string]string) string {
+	var b strings.Builder
+	rest := t.Source
+	for {
+		open := strings.Index(rest, "{")
+		if open < 0 {
+			b.WriteString(rest)
+			return b.String()
+		}
+		end := strings.Index(rest, "}")
+		p, _ := parsePlaceholder(rest[open+1 : end])
+		prefix, v := rest[:open], values[p.Name]
+		if n, err := strconv.Atoi(v); err == nil && p.Type == IntPlaceholder && strings.HasSuffix(prefix, "+") {
+			prefix, v = strings.TrimSuffix(prefix, "+"), fmt.Sprintf("%+d", n)
+		}
+		b.WriteString(prefix)
+		b.WriteString(v)
+		rest = rest[end+1:]
+	}
+}
+
+// ReadMacros reads named macro templates written by WriteMacros.
+func ReadMacros(r io.Reader) (map[string]string, error) {
+	macros := make(map[string]string)
+	if err := json.NewDecoder(r).Decode(&macros); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return macros, nil
+}
+
+// ReadMacroFile reads the macros of the file called name from r: TOML for
+// a .toml file, JSON as WriteMacros writes it for a .json file, and
+// otherwise whichever of the two the file starts like.
+func ReadMacroFile(name string, r io.Reader) (map[string]string, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	ext := filepath.Ext(name)
+	if ext == ".json" || ext != ".toml" && bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		macros, err := ReadMacros(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a JSON object of macros, as macro add saves them: %w", name, err)
+		}
+		return macros, nil
+	}
+	macros, err := ReadMacrosTOML(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", name, err)
+	}
+	return macros, nil
+}
+
+// ReadMacrosTOML reads named macro templates from TOML, one
+// NAME = "EXPR" pair to a line, with # comments:
+//
+//	# Fighter
+//	attack = "1d20+{mod}"
+//	"great weapon" = '2d6+{str}'
+//
+// Names may be bare or quoted. Tables, and values other than strings, are
+// not macros and are refused.
+func ReadMacrosTOML(r io.Reader) (map[string]string, error) {
+	macros := make(map[string]string)
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(stripComment(sc.Text()))
+		switch {
+		case text == "":
+			continue
+		case strings.HasPrefix(text, "["):
+			return nil, fmt.Errorf("%d: unknown table %s, list every macro at the top level as NAME = \"EXPR\"", line, text)
+		}
+		name, raw, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("%d: want NAME = \"EXPR\", got %q", line, text)
+		}
+		name, raw = strings.TrimSpace(name), strings.TrimSpace(raw)
+		var err error
+		if !bareKey.MatchString(name) {
+			if name, err = tomlString(name); err != nil {
+				return nil, fmt.Errorf("%d: macro names must be bare or quoted: %w", line, err)
+			}
+		}
+		if _, ok := macros[name]; ok {
+			return nil, fmt.Errorf("%d: two macros are named %q", line, name)
+		}
+		if macros[name], err = tomlString(raw); err != nil {
+			return nil, fmt.Errorf("%d: macro %s: %w", line, name, err)
+		}
+	}
+	return macros, sc.Err()
+}
+
+// bareKey matches the TOML keys that need no quotes.
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// WriteMacros writes named macro templates as JSON.
+func WriteMacros(w io.Writer, macros map[string]string) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(macros)
+}
+
+// stringList is a flag that collects every value it is given.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+func macroGen(args []string) error {
+	fs := flag.NewFlagSet("macro", flag.ContinueOnError)
+	var sets stringList
+	fs.Var(&sets, "set", "fill a placeholder as NAME=VALUE, may be repeated")
+	seed := fs.Int64("seed", 1, "seed to roll every macro with for macro test")
+	golden := fs.String("golden", "", "file of results macro test compares against")
+	update := fs.Bool("update", false, "have macro test rewrite the golden file instead of comparing with it")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		return fmt.Errorf("need to provide a macro command: add, use, list, lint, test or rm")
+	}
+	values := make(map[string]string, len(sets))
+	for _, s := range sets {
+		name, v, ok := strings.Cut(s, "=")
+		if !ok {
+			return fmt.Errorf("passed illegal --set: %q, want NAME=VALUE", s)
+		}
+		values[name] = v
+	}
+	if rest[0] == "test" && len(rest) == 2 {
+		return macroTest(rest[1], *golden, *seed, *update, values)
+	}
+
+	macros, err := LoadMacros()
+	if err != nil {
+		return err
+	}
+	switch {
+	case rest[0] == "add" && len(rest) == 3:
+		if _, err := ParseTemplate(rest[2]); err != nil {
+			return err
+		}
+		macros[rest[1]] = rest[2]
+		return SaveMacros(macros)
+	case rest[0] == "rm" && len(rest) == 2:
+		if _, ok := macros[rest[1]]; !ok {
+			return fmt.Errorf("no macro named %q", rest[1])
+		}
+		delete(macros, rest[1])
+		return SaveMacros(macros)
+	case rest[0] == "list" && len(rest) == 1:
+		for _, name := range macroNames(macros) {
+			fmt.Printf("%s: %s\n", name, macros[name])
+		}
+		return nil
+	case rest[0] == "lint" && len(rest) == 1:
+		broken := 0
+		for _, name := range macroNames(macros) {
+			text, err := lintMacro(macros[name])
+			if err != nil {
+				broken++
+				text = "error: " + err.Error()
+			}
+			fmt.Printf("%s: %s\n  %s\n", name, macros[name], text)
+		}
+		if broken > 0 {
+			verb := "do not parse"
+			if broken == 1 {
+				verb = "does not parse"
+			}
+			return fmt.Errorf("%d of %s %s", broken, quantity(len(macros), "macro"), verb)
+		}
+		return nil
+	case rest[0] == "use" && len(rest) == 2:
+		src, ok := macros[rest[1]]
+		if !ok {
+			return fmt.Errorf("no macro named %q", rest[1])
+		}
+		t, err := ParseTemplate(src)
+		if err != nil {
+			return err
+		}
+		expr, err := t.Fill(values)
+		if err != nil {
+			return err
+		}
+		res, err := RollString(expr)
+		if err != nil {
+			return err
+		}
+		logHistory(res)
+		fmt.Println(res)
+		return nil
+	}
+	return fmt.Errorf("unknown macro command %q, want add NAME EXPR, use NAME, list, lint, test FILE.toml or rm NAME", strings.Join(rest, " "))
+}
+
+func macroNames(macros map[string]string) []string {
+	names := make([]string, 0, len(macros))
+	for name := range macros {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lintMacro explains src with every placeholder filled by its sample value.
+func lintMacro(src string) (string, error) {
+	t, err := ParseTemplate(src)
+	if err != nil {
+		return "", err
+	}
+	sample := make(map[string]string, len(t.Placeholders))
+	filled := make([]string, 0, len(t.Placeholders))
+	for _, p := range t.Placeholders {
+		sample[p.Name] = sampleValues[p.Type]
+		filled = append(filled, fmt.Sprintf("{%s} as %s", p.Name, sampleValues[p.Type]))
+	}
+	text, err := Explain(t.substitute(sample))
+	if err != nil {
+		return "", err
+	}
+	if len(filled) > 0 {
+		text = fmt.Sprintf("With %s: %s", strings.Join(filled, ", "), text)
+	}
+	return text, nil
+}
+
+// ErrMacroTest is wrapped by the error macro test returns when it fails,
+// whether a macro changed or the macros could not be compared at all, so a
+// campaign's CI or git hooks can fail on it.
+var ErrMacroTest = errors.New("macro test failed")
+
+// macroTest rolls every macro in the TOML or JSON macro file at path with a
+// roller seeded from seed, filling placeholders from values or else their
+// sample values, and compares each result with the one recorded for it in
+// the golden file. Each macro gets a roller of its own, so adding or
+// removing one leaves the others' rolls alone. With update set, the golden
+// file is rewritten instead.
+func macroTest(path, golden string, seed int64, update bool, values map[string]string) (err error) {
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrMacroTest, err)
+		}
+	}()
+	if golden == "" {
+		return fmt.Errorf("need to provide a --golden file to compare the macros with")
+	}
+	macros, err := LoadMacroFile(path)
+	if err != nil {
+		return err
+	}
+	if len(macros) == 0 {
+		return fmt.Errorf("no macros in %s", path)
+	}
+
+	results := make(map[string]*Result, len(macros))
+	for _, name := range macroNames(macros) {
+		res, err := rollMacro(macros[name], seed, values)
+		if err != nil {
+			return fmt.Errorf("macro %s: %w", name, err)
+		}
+		results[name] = res
+	}
+	if update {
+		if err := SaveGolden(golden, results); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s to %s\n", quantity(len(results), "result"), golden)
+		return nil
+	}
+
+	want, err := LoadGolden(golden)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no golden file %s to compare with, write it with --update", golden)
+	}
+	if err != nil {
+		return err
+	}
+	changed := 0
+	for _, name := range macroNames(macros) {
+		old, ok := want[name]
+		if !ok {
+			changed++
+			fmt.Printf("%s: new, not in %s\n", name, golden)
+			continue
+		}
+		diffs := CompareResults(old, results[name])
+		if len(diffs) == 0 {
+			fmt.Printf("%s: ok\n", name)
+			continue
+		}
+		changed++
+		fmt.Printf("%s: changed\n", name)
+		for _, d := range diffs {
+			fmt.Printf("  %s\n", d)
+		}
+	}
+	for name := range want {
+		if _, ok := macros[name]; !ok {
+			changed++
+			fmt.Printf("%s: removed, still in %s\n", name, golden)
+		}
+	}
+	if changed > 0 {
+		verb := "differ"
+		if changed == 1 {
+			verb = "differs"
+		}
+		return fmt.Errorf("%s %s from %s, rerun with --update if that was intended", quantity(changed, "macro"), verb, golden)
+	}
+	return nil
+}
+
+// rollMacro fills src from values, falling back to each placeholder's sample
+// value, and rolls it with a roller seeded from seed.
+func rollMacro(src string, seed int64, values map[string]string) (*Result, error) {
+	t, err := ParseTemplate(src)
+	if err != nil {
+		return nil, err
+	}
+	filled := make(map[string]string, len(t.Placeholders))
+	for _, p := range t.Placeholders {
+		filled[p.Name] = sampleValues[p.Type]
+		if v, ok := values[p.Name]; ok {
+			filled[p.Name] = v
+		}
+	}
+	expr, err := t.Fill(filled)
+	if err != nil {
+		return nil, err
+	}
+	e, err := ParseExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+	return NewRoller(WithSeed(seed)).RollExpression(e), nil
+}
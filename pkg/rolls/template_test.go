@@ -0,0 +1,136 @@
+package rolls
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestMacroTest(t *testing.T) {
+	if runtime.GOOS == "js" {
+		t.Skip("js builds have no macro files")
+	}
+	dir := t.TempDir()
+	macros, golden := filepath.Join(dir, "macros.toml"), filepath.Join(dir, "golden.json")
+	write := func(src string) {
+		if err := os.WriteFile(macros, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("atk = \"1d20+{mod}\"\ndmg = \"2d6+3\"\n")
+
+	err := macroTest(macros, golden, 99, false, nil)
+	if !errors.Is(err, ErrMacroTest) || !strings.Contains(err.Error(), "--update") {
+		t.Errorf("macro test without a golden file = %v, want ErrMacroTest asking for --update", err)
+	}
+	if err := macroTest(macros, golden, 99, true, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := macroTest(macros, golden, 99, false, nil); err != nil {
+		t.Errorf("macro test against the golden file it just wrote: %v", err)
+	}
+
+	for _, src := range []string{
+		"atk = \"1d20+{mod}\"\ndmg = \"2d6+4\"\n",
+		"atk = \"1d20+{mod}\"\n",
+		"atk = \"1d20+{mod}\"\ndmg = \"2d6+3\"\nheal = \"1d8\"\n",
+		"# none\n",
+	} {
+		write(src)
+		if err := macroTest(macros, golden, 99, false, nil); !errors.Is(err, ErrMacroTest) {
+			t.Errorf("macro test of %q = %v, want ErrMacroTest", src, err)
+		} else if strings.Contains(err.Error(), "macros.toml:") {
+			t.Errorf("macro test of %q could not read the macros: %v", src, err)
+		}
+	}
+	if err := macroTest(macros, "", 99, false, nil); !errors.Is(err, ErrMacroTest) {
+		t.Errorf("macro test with no --golden = %v, want ErrMacroTest", err)
+	}
+}
+
+func TestReadMacroFile(t *testing.T) {
+	const toml = `# Fighter
+attack = "1d20+{mod}"   # to hit
+"great weapon" = '2d6+{str}'
+heal="1d8 # not a comment"
+`
+	want := map[string]string{"attack": "1d20+{mod}", "great weapon": "2d6+{str}", "heal": "1d8 # not a comment"}
+	tests := []struct {
+		name, src string
+	}{
+		{"macros.toml", toml},
+		{"macros", toml},
+		{"macros.json", `{"attack": "1d20+{mod}", "great weapon": "2d6+{str}", "heal": "1d8 # not a comment"}`},
+		{"macros", ` {"attack": "1d20+{mod}", "great weapon": "2d6+{str}", "heal": "1d8 # not a comment"}`},
+	}
+	for _, tt := range tests {
+		got, err := ReadMacroFile(tt.name, strings.NewReader(tt.src))
+		if err != nil || !maps.Equal(got, want) {
+			t.Errorf("ReadMacroFile(%s) = %v, %v, want %v", tt.name, got, err, want)
+		}
+	}
+
+	for _, tt := range []struct {
+		name, src, want string
+	}{
+		{"macros.json", "attack = \"1d20\"", "macros.json is not a JSON object of macros"},
+		{"macros.toml", "[fighter]\nattack = \"1d20\"", "macros.toml:1: unknown table [fighter]"},
+		{"macros.toml", "attack = 1d20", "macros.toml:1: macro attack: want a quoted string"},
+		{"macros.toml", "attack = \"1d20\"\nattack = \"1d20+1\"", `macros.toml:2: two macros are named "attack"`},
+		{"macros.toml", "great weapon = \"2d6\"", "macros.toml:1: macro names must be bare or quoted"},
+		{"macros.toml", "attack", `macros.toml:1: want NAME = "EXPR"`},
+	} {
+		if _, err := ReadMacroFile(tt.name, strings.NewReader(tt.src)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ReadMacroFile(%s, %q) = %v, want an error saying %s", tt.name, tt.src, err, tt.want)
+		}
+	}
+}
+
+// TestTemplateFill checks a template that saves also fills and rolls, sums
+// of dice groups included, so a macro never fails only at the table.
+func TestTemplateFill(t *testing.T) {
+	tests := []struct {
+		src    string
+		values map[string]string
+		want   string
+	}{
+		{"1d20+{mod}", map[string]string{"mod": "5"}, "1d20+5"},
+		{"1d20+{mod}", map[string]string{"mod": "-1"}, "1d20-1"},
+		{"1d20+{mod}+1d4", map[string]string{"mod": "3"}, "1d20+3+1d4"},
+		{"{dmg:dice}+{mod}", map[string]string{"dmg": "2d6+1d8", "mod": "2"}, "2d6+1d8+2"},
+		{"({dmg:dice})*2", map[string]string{"dmg": "1d6+1d4"}, "(1d6+1d4)*2"},
+	}
+	for _, tt := range tests {
+		tmpl, err := ParseTemplate(tt.src)
+		if err != nil {
+			t.Errorf("ParseTemplate(%s): %v", tt.src, err)
+			continue
+		}
+		expr, err := tmpl.Fill(tt.values)
+		if err != nil || expr != tt.want {
+			t.Errorf("%s filled with %v = %q, %v, want %q", tt.src, tt.values, expr, err, tt.want)
+			continue
+		}
+		if _, err := ParseExpression(expr); err != nil {
+			t.Errorf("%s filled as %s does not roll: %v", tt.src, expr, err)
+		}
+	}
+
+	tmpl, err := ParseTemplate("1d20+{mod}+{extra:dice}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, values := range []map[string]string{
+		{"mod": "x", "extra": "1d4"},
+		{"mod": "1", "extra": "1d"},
+		{"mod": "1"},
+	} {
+		if _, err := tmpl.Fill(values); err == nil {
+			t.Errorf("filling %s with %v did not fail", tmpl.Source, values)
+		}
+	}
+}
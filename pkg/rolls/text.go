@@ -0,0 +1,60 @@
+package rolls
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// irregularPlurals are the plurals pluralize cannot form by rule.
+var irregularPlurals = map[string]string{
+	"die": "dice",
+}
+
+// pluralize returns word as it reads for n of it: the word itself for one
+// and its plural otherwise, so "die" for 1 and "dice" for 0 or 2.
+func pluralize(word string, n int) string {
+	if n == 1 || n == -1 {
+		return word
+	}
+	if plural, ok := irregularPlurals[word]; ok {
+		return plural
+	}
+	switch {
+	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "x"), strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return word + "es"
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !strings.ContainsRune("aeiou", rune(word[len(word)-2])):
+		return word[:len(word)-1] + "ies"
+	}
+	return word + "s"
+}
+
+// quantity returns n followed by word pluralized for it, as in "1 success"
+// or "3 successes".
+func quantity(n int, word string) string {
+	return fmt.Sprintf("%d %s", n, pluralize(word, n))
+}
+
+// ordinal returns n as an ordinal number: 1st, 2nd, 3rd, 4th, 11th or 22nd.
+func ordinal(n int) string {
+	suffix := "th"
+	switch abs := max(n, -n); {
+	case abs%100 >= 11 && abs%100 <= 13:
+	case abs%10 == 1:
+		suffix = "st"
+	case abs%10 == 2:
+		suffix = "nd"
+	case abs%10 == 3:
+		suffix = "rd"
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// joinWithAnd joins words as "red, blue and green", "red and blue" or
+// "red".
+func joinWithAnd(words []string) string {
+	if len(words) < 2 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " and " + words[len(words)-1]
+}
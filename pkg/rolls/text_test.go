@@ -0,0 +1,108 @@
+package rolls
+
+import "testing"
+
+func TestPluralize(t *testing.T) {
+	tests := []struct {
+		word string
+		n    int
+		want string
+	}{
+		{"die", 1, "die"},
+		{"die", -1, "die"},
+		{"die", 0, "dice"},
+		{"die", 2, "dice"},
+		{"card", 1, "card"},
+		{"card", 52, "cards"},
+		{"success", 1, "success"},
+		{"success", 3, "successes"},
+		{"box", 2, "boxes"},
+		{"match", 2, "matches"},
+		{"wish", 2, "wishes"},
+		{"ally", 2, "allies"},
+		{"y", 2, "ys"},
+		{"day", 2, "days"},
+		{"round", -3, "rounds"},
+	}
+	for _, tt := range tests {
+		if got := pluralize(tt.word, tt.n); got != tt.want {
+			t.Errorf("pluralize(%q, %d) = %q, want %q", tt.word, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestQuantity(t *testing.T) {
+	tests := []struct {
+		n    int
+		word string
+		want string
+	}{
+		{0, "die", "0 dice"},
+		{1, "die", "1 die"},
+		{1, "round", "1 round"},
+		{2, "failure", "2 failures"},
+		{-1, "success", "-1 success"},
+		{-2, "success", "-2 successes"},
+	}
+	for _, tt := range tests {
+		if got := quantity(tt.n, tt.word); got != tt.want {
+			t.Errorf("quantity(%d, %q) = %q, want %q", tt.n, tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestOrdinal(t *testing.T) {
+	tests := map[int]string{
+		0: "0th", 1: "1st", 2: "2nd", 3: "3rd", 4: "4th", 10: "10th",
+		11: "11th", 12: "12th", 13: "13th", 21: "21st", 22: "22nd", 23: "23rd",
+		101: "101st", 111: "111th", 112: "112th", 1002: "1002nd", -1: "-1st", -12: "-12th",
+	}
+	for n, want := range tests {
+		if got := ordinal(n); got != want {
+			t.Errorf("ordinal(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestJoinWithAnd(t *testing.T) {
+	tests := []struct {
+		words []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"red"}, "red"},
+		{[]string{"red", "blue"}, "red and blue"},
+		{[]string{"red", "blue", "green"}, "red, blue and green"},
+		{[]string{"1", "2", "3", "4"}, "1, 2, 3 and 4"},
+	}
+	for _, tt := range tests {
+		if got := joinWithAnd(tt.words); got != tt.want {
+			t.Errorf("joinWithAnd(%q) = %q, want %q", tt.words, got, tt.want)
+		}
+	}
+}
+
+// TestResultProse checks the counts and ordinals a result writes read
+// correctly for one and for many.
+func TestResultProse(t *testing.T) {
+	tests := []struct {
+		res  *Result
+		want string
+	}{
+		{&Result{SuccessPool: true, Total: 1}, "1 success"},
+		{&Result{SuccessPool: true, Total: 0}, "0 successes"},
+		{&Result{SuccessPool: true, Total: 1, Successes: 2, Failures: 1}, "1 success (2 successes, 1 failure)"},
+		{&Result{SuccessPool: true, Total: -1, Successes: 1, Failures: 2, Botch: true}, "-1 success (1 success, 2 failures) BOTCH"},
+		{&Result{Total: 7}, "7"},
+	}
+	for _, tt := range tests {
+		if got := tt.res.totalString(); got != tt.want {
+			t.Errorf("totalString(%+v) = %q, want %q", *tt.res, got, tt.want)
+		}
+	}
+
+	r := &Result{Rerolled: []Reroll{{Index: 0, Original: 1, Value: 4}, {Index: 1, Original: 2, Value: 6}, {Index: 10, Original: 1, Value: 3}}}
+	if got, want := r.rerolledString(), " (1st die rerolled 1→4) (2nd die rerolled 2→6) (11th die rerolled 1→3)"; got != want {
+		t.Errorf("rerolledString = %q, want %q", got, want)
+	}
+}
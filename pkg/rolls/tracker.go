@@ -0,0 +1,150 @@
+package rolls
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// HPTracker tracks the hit points of a single creature.
+type HPTracker struct {
+	Max     int `json:"max"`
+	Current int `json:"current"`
+	TempHP  int `json:"temp_hp"`
+}
+
+// HPTransaction records one change to an HPTracker.
+type HPTransaction struct {
+	Op string `json:"op"`
+	// Amount is the damage, healing or temporary hit points offered.
+	Amount int `json:"amount"`
+	// Applied is how much of Amount changed the tracker; healing past the
+	// maximum and temporary hit points that do not stack are not applied.
+	Applied int `json:"applied"`
+	HP      int `json:"hp"`
+	TempHP  int `json:"temp_hp"`
+	// Damage is the outcome of a damage transaction.
+	Damage *DamageOutcome `json:"damage,omitempty"`
+}
+
+// NewHPTracker returns a tracker at max hit points.
+func NewHPTracker(max int) *HPTracker {
+	return &HPTracker{Max: max, Current: max}
+}
+
+// Damage applies the total of dmg, taking it from temporary hit points first.
+func (t *HPTracker) Damage(dmg *Result) *HPTransaction {
+	out := ApplyDamage(t.Current, t.Max, dmg, DamageRules{TempHP: t.TempHP})
+	t.Current, t.TempHP = out.HP, out.TempHP
+	return &HPTransaction{
+		Op:      "damage",
+		Amount:  out.Damage,
+		Applied: out.Damage - out.Overflow,
+		HP:      t.Current,
+		TempHP:  t.TempHP,
+		Damage:  out,
+	}
+}
+
+// Heal restores the total of heal, up to the maximum. Healing does not
+// restore temporary hit points.
+func (t *HPTracker) Heal(heal *Result) *HPTransaction {
+	amount := max(heal.Total, 0)
+	before := t.Current
+	t.Current = min(t.Current+amount, t.Max)
+	return &HPTransaction{Op: "heal", Amount: amount, Applied: t.Current - before, HP: t.Current, TempHP: t.TempHP}
+}
+
+// AddTempHP grants n temporary hit points. Temporary hit points do not
+// stack: the tracker keeps whichever of the old and new amounts is higher.
+func (t *HPTracker) AddTempHP(n int) *HPTransaction {
+	tx := &HPTransaction{Op: "temp", Amount: n, HP: t.Current}
+	if n > t.TempHP {
+		tx.Applied = n
+		t.TempHP = n
+	}
+	tx.TempHP = t.TempHP
+	return tx
+}
+
+func (t *HPTracker) String() string {
+	if t.TempHP > 0 {
+		return fmt.Sprintf("%d/%d (+%d temporary)", t.Current, t.Max, t.TempHP)
+	}
+	return fmt.Sprintf("%d/%d", t.Current, t.Max)
+}
+
+func (tx *HPTransaction) String() string {
+	switch {
+	case tx.Damage != nil:
+		return tx.Damage.String()
+	case tx.Op == "heal":
+		return fmt.Sprintf("healed %d of %d", tx.Applied, tx.Amount)
+	case tx.Applied == 0:
+		return fmt.Sprintf("%d temporary hit points do not stack with %d", tx.Amount, tx.TempHP)
+	}
+	return fmt.Sprintf("%d temporary hit points", tx.Applied)
+}
+
+// ReadTrackers reads named trackers written by WriteTrackers.
+func ReadTrackers(r io.Reader) (map[string]*HPTracker, error) {
+	trackers := make(map[string]*HPTracker)
+	if err := json.NewDecoder(r).Decode(&trackers); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return trackers, nil
+}
+
+// WriteTrackers writes named trackers as JSON.
+func WriteTrackers(w io.Writer, trackers map[string]*HPTracker) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(trackers)
+}
+
+// trackCreature applies the hp subcommand's tracker flags to the named
+// creature and saves it.
+func trackCreature(name string, maxHP int, damage, heal string, temp int) error {
+	trackers, err := LoadTrackers()
+	if err != nil {
+		return err
+	}
+	t, ok := trackers[name]
+	switch {
+	case maxHP > 0:
+		t = NewHPTracker(maxHP)
+		trackers[name] = t
+	case !ok:
+		names := make([]string, 0, len(trackers))
+		for n := range trackers {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown creature %q, start it with --max (tracking: %v)", name, names)
+	}
+
+	if temp > 0 {
+		fmt.Println(t.AddTempHP(temp))
+	}
+	if damage != "" {
+		res, err := RollString(damage)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Damage:", res)
+		fmt.Println(t.Damage(res))
+	}
+	if heal != "" {
+		res, err := RollString(heal)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Healing:", res)
+		fmt.Println(t.Heal(res))
+	}
+
+	fmt.Printf("%s: %s\n", name, t)
+
+	return SaveTrackers(trackers)
+}
@@ -0,0 +1,67 @@
+package rolls
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+)
+
+// DefaultTravellerTarget is the usual target number of a Traveller check.
+const DefaultTravellerTarget = 8
+
+// TravellerResult is a Traveller task check.
+type TravellerResult struct {
+	Roll   *Result
+	Target int
+	// Effect is how far the total beat or missed the target. A check
+	// succeeds when Effect is 0 or more.
+	Effect  int
+	Success bool
+}
+
+// RollTraveller rolls a Traveller check: 2d6 plus modifier against target,
+// rolling 3d6 and keeping the highest two with a boon or the lowest two with
+// a bane. A boon and a bane together cancel out.
+func RollTraveller(modifier, target int, boon, bane bool) *TravellerResult {
+	d := &Dice{Count: 2, Sides: 6, Bonus: modifier}
+	switch {
+	case boon && !bane:
+		d.Count, d.Modifier, d.ModifierCount = 3, KeepHighest, 2
+	case bane && !boon:
+		d.Count, d.Modifier, d.ModifierCount = 3, KeepLowest, 2
+	}
+
+	res := &TravellerResult{Roll: d.Roll(), Target: target}
+	res.Effect = res.Roll.Total - target
+	res.Success = res.Effect >= 0
+	return res
+}
+
+func travellerGen(args []string) error {
+	fs := flag.NewFlagSet("traveller", flag.ContinueOnError)
+	target := fs.Int("target", DefaultTravellerTarget, "target number of the check")
+	boon := fs.Bool("boon", false, "roll with a boon")
+	bane := fs.Bool("bane", false, "roll with a bane")
+	mods, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	modifier := 0
+	if len(mods) > 0 {
+		if modifier, err = strconv.Atoi(mods[0]); err != nil {
+			return err
+		}
+	}
+
+	res := RollTraveller(modifier, *target, *boon, *bane)
+	logHistory(res.Roll)
+
+	fmt.Println(res.Roll)
+	outcome := "failure"
+	if res.Success {
+		outcome = "success"
+	}
+	fmt.Printf("Effect: %+d (%s against %d+)\n", res.Effect, outcome, res.Target)
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package rolls
+
+import "fmt"
+
+// CheckRecharge rolls a d6 for an ability with "Recharge min-6" and reports
+// whether it recharges.
+func CheckRecharge(min int) (*Result, bool) {
+	res := (&Dice{Count: 1, Sides: 6}).Roll()
+	return res, res.Total >= min
+}
+
+// Uses counts the uses left of a limited-use ability, such as one usable
+// 3/day.
+type Uses struct {
+	Max       int `json:"max"`
+	Remaining int `json:"remaining"`
+}
+
+// NewUses returns a counter with every use available.
+func NewUses(max int) *Uses {
+	return &Uses{Max: max, Remaining: max}
+}
+
+// Spend uses the ability once, failing if it has no uses left.
+func (u *Uses) Spend() error {
+	if u.Remaining <= 0 {
+		return fmt.Errorf("no uses left (%s)", u)
+	}
+	u.Remaining--
+	return nil
+}
+
+// Reset restores every use, as after a long rest.
+func (u *Uses) Reset() {
+	u.Remaining = u.Max
+}
+
+func (u *Uses) String() string {
+	return fmt.Sprintf("%d/%d left", u.Remaining, u.Max)
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	// eventBuffer is how many events an /events client may fall behind by
+	// before it is evicted.
+	eventBuffer = 64
+	// webhookBuffer is the same for a webhook.
+	webhookBuffer = 1024
+	// heartbeatInterval is how often idle /events streams get a comment, so
+	// proxies do not time them out.
+	heartbeatInterval = 30 * time.Second
+	webhookTimeout    = 10 * time.Second
+)
+
+// handleEvents streams every roll made on the server as server-sent events.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "streaming unsupported", Code: CodeUnavailable})
+		return
+	}
+
+	sub := s.hub.subscribe(eventBuffer)
+	defer s.hub.unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case data, ok := <-sub.events:
+			if !ok {
+				// Evicted for falling behind.
+				return
+			}
+			fmt.Fprintf(w, "event: roll\ndata: %s\n\n", data)
+		}
+		flusher.Flush()
+	}
+}
+
+// runWebhook posts every event to url until ctx is done. Events that arrive
+// while the webhook is too far behind are dropped and logged.
+func (s *Server) runWebhook(ctx context.Context, url string) {
+	client := &http.Client{Timeout: webhookTimeout}
+	for {
+		sub := s.hub.subscribe(webhookBuffer)
+		for data := range sub.events {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+			if err != nil {
+				log.Printf("webhook %s: %v", url, err)
+				continue
+			}
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := client.Do(req)
+			if err != nil {
+				if ctx.Err() != nil {
+					s.hub.unsubscribe(sub)
+					return
+				}
+				log.Printf("webhook %s: %v", url, err)
+				continue
+			}
+			resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				log.Printf("webhook %s: %s", url, resp.Status)
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("webhook %s fell behind, events were dropped", url)
+	}
+}
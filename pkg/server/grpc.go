@@ -0,0 +1,185 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/Domo929/roll/pkg/rollpb"
+	"github.com/Domo929/roll/pkg/rolls"
+)
+
+// simulateFlushEvery is how many trials Simulate rolls between sending the
+// buckets that changed.
+const simulateFlushEvery = 1000
+
+// NewGRPC returns a gRPC server serving rollpb.RollService over s. Rolls go
+// through the same budget, rate limits and observers as POST /roll, so they
+// show up in /events, the webhooks and /metrics, and are refused with the
+// same messages. Idempotency keys are only honored over HTTP.
+func (s *Server) NewGRPC(opts ...grpc.ServerOption) *grpc.Server {
+	g := grpc.NewServer(opts...)
+	rollpb.RegisterRollServiceServer(g, &grpcService{s: s})
+	return g
+}
+
+type grpcService struct {
+	rollpb.UnimplementedRollServiceServer
+	s *Server
+}
+
+func (g *grpcService) Roll(ctx context.Context, req *rollpb.RollRequest) (*rollpb.RollResult, error) {
+	start := time.Now()
+	defer func() { g.s.metrics.observeLatency(time.Since(start)) }()
+
+	if err := g.allow(ctx); err != nil {
+		return nil, err
+	}
+	res, err := g.s.rollExpression(ctx, req.GetExpression(), req.Seed)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return resultProto(res), nil
+}
+
+// Simulate rolls the expression req.Trials times from a roller of its own,
+// so the trials are not sent to /events or counted in /metrics. After every
+// simulateFlushEvery trials, and after the last, it sends the buckets whose
+// counts changed, each with its running count.
+func (g *grpcService) Simulate(req *rollpb.SimulateRequest, stream rollpb.RollService_SimulateServer) error {
+	ctx := stream.Context()
+	if err := g.allow(ctx); err != nil {
+		return err
+	}
+	if req.GetTrials() <= 0 || req.GetTrials() > int64(g.s.opts.MaxTrials) {
+		return status.Errorf(codes.OutOfRange, "trials must be between 1 and %d", g.s.opts.MaxTrials)
+	}
+	e, err := g.s.parseBudgeted(req.GetExpression())
+	if err != nil {
+		return grpcError(err)
+	}
+	roller := rolls.NewRoller()
+	if req.Seed != nil {
+		roller = rolls.NewRoller(rolls.WithSeed(req.GetSeed()))
+	}
+
+	counts := make(map[int]int64)
+	changed := make(map[int]bool)
+	for trial := int64(1); trial <= req.GetTrials(); trial++ {
+		res, err := roller.RollExpressionContext(ctx, e)
+		if err != nil {
+			return status.FromContextError(err).Err()
+		}
+		counts[res.Total]++
+		changed[res.Total] = true
+		if trial%simulateFlushEvery != 0 && trial != req.GetTrials() {
+			continue
+		}
+		totals := make([]int, 0, len(changed))
+		for total := range changed {
+			totals = append(totals, total)
+		}
+		sort.Ints(totals)
+		for _, total := range totals {
+			if err := stream.Send(&rollpb.HistogramBucket{Total: int64(total), Count: counts[total]}); err != nil {
+				return err
+			}
+		}
+		clear(changed)
+	}
+	return nil
+}
+
+// allow applies the rate limit of the peer's IP, setting a retry-after
+// header as the HTTP API sets Retry-After.
+func (g *grpcService) allow(ctx context.Context) error {
+	if g.s.limiter == nil {
+		return nil
+	}
+	ip := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+	wait, ok := g.s.limiter.allow(ip)
+	if ok {
+		return nil
+	}
+	grpc.SetHeader(ctx, metadata.Pairs("retry-after", fmt.Sprint(int(math.Ceil(wait.Seconds())))))
+	return status.Error(codes.ResourceExhausted, "too many requests")
+}
+
+// grpcError converts a *rollError to a gRPC status with the same message.
+func grpcError(err error) error {
+	var refused *rollError
+	if !errors.As(err, &refused) {
+		return err
+	}
+	switch {
+	case refused.code == CodeInvalidExpression:
+		return status.Error(codes.InvalidArgument, refused.Error())
+	case refused.code == CodeOverBudget:
+		return status.Error(codes.OutOfRange, refused.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(refused.err).Err()
+	case refused.status == http.StatusServiceUnavailable:
+		return status.Error(codes.Unavailable, refused.Error())
+	}
+	return status.Error(codes.Internal, refused.Error())
+}
+
+// resultProto converts res to its protocol message.
+func resultProto(res *rolls.Result) *rollpb.RollResult {
+	pb := &rollpb.RollResult{
+		Expression: res.Expression,
+		Sides:      int64(res.Sides),
+		Rolls:      ints64(res.Rolls),
+		Kept:       ints64(res.Kept),
+		Dropped:    ints64(res.Dropped),
+		Bonus:      int64(res.Bonus),
+		Total:      int64(res.Total),
+		Successes:  int64(res.Successes),
+		Summarized: res.Summarized,
+		Negative:   res.Negative,
+		Label:      res.Label,
+	}
+	for _, sub := range res.Substituted {
+		pb.Substituted = append(pb.Substituted, &rollpb.Substitution{Original: int64(sub.Original), Value: int64(sub.Value)})
+	}
+	if res.Summary != nil {
+		pb.Summary = &rollpb.RollSummary{
+			Count: int64(res.Summary.Count),
+			Min:   int64(res.Summary.Min),
+			Max:   int64(res.Summary.Max),
+			Mean:  res.Summary.Mean,
+		}
+	}
+	for _, g := range res.Groups {
+		pb.Groups = append(pb.Groups, resultProto(g))
+	}
+	return pb
+}
+
+func ints64(vs []int) []int64 {
+	if vs == nil {
+		return nil
+	}
+	out := make([]int64, len(vs))
+	for i, v := range vs {
+		out[i] = int64(v)
+	}
+	return out
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Domo929/roll/pkg/rollpb"
+	"github.com/Domo929/roll/pkg/rolls"
+)
+
+// newGRPCClient serves s over gRPC on an in-memory listener.
+func newGRPCClient(t *testing.T, s *Server) rollpb.RollServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	g := s.NewGRPC()
+	go g.Serve(lis)
+	t.Cleanup(g.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return rollpb.NewRollServiceClient(conn)
+}
+
+// postRoll rolls body through POST /roll.
+func postRoll(t *testing.T, url, body string) (int, []byte) {
+	t.Helper()
+	resp, err := http.Post(url+"/roll", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, raw
+}
+
+// TestGRPCMatchesHTTP rolls the same requests over both APIs, with dice that
+// can only roll one way or a seed, and checks they return the same result or
+// refuse with the same message.
+func TestGRPCMatchesHTTP(t *testing.T) {
+	s := New(Options{RequestsPerSecond: -1})
+	defer s.Close()
+	web := httptest.NewServer(s)
+	defer web.Close()
+	client := newGRPCClient(t, s)
+
+	seed := int64(42)
+	tests := []struct {
+		expr   string
+		seed   *int64
+		status int
+		code   codes.Code
+	}{
+		{"3d1+2", nil, http.StatusOK, codes.OK},
+		{"4d1kh2+3", nil, http.StatusOK, codes.OK},
+		{"2d1+1d1-1", nil, http.StatusOK, codes.OK},
+		{"(2d1+3)*2", nil, http.StatusOK, codes.OK},
+		{"4d6kh3", &seed, http.StatusOK, codes.OK},
+		{"1d20+5-1d4", &seed, http.StatusOK, codes.OK},
+		{"3d", nil, http.StatusBadRequest, codes.InvalidArgument},
+		{"1000d6", nil, http.StatusUnprocessableEntity, codes.OutOfRange},
+		{"1d20000", nil, http.StatusUnprocessableEntity, codes.OutOfRange},
+		{strings.Repeat("1d4+", 20) + "1", nil, http.StatusUnprocessableEntity, codes.OutOfRange},
+	}
+	for _, tt := range tests {
+		body, _ := json.Marshal(rollRequest{Expression: tt.expr, Seed: tt.seed})
+		httpStatus, raw := postRoll(t, web.URL, string(body))
+		got, err := client.Roll(context.Background(), &rollpb.RollRequest{Expression: tt.expr, Seed: tt.seed})
+
+		if httpStatus != tt.status || status.Code(err) != tt.code {
+			t.Errorf("%s: POST /roll = %d, gRPC = %v, want %d and %v", tt.expr, httpStatus, err, tt.status, tt.code)
+			continue
+		}
+		if httpStatus != http.StatusOK {
+			var refused errorResponse
+			if err := json.Unmarshal(raw, &refused); err != nil {
+				t.Fatal(err)
+			}
+			if msg := status.Convert(err).Message(); msg != refused.Error {
+				t.Errorf("%s: gRPC refused with %q, POST /roll with %q", tt.expr, msg, refused.Error)
+			}
+			continue
+		}
+		var res rolls.Result
+		if err := json.Unmarshal(raw, &res); err != nil {
+			t.Fatal(err)
+		}
+		if want := resultProto(&res); !proto.Equal(got, want) {
+			t.Errorf("%s: gRPC rolled %v, POST /roll %v", tt.expr, got, want)
+		}
+	}
+}
+
+func TestGRPCRateLimit(t *testing.T) {
+	s := New(Options{RequestsPerSecond: 0.001, Burst: 1})
+	defer s.Close()
+	client := newGRPCClient(t, s)
+
+	if _, err := client.Roll(context.Background(), &rollpb.RollRequest{Expression: "1d20"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.Roll(context.Background(), &rollpb.RollRequest{Expression: "1d20"})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("second roll past a burst of 1 = %v, want %v", err, codes.ResourceExhausted)
+	}
+}
+
+func TestGRPCSimulate(t *testing.T) {
+	s := New(Options{RequestsPerSecond: -1})
+	defer s.Close()
+	client := newGRPCClient(t, s)
+
+	stream, err := client.Simulate(context.Background(), &rollpb.SimulateRequest{Expression: "2d1+1", Trials: 2500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var counts []int64
+	for {
+		bucket, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bucket.Total != 3 {
+			t.Fatalf("2d1+1 totalled %d", bucket.Total)
+		}
+		counts = append(counts, bucket.Count)
+	}
+	if want := []int64{1000, 2000, 2500}; !slices.Equal(counts, want) {
+		t.Errorf("Simulate(2d1+1, 2500) sent counts %v, want %v", counts, want)
+	}
+
+	var buf bytes.Buffer
+	s.metrics.writeTo(&buf)
+	if !strings.Contains(buf.String(), `roll_rolls_total{shape="other"} 0`) {
+		t.Errorf("Simulate's trials were counted as rolls:\n%s", buf.String())
+	}
+
+	for _, req := range []*rollpb.SimulateRequest{
+		{Expression: "1d6", Trials: 0},
+		{Expression: "1d6", Trials: DefaultMaxTrials + 1},
+		{Expression: "1000d6", Trials: 10},
+		{Expression: "3d", Trials: 10},
+	} {
+		stream, err := client.Simulate(context.Background(), req)
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if c := status.Code(err); c != codes.OutOfRange && c != codes.InvalidArgument {
+			t.Errorf("Simulate(%s, %d) = %v, want it refused", req.Expression, req.Trials, err)
+		}
+	}
+}
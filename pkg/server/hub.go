@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/Domo929/roll/pkg/rolls"
+)
+
+// Event is a roll broadcast to /events clients and webhooks.
+type Event struct {
+	ID     uint64        `json:"id"`
+	Time   time.Time     `json:"time"`
+	Result *rolls.Result `json:"result"`
+}
+
+// subscriber receives events on a buffered channel. The hub closes the
+// channel when the subscriber is evicted or unsubscribes.
+type subscriber struct {
+	events chan []byte
+}
+
+// hub broadcasts every observed roll to its subscribers. Events are
+// published in the order rolls complete and carry increasing IDs; every
+// subscriber sees them in that order. A subscriber whose buffer is full is
+// evicted rather than allowed to hold up the rolls, so a slow client sees a
+// closed stream, never a gap followed by more events.
+type hub struct {
+	mu     sync.Mutex
+	nextID uint64
+	subs   map[*subscriber]struct{}
+}
+
+func newHub() *hub {
+	return &hub{subs: make(map[*subscriber]struct{})}
+}
+
+// Observe implements rolls.Observer.
+func (h *hub) Observe(res *rolls.Result) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	data, err := json.Marshal(Event{ID: h.nextID, Time: time.Now(), Result: res})
+	if err != nil {
+		return
+	}
+	for sub := range h.subs {
+		select {
+		case sub.events <- data:
+		default:
+			delete(h.subs, sub)
+			close(sub.events)
+		}
+	}
+}
+
+func (h *hub) subscribe(buffer int) *subscriber {
+	sub := &subscriber{events: make(chan []byte, buffer)}
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+func (h *hub) unsubscribe(sub *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.events)
+	}
+}
+
+// clients is the number of current subscribers.
+func (h *hub) clients() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// idempotencyEntry is the response for one Idempotency-Key. done is closed
+// once the response is known, or once the entry is released without one.
+type idempotencyEntry struct {
+	request  rollRequest
+	done     chan struct{}
+	released bool
+	status   int
+	body     []byte
+	expires  time.Time
+}
+
+// sameRoll reports whether a and b ask for the same roll: the same
+// expression, and the same seed or none.
+func sameRoll(a, b rollRequest) bool {
+	if a.Expression != b.Expression || (a.Seed == nil) != (b.Seed == nil) {
+		return false
+	}
+	return a.Seed == nil || *a.Seed == *b.Seed
+}
+
+func (r rollRequest) String() string {
+	if r.Seed == nil {
+		return fmt.Sprintf("%q", r.Expression)
+	}
+	return fmt.Sprintf("%q with seed %d", r.Expression, *r.Seed)
+}
+
+// idempotencyStore caches responses by key until they expire.
+type idempotencyStore struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	now       func() time.Time
+	entries   map[string]*idempotencyEntry
+	nextSweep time.Time
+}
+
+func newIdempotencyStore(ttl time.Duration, now func() time.Time) *idempotencyStore {
+	return &idempotencyStore{
+		ttl:     ttl,
+		now:     now,
+		entries: make(map[string]*idempotencyEntry),
+	}
+}
+
+// begin returns the entry for key. The first caller for a key owns the
+// entry and must finish or release it; later callers wait on its done
+// channel, and call begin again if it was released. A later caller asking
+// for a different roll than the owner, other dice or another seed, is
+// refused.
+func (s *idempotencyStore) begin(key string, req rollRequest) (*idempotencyEntry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.After(s.nextSweep) {
+		s.sweep(now)
+		s.nextSweep = now.Add(s.ttl)
+	}
+
+	if e, ok := s.entries[key]; ok && !s.expired(e, now) {
+		if !sameRoll(e.request, req) {
+			return nil, false, fmt.Errorf("idempotency key %q was already used for %s", key, e.request)
+		}
+		return e, false, nil
+	}
+
+	e := &idempotencyEntry{request: req, done: make(chan struct{})}
+	s.entries[key] = e
+	return e, true, nil
+}
+
+// expired reports whether a finished entry has outlived its TTL. Entries
+// still being rolled never expire. s.mu must be held.
+func (s *idempotencyStore) expired(e *idempotencyEntry, now time.Time) bool {
+	select {
+	case <-e.done:
+		return now.After(e.expires)
+	default:
+		return false
+	}
+}
+
+// sweep drops every expired entry. s.mu must be held.
+func (s *idempotencyStore) sweep(now time.Time) {
+	for key, e := range s.entries {
+		if s.expired(e, now) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// finish records the response for e and releases any waiting callers.
+func (s *idempotencyStore) finish(e *idempotencyEntry, status int, body []byte) {
+	s.mu.Lock()
+	e.status, e.body = status, body
+	e.expires = s.now().Add(s.ttl)
+	s.mu.Unlock()
+	close(e.done)
+}
+
+// release forgets e without a response, so the next caller for key owns a
+// new entry, and wakes any waiting callers to call begin again.
+func (s *idempotencyStore) release(key string, e *idempotencyEntry) {
+	s.mu.Lock()
+	if s.entries[key] == e {
+		delete(s.entries, key)
+	}
+	e.released = true
+	s.mu.Unlock()
+	close(e.done)
+}
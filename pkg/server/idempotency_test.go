@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newIdempotentRequest(ctx context.Context, key, expr string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/roll", strings.NewReader(`{"expression": "`+expr+`"}`)).WithContext(ctx)
+	r.Header.Set("Idempotency-Key", key)
+	return r
+}
+
+// TestIdempotentConcurrentDuplicates sends the same keyed request many times
+// at once and checks it is rolled once, every other request replaying that
+// roll.
+func TestIdempotentConcurrentDuplicates(t *testing.T) {
+	s := New(Options{RequestsPerSecond: -1})
+	defer s.Close()
+
+	const requests = 32
+	var wg sync.WaitGroup
+	responses := make([]*httptest.ResponseRecorder, requests)
+	start := make(chan struct{})
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			responses[i] = httptest.NewRecorder()
+			s.ServeHTTP(responses[i], newIdempotentRequest(context.Background(), "k1", "4d6kh3"))
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	rolled := 0
+	for i, w := range responses {
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d = %d %s", i, w.Code, w.Body)
+		}
+		if w.Header().Get("Idempotent-Replayed") != "true" {
+			rolled++
+		}
+		if w.Body.String() != responses[0].Body.String() {
+			t.Errorf("request %d = %s, request 0 = %s", i, w.Body, responses[0].Body)
+		}
+	}
+	if rolled != 1 {
+		t.Errorf("%d of %d requests sharing a key were rolled, want 1", rolled, requests)
+	}
+	if n := s.metrics.rollsD20.Load() + s.metrics.rollsOther.Load(); n != 1 {
+		t.Errorf("the server made %d rolls for one key, want 1", n)
+	}
+}
+
+// TestIdempotencyReleasesFailures cancels a keyed request and checks its
+// 503 is not replayed to the retry.
+func TestIdempotencyReleasesFailures(t *testing.T) {
+	s := New(Options{RequestsPerSecond: -1})
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, newIdempotentRequest(ctx, "k1", "1d20"))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("cancelled request = %d %s, want 503", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, newIdempotentRequest(context.Background(), "k1", "1d20"))
+	if w.Code != http.StatusOK || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("retry after a 503 = %d %s, replayed %q, want a fresh 200", w.Code, w.Body, w.Header().Get("Idempotent-Replayed"))
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, newIdempotentRequest(context.Background(), "k1", "1d20"))
+	if w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("second retry was not replayed: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestIdempotencyConflict(t *testing.T) {
+	s := New(Options{RequestsPerSecond: -1})
+	defer s.Close()
+
+	s.ServeHTTP(httptest.NewRecorder(), newIdempotentRequest(context.Background(), "k1", "1d20"))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, newIdempotentRequest(context.Background(), "k1", "1d6"))
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), CodeIdempotencyConflict) {
+		t.Errorf("reusing a key for another expression = %d %s, want 422", w.Code, w.Body)
+	}
+}
+
+// TestIdempotencySeedConflict checks a key is matched on its seed as well as
+// its expression, so a retry never replays a roll made with another seed.
+func TestIdempotencySeedConflict(t *testing.T) {
+	s := New(Options{RequestsPerSecond: -1})
+	defer s.Close()
+
+	send := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/roll", strings.NewReader(body))
+		r.Header.Set("Idempotency-Key", "k1")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w
+	}
+	if w := send(`{"expression": "1d20", "seed": 7}`); w.Code != http.StatusOK {
+		t.Fatalf("seeded request = %d %s", w.Code, w.Body)
+	}
+	if w := send(`{"expression": "1d20", "seed": 7}`); w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry with the same seed = %d %s, want it replayed", w.Code, w.Body)
+	}
+	for _, body := range []string{`{"expression": "1d20", "seed": 8}`, `{"expression": "1d20"}`} {
+		w := send(body)
+		if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), CodeIdempotencyConflict) || !strings.Contains(w.Body.String(), "seed 7") {
+			t.Errorf("reusing a key seeded 7 for %s = %d %s, want 422", body, w.Code, w.Body)
+		}
+	}
+}
+
+func TestIdempotencyStore(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := newIdempotencyStore(time.Hour, func() time.Time { return now })
+
+	e, owner, err := s.begin("k1", rollRequest{Expression: "1d20"})
+	if err != nil || !owner {
+		t.Fatalf("first begin = %v, %v, want the owner", owner, err)
+	}
+	waiter, owner, _ := s.begin("k1", rollRequest{Expression: "1d20"})
+	if owner || waiter != e {
+		t.Fatal("second begin owns the key while the first is rolling")
+	}
+	s.release("k1", e)
+	<-waiter.done
+	if !waiter.released {
+		t.Fatal("waiter was not told the key was released")
+	}
+
+	e, owner, _ = s.begin("k1", rollRequest{Expression: "1d20"})
+	if !owner {
+		t.Fatal("begin after a release does not own the key")
+	}
+	s.finish(e, http.StatusOK, []byte("{}"))
+	now = now.Add(59 * time.Minute)
+	if _, owner, _ := s.begin("k1", rollRequest{Expression: "1d20"}); owner {
+		t.Error("begin within the TTL owns the key")
+	}
+	now = now.Add(2 * time.Minute)
+	if _, owner, _ := s.begin("k1", rollRequest{Expression: "1d20"}); !owner {
+		t.Error("begin past the TTL does not own the key")
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Domo929/roll/pkg/rolls"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the request latency
+// histogram.
+var latencyBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+// metrics collects server metrics and renders them in the Prometheus text
+// exposition format. Roll counts arrive through the rolls.Observer hook.
+type metrics struct {
+	rollsD20    atomic.Uint64
+	rollsOther  atomic.Uint64
+	parseErrors atomic.Uint64
+
+	mu            sync.Mutex
+	latencyCounts []uint64
+	latencySum    float64
+	latencyTotal  uint64
+	activeClients func() int
+}
+
+func newMetrics(activeClients func() int) *metrics {
+	return &metrics{
+		latencyCounts: make([]uint64, len(latencyBuckets)),
+		activeClients: activeClients,
+	}
+}
+
+// Observe implements rolls.Observer, counting each dice group of res, so
+// 1d20+5-1d4 counts a d20 group and another.
+func (m *metrics) Observe(res *rolls.Result) {
+	for _, g := range res.DiceGroups() {
+		if g.Sides == 20 {
+			m.rollsD20.Add(1)
+		} else {
+			m.rollsOther.Add(1)
+		}
+	}
+}
+
+func (m *metrics) observeLatency(d time.Duration) {
+	secs := d.Seconds()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, le := range latencyBuckets {
+		if secs <= le {
+			m.latencyCounts[i]++
+		}
+	}
+	m.latencySum += secs
+	m.latencyTotal++
+}
+
+func (m *metrics) writeTo(w io.Writer) {
+	fmt.Fprintln(w, "# HELP roll_rolls_total Dice groups rolled, d20s apart from other dice.")
+	fmt.Fprintln(w, "# TYPE roll_rolls_total counter")
+	fmt.Fprintf(w, "roll_rolls_total{shape=\"d20\"} %d\n", m.rollsD20.Load())
+	fmt.Fprintf(w, "roll_rolls_total{shape=\"other\"} %d\n", m.rollsOther.Load())
+
+	fmt.Fprintln(w, "# HELP roll_parse_errors_total Requests whose expression did not parse.")
+	fmt.Fprintln(w, "# TYPE roll_parse_errors_total counter")
+	fmt.Fprintf(w, "roll_parse_errors_total %d\n", m.parseErrors.Load())
+
+	m.mu.Lock()
+	fmt.Fprintln(w, "# HELP roll_request_duration_seconds Latency of roll requests.")
+	fmt.Fprintln(w, "# TYPE roll_request_duration_seconds histogram")
+	for i, le := range latencyBuckets {
+		fmt.Fprintf(w, "roll_request_duration_seconds_bucket{le=\"%s\"} %d\n", strconv.FormatFloat(le, 'g', -1, 64), m.latencyCounts[i])
+	}
+	fmt.Fprintf(w, "roll_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.latencyTotal)
+	fmt.Fprintf(w, "roll_request_duration_seconds_sum %g\n", m.latencySum)
+	fmt.Fprintf(w, "roll_request_duration_seconds_count %d\n", m.latencyTotal)
+	m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP roll_sse_clients Connected /events clients.")
+	fmt.Fprintln(w, "# TYPE roll_sse_clients gauge")
+	fmt.Fprintf(w, "roll_sse_clients %d\n", m.activeClients())
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	s.metrics.writeTo(w)
+}
+
+// handleDiceStats reports the dice stats. They cannot be reset over HTTP,
+// as the API has no authentication to keep anyone else from doing so.
+func (s *Server) handleDiceStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "use GET", Code: CodeBadRequest})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.roller.Stats())
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Domo929/roll/pkg/rolls"
+)
+
+func TestMetricsObserve(t *testing.T) {
+	tests := []struct {
+		expr       string
+		d20, other uint64
+	}{
+		{"1d20", 1, 0},
+		{"2d20kh1+5", 1, 0},
+		{"1d20+5-1d4", 1, 1},
+		{"(1d20+2)*2", 1, 0},
+		{"2d6+1d8+3", 0, 2},
+		{"1d20+1d20+1d6", 2, 1},
+		{"4d6kh3", 0, 1},
+	}
+	for _, tt := range tests {
+		e, err := rolls.ParseExpression(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := newMetrics(func() int { return 0 })
+		m.Observe(rolls.NewRoller(rolls.WithSeed(1)).RollExpression(e))
+		if d20, other := m.rollsD20.Load(), m.rollsOther.Load(); d20 != tt.d20 || other != tt.other {
+			t.Errorf("%s counted %d d20 and %d other groups, want %d and %d", tt.expr, d20, other, tt.d20, tt.other)
+		}
+	}
+}
+
+func TestMetricsWriteTo(t *testing.T) {
+	m := newMetrics(func() int { return 3 })
+	m.rollsD20.Add(4)
+	m.rollsOther.Add(2)
+	m.parseErrors.Add(1)
+	m.observeLatency(2 * time.Millisecond)
+	m.observeLatency(300 * time.Millisecond)
+
+	var buf bytes.Buffer
+	m.writeTo(&buf)
+	for _, want := range []string{
+		`roll_rolls_total{shape="d20"} 4`,
+		`roll_rolls_total{shape="other"} 2`,
+		"roll_parse_errors_total 1",
+		`roll_request_duration_seconds_bucket{le="0.001"} 0`,
+		`roll_request_duration_seconds_bucket{le="0.0025"} 1`,
+		`roll_request_duration_seconds_bucket{le="0.25"} 1`,
+		`roll_request_duration_seconds_bucket{le="0.5"} 2`,
+		`roll_request_duration_seconds_bucket{le="+Inf"} 2`,
+		"roll_request_duration_seconds_count 2",
+		"roll_sse_clients 3",
+	} {
+		if !strings.Contains(buf.String(), want+"\n") {
+			t.Errorf("metrics lack %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestParseErrorMetric(t *testing.T) {
+	s := New(Options{RequestsPerSecond: -1})
+	defer s.Close()
+	for _, expr := range []string{"3d", "1d20", "hello"} {
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/roll", strings.NewReader(`{"expression": "`+expr+`"}`)))
+	}
+	if n := s.metrics.parseErrors.Load(); n != 2 {
+		t.Errorf("two unparsable expressions counted %d parse errors", n)
+	}
+}
+
+func TestDiceStats(t *testing.T) {
+	s := New(Options{RequestsPerSecond: -1})
+	defer s.Close()
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/roll", strings.NewReader(`{"expression": "3d1"}`)))
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics/dice", nil))
+	var stats rolls.RollStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("GET /metrics/dice = %d %s: %v", w.Code, w.Body, err)
+	}
+	if want := s.roller.Stats(); !bytes.Equal(mustJSON(t, stats), mustJSON(t, want)) {
+		t.Errorf("GET /metrics/dice = %s, want %s", mustJSON(t, stats), mustJSON(t, want))
+	}
+
+	// Anyone could reset the stats, so they cannot be reset at all.
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/metrics/dice", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != http.MethodGet {
+		t.Errorf("DELETE /metrics/dice = %d, Allow %q, want 405 and GET", w.Code, w.Header().Get("Allow"))
+	}
+	if after := s.roller.Stats(); !bytes.Equal(mustJSON(t, after), mustJSON(t, stats)) {
+		t.Errorf("DELETE /metrics/dice changed the stats to %s", mustJSON(t, after))
+	}
+}
+
+func mustJSON(t *testing.T, v any) []byte {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// bucket is a token bucket for one client.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter holds a token bucket per client key.
+type rateLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	now       func() time.Time
+	buckets   map[string]*bucket
+	nextSweep time.Time
+}
+
+func newRateLimiter(rate float64, burst int, now func() time.Time) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		now:     now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow takes a token from key's bucket. When the bucket is empty it reports
+// false and how long until a token is available.
+func (l *rateLimiter) allow(key string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.After(l.nextSweep) {
+		l.sweep(now)
+		l.nextSweep = now.Add(l.fillTime())
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.rate * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// fillTime is how long an empty bucket takes to refill.
+func (l *rateLimiter) fillTime() time.Duration {
+	return time.Duration(l.burst / l.rate * float64(time.Second))
+}
+
+// sweep forgets clients whose buckets have refilled, since a new bucket
+// would be identical. l.mu must be held.
+func (l *rateLimiter) sweep(now time.Time) {
+	full := l.fillTime()
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestRateLimiterConcurrent hammers a few clients' buckets from many
+// goroutines while no time passes, so each must allow exactly its burst.
+// Run it with -race.
+func TestRateLimiterConcurrent(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newRateLimiter(5, 10, func() time.Time { return now })
+
+	const clients, goroutines, calls = 4, 16, 50
+	var allowed [clients]atomic.Int64
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < calls; i++ {
+				c := (g + i) % clients
+				if _, ok := l.allow(fmt.Sprint("client", c)); ok {
+					allowed[c].Add(1)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	for c := range allowed {
+		if n := allowed[c].Load(); n != 10 {
+			t.Errorf("client %d was allowed %d requests, want its burst of 10", c, n)
+		}
+	}
+}
+
+// TestRateLimiterConcurrentClock also moves the clock from other goroutines,
+// as the sweep and refill read it under the limiter's lock.
+func TestRateLimiterConcurrentClock(t *testing.T) {
+	var nanos atomic.Int64
+	l := newRateLimiter(1000, 1, func() time.Time { return time.Unix(0, nanos.Load()) })
+
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				nanos.Add(int64(time.Microsecond))
+				l.allow(fmt.Sprint("client", (g*i)%32))
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newRateLimiter(2, 3, func() time.Time { return now })
+
+	for i := 0; i < 3; i++ {
+		if _, ok := l.allow("a"); !ok {
+			t.Fatalf("request %d of a burst of 3 was refused", i+1)
+		}
+	}
+	wait, ok := l.allow("a")
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("fourth request = %v, %v, want refused for 500ms", wait, ok)
+	}
+	if _, ok := l.allow("b"); !ok {
+		t.Error("another client was refused")
+	}
+
+	now = now.Add(250 * time.Millisecond)
+	if wait, ok := l.allow("a"); ok || wait != 250*time.Millisecond {
+		t.Errorf("request halfway to a token = %v, %v, want refused for 250ms", wait, ok)
+	}
+	now = now.Add(250 * time.Millisecond)
+	if _, ok := l.allow("a"); !ok {
+		t.Error("request once a token refilled was refused")
+	}
+
+	// A bucket refills to its burst and no further.
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if _, ok := l.allow("a"); !ok {
+			t.Fatalf("request %d after an hour was refused", i+1)
+		}
+	}
+	if _, ok := l.allow("a"); ok {
+		t.Error("a bucket filled past its burst")
+	}
+}
+
+func TestRateLimitedRequest(t *testing.T) {
+	s := New(Options{RequestsPerSecond: 0.5, Burst: 1})
+	defer s.Close()
+
+	post := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/roll", strings.NewReader(`{"expression": "1d20"}`)))
+		return w
+	}
+	if w := post(); w.Code != http.StatusOK {
+		t.Fatalf("first request = %d %s", w.Code, w.Body)
+	}
+	w := post()
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" || !strings.Contains(w.Body.String(), CodeRateLimited) {
+		t.Errorf("second request = %d %s, Retry-After %q, want 429 and 2", w.Code, w.Body, w.Header().Get("Retry-After"))
+	}
+}
@@ -0,0 +1,357 @@
+// Package server serves rolls over HTTP as JSON.
+//
+// POST /roll takes {"expression": "1d20+5"}, or a sum of dice groups such as
+// "2d6+1d8+3-1d4" or "(2d6+3)*2", and responds with the rolled rolls.Result,
+// or {"error": "..."} with a 4xx status. An optional "seed" makes the roll
+// reproducible; seeded rolls are left out of the dice stats.
+//
+// NewGRPC serves the same rolls over gRPC as the rollpb.RollService of
+// proto/roll/v1/roll.proto.
+//
+// A request carrying an Idempotency-Key header is rolled at most once per
+// key: retries within Options.IdempotencyTTL, including concurrent ones,
+// replay the first response with an Idempotent-Replayed: true header. Reusing
+// a key for a different expression or seed is rejected with 422. A 5xx
+// response, such as a roll cut short by its request being cancelled, is not
+// replayed: the key is released so the next request with it rolls afresh.
+//
+// GET /events streams every roll made on the server as server-sent events,
+// and each of Options.Webhooks receives the same events as POSTed JSON. See
+// the hub type for ordering guarantees.
+//
+// GET /metrics reports dice group, parse error, latency and /events client
+// metrics in the Prometheus text format, and GET /metrics/dice the server's
+// dice stats as JSON, a rolls.RollStats, since it started.
+//
+// Every client IP is rate limited with a token bucket and each request is
+// held to a dice budget, both set through Options. Rejections use 413, 422
+// or 429 (with Retry-After) and a JSON body with an error code.
+//
+// Trust model: the server operator controls the random source and sees every
+// result, so players trust the operator not to predict, choose or leak
+// rolls. Anyone who presents a key within its TTL receives the cached result,
+// so clients should use unguessable keys such as random UUIDs.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Domo929/roll/pkg/rolls"
+)
+
+// Options configures a Server. Zero fields use the matching default.
+type Options struct {
+	// IdempotencyTTL is how long a response is replayed for its
+	// Idempotency-Key.
+	IdempotencyTTL time.Duration
+
+	// RequestsPerSecond is the rate each client IP's bucket refills at, and
+	// Burst its size. A negative RequestsPerSecond disables rate limiting.
+	RequestsPerSecond float64
+	Burst             int
+
+	// MaxDice bounds the dice of a single request, across all its groups,
+	// MaxSides the sides of each and MaxExpressionLength the length of its
+	// expression.
+	MaxDice             int
+	MaxSides            int
+	MaxExpressionLength int
+
+	// MaxBodyBytes bounds the size of a request body.
+	MaxBodyBytes int64
+
+	// MaxTrials bounds the trials of a single gRPC Simulate call.
+	MaxTrials int
+
+	// Webhooks are URLs that every roll is POSTed to.
+	Webhooks []string
+}
+
+const (
+	DefaultIdempotencyTTL      = 24 * time.Hour
+	DefaultRequestsPerSecond   = 5
+	DefaultBurst               = 10
+	DefaultMaxDice             = 100
+	DefaultMaxSides            = 1000
+	DefaultMaxExpressionLength = 64
+	DefaultMaxBodyBytes        = 4 << 10
+	DefaultMaxTrials           = 100000
+)
+
+func (o *Options) setDefaults() {
+	if o.IdempotencyTTL == 0 {
+		o.IdempotencyTTL = DefaultIdempotencyTTL
+	}
+	if o.RequestsPerSecond == 0 {
+		o.RequestsPerSecond = DefaultRequestsPerSecond
+	}
+	if o.Burst == 0 {
+		o.Burst = DefaultBurst
+	}
+	if o.MaxDice == 0 {
+		o.MaxDice = DefaultMaxDice
+	}
+	if o.MaxSides == 0 {
+		o.MaxSides = DefaultMaxSides
+	}
+	if o.MaxExpressionLength == 0 {
+		o.MaxExpressionLength = DefaultMaxExpressionLength
+	}
+	if o.MaxBodyBytes == 0 {
+		o.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if o.MaxTrials == 0 {
+		o.MaxTrials = DefaultMaxTrials
+	}
+}
+
+// Error codes reported in error responses.
+const (
+	CodeBadRequest          = "bad_request"
+	CodeInvalidExpression   = "invalid_expression"
+	CodeOverBudget          = "over_budget"
+	CodeRateLimited         = "rate_limited"
+	CodeTooLarge            = "request_too_large"
+	CodeIdempotencyConflict = "idempotency_conflict"
+	CodeUnavailable         = "unavailable"
+)
+
+// Server handles roll requests.
+type Server struct {
+	opts    Options
+	idem    *idempotencyStore
+	limiter *rateLimiter
+	hub     *hub
+	metrics *metrics
+	roller  *rolls.Roller
+	mux     *http.ServeMux
+	stop    context.CancelFunc
+}
+
+// New returns a Server configured by opts.
+func New(opts Options) *Server {
+	opts.setDefaults()
+	ctx, stop := context.WithCancel(context.Background())
+	s := &Server{
+		opts: opts,
+		idem: newIdempotencyStore(opts.IdempotencyTTL, time.Now),
+		hub:  newHub(),
+		mux:  http.NewServeMux(),
+		stop: stop,
+	}
+	s.metrics = newMetrics(s.hub.clients)
+	s.roller = rolls.NewRoller(rolls.WithObserver(s.hub), rolls.WithObserver(s.metrics), rolls.WithStats())
+	if opts.RequestsPerSecond > 0 {
+		s.limiter = newRateLimiter(opts.RequestsPerSecond, opts.Burst, time.Now)
+	}
+	for _, url := range opts.Webhooks {
+		go s.runWebhook(ctx, url)
+	}
+	s.mux.HandleFunc("/roll", s.handleRoll)
+	s.mux.HandleFunc("/events", s.handleEvents)
+	s.mux.HandleFunc("/metrics", s.handleMetrics)
+	s.mux.HandleFunc("/metrics/dice", s.handleDiceStats)
+	return s
+}
+
+// Close stops delivering webhooks.
+func (s *Server) Close() error {
+	s.stop()
+	return nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+type rollRequest struct {
+	Expression string `json:"expression"`
+	// Seed, when set, makes the roll reproducible.
+	Seed *int64 `json:"seed,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+func (s *Server) handleRoll(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	defer func() { s.metrics.observeLatency(time.Since(start)) }()
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "use POST", Code: CodeBadRequest})
+		return
+	}
+	if s.limiter != nil {
+		if wait, ok := s.limiter.allow(clientIP(r)); !ok {
+			w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+			writeJSON(w, http.StatusTooManyRequests, errorResponse{Error: "too many requests", Code: CodeRateLimited})
+			return
+		}
+	}
+
+	var req rollRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.opts.MaxBodyBytes)).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, errorResponse{Error: err.Error(), Code: CodeTooLarge})
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request body: " + err.Error(), Code: CodeBadRequest})
+		return
+	}
+
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" {
+		status, body := s.roll(r, req.Expression, req.Seed)
+		writeRaw(w, status, body)
+		return
+	}
+
+	for {
+		e, owner, err := s.idem.begin(key, req)
+		if err != nil {
+			writeJSON(w, http.StatusUnprocessableEntity, errorResponse{Error: err.Error(), Code: CodeIdempotencyConflict})
+			return
+		}
+		if owner {
+			status, body := s.roll(r, req.Expression, req.Seed)
+			if status >= http.StatusInternalServerError {
+				// The roll was not made, so a retry should make it rather
+				// than replay the failure.
+				s.idem.release(key, e)
+			} else {
+				s.idem.finish(e, status, body)
+			}
+			writeRaw(w, status, body)
+			return
+		}
+		select {
+		case <-e.done:
+		case <-r.Context().Done():
+			return
+		}
+		if e.released {
+			continue
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		writeRaw(w, e.status, e.body)
+		return
+	}
+}
+
+// roll rolls expr for an HTTP request and returns the response status and
+// body.
+func (s *Server) roll(r *http.Request, expr string, seed *int64) (int, []byte) {
+	res, err := s.rollExpression(r.Context(), expr, seed)
+	var refused *rollError
+	if errors.As(err, &refused) {
+		return marshal(refused.status, errorResponse{Error: refused.Error(), Code: refused.code})
+	}
+	return marshal(http.StatusOK, res)
+}
+
+// rollError is a roll the server refused or could not make, with the HTTP
+// status and error code it is reported under.
+type rollError struct {
+	status int
+	code   string
+	err    error
+}
+
+func (e *rollError) Error() string {
+	return e.err.Error()
+}
+
+func (e *rollError) Unwrap() error {
+	return e.err
+}
+
+// rollExpression checks expr against the request budget and rolls it, from
+// seed when one is given. It is the core both the HTTP and gRPC APIs roll
+// through, and every error it returns is a *rollError.
+func (s *Server) rollExpression(ctx context.Context, expr string, seed *int64) (*rolls.Result, error) {
+	e, err := s.parseBudgeted(expr)
+	if err != nil {
+		return nil, err
+	}
+	roller := s.roller
+	if seed != nil {
+		// Seeded rolls are chosen by the client, so they are left out of
+		// the dice stats.
+		roller = rolls.NewRoller(rolls.WithSeed(*seed), rolls.WithObserver(s.hub), rolls.WithObserver(s.metrics))
+	}
+	res, err := roller.RollExpressionContext(ctx, e)
+	if err != nil {
+		return nil, &rollError{http.StatusServiceUnavailable, CodeUnavailable, err}
+	}
+	return res, nil
+}
+
+// parseBudgeted parses expr and checks it against the request budget.
+func (s *Server) parseBudgeted(expr string) (*rolls.Expression, error) {
+	if len(expr) > s.opts.MaxExpressionLength {
+		return nil, &rollError{http.StatusUnprocessableEntity, CodeOverBudget,
+			fmt.Errorf("expression is longer than %d characters", s.opts.MaxExpressionLength)}
+	}
+	e, err := rolls.ParseExpression(expr)
+	var limit *rolls.LimitError
+	if errors.As(err, &limit) {
+		return nil, &rollError{http.StatusUnprocessableEntity, CodeOverBudget, err}
+	}
+	if err != nil {
+		s.metrics.parseErrors.Add(1)
+		return nil, &rollError{http.StatusBadRequest, CodeInvalidExpression, err}
+	}
+	dice, sides := 0, 0
+	for _, d := range e.AllDice() {
+		dice, sides = dice+d.Count, max(sides, d.Sides)
+	}
+	if dice > s.opts.MaxDice || sides > s.opts.MaxSides {
+		return nil, &rollError{http.StatusUnprocessableEntity, CodeOverBudget,
+			fmt.Errorf("%s is over the budget of %d dice of at most %d sides", expr, s.opts.MaxDice, s.opts.MaxSides)}
+	}
+	return e, nil
+}
+
+// clientIP is the address the request came from. Forwarding headers are not
+// trusted, so servers behind a proxy see the proxy's address.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func marshal(status int, v interface{}) (int, []byte) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		body, _ = json.Marshal(errorResponse{Error: err.Error(), Code: CodeUnavailable})
+		return http.StatusInternalServerError, body
+	}
+	return status, body
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	status, body := marshal(status, v)
+	writeRaw(w, status, body)
+}
+
+// writeRaw writes body and a newline. body may be a cached response
+// replayed to many requests at once, so it is not appended to.
+func writeRaw(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+	w.Write([]byte("\n"))
+}